@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/Mario-Kamel/EKMS/pkg/storage"
+)
+
+// getAttachmentStore returns the store for uploaded files. ATTACHMENT_STORE
+// selects "gridfs" (the default) or "local", which keeps the files in
+// ATTACHMENT_DIR.
+func getAttachmentStore(client *mongo.Client) (storage.Store, error) {
+	switch backend := envString("ATTACHMENT_STORE", "gridfs"); backend {
+	case "gridfs":
+		return storage.NewGridFSStore(client.Database("ekms"), envString("ATTACHMENT_BUCKET", "attachments"))
+	case "local":
+		return storage.NewFileStore(envString("ATTACHMENT_DIR", "./attachments"))
+	default:
+		return nil, fmt.Errorf("unknown attachment store %q", backend)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// runExport implements the "export" command:
+//
+//	ekms export -entity services -format xlsx -from 2023-01-01 -out services.xlsx
+func runExport(svc *service.ExportService, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	entity := fs.String("entity", "persons", "what to export: persons, services or assignments")
+	format := fs.String("format", service.ExportFormatCSV, "output format: csv, xlsx or ndjson")
+	columns := fs.String("columns", "", "comma separated list of columns, all columns when empty")
+	out := fs.String("out", "", "output file, standard output when empty")
+	fr := fs.String("fr", "", "persons: only persons with this confession father")
+	degree := fs.String("degree", "", "persons: only persons with this degree")
+	speaker := fs.String("speaker", "", "services: only services given by this speaker")
+	serviceID := fs.String("service", "", "assignments: only assignments of this service id")
+	from := fs.String("from", "", "services and assignments: start date (2006-01-02)")
+	to := fs.String("to", "", "services and assignments: end date (2006-01-02)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var cols []string
+	if *columns != "" {
+		cols = strings.Split(*columns, ",")
+	}
+
+	var fromDate, toDate time.Time
+	var err error
+	if *from != "" {
+		if fromDate, err = time.Parse("2006-01-02", *from); err != nil {
+			return err
+		}
+	}
+	if *to != "" {
+		if toDate, err = time.Parse("2006-01-02", *to); err != nil {
+			return err
+		}
+		toDate = toDate.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	ctx := context.Background()
+	switch *entity {
+	case "persons":
+		err = svc.ExportPersons(ctx, w, *format, cols, repositories.PersonFilter{Fr: *fr, Degree: *degree})
+	case "services":
+		err = svc.ExportServices(ctx, w, *format, cols, repositories.ServiceFilter{From: fromDate, To: toDate, Speaker: *speaker})
+	case "assignments":
+		filter := repositories.AssignmentFilter{DeadlineFrom: fromDate, DeadlineTo: toDate}
+		if *serviceID != "" {
+			if filter.ServiceID, err = primitive.ObjectIDFromHex(*serviceID); err != nil {
+				return err
+			}
+		}
+		err = svc.ExportAssignments(ctx, w, *format, cols, filter)
+	default:
+		return fmt.Errorf("unknown entity %q", *entity)
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+)
+
+// runMatchFathers implements the "match-fathers" command, which links the
+// free-text confession fathers of persons to Father entities:
+//
+//	ekms match-fathers -apply -create
+//
+// Without -apply it only prints the proposed matches.
+func runMatchFathers(svc *service.FatherService, args []string) error {
+	fs := flag.NewFlagSet("match-fathers", flag.ContinueOnError)
+	apply := fs.Bool("apply", false, "link the matched persons and record the spellings as aliases")
+	create := fs.Bool("create", false, "with -apply, create a father for every unmatched spelling")
+	minScore := fs.Float64("min", service.DefaultFatherMatchScore, "minimum name similarity for a match")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	matches, err := svc.MatchFr(context.Background(), *apply, *create, *minScore)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(matches)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+)
+
+// registerJobs registers the background jobs. Their schedules can be changed
+// with the environment variables named below, in cron syntax.
+func registerJobs(scheduler *service.Scheduler, notifications *service.NotificationService, birthdays *service.BirthdayService, reminders *service.AssignmentReminderService, attachments *service.AttachmentService, schedules *service.ScheduleService, curricula *service.CurriculumService) {
+	jobs := []struct {
+		name  string
+		env   string
+		spec  string
+		lease time.Duration
+		run   service.JobFunc
+	}{
+		{"notification-retries", "NOTIFICATION_RETRY_SCHEDULE", "* * * * *", 5 * time.Minute, func(ctx context.Context) error {
+			_, err := notifications.RetryDue(ctx)
+			return err
+		}},
+		{"birthday-reminders", "BIRTHDAY_REMINDER_SCHEDULE", "0 7 * * *", 30 * time.Minute, func(ctx context.Context) error {
+			_, err := birthdays.SendReminders(ctx, time.Now())
+			return err
+		}},
+		{"assignment-reminders", "ASSIGNMENT_REMINDER_SCHEDULE", "*/15 * * * *", 10 * time.Minute, func(ctx context.Context) error {
+			run, err := reminders.SendDue(ctx, time.Now())
+			if run != nil && len(run.Skipped) > 0 {
+				log.Printf("assignment-reminders: skipped %d assignments whose service has no group", len(run.Skipped))
+			}
+			return err
+		}},
+		{"service-generation", "SERVICE_GENERATION_SCHEDULE", "0 2 * * *", 30 * time.Minute, func(ctx context.Context) error {
+			_, err := schedules.GenerateServices(ctx, time.Now())
+			return err
+		}},
+		{"curriculum-scheduling", "CURRICULUM_SCHEDULING_SCHEDULE", "30 2 * * *", 30 * time.Minute, func(ctx context.Context) error {
+			_, err := curricula.RescheduleAll(ctx, time.Now())
+			return err
+		}},
+		{"attachment-cleanup", "ATTACHMENT_CLEANUP_SCHEDULE", "30 3 * * *", 30 * time.Minute, func(ctx context.Context) error {
+			_, err := attachments.CleanupOrphans(ctx)
+			return err
+		}},
+	}
+	for _, job := range jobs {
+		if err := scheduler.Register(job.name, envString(job.env, job.spec), job.lease, job.run); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"github.com/Mario-Kamel/EKMS/pkg/vault"
+)
+
+// getKeyring reads the keys data is encrypted with at rest from
+// ENCRYPTION_KEYS, "id:base64key,...", each key 32 bytes. The first key
+// encrypts new data; keep the older ones listed after it until nothing is
+// encrypted with them anymore.
+func getKeyring() (*vault.Keyring, error) {
+	keys := os.Getenv("ENCRYPTION_KEYS")
+	if keys == "" {
+		return nil, errors.New("ENCRYPTION_KEYS is not set; generate a key with `openssl rand -base64 32` and set it as ENCRYPTION_KEYS=1:<key>")
+	}
+	return vault.ParseKeyring(keys)
+}
+
+// getBlindIndex reads the key of the blind indexes used to look persons up
+// by phone from BLIND_INDEX_KEY, base64, at least 32 bytes. Unlike the
+// encryption keys it cannot be changed without running encrypt to index
+// everyone again.
+func getBlindIndex() (*vault.Index, error) {
+	encoded := os.Getenv("BLIND_INDEX_KEY")
+	if encoded == "" {
+		return nil, errors.New("BLIND_INDEX_KEY is not set; generate a key with `openssl rand -base64 32`")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("BLIND_INDEX_KEY: %v", err)
+	}
+	return vault.NewIndex(key)
+}
+
+// runEncrypt encrypts the persons and notifications stored before
+// encryption, and the persons, notes and notifications sealed with an older
+// key after ENCRYPTION_KEYS got a new first key. Older keys can be dropped
+// once it ran. It also indexes again the phones indexed before the index
+// used normalized phones.
+func runEncrypt(persons *repositories.PersonRepo, notes *repositories.NoteRepo, notifications *repositories.NotificationRepo) error {
+	n, err := persons.EncryptPersons(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d persons encrypted\n", n)
+	n, err = notes.ResealNotes(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d notes encrypted\n", n)
+	n, err = notifications.ResealNotifications(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d notifications encrypted\n", n)
+	return nil
+}
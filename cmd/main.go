@@ -6,6 +6,8 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -14,6 +16,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/Mario-Kamel/EKMS/pkg/controllers"
+	"github.com/Mario-Kamel/EKMS/pkg/notify"
 	"github.com/Mario-Kamel/EKMS/pkg/repositories"
 	"github.com/Mario-Kamel/EKMS/pkg/service"
 )
@@ -105,40 +108,301 @@ func main() {
 
 	// fmt.Println("Added this\n", service, err)
 
-	personRepo := repositories.NewPersonRepo(client)
-	personService := service.NewPersonService(personRepo)
+	keyring, err := getKeyring()
+	if err != nil {
+		log.Fatal(err)
+	}
+	blindIndex, err := getBlindIndex()
+	if err != nil {
+		log.Fatal(err)
+	}
+	personRepo := repositories.NewPersonRepo(client, keyring, blindIndex)
+	fatherRepo := repositories.NewFatherRepo(client)
+	personService := service.NewPersonService(personRepo, fatherRepo)
 	personController := controllers.NewPersonController(personService)
 
 	serviceRepo := repositories.NewServiceRepo(client)
-	serviceService := service.NewServiceService(serviceRepo)
+	assignmentRepo := repositories.NewAssignmentRepo(client)
+	groupRepo := repositories.NewGroupRepo(client)
+
+	pointsRepo := repositories.NewPointsRepo(client)
+	pointsService := service.NewPointsService(pointsRepo, serviceRepo, assignmentRepo, personRepo, groupRepo)
+	pointsController := controllers.NewPointsController(pointsService)
+
+	timeZone := envString("SCHEDULE_TIMEZONE", "Africa/Cairo")
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		log.Fatal(err)
+	}
+	calendarService := service.NewCalendarFeedService(repositories.NewCalendarFeedRepo(client), serviceRepo, assignmentRepo, groupRepo, personRepo)
+	calendarController := controllers.NewCalendarController(calendarService, loc)
+
+	curriculumService := service.NewCurriculumService(repositories.NewCurriculumRepo(client), serviceRepo)
+	curriculumController := controllers.NewCurriculumController(curriculumService)
+
+	serviceService := service.NewServiceService(serviceRepo, pointsService, curriculumService, loc)
 	serviceController := controllers.NewServiceController(serviceService)
+	bibleController := controllers.NewBibleController(service.NewBibleService(serviceRepo))
 
-	assignmentRepo := repositories.NewAssignmentRepo(client)
-	assignmentService := service.NewAssignmentService(assignmentRepo)
+	scheduleService := service.NewScheduleService(repositories.NewScheduleRepo(client), serviceRepo, curriculumService, envDays("SCHEDULE_HORIZON_DAYS", 28), timeZone)
+	scheduleController := controllers.NewScheduleController(scheduleService)
+
+	speakerService := service.NewSpeakerService(repositories.NewSpeakerRepo(client), serviceRepo, personRepo)
+	speakerController := controllers.NewSpeakerController(speakerService)
+
+	store, err := getAttachmentStore(client)
+	if err != nil {
+		log.Fatal(err)
+	}
+	attachmentService := service.NewAttachmentService(repositories.NewAttachmentRepo(client), assignmentRepo, store, int64(envInt("ATTACHMENT_MAX_MB", 10))<<20, envList("ATTACHMENT_TYPES"))
+	attachmentController := controllers.NewAttachmentController(attachmentService)
+	assignmentService := service.NewAssignmentService(assignmentRepo, personRepo, attachmentService, pointsService)
 	assignmentController := controllers.NewAssignmentController(assignmentService)
 
+	quizRepo := repositories.NewQuizRepo(client)
+	quizService := service.NewQuizService(quizRepo, serviceRepo, assignmentRepo, personRepo)
+	quizController := controllers.NewQuizController(quizService)
+
+	groupService := service.NewGroupService(groupRepo, personRepo, serviceRepo)
+	groupController := controllers.NewGroupController(groupService)
+
+	householdRepo := repositories.NewHouseholdRepo(client)
+	householdService := service.NewHouseholdService(householdRepo, personRepo, serviceRepo, groupRepo)
+	householdController := controllers.NewHouseholdController(householdService)
+
+	exportService := service.NewExportService(personRepo, serviceRepo, assignmentRepo)
+	exportController := controllers.NewExportController(exportService)
+
+	reportFonts, err := getReportFonts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	reportService := service.NewReportService(personRepo, serviceRepo, assignmentRepo, groupRepo, reportFonts, loc)
+	reportController := controllers.NewReportController(reportService)
+
+	fatherService := service.NewFatherService(fatherRepo, personRepo, envDays("CONFESSION_PERIOD_DAYS", 90))
+	fatherController := controllers.NewFatherController(fatherService)
+
+	authService := service.NewAuthService(repositories.NewAccessTokenRepo(client), personRepo, adminToken())
+	authController := controllers.NewAuthController(authService)
+
+	noteRepo := repositories.NewNoteRepo(client, keyring)
+	noteService := service.NewNoteService(noteRepo, personRepo, groupRepo, fatherRepo)
+	noteController := controllers.NewNoteController(noteService)
+
+	drivers, err := getNotificationDrivers()
+	if err != nil {
+		log.Fatal(err)
+	}
+	templates := notify.NewTemplates(envString("NOTIFY_LANGUAGE", notify.LanguageArabic))
+	notificationRepo := repositories.NewNotificationRepo(client, keyring)
+	notificationService := service.NewNotificationService(notificationRepo, personRepo, householdService, drivers, strings.Split(envString("NOTIFY_CHANNELS", "whatsapp,sms,email"), ","), templates)
+	notificationController := controllers.NewNotificationController(notificationService)
+
+	privacyService := service.NewPrivacyService(personRepo, serviceRepo, assignmentRepo, quizRepo, pointsRepo, notificationRepo, fatherRepo, groupRepo, householdRepo, noteRepo, attachmentService, personService)
+	privacyController := controllers.NewPrivacyController(privacyService)
+
+	birthdayService := service.NewBirthdayService(personRepo, groupRepo, notificationService)
+	birthdayController := controllers.NewBirthdayController(birthdayService)
+
+	reminderService := service.NewAssignmentReminderService(assignmentRepo, serviceRepo, personRepo, groupRepo, notificationService, envInts("ASSIGNMENT_REMINDER_HOURS", []int{24}), loc)
+	reminderController := controllers.NewAssignmentReminderController(reminderService)
+
+	scheduler := service.NewScheduler(repositories.NewJobRepo(client))
+	jobController := controllers.NewJobController(scheduler)
+	registerJobs(scheduler, notificationService, birthdayService, reminderService, attachmentService, scheduleService, curriculumService)
+
+	if len(os.Args) > 1 {
+		var err error
+		switch os.Args[1] {
+		case "export":
+			err = runExport(exportService, os.Args[2:])
+		case "match-fathers":
+			err = runMatchFathers(fatherService, os.Args[2:])
+		case "encrypt":
+			err = runEncrypt(personRepo, noteRepo, notificationRepo)
+		default:
+			log.Fatalf("unknown command %q", os.Args[1])
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	r := mux.NewRouter()
 	r.HandleFunc("/persons", personController.GetAllPersons).Methods("GET")
+	r.HandleFunc("/persons/export", authController.Authenticated(exportController.ExportPersons)).Methods("GET")
+	r.HandleFunc("/persons/birthdays", birthdayController.UpcomingBirthdays).Methods("GET")
+	r.HandleFunc("/persons/search", personController.SearchPersons).Methods("GET")
+	r.HandleFunc("/persons/duplicates", personController.FindDuplicates).Methods("GET")
+	r.HandleFunc("/persons/merge", personController.MergePersons).Methods("POST")
 	r.HandleFunc("/persons/{id}", personController.GetPersonById).Methods("GET")
 	r.HandleFunc("/persons", personController.CreatePerson).Methods("POST")
+	r.HandleFunc("/persons/import", personController.ImportPersons).Methods("POST")
 	r.HandleFunc("/persons/{id}", personController.UpdatePerson).Methods("PUT")
 	r.HandleFunc("/persons/{id}", personController.DeletePerson).Methods("DELETE")
+	r.HandleFunc("/persons/{id}/profile.pdf", authController.Authenticated(reportController.PersonProfile)).Methods("GET")
+	r.HandleFunc("/persons/{id}/groups", groupController.GetPersonGroups).Methods("GET")
+	r.HandleFunc("/persons/{id}/relationships", householdController.GetPersonRelations).Methods("GET")
+	r.HandleFunc("/persons/{id}/guardians", householdController.GuardianContacts).Methods("GET")
+	r.HandleFunc("/persons/{id}/confessions", fatherController.GetPersonConfessions).Methods("GET")
+	r.HandleFunc("/persons/{id}/confessions", fatherController.AddConfession).Methods("POST")
+	r.HandleFunc("/persons/{id}/notifications", authController.Authenticated(notificationController.NotifyPerson)).Methods("POST")
+	r.HandleFunc("/persons/{id}/points", pointsController.GetBalance).Methods("GET")
+	r.HandleFunc("/persons/{id}/tokens", authController.Authenticated(authController.GetPersonTokens)).Methods("GET")
+	r.HandleFunc("/persons/{id}/tokens", authController.Authenticated(authController.IssueToken)).Methods("POST")
+	r.HandleFunc("/tokens/{id}", authController.Authenticated(authController.RevokeToken)).Methods("DELETE")
+	r.HandleFunc("/persons/{id}/notes", authController.Authenticated(noteController.GetPersonNotes)).Methods("GET")
+	r.HandleFunc("/persons/{id}/notes", authController.Authenticated(noteController.CreateNote)).Methods("POST")
+	r.HandleFunc("/persons/{id}/notes/reads", authController.Authenticated(noteController.GetPersonNoteReads)).Methods("GET")
+	r.HandleFunc("/persons/{id}/data-export", authController.Authenticated(privacyController.ExportPersonData)).Methods("GET")
+	r.HandleFunc("/persons/{id}/erase", authController.Authenticated(privacyController.ErasePerson)).Methods("POST")
+	r.HandleFunc("/notes/{id}", authController.Authenticated(noteController.GetNoteById)).Methods("GET")
+	r.HandleFunc("/notes/{id}", authController.Authenticated(noteController.UpdateNote)).Methods("PUT")
+	r.HandleFunc("/notes/{id}", authController.Authenticated(noteController.DeleteNote)).Methods("DELETE")
+	r.HandleFunc("/notes/{id}/reads", authController.Authenticated(noteController.GetNoteReads)).Methods("GET")
+	r.HandleFunc("/persons/{id}/calendar", authController.Authenticated(calendarController.IssuePersonFeedLink)).Methods("POST")
+	r.HandleFunc("/persons/{id}/calendar", authController.Authenticated(calendarController.RevokePersonFeedLink)).Methods("DELETE")
+	r.HandleFunc("/persons/{id}/calendar.ics", calendarController.PersonFeed).Methods("GET")
+	r.HandleFunc("/persons/{id}/points/adjustments", pointsController.Adjust).Methods("POST")
+	r.HandleFunc("/persons/{id}/points/redemptions", pointsController.Redeem).Methods("POST")
 
 	r.HandleFunc("/services", serviceController.GetAllServices).Methods("GET")
+	r.HandleFunc("/services/export", authController.Authenticated(exportController.ExportServices)).Methods("GET")
+	r.HandleFunc("/services/search", serviceController.SearchByReference).Methods("GET")
+	r.HandleFunc("/services/bible-refs/reindex", serviceController.ReindexBibleRefs).Methods("POST")
 	r.HandleFunc("/services/{id}", serviceController.GetServiceById).Methods("GET")
 	r.HandleFunc("/services", serviceController.CreateService).Methods("POST")
 	r.HandleFunc("/services/{id}", serviceController.UpdateService).Methods("PUT")
 	r.HandleFunc("/services/{id}", serviceController.DeleteService).Methods("DELETE")
+	r.HandleFunc("/services/{id}/report.pdf", authController.Authenticated(reportController.ServiceReport)).Methods("GET")
+	r.HandleFunc("/services/{id}/absentees", groupController.GetServiceAbsentees).Methods("GET")
+	r.HandleFunc("/services/{id}/speaker", speakerController.AssignSpeaker).Methods("PUT")
+	r.HandleFunc("/services/{id}/speaker", speakerController.UnassignSpeaker).Methods("DELETE")
 
 	r.HandleFunc("/services/{id}/attendance", serviceController.AddAttendanceRecord).Methods("POST")
 	r.HandleFunc("/services/{id}/attendance", serviceController.EditAttendanceRecord).Methods("PUT")
 	r.HandleFunc("/services/{id}/attendance", serviceController.DeleteAttendanceRecord).Methods("DELETE")
 
+	r.HandleFunc("/schedules", scheduleController.GetAllSchedules).Methods("GET")
+	r.HandleFunc("/schedules/{id}", scheduleController.GetScheduleById).Methods("GET")
+	r.HandleFunc("/schedules", scheduleController.CreateSchedule).Methods("POST")
+	r.HandleFunc("/schedules/{id}", scheduleController.UpdateSchedule).Methods("PUT")
+	r.HandleFunc("/schedules/{id}", scheduleController.DeleteSchedule).Methods("DELETE")
+	r.HandleFunc("/schedules/{id}/occurrences", scheduleController.GetOccurrences).Methods("GET")
+	r.HandleFunc("/schedules/{id}/occurrences/{date}", scheduleController.EditOccurrence).Methods("PUT")
+	r.HandleFunc("/schedules/{id}/occurrences/{date}", scheduleController.SkipOccurrence).Methods("DELETE")
+
+	r.HandleFunc("/curricula", curriculumController.GetAllCurricula).Methods("GET")
+	r.HandleFunc("/curricula/{id}", curriculumController.GetCurriculumById).Methods("GET")
+	r.HandleFunc("/curricula", curriculumController.CreateCurriculum).Methods("POST")
+	r.HandleFunc("/curricula/{id}", curriculumController.UpdateCurriculum).Methods("PUT")
+	r.HandleFunc("/curricula/{id}", curriculumController.DeleteCurriculum).Methods("DELETE")
+	r.HandleFunc("/curricula/{id}/progress", curriculumController.Progress).Methods("GET")
+	r.HandleFunc("/curricula/{id}/reschedule", curriculumController.Reschedule).Methods("POST")
+
+	r.HandleFunc("/speakers", speakerController.GetAllSpeakers).Methods("GET")
+	r.HandleFunc("/speakers/conflicts", speakerController.Conflicts).Methods("GET")
+	r.HandleFunc("/speakers/plan", speakerController.Plan).Methods("GET", "POST")
+	r.HandleFunc("/speakers/{id}", speakerController.GetSpeakerById).Methods("GET")
+	r.HandleFunc("/speakers", speakerController.CreateSpeaker).Methods("POST")
+	r.HandleFunc("/speakers/{id}", speakerController.UpdateSpeaker).Methods("PUT")
+	r.HandleFunc("/speakers/{id}", speakerController.DeleteSpeaker).Methods("DELETE")
+	r.HandleFunc("/speakers/{id}/history", speakerController.History).Methods("GET")
+	r.HandleFunc("/speakers/{id}/history/export", speakerController.ExportHistory).Methods("GET")
+
+	r.HandleFunc("/bible/books", bibleController.GetBooks).Methods("GET")
+	r.HandleFunc("/bible/parse", bibleController.Parse).Methods("GET")
+	r.HandleFunc("/bible/coverage", bibleController.Coverage).Methods("GET")
+
+	r.HandleFunc("/calendar.ics", calendarController.Feed).Methods("GET")
+	r.HandleFunc("/calendar/date", calendarController.GetDate).Methods("GET")
+	r.HandleFunc("/calendar/{year}", calendarController.GetYear).Methods("GET")
+
+	r.HandleFunc("/groups", groupController.GetAllGroups).Methods("GET")
+	r.HandleFunc("/groups/{id}", groupController.GetGroupById).Methods("GET")
+	r.HandleFunc("/groups", groupController.CreateGroup).Methods("POST")
+	r.HandleFunc("/groups/{id}", groupController.UpdateGroup).Methods("PUT")
+	r.HandleFunc("/groups/{id}", groupController.DeleteGroup).Methods("DELETE")
+	r.HandleFunc("/groups/{id}/members", groupController.AddMember).Methods("POST")
+	r.HandleFunc("/groups/{id}/members/{personId}", groupController.RemoveMember).Methods("DELETE")
+	r.HandleFunc("/groups/{id}/roster", groupController.GetRoster).Methods("GET")
+	r.HandleFunc("/groups/{id}/services", groupController.GetGroupServices).Methods("GET")
+	r.HandleFunc("/groups/{id}/leaderboard", pointsController.Leaderboard).Methods("GET")
+	r.HandleFunc("/groups/{id}/calendar", authController.Authenticated(calendarController.IssueGroupFeedLink)).Methods("POST")
+	r.HandleFunc("/groups/{id}/calendar", authController.Authenticated(calendarController.RevokeGroupFeedLink)).Methods("DELETE")
+	r.HandleFunc("/groups/{id}/calendar.ics", calendarController.GroupFeed).Methods("GET")
+
+	r.HandleFunc("/households", householdController.GetAllHouseholds).Methods("GET")
+	r.HandleFunc("/households/{id}", householdController.GetHouseholdById).Methods("GET")
+	r.HandleFunc("/households", householdController.CreateHousehold).Methods("POST")
+	r.HandleFunc("/households/{id}", householdController.UpdateHousehold).Methods("PUT")
+	r.HandleFunc("/households/{id}", householdController.DeleteHousehold).Methods("DELETE")
+	r.HandleFunc("/households/{id}/members", householdController.AddMember).Methods("POST")
+	r.HandleFunc("/households/{id}/members/{personId}", householdController.RemoveMember).Methods("DELETE")
+	r.HandleFunc("/households/{id}/attendance", householdController.HouseholdAttendance).Methods("GET")
+
+	r.HandleFunc("/fathers", fatherController.GetAllFathers).Methods("GET")
+	r.HandleFunc("/fathers/report", fatherController.Report).Methods("GET")
+	r.HandleFunc("/fathers/match", fatherController.MatchFathers).Methods("POST")
+	r.HandleFunc("/fathers/{id}", fatherController.GetFatherById).Methods("GET")
+	r.HandleFunc("/fathers", fatherController.CreateFather).Methods("POST")
+	r.HandleFunc("/fathers/{id}", fatherController.UpdateFather).Methods("PUT")
+	r.HandleFunc("/fathers/{id}", fatherController.DeleteFather).Methods("DELETE")
+	r.HandleFunc("/fathers/{id}/persons", fatherController.GetFatherPersons).Methods("GET")
+
+	r.HandleFunc("/notifications", authController.Authenticated(notificationController.GetNotifications)).Methods("GET")
+	r.HandleFunc("/notifications/{id}", authController.Authenticated(notificationController.GetNotificationById)).Methods("GET")
+	r.HandleFunc("/notifications/{id}/retry", authController.Authenticated(notificationController.RetryNotification)).Methods("POST")
+
+	r.HandleFunc("/relationships", householdController.CreateRelationship).Methods("POST")
+	r.HandleFunc("/relationships/{id}", householdController.DeleteRelationship).Methods("DELETE")
+
 	r.HandleFunc("/assignments", assignmentController.GetAllAssignments).Methods("GET")
+	r.HandleFunc("/assignments/export", authController.Authenticated(exportController.ExportAssignments)).Methods("GET")
+	r.HandleFunc("/assignments/missing", reminderController.MissingSubmissionsReport).Methods("GET")
 	r.HandleFunc("/assignments/{id}", assignmentController.GetAssignmentById).Methods("GET")
 	r.HandleFunc("/assignments", assignmentController.CreateAssignment).Methods("POST")
 	r.HandleFunc("/assignments/{id}", assignmentController.UpdateAssignment).Methods("PUT")
 	r.HandleFunc("/assignments/{id}", assignmentController.DeleteAssignment).Methods("DELETE")
+	r.HandleFunc("/assignments/{id}/missing", reminderController.GetMissingSubmissions).Methods("GET")
+	r.HandleFunc("/assignments/{id}/submissions", assignmentController.Submit).Methods("POST")
+	r.HandleFunc("/assignments/{id}/submissions/{personId}/grade", assignmentController.GradeSubmission).Methods("PUT")
+	r.HandleFunc("/assignments/{id}/grades", assignmentController.GradeSummary).Methods("GET")
+	r.HandleFunc("/assignments/{id}/attachments", attachmentController.GetAssignmentAttachments).Methods("GET")
+	r.HandleFunc("/assignments/{id}/attachments", attachmentController.UploadAssignmentAttachment).Methods("POST")
+	r.HandleFunc("/assignments/{id}/submissions/{personId}/attachments", attachmentController.UploadSubmissionAttachment).Methods("POST")
+
+	r.HandleFunc("/attachments/{id}", attachmentController.DownloadAttachment).Methods("GET")
+	r.HandleFunc("/attachments/{id}", attachmentController.DeleteAttachment).Methods("DELETE")
+
+	r.HandleFunc("/quizzes", quizController.GetQuizzes).Methods("GET")
+	r.HandleFunc("/quizzes/{id}", quizController.GetQuizById).Methods("GET")
+	r.HandleFunc("/quizzes", quizController.CreateQuiz).Methods("POST")
+	r.HandleFunc("/quizzes/{id}", quizController.UpdateQuiz).Methods("PUT")
+	r.HandleFunc("/quizzes/{id}", quizController.DeleteQuiz).Methods("DELETE")
+	r.HandleFunc("/quizzes/{id}/paper", quizController.GetQuizPaper).Methods("GET")
+	r.HandleFunc("/quizzes/{id}/submissions", quizController.SubmitQuiz).Methods("POST")
+	r.HandleFunc("/quizzes/{id}/stats", quizController.QuizStats).Methods("GET")
+	r.HandleFunc("/quizzes/{id}/results", quizController.ExportResults).Methods("GET")
+
+	r.HandleFunc("/points/rules", pointsController.GetRules).Methods("GET")
+	r.HandleFunc("/points/rules/{id}", pointsController.GetRuleById).Methods("GET")
+	r.HandleFunc("/points/rules", pointsController.CreateRule).Methods("POST")
+	r.HandleFunc("/points/rules/{id}", pointsController.UpdateRule).Methods("PUT")
+	r.HandleFunc("/points/rules/{id}", pointsController.DeleteRule).Methods("DELETE")
+	r.HandleFunc("/points/recalculate", pointsController.Recalculate).Methods("POST")
+
+	r.HandleFunc("/jobs", jobController.GetJobs).Methods("GET")
+	r.HandleFunc("/jobs/{name}", jobController.GetJob).Methods("GET")
+	r.HandleFunc("/jobs/{name}/trigger", jobController.TriggerJob).Methods("POST")
+	r.HandleFunc("/jobs/{name}/pause", jobController.PauseJob).Methods("POST")
+	r.HandleFunc("/jobs/{name}/resume", jobController.ResumeJob).Methods("POST")
+
+	if err := scheduler.Start(context.Background()); err != nil {
+		log.Fatal(err)
+	}
 
 	server := http.Server{
 		ReadTimeout:  20 * time.Second,
@@ -161,3 +425,75 @@ func getClient() *mongo.Client {
 	fmt.Println("Connected to MongoDB!")
 	return client
 }
+
+// envString reads the environment variable name, falling back to def when it
+// is unset.
+func envString(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// envInt reads a non-negative number from the environment variable name,
+// falling back to def when it is unset or invalid.
+func envInt(name string, def int) int {
+	n, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || n < 0 {
+		return def
+	}
+	return n
+}
+
+// envInts reads a comma separated list of numbers from the environment
+// variable name, falling back to def when it is unset or invalid.
+func envInts(name string, def []int) []int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	values := []int{}
+	for _, field := range strings.Split(raw, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return def
+		}
+		values = append(values, n)
+	}
+	return values
+}
+
+// envList reads a comma separated list from the environment variable name.
+// It returns nil when the variable is unset.
+func envList(name string) []string {
+	var values []string
+	for _, field := range strings.Split(os.Getenv(name), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			values = append(values, field)
+		}
+	}
+	return values
+}
+
+// envDays reads a number of days from the environment variable name, falling
+// back to def when it is unset or invalid.
+func envDays(name string, def int) time.Duration {
+	days := envInt(name, def)
+	if days == 0 {
+		days = def
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// adminToken reads the token admins authenticate with from ADMIN_TOKEN.
+// Admins issue the access tokens persons authenticate with; without it no
+// tokens can be issued.
+func adminToken() string {
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		log.Println("ADMIN_TOKEN is not set; access tokens cannot be issued")
+	} else if len(token) < 32 {
+		log.Fatal("ADMIN_TOKEN must be at least 32 characters; generate one with `openssl rand -base64 32`")
+	}
+	return token
+}
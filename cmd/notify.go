@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+
+	"github.com/Mario-Kamel/EKMS/pkg/notify"
+)
+
+// getNotificationDrivers configures a driver for every channel with settings
+// in the environment:
+//
+//	email:    SMTP_ADDR, SMTP_USERNAME, SMTP_PASSWORD, SMTP_FROM
+//	sms:      SMS_GATEWAY_URL, SMS_GATEWAY_TOKEN, SMS_SENDER
+//	whatsapp: WHATSAPP_PHONE_NUMBER_ID, WHATSAPP_TOKEN
+//
+// With NOTIFY_FAKE_DIR set, or when no channel is configured, nothing is sent:
+// every channel writes its messages to that directory, or to the file named by
+// NOTIFY_FILE, or to standard error.
+func getNotificationDrivers() (map[string]notify.Notifier, error) {
+	channels := []string{notify.ChannelEmail, notify.ChannelSMS, notify.ChannelWhatsApp}
+
+	if dir := os.Getenv("NOTIFY_FAKE_DIR"); dir != "" {
+		fake, err := notify.NewFakeNotifier(dir)
+		if err != nil {
+			return nil, err
+		}
+		return sameDriver(channels, fake), nil
+	}
+
+	drivers := map[string]notify.Notifier{}
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		drivers[notify.ChannelEmail] = notify.NewSMTPNotifier(addr, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), os.Getenv("SMTP_FROM"))
+	}
+	if url := os.Getenv("SMS_GATEWAY_URL"); url != "" {
+		drivers[notify.ChannelSMS] = notify.NewSMSNotifier(url, os.Getenv("SMS_GATEWAY_TOKEN"), os.Getenv("SMS_SENDER"))
+	}
+	if id := os.Getenv("WHATSAPP_PHONE_NUMBER_ID"); id != "" {
+		drivers[notify.ChannelWhatsApp] = notify.NewWhatsAppNotifier(id, os.Getenv("WHATSAPP_TOKEN"))
+	}
+	if len(drivers) > 0 {
+		return drivers, nil
+	}
+
+	path := os.Getenv("NOTIFY_FILE")
+	if path == "" {
+		return sameDriver(channels, notify.NewLogNotifier(os.Stderr)), nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return sameDriver(channels, notify.NewLogNotifier(f)), nil
+}
+
+func sameDriver(channels []string, driver notify.Notifier) map[string]notify.Notifier {
+	drivers := map[string]notify.Notifier{}
+	for _, channel := range channels {
+		drivers[channel] = driver
+	}
+	return drivers
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/Mario-Kamel/EKMS/pkg/pdf"
+)
+
+const (
+	defaultReportFont     = "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf"
+	defaultReportBoldFont = "/usr/share/fonts/truetype/dejavu/DejaVuSans-Bold.ttf"
+)
+
+// getReportFonts loads the TrueType fonts PDF reports are drawn with from
+// REPORT_FONT and REPORT_BOLD_FONT, by default DejaVu Sans. The fonts need
+// the Arabic presentation forms for Arabic text to join. When the variables
+// are unset and DejaVu Sans is not installed, reports fall back to the
+// standard fonts, which have no Arabic.
+func getReportFonts() (*pdf.Fonts, error) {
+	regular, err := loadReportFont("REPORT_FONT", defaultReportFont)
+	if err != nil || regular == nil {
+		return nil, err
+	}
+	bold, err := loadReportFont("REPORT_BOLD_FONT", defaultReportBoldFont)
+	if err != nil {
+		return nil, err
+	}
+	return &pdf.Fonts{Regular: regular, Bold: bold}, nil
+}
+
+func loadReportFont(name, def string) (*pdf.Font, error) {
+	path := os.Getenv(name)
+	if path == "" {
+		path = def
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			log.Printf("%s is not set and %s does not exist; reports cannot show Arabic text", name, path)
+			return nil, nil
+		}
+	}
+	return pdf.LoadFont(path)
+}
@@ -0,0 +1,129 @@
+package bible
+
+const (
+	OldTestament = "old"
+	Deuterocanon = "deuterocanon"
+	NewTestament = "new"
+)
+
+// Book is a book of the Bible as read in the Coptic church, identified by
+// its USFM code.
+type Book struct {
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	NameAr    string `json:"nameAr"`
+	Testament string `json:"testament"`
+	Chapters  int    `json:"chapters"`
+	aliases   []string
+}
+
+var books = []Book{
+	{"GEN", "Genesis", "التكوين", OldTestament, 50, []string{"gen", "gn", "ge"}},
+	{"EXO", "Exodus", "الخروج", OldTestament, 40, []string{"ex", "exo", "exod"}},
+	{"LEV", "Leviticus", "اللاويين", OldTestament, 27, []string{"lev", "lv", "le", "لاويين"}},
+	{"NUM", "Numbers", "العدد", OldTestament, 36, []string{"num", "nm", "nu", "اعداد"}},
+	{"DEU", "Deuteronomy", "التثنية", OldTestament, 34, []string{"deut", "deu", "dt"}},
+	{"JOS", "Joshua", "يشوع", OldTestament, 24, []string{"josh", "jos", "jsh"}},
+	{"JDG", "Judges", "القضاة", OldTestament, 21, []string{"judg", "jdg", "jg", "jdgs"}},
+	{"RUT", "Ruth", "راعوث", OldTestament, 4, []string{"ru", "rth", "rut"}},
+	{"1SA", "1 Samuel", "صموئيل الأول", OldTestament, 31, []string{"1 sam", "1 sa", "1 sm", "1 kingdoms"}},
+	{"2SA", "2 Samuel", "صموئيل الثاني", OldTestament, 24, []string{"2 sam", "2 sa", "2 sm", "2 kingdoms"}},
+	{"1KI", "1 Kings", "الملوك الأول", OldTestament, 22, []string{"1 kgs", "1 ki", "1 kin", "3 kingdoms"}},
+	{"2KI", "2 Kings", "الملوك الثاني", OldTestament, 25, []string{"2 kgs", "2 ki", "2 kin", "4 kingdoms"}},
+	{"1CH", "1 Chronicles", "أخبار الأيام الأول", OldTestament, 29, []string{"1 chr", "1 chron", "1 ch", "1 paralipomenon", "الأيام الأول"}},
+	{"2CH", "2 Chronicles", "أخبار الأيام الثاني", OldTestament, 36, []string{"2 chr", "2 chron", "2 ch", "2 paralipomenon", "الأيام الثاني"}},
+	{"EZR", "Ezra", "عزرا", OldTestament, 10, []string{"ezr", "ezra"}},
+	{"NEH", "Nehemiah", "نحميا", OldTestament, 13, []string{"neh", "ne"}},
+	{"EST", "Esther", "أستير", OldTestament, 10, []string{"est", "esth", "es"}},
+	{"JOB", "Job", "أيوب", OldTestament, 42, []string{"jb"}},
+	{"PSA", "Psalms", "المزامير", OldTestament, 151, []string{"ps", "psa", "psalm", "pss", "psm", "مزمور", "مز"}},
+	{"PRO", "Proverbs", "الأمثال", OldTestament, 31, []string{"prov", "pro", "prv", "pr", "أم"}},
+	{"ECC", "Ecclesiastes", "الجامعة", OldTestament, 12, []string{"eccl", "ecc", "eccles", "qoheleth", "جا"}},
+	{"SNG", "Song of Songs", "نشيد الأنشاد", OldTestament, 8, []string{"song", "sng", "song of solomon", "canticles", "cant", "sos", "نش"}},
+	{"ISA", "Isaiah", "إشعياء", OldTestament, 66, []string{"isa", "is", "اش"}},
+	{"JER", "Jeremiah", "إرميا", OldTestament, 52, []string{"jer", "je", "jr", "ار"}},
+	{"LAM", "Lamentations", "مراثي إرميا", OldTestament, 5, []string{"lam", "la", "مراثي", "مرا"}},
+	{"EZK", "Ezekiel", "حزقيال", OldTestament, 48, []string{"ezek", "eze", "ezk", "حز"}},
+	{"DAN", "Daniel", "دانيال", OldTestament, 14, []string{"dan", "da", "dn", "دا"}},
+	{"HOS", "Hosea", "هوشع", OldTestament, 14, []string{"hos", "ho"}},
+	{"JOL", "Joel", "يوئيل", OldTestament, 3, []string{"joe", "jl", "jol"}},
+	{"AMO", "Amos", "عاموس", OldTestament, 9, []string{"am", "amo"}},
+	{"OBA", "Obadiah", "عوبديا", OldTestament, 1, []string{"obad", "ob", "oba"}},
+	{"JON", "Jonah", "يونان", OldTestament, 4, []string{"jon", "jnh", "jonas"}},
+	{"MIC", "Micah", "ميخا", OldTestament, 7, []string{"mic", "mc"}},
+	{"NAM", "Nahum", "ناحوم", OldTestament, 3, []string{"nah", "na", "nam"}},
+	{"HAB", "Habakkuk", "حبقوق", OldTestament, 3, []string{"hab", "hb"}},
+	{"ZEP", "Zephaniah", "صفنيا", OldTestament, 3, []string{"zeph", "zep", "zp"}},
+	{"HAG", "Haggai", "حجي", OldTestament, 2, []string{"hag", "hg"}},
+	{"ZEC", "Zechariah", "زكريا", OldTestament, 14, []string{"zech", "zec", "zc"}},
+	{"MAL", "Malachi", "ملاخي", OldTestament, 4, []string{"mal", "ml"}},
+	{"TOB", "Tobit", "طوبيا", Deuterocanon, 14, []string{"tob", "tb", "tobias"}},
+	{"JDT", "Judith", "يهوديت", Deuterocanon, 16, []string{"jdt", "jdth", "jth"}},
+	{"WIS", "Wisdom", "الحكمة", Deuterocanon, 19, []string{"wis", "ws", "wisdom of solomon", "حكمة سليمان"}},
+	{"SIR", "Sirach", "يشوع بن سيراخ", Deuterocanon, 51, []string{"sir", "ecclesiasticus", "ben sira", "سيراخ"}},
+	{"BAR", "Baruch", "باروخ", Deuterocanon, 6, []string{"bar"}},
+	{"1MA", "1 Maccabees", "المكابيين الأول", Deuterocanon, 16, []string{"1 macc", "1 mac", "1 ma"}},
+	{"2MA", "2 Maccabees", "المكابيين الثاني", Deuterocanon, 15, []string{"2 macc", "2 mac", "2 ma"}},
+	{"MAT", "Matthew", "متى", NewTestament, 28, []string{"matt", "mat", "mt", "مت"}},
+	{"MRK", "Mark", "مرقس", NewTestament, 16, []string{"mrk", "mk", "mar", "mr", "مر"}},
+	{"LUK", "Luke", "لوقا", NewTestament, 24, []string{"luk", "lk", "lu", "لو"}},
+	{"JHN", "John", "يوحنا", NewTestament, 21, []string{"jn", "jhn", "joh", "يو"}},
+	{"ACT", "Acts", "أعمال الرسل", NewTestament, 28, []string{"acts", "act", "ac", "أعمال", "أع"}},
+	{"ROM", "Romans", "رومية", NewTestament, 16, []string{"rom", "ro", "rm", "رو"}},
+	{"1CO", "1 Corinthians", "كورنثوس الأولى", NewTestament, 16, []string{"1 cor", "1 co", "1 كو"}},
+	{"2CO", "2 Corinthians", "كورنثوس الثانية", NewTestament, 13, []string{"2 cor", "2 co", "2 كو"}},
+	{"GAL", "Galatians", "غلاطية", NewTestament, 6, []string{"gal", "ga", "غل"}},
+	{"EPH", "Ephesians", "أفسس", NewTestament, 6, []string{"eph", "ephes", "أف"}},
+	{"PHP", "Philippians", "فيلبي", NewTestament, 4, []string{"phil", "php", "pp", "في"}},
+	{"COL", "Colossians", "كولوسي", NewTestament, 4, []string{"col", "كو"}},
+	{"1TH", "1 Thessalonians", "تسالونيكي الأولى", NewTestament, 5, []string{"1 thess", "1 thes", "1 th", "1 تس"}},
+	{"2TH", "2 Thessalonians", "تسالونيكي الثانية", NewTestament, 3, []string{"2 thess", "2 thes", "2 th", "2 تس"}},
+	{"1TI", "1 Timothy", "تيموثاوس الأولى", NewTestament, 6, []string{"1 tim", "1 ti", "1 تي"}},
+	{"2TI", "2 Timothy", "تيموثاوس الثانية", NewTestament, 4, []string{"2 tim", "2 ti", "2 تي"}},
+	{"TIT", "Titus", "تيطس", NewTestament, 3, []string{"tit", "ti"}},
+	{"PHM", "Philemon", "فليمون", NewTestament, 1, []string{"philem", "phm", "phlm", "pm"}},
+	{"HEB", "Hebrews", "العبرانيين", NewTestament, 13, []string{"heb", "عب"}},
+	{"JAS", "James", "يعقوب", NewTestament, 5, []string{"jas", "jm", "jam", "يع"}},
+	{"1PE", "1 Peter", "بطرس الأولى", NewTestament, 5, []string{"1 pet", "1 pe", "1 pt", "1 بط"}},
+	{"2PE", "2 Peter", "بطرس الثانية", NewTestament, 3, []string{"2 pet", "2 pe", "2 pt", "2 بط"}},
+	{"1JN", "1 John", "يوحنا الأولى", NewTestament, 5, []string{"1 jn", "1 jhn", "1 joh", "1 يو"}},
+	{"2JN", "2 John", "يوحنا الثانية", NewTestament, 1, []string{"2 jn", "2 jhn", "2 joh", "2 يو"}},
+	{"3JN", "3 John", "يوحنا الثالثة", NewTestament, 1, []string{"3 jn", "3 jhn", "3 joh", "3 يو"}},
+	{"JUD", "Jude", "يهوذا", NewTestament, 1, []string{"jud", "jd", "jude"}},
+	{"REV", "Revelation", "الرؤيا", NewTestament, 22, []string{"rev", "re", "revelations", "apocalypse", "apoc", "رؤ", "سفر الرؤيا"}},
+}
+
+// Books returns the books in canonical order.
+func Books() []Book {
+	return append([]Book{}, books...)
+}
+
+// BookByCode returns the book with the USFM code, or nil.
+func BookByCode(code string) *Book {
+	for i := range books {
+		if books[i].Code == code {
+			return &books[i]
+		}
+	}
+	return nil
+}
+
+// bookIndex maps the normalized names and abbreviations of every book to
+// it.
+var bookIndex = indexBooks(bookKey)
+
+// exactBookIndex is keyed without folding Arabic letter variants, so that
+// abbreviations which only differ in them, as "رو" (Romans) and "رؤ"
+// (Revelation), find their own book.
+var exactBookIndex = indexBooks(exactBookKey)
+
+func indexBooks(key func(string) string) map[string]*Book {
+	index := map[string]*Book{}
+	for i := range books {
+		b := &books[i]
+		for _, name := range append([]string{b.Code, b.Name, b.NameAr}, b.aliases...) {
+			index[key(name)] = b
+		}
+	}
+	return index
+}
@@ -0,0 +1,270 @@
+// Package bible parses and formats Bible references written in English or
+// Arabic, such as "John 3:16-18; 4:1" or "يوحنا 3: 16".
+package bible
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Reference is a passage of a book, from StartChapter:StartVerse to
+// EndChapter:EndVerse. A zero StartVerse starts at the beginning of the
+// chapter and a zero EndVerse runs to its end.
+type Reference struct {
+	Book         string `json:"book" bson:"book"`
+	StartChapter int    `json:"startChapter" bson:"startChapter"`
+	StartVerse   int    `json:"startVerse,omitempty" bson:"startVerse,omitempty"`
+	EndChapter   int    `json:"endChapter" bson:"endChapter"`
+	EndVerse     int    `json:"endVerse,omitempty" bson:"endVerse,omitempty"`
+}
+
+// String formats the reference with the English book name.
+func (r Reference) String() string {
+	return r.format(BookByCode(r.Book).Name)
+}
+
+// Arabic formats the reference with the Arabic book name.
+func (r Reference) Arabic() string {
+	return r.format(BookByCode(r.Book).NameAr)
+}
+
+func (r Reference) format(name string) string {
+	s := name + " " + strconv.Itoa(r.StartChapter)
+	if r.StartVerse > 0 {
+		s += ":" + strconv.Itoa(r.StartVerse)
+	}
+	switch {
+	case r.EndChapter != r.StartChapter:
+		s += "-" + strconv.Itoa(r.EndChapter)
+		if r.EndVerse > 0 {
+			s += ":" + strconv.Itoa(r.EndVerse)
+		}
+	case r.EndVerse > 0 && r.EndVerse != r.StartVerse:
+		s += "-" + strconv.Itoa(r.EndVerse)
+	}
+	return s
+}
+
+// Overlaps reports whether the two passages share a verse.
+func (r Reference) Overlaps(o Reference) bool {
+	if r.Book != o.Book {
+		return false
+	}
+	return !positionAfter(r.StartChapter, r.StartVerse, o.EndChapter, o.EndVerse) &&
+		!positionAfter(o.StartChapter, o.StartVerse, r.EndChapter, r.EndVerse)
+}
+
+// positionAfter reports whether the start c1:v1 comes after the end c2:v2,
+// where a zero v2 is the end of the chapter.
+func positionAfter(c1, v1, c2, v2 int) bool {
+	if c1 != c2 {
+		return c1 > c2
+	}
+	return v2 > 0 && v1 > v2
+}
+
+// Format joins references with "; ", writing the book of consecutive
+// references of the same book once.
+func Format(refs []Reference) string {
+	parts := make([]string, len(refs))
+	for i, r := range refs {
+		parts[i] = r.String()
+		if i > 0 && refs[i-1].Book == r.Book {
+			parts[i] = strings.TrimPrefix(parts[i], BookByCode(r.Book).Name+" ")
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+var (
+	separators = strings.NewReplacer("؛", ";", "،", ",", "–", "-", "—", "-", "−", "-", " and ", ";", " و ", ";")
+	passageRe  = regexp.MustCompile(`^(.*?)\s*(\d+)(?:\s*[:.]\s*(\d+))?(?:\s*-\s*(\d+)(?:\s*[:.]\s*(\d+))?)?$`)
+)
+
+// Parse reads the references in text. References are separated by ";" or
+// ","; one without a book continues the previous book, and numbers after a
+// reference with verses, as in "John 3:16, 18", are verses of its chapter.
+// A book without chapters stands for the whole book.
+func Parse(text string) ([]Reference, error) {
+	text = separators.Replace(" " + foldDigits(text) + " ")
+	refs := []Reference{}
+	var prev *Reference
+	for _, group := range strings.Split(text, ";") {
+		for _, item := range strings.Split(group, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			ref, err := parsePassage(item, prev, strings.Contains(group, ":") && prev != nil && prev.StartVerse > 0)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, ref)
+			prev = &refs[len(refs)-1]
+		}
+	}
+	return refs, nil
+}
+
+func parsePassage(item string, prev *Reference, versesOfPrev bool) (Reference, error) {
+	m := passageRe.FindStringSubmatch(item)
+	if m == nil {
+		book := lookupBook(item)
+		if book == nil {
+			return Reference{}, fmt.Errorf("unknown book %q", item)
+		}
+		return Reference{Book: book.Code, StartChapter: 1, EndChapter: book.Chapters}, nil
+	}
+	var book *Book
+	if m[1] == "" {
+		if prev == nil {
+			return Reference{}, fmt.Errorf("%q has no book", item)
+		}
+		book = BookByCode(prev.Book)
+	} else if book = lookupBook(m[1]); book == nil {
+		return Reference{}, fmt.Errorf("unknown book %q", m[1])
+	}
+	n := func(i int) int {
+		v, _ := strconv.Atoi(m[i])
+		return v
+	}
+
+	ref := Reference{Book: book.Code}
+	switch {
+	case m[3] != "":
+		ref.StartChapter, ref.StartVerse = n(2), n(3)
+		ref.EndChapter, ref.EndVerse = ref.StartChapter, ref.StartVerse
+		if m[5] != "" {
+			ref.EndChapter, ref.EndVerse = n(4), n(5)
+		} else if m[4] != "" {
+			ref.EndVerse = n(4)
+		}
+	case m[1] == "" && versesOfPrev, m[1] != "" && book.Chapters == 1:
+		// Verses only: of the previous chapter, or of a book with one
+		// chapter.
+		chapter := 1
+		if m[1] == "" {
+			chapter = prev.EndChapter
+		}
+		ref.StartChapter, ref.EndChapter = chapter, chapter
+		ref.StartVerse, ref.EndVerse = n(2), n(2)
+		if m[4] != "" {
+			ref.EndVerse = n(4)
+		}
+	default:
+		ref.StartChapter, ref.EndChapter = n(2), n(2)
+		if m[4] != "" {
+			ref.EndChapter = n(4)
+			ref.EndVerse = n(5)
+		}
+	}
+
+	for _, c := range []int{ref.StartChapter, ref.EndChapter} {
+		if c < 1 || c > book.Chapters {
+			return Reference{}, fmt.Errorf("%s has no chapter %d", book.Name, c)
+		}
+	}
+	if positionAfter(ref.StartChapter, ref.StartVerse, ref.EndChapter, ref.EndVerse) ||
+		(ref.StartChapter == ref.EndChapter && ref.EndVerse > 0 && ref.StartVerse == 0) {
+		return Reference{}, fmt.Errorf("%q ends before it starts", item)
+	}
+	return ref, nil
+}
+
+func lookupBook(name string) *Book {
+	if book := exactBookIndex[exactBookKey(name)]; book != nil {
+		return book
+	}
+	return bookIndex[bookKey(name)]
+}
+
+var arabicLetterVariants = map[rune]rune{
+	'أ': 'ا', 'إ': 'ا', 'آ': 'ا', 'ٱ': 'ا', 'ة': 'ه', 'ى': 'ي', 'ؤ': 'و', 'ئ': 'ي',
+}
+
+var ordinals = map[string]string{
+	"1": "1", "i": "1", "first": "1", "اول": "1", "اولي": "1", "الاول": "1", "الاولي": "1",
+	"2": "2", "ii": "2", "second": "2", "ثاني": "2", "ثانيه": "2", "الثاني": "2", "الثانيه": "2",
+	"3": "3", "iii": "3", "third": "3", "ثالث": "3", "ثالثه": "3", "الثالث": "3", "الثالثه": "3",
+	"4": "4", "iv": "4",
+}
+
+var fillers = map[string]bool{
+	"the": true, "book": true, "of": true, "gospel": true, "according": true, "to": true,
+	"st": true, "saint": true, "epistle": true, "letter": true, "general": true,
+	"سفر": true, "انجيل": true, "رساله": true, "بشاره": true, "معلمنا": true, "القديس": true,
+	"الي": true, "اهل": true, "بولس": true, "الرسول": true, "كما": true, "رواها": true,
+}
+
+// bookKey reduces a book name to the form its index is keyed by: case,
+// dots, Arabic diacritics and letter variants, the article and filler words
+// are dropped and an ordinal is moved to the front, so that "1 Cor.",
+// "1Cor" and "رسالة كورنثوس الأولى" share keys with their books.
+func bookKey(name string) string {
+	return nameKey(name, true)
+}
+
+// exactBookKey is bookKey keeping the Arabic letter variants.
+func exactBookKey(name string) string {
+	return nameKey(name, false)
+}
+
+func nameKey(name string, foldLetters bool) string {
+	var b strings.Builder
+	for _, ch := range strings.ToLower(name) {
+		if v, ok := arabicLetterVariants[ch]; ok && foldLetters {
+			ch = v
+		}
+		switch {
+		case ch == 'ـ' || unicode.Is(unicode.Mn, ch):
+		case unicode.IsLetter(ch) || unicode.IsDigit(ch):
+			b.WriteRune(ch)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	// Split "1cor" into "1 cor".
+	fields := []string{}
+	for _, f := range strings.Fields(b.String()) {
+		i := 0
+		for i < len(f) && f[i] >= '0' && f[i] <= '9' {
+			i++
+		}
+		if i > 0 && i < len(f) {
+			fields = append(fields, f[:i], f[i:])
+		} else {
+			fields = append(fields, f)
+		}
+	}
+
+	ordinal := ""
+	words := []string{}
+	for i, f := range fields {
+		if fillers[f] {
+			continue
+		}
+		// Numbers are ordinals only in front, as in "1 John"; ordinal words
+		// may follow, as in "يوحنا الأولى".
+		if o, ok := ordinals[f]; ok && ordinal == "" && (i == 0 || !unicode.IsDigit(rune(f[0]))) {
+			ordinal = o
+			continue
+		}
+		if strings.HasPrefix(f, "ال") && len([]rune(f)) > 3 {
+			f = strings.TrimPrefix(f, "ال")
+		}
+		words = append(words, f)
+	}
+	return ordinal + strings.Join(words, "")
+}
+
+func foldDigits(s string) string {
+	return strings.Map(func(ch rune) rune {
+		if ch >= '٠' && ch <= '٩' {
+			return '0' + (ch - '٠')
+		}
+		return ch
+	}, s)
+}
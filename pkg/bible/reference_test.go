@@ -0,0 +1,134 @@
+package bible
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []Reference
+	}{
+		{"John 3:16", []Reference{{"JHN", 3, 16, 3, 16}}},
+		{"John 3:16-18", []Reference{{"JHN", 3, 16, 3, 18}}},
+		{"John 3:16-4:2", []Reference{{"JHN", 3, 16, 4, 2}}},
+		{"John 3", []Reference{{"JHN", 3, 0, 3, 0}}},
+		{"John 3-5", []Reference{{"JHN", 3, 0, 5, 0}}},
+		{"John 3.16", []Reference{{"JHN", 3, 16, 3, 16}}},
+		{"John 3:16-18; 4:1", []Reference{{"JHN", 3, 16, 3, 18}, {"JHN", 4, 1, 4, 1}}},
+		{"John 3:16, 18", []Reference{{"JHN", 3, 16, 3, 16}, {"JHN", 3, 18, 3, 18}}},
+		{"John 3:16 and Rom 8:28", []Reference{{"JHN", 3, 16, 3, 16}, {"ROM", 8, 28, 8, 28}}},
+		{"1 Cor 13", []Reference{{"1CO", 13, 0, 13, 0}}},
+		{"1Cor. 13:4–7", []Reference{{"1CO", 13, 4, 13, 7}}},
+		{"First Corinthians 13:4", []Reference{{"1CO", 13, 4, 13, 4}}},
+		{"The Gospel according to St. Matthew 5:3", []Reference{{"MAT", 5, 3, 5, 3}}},
+		{"Jude 3", []Reference{{"JUD", 1, 3, 1, 3}}},
+		{"Jude", []Reference{{"JUD", 1, 0, 1, 0}}},
+		{"Ruth", []Reference{{"RUT", 1, 0, 4, 0}}},
+		{"Psalm 151", []Reference{{"PSA", 151, 0, 151, 0}}},
+		{"يوحنا 3: 16", []Reference{{"JHN", 3, 16, 3, 16}}},
+		{"يوحنا ٣: ١٦-١٨", []Reference{{"JHN", 3, 16, 3, 18}}},
+		{"رسالة كورنثوس الأولى 13", []Reference{{"1CO", 13, 0, 13, 0}}},
+		{"رو 8: 28", []Reference{{"ROM", 8, 28, 8, 28}}},
+		{"رؤ 21: 1", []Reference{{"REV", 21, 1, 21, 1}}},
+		{"الرويا 21", []Reference{{"REV", 21, 0, 21, 0}}},
+		{"إنجيل متى 5؛ مرقس 1", []Reference{{"MAT", 5, 0, 5, 0}, {"MRK", 1, 0, 1, 0}}},
+		{"", []Reference{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"Hezekiah 3:16",
+		"3:16",
+		"John 22",
+		"John 0",
+		"Jude 2-1",
+		"John 3:18-16",
+		"John 4-3",
+	}
+	for _, in := range tests {
+		t.Run(in, func(t *testing.T) {
+			_, err := Parse(in)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		refs    []Reference
+		want    string
+		wantOne string
+	}{
+		{[]Reference{{"JHN", 3, 16, 3, 16}}, "John 3:16", "يوحنا 3:16"},
+		{[]Reference{{"JHN", 3, 16, 3, 18}, {"JHN", 4, 1, 4, 1}}, "John 3:16-18; 4:1", "يوحنا 3:16-18"},
+		{[]Reference{{"JHN", 3, 16, 4, 2}, {"ROM", 8, 0, 8, 0}}, "John 3:16-4:2; Romans 8", "يوحنا 3:16-4:2"},
+		{[]Reference{{"RUT", 1, 0, 4, 0}}, "Ruth 1-4", "راعوث 1-4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, Format(tt.refs))
+			assert.Equal(t, tt.wantOne, tt.refs[0].Arabic())
+			parsed, err := Parse(Format(tt.refs))
+			require.NoError(t, err)
+			assert.Equal(t, tt.refs, parsed)
+		})
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	tests := []struct {
+		a, b Reference
+		want bool
+	}{
+		{Reference{"JHN", 3, 16, 3, 18}, Reference{"JHN", 3, 18, 3, 20}, true},
+		{Reference{"JHN", 3, 16, 3, 18}, Reference{"JHN", 3, 19, 3, 20}, false},
+		{Reference{"JHN", 3, 0, 3, 0}, Reference{"JHN", 3, 36, 3, 36}, true},
+		{Reference{"JHN", 3, 0, 4, 0}, Reference{"JHN", 5, 1, 5, 1}, false},
+		{Reference{"JHN", 3, 30, 4, 2}, Reference{"JHN", 4, 1, 4, 1}, true},
+		{Reference{"JHN", 3, 16, 3, 16}, Reference{"1JN", 3, 16, 3, 16}, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.a.Overlaps(tt.b), "%v and %v", tt.a, tt.b)
+		assert.Equal(t, tt.want, tt.b.Overlaps(tt.a), "%v and %v", tt.b, tt.a)
+	}
+}
+
+func TestBookKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"1 Cor.", "1cor"},
+		{"1Cor", "1cor"},
+		{"I Corinthians", "1corinthians"},
+		{"رسالة كورنثوس الأولى", "1كورنثوس"},
+		{"سفر التكوين", "تكوين"},
+		{"أعمال الرسل", "اعمالرسل"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, bookKey(tt.name), tt.name)
+	}
+}
+
+func TestEveryBookResolves(t *testing.T) {
+	for _, b := range Books() {
+		for _, name := range append([]string{b.Name, b.NameAr, b.Code}, b.aliases...) {
+			book := lookupBook(name)
+			if assert.NotNil(t, book, "%s: %q", b.Code, name) {
+				assert.Equal(t, b.Code, book.Code, "%s: %q", b.Code, name)
+			}
+		}
+	}
+}
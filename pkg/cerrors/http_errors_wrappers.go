@@ -51,3 +51,81 @@ func NewInternalServerError(method, service string, err error) *InternalServerEr
 		Err:     err,
 	}
 }
+
+type BadRequestError struct {
+	Method  string
+	Service string
+	Err     error
+}
+
+func (e *BadRequestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BadRequestError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BadRequestError) Log() string {
+	return e.Service + " " + e.Method + ": " + e.Error()
+}
+
+func NewBadRequestError(method, service string, err error) *BadRequestError {
+	return &BadRequestError{
+		Method:  method,
+		Service: service,
+		Err:     err,
+	}
+}
+
+type ForbiddenError struct {
+	Method  string
+	Service string
+	Err     error
+}
+
+func (e *ForbiddenError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ForbiddenError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ForbiddenError) Log() string {
+	return e.Service + " " + e.Method + ": " + e.Error()
+}
+
+func NewForbiddenError(method, service string, err error) *ForbiddenError {
+	return &ForbiddenError{
+		Method:  method,
+		Service: service,
+		Err:     err,
+	}
+}
+
+type UnauthorizedError struct {
+	Method  string
+	Service string
+	Err     error
+}
+
+func (e *UnauthorizedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UnauthorizedError) Unwrap() error {
+	return e.Err
+}
+
+func (e *UnauthorizedError) Log() string {
+	return e.Service + " " + e.Method + ": " + e.Error()
+}
+
+func NewUnauthorizedError(method, service string, err error) *UnauthorizedError {
+	return &UnauthorizedError{
+		Method:  method,
+		Service: service,
+		Err:     err,
+	}
+}
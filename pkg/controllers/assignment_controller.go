@@ -93,8 +93,60 @@ func (c *AssignmentController) DeleteAssignment(w http.ResponseWriter, r *http.R
 	err := c.svc.DeleteAssignment(context.Background(), id)
 	if err != nil {
 		fmt.Printf("Error while deleting assignment: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Submit handles POST /assignments/{id}/submissions.
+func (c *AssignmentController) Submit(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var submission models.AssignmentSubmission
+	err := json.NewDecoder(r.Body).Decode(&submission)
+	if err != nil {
+		fmt.Printf("Error while decoding submission: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	a, err := c.svc.Submit(context.Background(), id, submission)
+	if err != nil {
+		fmt.Printf("Error while submitting assignment: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a)
+}
+
+// GradeSubmission handles PUT /assignments/{id}/submissions/{personId}/grade.
+func (c *AssignmentController) GradeSubmission(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	var grade service.Grade
+	err := json.NewDecoder(r.Body).Decode(&grade)
+	if err != nil {
+		fmt.Printf("Error while decoding grade: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	a, err := c.svc.GradeSubmission(context.Background(), vars["id"], vars["personId"], grade)
+	if err != nil {
+		fmt.Printf("Error while grading submission: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a)
+}
+
+func (c *AssignmentController) GradeSummary(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	summary, err := c.svc.GradeSummary(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting grade summary: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
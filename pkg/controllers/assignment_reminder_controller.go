@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/gorilla/mux"
+)
+
+type AssignmentReminderController struct {
+	svc *service.AssignmentReminderService
+}
+
+func NewAssignmentReminderController(svc *service.AssignmentReminderService) *AssignmentReminderController {
+	return &AssignmentReminderController{
+		svc: svc,
+	}
+}
+
+func (c *AssignmentReminderController) GetMissingSubmissions(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	missing, err := c.svc.GetMissingSubmissions(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting missing submissions: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(missing)
+}
+
+// MissingSubmissionsReport handles GET /assignments/missing?from=&to=, listing
+// the missing submissions of the assignments whose deadline passed in the
+// range.
+func (c *AssignmentReminderController) MissingSubmissionsReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to, err := parseDateRange(q.Get("from"), q.Get("to"))
+	if err != nil {
+		fmt.Printf("Error while parsing missing submissions date range: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	report, err := c.svc.MissingSubmissionsReport(context.Background(), from, to)
+	if err != nil {
+		fmt.Printf("Error while getting missing submissions report: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
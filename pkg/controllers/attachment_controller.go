@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+
+	"github.com/gorilla/mux"
+)
+
+type AttachmentController struct {
+	svc *service.AttachmentService
+}
+
+func NewAttachmentController(svc *service.AttachmentService) *AttachmentController {
+	return &AttachmentController{
+		svc: svc,
+	}
+}
+
+type uploadFunc func(ctx context.Context, filename string, r io.Reader) (*models.Attachment, error)
+
+// UploadAssignmentAttachment handles POST /assignments/{id}/attachments with
+// the file in the multipart field "file".
+func (c *AttachmentController) UploadAssignmentAttachment(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	c.upload(w, r, func(ctx context.Context, filename string, body io.Reader) (*models.Attachment, error) {
+		return c.svc.UploadAssignmentAttachment(ctx, id, filename, body)
+	})
+}
+
+// UploadSubmissionAttachment handles
+// POST /assignments/{id}/submissions/{personId}/attachments.
+func (c *AttachmentController) UploadSubmissionAttachment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	c.upload(w, r, func(ctx context.Context, filename string, body io.Reader) (*models.Attachment, error) {
+		return c.svc.UploadSubmissionAttachment(ctx, vars["id"], vars["personId"], filename, body)
+	})
+}
+
+// upload streams the "file" part of the multipart body to fn without
+// buffering it. The body is capped a little above the size limit so the
+// service can still report oversized files.
+func (c *AttachmentController) upload(w http.ResponseWriter, r *http.Request, fn uploadFunc) {
+	r.Body = http.MaxBytesReader(w, r.Body, c.svc.MaxSize()+1<<20)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		fmt.Printf("Error while reading attachment upload: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var part *multipart.Part
+	for {
+		part, err = mr.NextPart()
+		if err != nil {
+			fmt.Printf("Error while reading attachment upload: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if part.FormName() == "file" {
+			break
+		}
+		part.Close()
+	}
+	defer part.Close()
+
+	attachment, err := fn(context.Background(), part.FileName(), part)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		fmt.Printf("Error while uploading attachment: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+func (c *AttachmentController) GetAssignmentAttachments(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	attachments, err := c.svc.GetAssignmentAttachments(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting assignment attachments: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachments)
+}
+
+// DownloadAttachment handles GET /attachments/{id}. Images and PDFs are shown
+// inline, everything else is downloaded.
+func (c *AttachmentController) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	attachment, rc, err := c.svc.OpenAttachment(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while opening attachment: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	defer rc.Close()
+
+	disposition := "attachment"
+	if attachment.ContentType == "application/pdf" || strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, rc); err != nil {
+		fmt.Printf("Error while sending attachment: %v\n", err)
+	}
+}
+
+func (c *AttachmentController) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	err := c.svc.DeleteAttachment(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while deleting attachment: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
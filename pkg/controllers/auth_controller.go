@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/gorilla/mux"
+)
+
+type callerKey struct{}
+
+type AuthController struct {
+	svc *service.AuthService
+}
+
+func NewAuthController(svc *service.AuthService) *AuthController {
+	return &AuthController{
+		svc: svc,
+	}
+}
+
+// Authenticated wraps a handler that needs to know who is calling. The
+// caller is established from the "Authorization: Bearer <token>" header and
+// requests without a valid token get 401.
+func (c *AuthController) Authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		caller, err := c.svc.Authenticate(context.Background(), strings.TrimSpace(token), time.Now())
+		if err != nil {
+			fmt.Printf("Error while authenticating request: %v\n", err)
+			writeError(w, err)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, *caller)))
+	}
+}
+
+// callerOf returns the caller Authenticated established for the request.
+func callerOf(r *http.Request) service.Caller {
+	caller, _ := r.Context().Value(callerKey{}).(service.Caller)
+	return caller
+}
+
+// IssueToken handles POST /persons/{id}/tokens, {"name": ""}. The token is
+// in the response only.
+func (c *AuthController) IssueToken(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var body struct {
+		Name string `json:"name"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			fmt.Printf("Error while decoding access token: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	issued, err := c.svc.IssueToken(context.Background(), callerOf(r), id, body.Name, time.Now())
+	if err != nil {
+		fmt.Printf("Error while issuing access token: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(issued)
+}
+
+// GetPersonTokens handles GET /persons/{id}/tokens.
+func (c *AuthController) GetPersonTokens(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	tokens, err := c.svc.GetPersonTokens(context.Background(), callerOf(r), id)
+	if err != nil {
+		fmt.Printf("Error while getting access tokens: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// RevokeToken handles DELETE /tokens/{id}.
+func (c *AuthController) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := c.svc.RevokeToken(context.Background(), callerOf(r), id, time.Now()); err != nil {
+		fmt.Printf("Error while revoking access token: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
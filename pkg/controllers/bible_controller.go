@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Mario-Kamel/EKMS/pkg/bible"
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+)
+
+type BibleController struct {
+	svc *service.BibleService
+}
+
+func NewBibleController(svc *service.BibleService) *BibleController {
+	return &BibleController{
+		svc: svc,
+	}
+}
+
+// GetBooks handles GET /bible/books.
+func (c *BibleController) GetBooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bible.Books())
+}
+
+// Parse handles GET /bible/parse?q=, returning the references in q with
+// their English and Arabic forms.
+func (c *BibleController) Parse(w http.ResponseWriter, r *http.Request) {
+	refs, err := c.svc.Parse(r.URL.Query().Get("q"))
+	if err != nil {
+		fmt.Printf("Error while parsing bible reference: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	type parsed struct {
+		bible.Reference
+		Text   string `json:"text"`
+		TextAr string `json:"textAr"`
+	}
+	res := make([]parsed, len(refs))
+	for i, ref := range refs {
+		res[i] = parsed{ref, ref.String(), ref.Arabic()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// Coverage handles GET /bible/coverage?from=&to=&groupId=.
+func (c *BibleController) Coverage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to, err := parseDateRange(q.Get("from"), q.Get("to"))
+	if err != nil {
+		fmt.Printf("Error while parsing coverage date range: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	coverage, err := c.svc.Coverage(context.Background(), from, to, q.Get("groupId"))
+	if err != nil {
+		fmt.Printf("Error while getting bible coverage: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(coverage)
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+)
+
+type BirthdayController struct {
+	svc *service.BirthdayService
+}
+
+func NewBirthdayController(svc *service.BirthdayService) *BirthdayController {
+	return &BirthdayController{
+		svc: svc,
+	}
+}
+
+// UpcomingBirthdays handles GET /persons/birthdays?from=&to=. from defaults to
+// today and to to a week after from.
+func (c *BirthdayController) UpcomingBirthdays(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to, err := parseDateRange(q.Get("from"), q.Get("to"))
+	if err != nil {
+		fmt.Printf("Error while parsing birthdays date range: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		fmt.Printf("Error while getting birthdays: to %v is before from %v\n", to, from)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	birthdays, err := c.svc.UpcomingBirthdays(context.Background(), from, to)
+	if err != nil {
+		fmt.Printf("Error while getting upcoming birthdays: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(birthdays)
+}
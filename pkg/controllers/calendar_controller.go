@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/coptic"
+	"github.com/Mario-Kamel/EKMS/pkg/ical"
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+
+	"github.com/gorilla/mux"
+)
+
+type CalendarController struct {
+	svc *service.CalendarFeedService
+	loc *time.Location
+}
+
+func NewCalendarController(svc *service.CalendarFeedService, loc *time.Location) *CalendarController {
+	return &CalendarController{
+		svc: svc,
+		loc: loc,
+	}
+}
+
+// GetYear handles GET /calendar/{year}, the feasts and fasts of the church
+// in a Gregorian year.
+func (c *CalendarController) GetYear(w http.ResponseWriter, r *http.Request) {
+	year, err := strconv.Atoi(mux.Vars(r)["year"])
+	if err != nil || year < 1 || year > 9999 {
+		fmt.Printf("Error while parsing calendar year %q\n", mux.Vars(r)["year"])
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Year   int            `json:"year"`
+		Easter time.Time      `json:"easter"`
+		Feasts []coptic.Feast `json:"feasts"`
+		Fasts  []coptic.Fast  `json:"fasts"`
+	}{year, coptic.Easter(year, c.loc), coptic.Feasts(year, c.loc), coptic.Fasts(year, c.loc)})
+}
+
+// GetDate handles GET /calendar/date?date=2006-01-02, the Coptic date of a
+// day and its feasts. date defaults to today.
+func (c *CalendarController) GetDate(w http.ResponseWriter, r *http.Request) {
+	day := time.Now().In(c.loc)
+	if v := r.URL.Query().Get("date"); v != "" {
+		d, err := time.ParseInLocation("2006-01-02", v, c.loc)
+		if err != nil {
+			fmt.Printf("Error while parsing calendar date: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		day = d
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Date       string         `json:"date"`
+		CopticDate coptic.Date    `json:"copticDate"`
+		Feasts     []coptic.Feast `json:"feasts"`
+	}{day.Format("2006-01-02"), coptic.FromGregorian(day), coptic.FeastsOn(day, c.loc)})
+}
+
+// Feed handles GET /calendar.ics.
+func (c *CalendarController) Feed(w http.ResponseWriter, r *http.Request) {
+	cal, err := c.svc.Feed(context.Background(), time.Now())
+	c.writeFeed(w, cal, err)
+}
+
+// GroupFeed handles GET /groups/{id}/calendar.ics?token=.
+func (c *CalendarController) GroupFeed(w http.ResponseWriter, r *http.Request) {
+	cal, err := c.svc.GroupFeed(context.Background(), mux.Vars(r)["id"], r.URL.Query().Get("token"), time.Now())
+	c.writeFeed(w, cal, err)
+}
+
+// PersonFeed handles GET /persons/{id}/calendar.ics?token=.
+func (c *CalendarController) PersonFeed(w http.ResponseWriter, r *http.Request) {
+	cal, err := c.svc.PersonFeed(context.Background(), mux.Vars(r)["id"], r.URL.Query().Get("token"), time.Now())
+	c.writeFeed(w, cal, err)
+}
+
+// IssueGroupFeedLink handles POST /groups/{id}/calendar, a new address of
+// the group's feed. The previous address stops working.
+func (c *CalendarController) IssueGroupFeedLink(w http.ResponseWriter, r *http.Request) {
+	c.writeFeedLink(w, r, service.FeedGroup, "/groups/", mux.Vars(r)["id"])
+}
+
+// RevokeGroupFeedLink handles DELETE /groups/{id}/calendar.
+func (c *CalendarController) RevokeGroupFeedLink(w http.ResponseWriter, r *http.Request) {
+	c.revokeFeedLink(w, r, service.FeedGroup, mux.Vars(r)["id"])
+}
+
+// IssuePersonFeedLink handles POST /persons/{id}/calendar, a new address of
+// the person's feed. The previous address stops working.
+func (c *CalendarController) IssuePersonFeedLink(w http.ResponseWriter, r *http.Request) {
+	c.writeFeedLink(w, r, service.FeedPerson, "/persons/", mux.Vars(r)["id"])
+}
+
+// RevokePersonFeedLink handles DELETE /persons/{id}/calendar.
+func (c *CalendarController) RevokePersonFeedLink(w http.ResponseWriter, r *http.Request) {
+	c.revokeFeedLink(w, r, service.FeedPerson, mux.Vars(r)["id"])
+}
+
+func (c *CalendarController) writeFeed(w http.ResponseWriter, cal *ical.Calendar, err error) {
+	if err != nil {
+		fmt.Printf("Error while building calendar feed: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="calendar.ics"`)
+	if err := cal.Write(w); err != nil {
+		fmt.Printf("Error while writing calendar feed: %v\n", err)
+	}
+}
+
+func (c *CalendarController) writeFeedLink(w http.ResponseWriter, r *http.Request, kind, prefix, id string) {
+	token, err := c.svc.IssueFeedToken(context.Background(), callerOf(r), kind, id, time.Now())
+	if err != nil {
+		fmt.Printf("Error while issuing calendar feed token: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{
+		"token": token,
+		"url":   prefix + url.PathEscape(id) + "/calendar.ics?token=" + token,
+	})
+}
+
+func (c *CalendarController) revokeFeedLink(w http.ResponseWriter, r *http.Request, kind, id string) {
+	if err := c.svc.RevokeFeedToken(context.Background(), callerOf(r), kind, id, time.Now()); err != nil {
+		fmt.Printf("Error while revoking calendar feed token: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/gorilla/mux"
+)
+
+type CurriculumController struct {
+	svc *service.CurriculumService
+}
+
+func NewCurriculumController(svc *service.CurriculumService) *CurriculumController {
+	return &CurriculumController{
+		svc: svc,
+	}
+}
+
+func (c *CurriculumController) GetAllCurricula(w http.ResponseWriter, r *http.Request) {
+	curricula, err := c.svc.GetAllCurricula(context.Background())
+	if err != nil {
+		fmt.Printf("Error while getting all curricula: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(curricula)
+}
+
+func (c *CurriculumController) GetCurriculumById(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	curriculum, err := c.svc.GetCurriculumById(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting curriculum by id: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(curriculum)
+}
+
+func (c *CurriculumController) CreateCurriculum(w http.ResponseWriter, r *http.Request) {
+	var curriculum models.Curriculum
+	err := json.NewDecoder(r.Body).Decode(&curriculum)
+	if err != nil {
+		fmt.Printf("Error while decoding curriculum: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	created, err := c.svc.CreateCurriculum(context.Background(), curriculum)
+	if err != nil {
+		fmt.Printf("Error while creating curriculum: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (c *CurriculumController) UpdateCurriculum(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var curriculum models.Curriculum
+	err := json.NewDecoder(r.Body).Decode(&curriculum)
+	if err != nil {
+		fmt.Printf("Error while decoding curriculum: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	updated, err := c.svc.UpdateCurriculum(context.Background(), id, curriculum)
+	if err != nil {
+		fmt.Printf("Error while updating curriculum: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (c *CurriculumController) DeleteCurriculum(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	err := c.svc.DeleteCurriculum(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while deleting curriculum: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Progress handles GET /curricula/{id}/progress.
+func (c *CurriculumController) Progress(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	progress, err := c.svc.Progress(context.Background(), id, time.Now())
+	if err != nil {
+		fmt.Printf("Error while getting curriculum progress: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(progress)
+}
+
+// Reschedule handles POST /curricula/{id}/reschedule, planning the lessons
+// not delivered yet on the upcoming services again.
+func (c *CurriculumController) Reschedule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	curriculum, err := c.svc.Reschedule(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while rescheduling curriculum: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(curriculum)
+}
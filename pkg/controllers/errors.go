@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// writeError maps the errors returned by the services to a status code.
+func writeError(w http.ResponseWriter, err error) {
+	var IDErr *cerrors.InvalidIDError
+	var badReqErr *cerrors.BadRequestError
+	var forbiddenErr *cerrors.ForbiddenError
+	var unauthorizedErr *cerrors.UnauthorizedError
+	switch {
+	case errors.As(err, &IDErr), errors.Is(err, mongo.ErrNoDocuments):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.As(err, &badReqErr):
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(badReqErr.Error()))
+	case errors.As(err, &unauthorizedErr):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(unauthorizedErr.Error()))
+	case errors.As(err, &forbiddenErr):
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(forbiddenErr.Error()))
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// longWriteTimeout is how long streamed exports and generated documents may
+// take to write. It replaces the server's WriteTimeout, which is meant for
+// ordinary requests and would cut them off on large data sets.
+const longWriteTimeout = 15 * time.Minute
+
+// extendWriteDeadline gives a long response longWriteTimeout from now to be
+// written.
+func extendWriteDeadline(w http.ResponseWriter) {
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(longWriteTimeout)); err != nil {
+		fmt.Printf("Error while extending write deadline: %v\n", err)
+	}
+}
+
+type ExportController struct {
+	svc *service.ExportService
+}
+
+func NewExportController(svc *service.ExportService) *ExportController {
+	return &ExportController{
+		svc: svc,
+	}
+}
+
+// ExportPersons streams persons as csv, xlsx or ndjson. Supported query
+// parameters are format, columns (comma separated), fr, fatherId and degree.
+func (c *ExportController) ExportPersons(w http.ResponseWriter, r *http.Request) {
+	extendWriteDeadline(w)
+	q := r.URL.Query()
+	format := exportFormat(r)
+	filter := repositories.PersonFilter{
+		Fr:     q.Get("fr"),
+		Degree: q.Get("degree"),
+	}
+	if fatherID := q.Get("fatherId"); fatherID != "" {
+		var err error
+		filter.FatherID, err = primitive.ObjectIDFromHex(fatherID)
+		if err != nil {
+			fmt.Printf("Error while converting id to object id %v: %v\n", fatherID, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	setExportHeaders(w, "persons", format)
+	err := c.svc.ExportPersons(context.Background(), w, format, exportColumns(r), filter)
+	writeExportError(w, "persons", err)
+}
+
+// ExportServices streams services flattened to one row per attendance record.
+// Supported query parameters are format, columns, from, to, speaker and
+// groupId.
+func (c *ExportController) ExportServices(w http.ResponseWriter, r *http.Request) {
+	extendWriteDeadline(w)
+	q := r.URL.Query()
+	format := exportFormat(r)
+	from, to, err := parseDateRange(q.Get("from"), q.Get("to"))
+	if err != nil {
+		fmt.Printf("Error while parsing export date range: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	filter := repositories.ServiceFilter{
+		From:    from,
+		To:      to,
+		Speaker: q.Get("speaker"),
+	}
+	if groupID := q.Get("groupId"); groupID != "" {
+		filter.GroupID, err = primitive.ObjectIDFromHex(groupID)
+		if err != nil {
+			fmt.Printf("Error while converting id to object id %v: %v\n", groupID, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	setExportHeaders(w, "services", format)
+	err = c.svc.ExportServices(context.Background(), w, format, exportColumns(r), filter)
+	writeExportError(w, "services", err)
+}
+
+// ExportAssignments streams assignments flattened to one row per submission.
+// Supported query parameters are format, columns, serviceId, from and to,
+// where from and to bound the deadline.
+func (c *ExportController) ExportAssignments(w http.ResponseWriter, r *http.Request) {
+	extendWriteDeadline(w)
+	q := r.URL.Query()
+	format := exportFormat(r)
+	from, to, err := parseDateRange(q.Get("from"), q.Get("to"))
+	if err != nil {
+		fmt.Printf("Error while parsing export date range: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	filter := repositories.AssignmentFilter{
+		DeadlineFrom: from,
+		DeadlineTo:   to,
+	}
+	if serviceID := q.Get("serviceId"); serviceID != "" {
+		filter.ServiceID, err = primitive.ObjectIDFromHex(serviceID)
+		if err != nil {
+			fmt.Printf("Error while converting id to object id %v: %v\n", serviceID, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	setExportHeaders(w, "assignments", format)
+	err = c.svc.ExportAssignments(context.Background(), w, format, exportColumns(r), filter)
+	writeExportError(w, "assignments", err)
+}
+
+func exportFormat(r *http.Request) string {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		return service.ExportFormatCSV
+	}
+	return format
+}
+
+func exportColumns(r *http.Request) []string {
+	raw := r.URL.Query().Get("columns")
+	if raw == "" {
+		return nil
+	}
+	columns := []string{}
+	for _, col := range strings.Split(raw, ",") {
+		if col = strings.TrimSpace(col); col != "" {
+			columns = append(columns, col)
+		}
+	}
+	return columns
+}
+
+func setExportHeaders(w http.ResponseWriter, name, format string) {
+	w.Header().Set("Content-Type", service.ExportContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+format))
+}
+
+// writeExportError reports errors that happened before anything was streamed.
+// Once rows were written the status is already sent and the error is only
+// logged.
+func writeExportError(w http.ResponseWriter, name string, err error) {
+	if err == nil {
+		return
+	}
+	fmt.Printf("Error while exporting %v: %v\n", name, err)
+	w.Header().Del("Content-Disposition")
+	var badReqErr *cerrors.BadRequestError
+	if errors.As(err, &badReqErr) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(badReqErr.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+// parseDateRange parses optional from and to query parameters given either as
+// dates (2006-01-02) or RFC 3339 timestamps. A bare to date includes the whole
+// day.
+func parseDateRange(fromRaw, toRaw string) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if fromRaw != "" {
+		from, err = parseQueryDate(fromRaw)
+		if err != nil {
+			return from, to, err
+		}
+	}
+	if toRaw != "" {
+		to, err = parseQueryDate(toRaw)
+		if err != nil {
+			return from, to, err
+		}
+		if len(toRaw) == len("2006-01-02") {
+			to = to.Add(24*time.Hour - time.Nanosecond)
+		}
+	}
+	return from, to, nil
+}
+
+func parseQueryDate(raw string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
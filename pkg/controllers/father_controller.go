@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/gorilla/mux"
+)
+
+type FatherController struct {
+	svc *service.FatherService
+}
+
+func NewFatherController(svc *service.FatherService) *FatherController {
+	return &FatherController{
+		svc: svc,
+	}
+}
+
+func (c *FatherController) GetAllFathers(w http.ResponseWriter, r *http.Request) {
+	fathers, err := c.svc.GetAllFathers(context.Background())
+	if err != nil {
+		fmt.Printf("Error while getting all fathers: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fathers)
+}
+
+func (c *FatherController) GetFatherById(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	father, err := c.svc.GetFatherById(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting father by id: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(father)
+}
+
+func (c *FatherController) CreateFather(w http.ResponseWriter, r *http.Request) {
+	var father models.Father
+	err := json.NewDecoder(r.Body).Decode(&father)
+	if err != nil {
+		fmt.Printf("Error while decoding father: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f, err := c.svc.CreateFather(context.Background(), father)
+	if err != nil {
+		fmt.Printf("Error while creating father: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(f)
+}
+
+func (c *FatherController) UpdateFather(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var father models.Father
+	err := json.NewDecoder(r.Body).Decode(&father)
+	if err != nil {
+		fmt.Printf("Error while decoding father: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f, err := c.svc.UpdateFather(context.Background(), id, father)
+	if err != nil {
+		fmt.Printf("Error while updating father: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(f)
+}
+
+func (c *FatherController) DeleteFather(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	err := c.svc.DeleteFather(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while deleting father: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *FatherController) GetFatherPersons(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	persons, err := c.svc.GetFatherPersons(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting father persons: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(persons)
+}
+
+// MatchFathers handles POST /fathers/match?apply=&createMissing=&minScore=.
+// Without apply it only reports the proposed matches.
+func (c *FatherController) MatchFathers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	apply, _ := strconv.ParseBool(q.Get("apply"))
+	createMissing, _ := strconv.ParseBool(q.Get("createMissing"))
+	minScore := 0.0
+	if v := q.Get("minScore"); v != "" {
+		var err error
+		if minScore, err = strconv.ParseFloat(v, 64); err != nil {
+			fmt.Printf("Error while parsing minScore: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	matches, err := c.svc.MatchFr(context.Background(), apply, createMissing, minScore)
+	if err != nil {
+		fmt.Printf("Error while matching fathers: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(matches)
+}
+
+// Report handles GET /fathers/report?days=. days overrides the configured
+// confession period.
+func (c *FatherController) Report(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if v := r.URL.Query().Get("days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days <= 0 {
+			fmt.Printf("Error while parsing days: %q\n", v)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		since = time.Now().AddDate(0, 0, -days)
+	}
+	report, err := c.svc.Report(context.Background(), since)
+	if err != nil {
+		fmt.Printf("Error while getting father report: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func (c *FatherController) AddConfession(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var confession models.Confession
+	err := json.NewDecoder(r.Body).Decode(&confession)
+	if err != nil {
+		fmt.Printf("Error while decoding confession: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	res, err := c.svc.AddConfession(context.Background(), id, confession)
+	if err != nil {
+		fmt.Printf("Error while adding confession: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (c *FatherController) GetPersonConfessions(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	confessions, err := c.svc.GetPersonConfessions(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting person confessions: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(confessions)
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/gorilla/mux"
+)
+
+type GroupController struct {
+	svc *service.GroupService
+}
+
+func NewGroupController(svc *service.GroupService) *GroupController {
+	return &GroupController{
+		svc: svc,
+	}
+}
+
+func (c *GroupController) GetAllGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := c.svc.GetAllGroups(context.Background())
+	if err != nil {
+		fmt.Printf("Error while getting all groups: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+func (c *GroupController) GetGroupById(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	group, err := c.svc.GetGroupById(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting group by id: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
+
+func (c *GroupController) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var group models.Group
+	err := json.NewDecoder(r.Body).Decode(&group)
+	if err != nil {
+		fmt.Printf("Error while decoding group: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	g, err := c.svc.CreateGroup(context.Background(), group)
+	if err != nil {
+		fmt.Printf("Error while creating group: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(g)
+}
+
+func (c *GroupController) UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var group models.Group
+	err := json.NewDecoder(r.Body).Decode(&group)
+	if err != nil {
+		fmt.Printf("Error while decoding group: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	g, err := c.svc.UpdateGroup(context.Background(), id, group)
+	if err != nil {
+		fmt.Printf("Error while updating group: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g)
+}
+
+func (c *GroupController) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	err := c.svc.DeleteGroup(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while deleting group: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// AddMember handles POST /groups/{id}/members with a membership body.
+func (c *GroupController) AddMember(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var membership models.GroupMembership
+	err := json.NewDecoder(r.Body).Decode(&membership)
+	if err != nil {
+		fmt.Printf("Error while decoding group membership: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	g, err := c.svc.AddMember(context.Background(), id, membership)
+	if err != nil {
+		fmt.Printf("Error while adding group member: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g)
+}
+
+// RemoveMember handles DELETE /groups/{id}/members/{personId}. The optional
+// leftAt query parameter sets the leave date, it defaults to now.
+func (c *GroupController) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	var leftAt time.Time
+	if raw := r.URL.Query().Get("leftAt"); raw != "" {
+		var err error
+		leftAt, err = parseQueryDate(raw)
+		if err != nil {
+			fmt.Printf("Error while parsing leftAt: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	g, err := c.svc.RemoveMember(context.Background(), vars["id"], vars["personId"], leftAt)
+	if err != nil {
+		fmt.Printf("Error while removing group member: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g)
+}
+
+// GetRoster handles GET /groups/{id}/roster?at=&role=.
+func (c *GroupController) GetRoster(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	q := r.URL.Query()
+	var at time.Time
+	if raw := q.Get("at"); raw != "" {
+		var err error
+		at, err = parseQueryDate(raw)
+		if err != nil {
+			fmt.Printf("Error while parsing roster date: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	persons, err := c.svc.GetRoster(context.Background(), id, at, q.Get("role"))
+	if err != nil {
+		fmt.Printf("Error while getting group roster: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(persons)
+}
+
+func (c *GroupController) GetGroupServices(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	services, err := c.svc.GetGroupServices(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting group services: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services)
+}
+
+func (c *GroupController) GetPersonGroups(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	groups, err := c.svc.GetPersonGroups(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting person groups: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+func (c *GroupController) GetServiceAbsentees(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	persons, err := c.svc.GetServiceAbsentees(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting service absentees: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(persons)
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/gorilla/mux"
+)
+
+type HouseholdController struct {
+	svc *service.HouseholdService
+}
+
+func NewHouseholdController(svc *service.HouseholdService) *HouseholdController {
+	return &HouseholdController{
+		svc: svc,
+	}
+}
+
+func (c *HouseholdController) GetAllHouseholds(w http.ResponseWriter, r *http.Request) {
+	households, err := c.svc.GetAllHouseholds(context.Background())
+	if err != nil {
+		fmt.Printf("Error while getting all households: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(households)
+}
+
+func (c *HouseholdController) GetHouseholdById(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	household, err := c.svc.GetHouseholdById(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting household by id: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(household)
+}
+
+func (c *HouseholdController) CreateHousehold(w http.ResponseWriter, r *http.Request) {
+	var household models.Household
+	err := json.NewDecoder(r.Body).Decode(&household)
+	if err != nil {
+		fmt.Printf("Error while decoding household: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	h, err := c.svc.CreateHousehold(context.Background(), household)
+	if err != nil {
+		fmt.Printf("Error while creating household: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(h)
+}
+
+func (c *HouseholdController) UpdateHousehold(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var household models.Household
+	err := json.NewDecoder(r.Body).Decode(&household)
+	if err != nil {
+		fmt.Printf("Error while decoding household: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	h, err := c.svc.UpdateHousehold(context.Background(), id, household)
+	if err != nil {
+		fmt.Printf("Error while updating household: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h)
+}
+
+func (c *HouseholdController) DeleteHousehold(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	err := c.svc.DeleteHousehold(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while deleting household: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// AddMember handles POST /households/{id}/members with a {"personId": ...} body.
+func (c *HouseholdController) AddMember(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var body struct {
+		PersonID string `json:"personId"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		fmt.Printf("Error while decoding household member: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	h, err := c.svc.AddMember(context.Background(), id, body.PersonID)
+	if err != nil {
+		fmt.Printf("Error while adding household member: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h)
+}
+
+func (c *HouseholdController) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	h, err := c.svc.RemoveMember(context.Background(), vars["id"], vars["personId"])
+	if err != nil {
+		fmt.Printf("Error while removing household member: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h)
+}
+
+// HouseholdAttendance handles GET /households/{id}/attendance?from=&to=.
+func (c *HouseholdController) HouseholdAttendance(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	q := r.URL.Query()
+	from, to, err := parseDateRange(q.Get("from"), q.Get("to"))
+	if err != nil {
+		fmt.Printf("Error while parsing attendance date range: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	summary, err := c.svc.HouseholdAttendance(context.Background(), id, from, to)
+	if err != nil {
+		fmt.Printf("Error while getting household attendance: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+func (c *HouseholdController) CreateRelationship(w http.ResponseWriter, r *http.Request) {
+	var rel models.Relationship
+	err := json.NewDecoder(r.Body).Decode(&rel)
+	if err != nil {
+		fmt.Printf("Error while decoding relationship: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	res, err := c.svc.CreateRelationship(context.Background(), rel)
+	if err != nil {
+		fmt.Printf("Error while creating relationship: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (c *HouseholdController) DeleteRelationship(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	err := c.svc.DeleteRelationship(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while deleting relationship: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (c *HouseholdController) GetPersonRelations(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	relations, err := c.svc.GetPersonRelations(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting person relationships: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(relations)
+}
+
+func (c *HouseholdController) GuardianContacts(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	contacts, err := c.svc.GuardianContacts(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting guardian contacts: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contacts)
+}
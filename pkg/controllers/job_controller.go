@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/gorilla/mux"
+)
+
+type JobController struct {
+	scheduler *service.Scheduler
+}
+
+func NewJobController(scheduler *service.Scheduler) *JobController {
+	return &JobController{
+		scheduler: scheduler,
+	}
+}
+
+func (c *JobController) GetJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := c.scheduler.GetJobs(context.Background())
+	if err != nil {
+		fmt.Printf("Error while getting jobs: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+func (c *JobController) GetJob(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	job, err := c.scheduler.GetJob(context.Background(), name)
+	if err != nil {
+		fmt.Printf("Error while getting job: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+func (c *JobController) TriggerJob(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	job, err := c.scheduler.Trigger(context.Background(), name)
+	if err != nil {
+		fmt.Printf("Error while triggering job: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+func (c *JobController) PauseJob(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	job, err := c.scheduler.Pause(context.Background(), name)
+	if err != nil {
+		fmt.Printf("Error while pausing job: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+func (c *JobController) ResumeJob(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	job, err := c.scheduler.Resume(context.Background(), name)
+	if err != nil {
+		fmt.Printf("Error while resuming job: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/gorilla/mux"
+)
+
+// readerOf returns the ID of the authenticated person reading or writing
+// notes. Admins act for no person and read no notes.
+func readerOf(r *http.Request) string {
+	return callerOf(r).PersonID.Hex()
+}
+
+type NoteController struct {
+	svc *service.NoteService
+}
+
+func NewNoteController(svc *service.NoteService) *NoteController {
+	return &NoteController{
+		svc: svc,
+	}
+}
+
+// GetPersonNotes handles GET /persons/{id}/notes.
+func (c *NoteController) GetPersonNotes(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	notes, err := c.svc.GetPersonNotes(context.Background(), id, readerOf(r), time.Now())
+	if err != nil {
+		fmt.Printf("Error while getting person notes: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notes)
+}
+
+// CreateNote handles POST /persons/{id}/notes.
+func (c *NoteController) CreateNote(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var note models.Note
+	err := json.NewDecoder(r.Body).Decode(&note)
+	if err != nil {
+		fmt.Printf("Error while decoding note: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	created, err := c.svc.CreateNote(context.Background(), id, readerOf(r), note, time.Now())
+	if err != nil {
+		fmt.Printf("Error while creating note: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetPersonNoteReads handles GET /persons/{id}/notes/reads.
+func (c *NoteController) GetPersonNoteReads(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	reads, err := c.svc.GetPersonNoteReads(context.Background(), id, readerOf(r), time.Now())
+	if err != nil {
+		fmt.Printf("Error while getting person note reads: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reads)
+}
+
+func (c *NoteController) GetNoteById(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	note, err := c.svc.GetNoteById(context.Background(), id, readerOf(r), time.Now())
+	if err != nil {
+		fmt.Printf("Error while getting note by id: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(note)
+}
+
+func (c *NoteController) UpdateNote(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var note models.Note
+	err := json.NewDecoder(r.Body).Decode(&note)
+	if err != nil {
+		fmt.Printf("Error while decoding note: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	updated, err := c.svc.UpdateNote(context.Background(), id, readerOf(r), note, time.Now())
+	if err != nil {
+		fmt.Printf("Error while updating note: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (c *NoteController) DeleteNote(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	err := c.svc.DeleteNote(context.Background(), id, readerOf(r))
+	if err != nil {
+		fmt.Printf("Error while deleting note: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetNoteReads handles GET /notes/{id}/reads.
+func (c *NoteController) GetNoteReads(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	reads, err := c.svc.GetNoteReads(context.Background(), id, readerOf(r), time.Now())
+	if err != nil {
+		fmt.Printf("Error while getting note reads: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reads)
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type NotificationController struct {
+	svc *service.NotificationService
+}
+
+func NewNotificationController(svc *service.NotificationService) *NotificationController {
+	return &NotificationController{
+		svc: svc,
+	}
+}
+
+// GetNotifications handles GET /notifications?status=&personId=&template=&limit=.
+func (c *NotificationController) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := repositories.NotificationFilter{
+		Status:   q.Get("status"),
+		Template: q.Get("template"),
+		Limit:    100,
+	}
+	if personID := q.Get("personId"); personID != "" {
+		var err error
+		filter.PersonID, err = primitive.ObjectIDFromHex(personID)
+		if err != nil {
+			fmt.Printf("Error while converting id to object id %v: %v\n", personID, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if limit := q.Get("limit"); limit != "" {
+		var err error
+		filter.Limit, err = strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			fmt.Printf("Error while parsing limit: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	notifications, err := c.svc.GetNotifications(context.Background(), filter)
+	if err != nil {
+		fmt.Printf("Error while getting notifications: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notifications)
+}
+
+func (c *NotificationController) GetNotificationById(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	notification, err := c.svc.GetNotificationById(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting notification by id: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notification)
+}
+
+func (c *NotificationController) RetryNotification(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	notification, err := c.svc.Retry(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while retrying notification: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notification)
+}
+
+// NotifyPerson handles POST /persons/{id}/notifications. The body names a
+// template and its data, or gives a subject and body for a one-off message.
+func (c *NotificationController) NotifyPerson(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var body struct {
+		Template string                 `json:"template"`
+		Data     map[string]interface{} `json:"data"`
+		Subject  string                 `json:"subject"`
+		Body     string                 `json:"body"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		fmt.Printf("Error while decoding notification: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if body.Template == "" {
+		body.Template = "custom"
+		body.Data = map[string]interface{}{"Subject": body.Subject, "Body": body.Body}
+	}
+	notification, err := c.svc.NotifyPersonById(context.Background(), id, body.Template, body.Data)
+	if err != nil {
+		fmt.Printf("Error while notifying person: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(notification)
+}
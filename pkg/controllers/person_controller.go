@@ -2,17 +2,29 @@ package controllers
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
 	"github.com/Mario-Kamel/EKMS/pkg/models"
 	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/Mario-Kamel/EKMS/pkg/xlsx"
 	"github.com/gorilla/mux"
 )
 
+// maxImportMemory is the part of an uploaded import file kept in memory, the
+// rest is spooled to a temporary file by the multipart parser.
+const maxImportMemory = 8 << 20
+
+// maxImportSize caps the whole import request.
+const maxImportSize = 64 << 20
+
 type PersonController struct {
 	svc *service.PersonService
 }
@@ -98,3 +110,146 @@ func (c *PersonController) DeletePerson(w http.ResponseWriter, r *http.Request)
 	}
 	w.WriteHeader(http.StatusOK)
 }
+
+// ImportPersons accepts a multipart form with a "file" field holding a CSV or
+// XLSX roster, an optional "mapping" JSON object mapping person fields to
+// column headers, an optional "mode" (insert, upsert or skip) and an optional
+// "dryRun" flag.
+func (c *PersonController) ImportPersons(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	err := r.ParseMultipartForm(maxImportMemory)
+	if err != nil {
+		fmt.Printf("Error while parsing import form: %v\n", err)
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		fmt.Printf("Error while reading import file: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	opts := service.ImportOptions{Mode: r.FormValue("mode")}
+	if mapping := r.FormValue("mapping"); mapping != "" {
+		err = json.Unmarshal([]byte(mapping), &opts.Mapping)
+		if err != nil {
+			fmt.Printf("Error while decoding import mapping: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if dryRun := r.FormValue("dryRun"); dryRun != "" {
+		opts.DryRun, err = strconv.ParseBool(dryRun)
+		if err != nil {
+			fmt.Printf("Error while parsing dryRun flag: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	var rows service.RowReader
+	format := strings.ToLower(r.FormValue("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+	switch format {
+	case "csv":
+		reader := csv.NewReader(file)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		rows = reader
+	case "xlsx":
+		reader, err := xlsx.NewReader(file, header.Size)
+		if err != nil {
+			fmt.Printf("Error while opening xlsx import: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer reader.Close()
+		rows = reader
+	default:
+		fmt.Printf("Unsupported import format: %q\n", format)
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	report, err := c.svc.ImportPersons(context.Background(), rows, opts)
+	if err != nil {
+		fmt.Printf("Error while importing persons: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func (c *PersonController) FindDuplicates(w http.ResponseWriter, r *http.Request) {
+	minScore := service.DefaultDuplicateScore
+	if raw := r.URL.Query().Get("minScore"); raw != "" {
+		var err error
+		minScore, err = strconv.ParseFloat(raw, 64)
+		if err != nil {
+			fmt.Printf("Error while parsing minScore: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	candidates, err := c.svc.FindDuplicates(context.Background(), minScore)
+	if err != nil {
+		fmt.Printf("Error while finding duplicate persons: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(candidates)
+}
+
+func (c *PersonController) MergePersons(w http.ResponseWriter, r *http.Request) {
+	var req service.MergeRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		fmt.Printf("Error while decoding merge request: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	p, err := c.svc.MergePersons(context.Background(), req)
+	if err != nil {
+		fmt.Printf("Error while merging persons: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// SearchPersons handles GET /persons/search?q=&limit=.
+func (c *PersonController) SearchPersons(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	limit := service.DefaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil {
+			fmt.Printf("Error while parsing search limit: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	results, err := c.svc.SearchPersons(context.Background(), q, limit)
+	if err != nil {
+		fmt.Printf("Error while searching persons: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/gorilla/mux"
+)
+
+type PointsController struct {
+	svc *service.PointsService
+}
+
+func NewPointsController(svc *service.PointsService) *PointsController {
+	return &PointsController{
+		svc: svc,
+	}
+}
+
+func (c *PointsController) GetRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := c.svc.GetRules(context.Background())
+	if err != nil {
+		fmt.Printf("Error while getting points rules: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+func (c *PointsController) GetRuleById(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	rule, err := c.svc.GetRuleById(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting points rule by id: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (c *PointsController) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var rule models.PointsRule
+	err := json.NewDecoder(r.Body).Decode(&rule)
+	if err != nil {
+		fmt.Printf("Error while decoding points rule: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	created, err := c.svc.CreateRule(context.Background(), rule)
+	if err != nil {
+		fmt.Printf("Error while creating points rule: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (c *PointsController) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var rule models.PointsRule
+	err := json.NewDecoder(r.Body).Decode(&rule)
+	if err != nil {
+		fmt.Printf("Error while decoding points rule: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	updated, err := c.svc.UpdateRule(context.Background(), id, rule)
+	if err != nil {
+		fmt.Printf("Error while updating points rule: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (c *PointsController) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	err := c.svc.DeleteRule(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while deleting points rule: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Recalculate handles POST /points/recalculate?from=&to=.
+func (c *PointsController) Recalculate(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to, err := parseDateRange(q.Get("from"), q.Get("to"))
+	if err != nil {
+		fmt.Printf("Error while parsing points date range: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	count, err := c.svc.Recalculate(context.Background(), from, to)
+	if err != nil {
+		fmt.Printf("Error while recalculating points: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"evaluated": count})
+}
+
+// GetBalance handles GET /persons/{id}/points?limit=, limit bounding the
+// number of ledger entries returned.
+func (c *PointsController) GetBalance(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var limit int64 = 50
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		var err error
+		limit, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			fmt.Printf("Error while parsing limit: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	balance, err := c.svc.GetBalance(context.Background(), id, limit)
+	if err != nil {
+		fmt.Printf("Error while getting points balance: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(balance)
+}
+
+// Adjust handles POST /persons/{id}/points/adjustments.
+func (c *PointsController) Adjust(w http.ResponseWriter, r *http.Request) {
+	c.addEntry(w, r, "adjusting points", c.svc.Adjust)
+}
+
+// Redeem handles POST /persons/{id}/points/redemptions.
+func (c *PointsController) Redeem(w http.ResponseWriter, r *http.Request) {
+	c.addEntry(w, r, "redeeming points", c.svc.Redeem)
+}
+
+func (c *PointsController) addEntry(w http.ResponseWriter, r *http.Request, action string, fn func(context.Context, string, service.PointsRequest) (*models.PointsEntry, error)) {
+	id := mux.Vars(r)["id"]
+	var req service.PointsRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		fmt.Printf("Error while decoding points request: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	entry, err := fn(context.Background(), id, req)
+	if err != nil {
+		fmt.Printf("Error while %v: %v\n", action, err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// Leaderboard handles GET /groups/{id}/leaderboard. The period is either
+// period=week|month|year|all or an explicit from and to; limit caps the
+// number of persons returned.
+func (c *PointsController) Leaderboard(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	q := r.URL.Query()
+	from, to, err := service.PeriodRange(q.Get("period"), time.Now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if q.Get("from") != "" || q.Get("to") != "" {
+		from, to, err = parseDateRange(q.Get("from"), q.Get("to"))
+		if err != nil {
+			fmt.Printf("Error while parsing leaderboard date range: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	limit := 0
+	if raw := q.Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil {
+			fmt.Printf("Error while parsing limit: %v\n", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	board, err := c.svc.Leaderboard(context.Background(), id, from, to, limit)
+	if err != nil {
+		fmt.Printf("Error while getting leaderboard: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(board)
+}
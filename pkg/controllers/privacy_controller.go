@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/gorilla/mux"
+)
+
+type PrivacyController struct {
+	svc *service.PrivacyService
+}
+
+func NewPrivacyController(svc *service.PrivacyService) *PrivacyController {
+	return &PrivacyController{
+		svc: svc,
+	}
+}
+
+// ExportPersonData handles GET /persons/{id}/data-export, a ZIP of
+// everything stored about the person.
+func (c *PrivacyController) ExportPersonData(w http.ResponseWriter, r *http.Request) {
+	extendWriteDeadline(w)
+	id := mux.Vars(r)["id"]
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "person-"+id+".zip"))
+	err := c.svc.ExportPersonData(context.Background(), id, readerOf(r), w, time.Now())
+	if err != nil {
+		fmt.Printf("Error while exporting person data: %v\n", err)
+		w.Header().Del("Content-Disposition")
+		writeError(w, err)
+	}
+}
+
+// ErasePerson handles POST /persons/{id}/erase.
+func (c *PrivacyController) ErasePerson(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	person, err := c.svc.ErasePerson(context.Background(), id, readerOf(r), time.Now())
+	if err != nil {
+		fmt.Printf("Error while erasing person: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(person)
+}
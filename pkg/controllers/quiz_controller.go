@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type QuizController struct {
+	svc *service.QuizService
+}
+
+func NewQuizController(svc *service.QuizService) *QuizController {
+	return &QuizController{
+		svc: svc,
+	}
+}
+
+// GetQuizzes handles GET /quizzes, optionally filtered by the serviceId and
+// assignmentId query parameters.
+func (c *QuizController) GetQuizzes(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var filter repositories.QuizFilter
+	var err error
+	if serviceID := q.Get("serviceId"); serviceID != "" {
+		filter.ServiceID, err = primitive.ObjectIDFromHex(serviceID)
+		if err != nil {
+			fmt.Printf("Error while converting id to object id %v: %v\n", serviceID, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if assignmentID := q.Get("assignmentId"); assignmentID != "" {
+		filter.AssignmentID, err = primitive.ObjectIDFromHex(assignmentID)
+		if err != nil {
+			fmt.Printf("Error while converting id to object id %v: %v\n", assignmentID, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	quizzes, err := c.svc.GetQuizzes(context.Background(), filter)
+	if err != nil {
+		fmt.Printf("Error while getting quizzes: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quizzes)
+}
+
+func (c *QuizController) GetQuizById(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	quiz, err := c.svc.GetQuizById(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting quiz by id: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quiz)
+}
+
+// GetQuizPaper handles GET /quizzes/{id}/paper, the quiz without its answers.
+func (c *QuizController) GetQuizPaper(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	quiz, err := c.svc.GetQuizPaper(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting quiz paper: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quiz)
+}
+
+func (c *QuizController) CreateQuiz(w http.ResponseWriter, r *http.Request) {
+	var quiz models.Quiz
+	err := json.NewDecoder(r.Body).Decode(&quiz)
+	if err != nil {
+		fmt.Printf("Error while decoding quiz: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	q, err := c.svc.CreateQuiz(context.Background(), quiz)
+	if err != nil {
+		fmt.Printf("Error while creating quiz: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(q)
+}
+
+func (c *QuizController) UpdateQuiz(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var quiz models.Quiz
+	err := json.NewDecoder(r.Body).Decode(&quiz)
+	if err != nil {
+		fmt.Printf("Error while decoding quiz: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	q, err := c.svc.UpdateQuiz(context.Background(), id, quiz)
+	if err != nil {
+		fmt.Printf("Error while updating quiz: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(q)
+}
+
+func (c *QuizController) DeleteQuiz(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	err := c.svc.DeleteQuiz(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while deleting quiz: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SubmitQuiz handles POST /quizzes/{id}/submissions and returns a receipt,
+// with the scored submission once the quiz is closed.
+func (c *QuizController) SubmitQuiz(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var submission models.QuizSubmission
+	err := json.NewDecoder(r.Body).Decode(&submission)
+	if err != nil {
+		fmt.Printf("Error while decoding quiz submission: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	scored, err := c.svc.SubmitQuiz(context.Background(), id, submission, time.Now())
+	if err != nil {
+		fmt.Printf("Error while submitting quiz: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scored)
+}
+
+func (c *QuizController) QuizStats(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	stats, err := c.svc.QuizStats(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting quiz stats: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// ExportResults streams the quiz results as csv, xlsx or ndjson, chosen with
+// the format query parameter.
+func (c *QuizController) ExportResults(w http.ResponseWriter, r *http.Request) {
+	extendWriteDeadline(w)
+	id := mux.Vars(r)["id"]
+	format := exportFormat(r)
+	setExportHeaders(w, "quiz-results", format)
+	err := c.svc.ExportResults(context.Background(), w, format, id)
+	if err != nil {
+		fmt.Printf("Error while exporting quiz results: %v\n", err)
+		w.Header().Del("Content-Disposition")
+		writeError(w, err)
+	}
+}
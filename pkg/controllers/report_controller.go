@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/gorilla/mux"
+)
+
+type ReportController struct {
+	svc *service.ReportService
+}
+
+func NewReportController(svc *service.ReportService) *ReportController {
+	return &ReportController{
+		svc: svc,
+	}
+}
+
+// ServiceReport renders the attendance report of a service, or a blank
+// sign-in sheet when called with type=signin.
+func (c *ReportController) ServiceReport(w http.ResponseWriter, r *http.Request) {
+	extendWriteDeadline(w)
+	id := mux.Vars(r)["id"]
+	kind := r.URL.Query().Get("type")
+	var buf bytes.Buffer
+	err := c.svc.ServiceReport(context.Background(), id, kind, &buf)
+	if err != nil {
+		fmt.Printf("Error while generating service report: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	writePDF(w, "service-"+id+".pdf", &buf)
+}
+
+func (c *ReportController) PersonProfile(w http.ResponseWriter, r *http.Request) {
+	extendWriteDeadline(w)
+	id := mux.Vars(r)["id"]
+	var buf bytes.Buffer
+	err := c.svc.PersonProfile(context.Background(), id, &buf)
+	if err != nil {
+		fmt.Printf("Error while generating person profile: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	writePDF(w, "person-"+id+".pdf", &buf)
+}
+
+func writePDF(w http.ResponseWriter, filename string, buf *bytes.Buffer) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	buf.WriteTo(w)
+}
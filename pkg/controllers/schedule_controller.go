@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/gorilla/mux"
+)
+
+type ScheduleController struct {
+	svc *service.ScheduleService
+}
+
+func NewScheduleController(svc *service.ScheduleService) *ScheduleController {
+	return &ScheduleController{
+		svc: svc,
+	}
+}
+
+func (c *ScheduleController) GetAllSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := c.svc.GetAllSchedules(context.Background())
+	if err != nil {
+		fmt.Printf("Error while getting all schedules: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedules)
+}
+
+func (c *ScheduleController) GetScheduleById(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	schedule, err := c.svc.GetScheduleById(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting schedule by id: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+func (c *ScheduleController) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var schedule models.ServiceSchedule
+	err := json.NewDecoder(r.Body).Decode(&schedule)
+	if err != nil {
+		fmt.Printf("Error while decoding schedule: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	created, err := c.svc.CreateSchedule(context.Background(), schedule)
+	if err != nil {
+		fmt.Printf("Error while creating schedule: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (c *ScheduleController) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var schedule models.ServiceSchedule
+	err := json.NewDecoder(r.Body).Decode(&schedule)
+	if err != nil {
+		fmt.Printf("Error while decoding schedule: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	updated, err := c.svc.UpdateSchedule(context.Background(), id, schedule)
+	if err != nil {
+		fmt.Printf("Error while updating schedule: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (c *ScheduleController) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	err := c.svc.DeleteSchedule(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while deleting schedule: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetOccurrences handles GET /schedules/{id}/occurrences?from=&to=.
+func (c *ScheduleController) GetOccurrences(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	q := r.URL.Query()
+	from, to, err := parseDateRange(q.Get("from"), q.Get("to"))
+	if err != nil {
+		fmt.Printf("Error while parsing occurrence date range: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	occurrences, err := c.svc.GetOccurrences(context.Background(), id, from, to)
+	if err != nil {
+		fmt.Printf("Error while getting schedule occurrences: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(occurrences)
+}
+
+// EditOccurrence handles PUT /schedules/{id}/occurrences/{date}?scope=this|following.
+func (c *ScheduleController) EditOccurrence(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	var edit service.OccurrenceEdit
+	err := json.NewDecoder(r.Body).Decode(&edit)
+	if err != nil {
+		fmt.Printf("Error while decoding occurrence edit: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	schedule, err := c.svc.EditOccurrence(context.Background(), vars["id"], vars["date"], r.URL.Query().Get("scope"), edit)
+	if err != nil {
+		fmt.Printf("Error while editing schedule occurrence: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// SkipOccurrence handles DELETE /schedules/{id}/occurrences/{date}.
+func (c *ScheduleController) SkipOccurrence(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	schedule, err := c.svc.SkipOccurrence(context.Background(), vars["id"], vars["date"])
+	if err != nil {
+		fmt.Printf("Error while skipping schedule occurrence: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
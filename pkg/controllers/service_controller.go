@@ -179,3 +179,34 @@ func (c *ServiceController) DeleteAttendanceRecord(w http.ResponseWriter, r *htt
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(updatedService)
 }
+
+// SearchByReference handles GET /services/search?ref=John 3&from=&to=.
+func (c *ServiceController) SearchByReference(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to, err := parseDateRange(q.Get("from"), q.Get("to"))
+	if err != nil {
+		fmt.Printf("Error while parsing search date range: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	services, err := c.svc.SearchByReference(context.Background(), q.Get("ref"), from, to)
+	if err != nil {
+		fmt.Printf("Error while searching services by reference: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(services)
+}
+
+// ReindexBibleRefs handles POST /services/bible-refs/reindex.
+func (c *ServiceController) ReindexBibleRefs(w http.ResponseWriter, r *http.Request) {
+	changed, err := c.svc.ReindexBibleRefs(context.Background())
+	if err != nil {
+		fmt.Printf("Error while reindexing bible references: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"changed": changed})
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/service"
+	"github.com/gorilla/mux"
+)
+
+type SpeakerController struct {
+	svc *service.SpeakerService
+}
+
+func NewSpeakerController(svc *service.SpeakerService) *SpeakerController {
+	return &SpeakerController{
+		svc: svc,
+	}
+}
+
+func (c *SpeakerController) GetAllSpeakers(w http.ResponseWriter, r *http.Request) {
+	speakers, err := c.svc.GetAllSpeakers(context.Background())
+	if err != nil {
+		fmt.Printf("Error while getting all speakers: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(speakers)
+}
+
+func (c *SpeakerController) GetSpeakerById(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	speaker, err := c.svc.GetSpeakerById(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while getting speaker by id: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(speaker)
+}
+
+func (c *SpeakerController) CreateSpeaker(w http.ResponseWriter, r *http.Request) {
+	var speaker models.Speaker
+	err := json.NewDecoder(r.Body).Decode(&speaker)
+	if err != nil {
+		fmt.Printf("Error while decoding speaker: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	created, err := c.svc.CreateSpeaker(context.Background(), speaker)
+	if err != nil {
+		fmt.Printf("Error while creating speaker: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (c *SpeakerController) UpdateSpeaker(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var speaker models.Speaker
+	err := json.NewDecoder(r.Body).Decode(&speaker)
+	if err != nil {
+		fmt.Printf("Error while decoding speaker: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	updated, err := c.svc.UpdateSpeaker(context.Background(), id, speaker)
+	if err != nil {
+		fmt.Printf("Error while updating speaker: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (c *SpeakerController) DeleteSpeaker(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	err := c.svc.DeleteSpeaker(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while deleting speaker: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AssignSpeaker handles PUT /services/{id}/speaker?force=true with a body of
+// {"speakerId": ...}.
+func (c *SpeakerController) AssignSpeaker(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var body struct {
+		SpeakerID string `json:"speakerId"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		fmt.Printf("Error while decoding speaker assignment: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	serv, err := c.svc.AssignSpeaker(context.Background(), id, body.SpeakerID, r.URL.Query().Get("force") == "true")
+	if err != nil {
+		fmt.Printf("Error while assigning speaker: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(serv)
+}
+
+// UnassignSpeaker handles DELETE /services/{id}/speaker.
+func (c *SpeakerController) UnassignSpeaker(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	serv, err := c.svc.UnassignSpeaker(context.Background(), id)
+	if err != nil {
+		fmt.Printf("Error while unassigning speaker: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(serv)
+}
+
+// Conflicts handles GET /speakers/conflicts?from=&to=.
+func (c *SpeakerController) Conflicts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to, err := parseDateRange(q.Get("from"), q.Get("to"))
+	if err != nil {
+		fmt.Printf("Error while parsing conflicts date range: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	conflicts, err := c.svc.Conflicts(context.Background(), from, to)
+	if err != nil {
+		fmt.Printf("Error while getting speaker conflicts: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conflicts)
+}
+
+// Plan handles GET /speakers/plan?from=&to=&groupId=, the proposed rotation,
+// and POST with the same parameters, which assigns it.
+func (c *SpeakerController) Plan(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to, err := parseDateRange(q.Get("from"), q.Get("to"))
+	if err != nil {
+		fmt.Printf("Error while parsing plan date range: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	plan := c.svc.PlanSpeakers
+	if r.Method == http.MethodPost {
+		plan = c.svc.ApplyPlan
+	}
+	proposals, err := plan(context.Background(), from, to, q.Get("groupId"))
+	if err != nil {
+		fmt.Printf("Error while planning speakers: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(proposals)
+}
+
+// History handles GET /speakers/{id}/history?from=&to=.
+func (c *SpeakerController) History(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	from, to, ok := c.historyRange(w, r)
+	if !ok {
+		return
+	}
+	history, err := c.svc.History(context.Background(), id, from, to)
+	if err != nil {
+		fmt.Printf("Error while getting speaker history: %v\n", err)
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// ExportHistory handles GET /speakers/{id}/history/export?from=&to=&format=.
+func (c *SpeakerController) ExportHistory(w http.ResponseWriter, r *http.Request) {
+	extendWriteDeadline(w)
+	id := mux.Vars(r)["id"]
+	from, to, ok := c.historyRange(w, r)
+	if !ok {
+		return
+	}
+	format := exportFormat(r)
+	setExportHeaders(w, "speaker-history", format)
+	err := c.svc.ExportHistory(context.Background(), w, format, id, from, to)
+	if err != nil {
+		fmt.Printf("Error while exporting speaker history: %v\n", err)
+		w.Header().Del("Content-Disposition")
+		writeError(w, err)
+	}
+}
+
+func (c *SpeakerController) historyRange(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	q := r.URL.Query()
+	from, to, err := parseDateRange(q.Get("from"), q.Get("to"))
+	if err != nil {
+		fmt.Printf("Error while parsing history date range: %v\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return from, to, false
+	}
+	return from, to, true
+}
@@ -0,0 +1,131 @@
+// Package coptic converts between Gregorian and Coptic dates and computes
+// the feasts and fasts of the Coptic Orthodox church.
+package coptic
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// epoch is the Julian day number of 1 Thout 1 A.M. (29 August 284 Julian).
+const epoch = 1825030
+
+var monthNames = [...]string{
+	"Thout", "Paopi", "Hathor", "Koiak", "Tobi", "Meshir", "Paremhat",
+	"Parmouti", "Pashons", "Paoni", "Epip", "Mesori", "Nasie",
+}
+
+var monthNamesAr = [...]string{
+	"توت", "بابه", "هاتور", "كيهك", "طوبه", "أمشير", "برمهات",
+	"برموده", "بشنس", "بؤونه", "أبيب", "مسرى", "نسيء",
+}
+
+// Date is a day of the Coptic calendar. Months 1 to 12 have 30 days; the
+// thirteenth, Nasie, has 5 days, or 6 in years following a Coptic leap year
+// (years divisible by 4 plus 3).
+type Date struct {
+	Year  int
+	Month int
+	Day   int
+}
+
+// FromGregorian returns the Coptic date of t's calendar day in t's location.
+func FromGregorian(t time.Time) Date {
+	jdn := gregorianToJDN(t.Year(), int(t.Month()), t.Day())
+	year := (4*(jdn-epoch) + 1463) / 1461
+	month := (jdn-toJDN(year, 1, 1))/30 + 1
+	day := jdn - toJDN(year, month, 1) + 1
+	return Date{Year: year, Month: month, Day: day}
+}
+
+// Gregorian returns midnight of the date in loc.
+func (d Date) Gregorian(loc *time.Location) time.Time {
+	y, m, day := jdnToGregorian(toJDN(d.Year, d.Month, d.Day))
+	return time.Date(y, time.Month(m), day, 0, 0, 0, 0, loc)
+}
+
+// Valid reports whether the date exists.
+func (d Date) Valid() bool {
+	if d.Month < 1 || d.Month > 13 || d.Day < 1 {
+		return false
+	}
+	if d.Month == 13 {
+		return d.Day <= 5 || (d.Day == 6 && IsLeapYear(d.Year))
+	}
+	return d.Day <= 30
+}
+
+// IsLeapYear reports whether the Coptic year has a sixth day of Nasie.
+func IsLeapYear(year int) bool {
+	return year%4 == 3
+}
+
+// MonthName returns the name of the month, or "" if there is no such month.
+func (d Date) MonthName() string {
+	if d.Month < 1 || d.Month > len(monthNames) {
+		return ""
+	}
+	return monthNames[d.Month-1]
+}
+
+func (d Date) MonthNameAr() string {
+	if d.Month < 1 || d.Month > len(monthNamesAr) {
+		return ""
+	}
+	return monthNamesAr[d.Month-1]
+}
+
+func (d Date) String() string {
+	return fmt.Sprintf("%d %s %d", d.Day, d.MonthName(), d.Year)
+}
+
+// Arabic formats the date with the Arabic month name, e.g. "12 بابه 1743".
+func (d Date) Arabic() string {
+	return fmt.Sprintf("%d %s %d", d.Day, d.MonthNameAr(), d.Year)
+}
+
+// MarshalJSON includes the month names and formatted dates next to the
+// numbers so clients can display the date as is.
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Year        int    `json:"year"`
+		Month       int    `json:"month"`
+		Day         int    `json:"day"`
+		MonthName   string `json:"monthName"`
+		MonthNameAr string `json:"monthNameAr"`
+		Text        string `json:"text"`
+		TextAr      string `json:"textAr"`
+	}{d.Year, d.Month, d.Day, d.MonthName(), d.MonthNameAr(), d.String(), d.Arabic()})
+}
+
+func toJDN(year, month, day int) int {
+	return epoch - 1 + 365*(year-1) + year/4 + 30*(month-1) + day
+}
+
+func gregorianToJDN(year, month, day int) int {
+	a := (14 - month) / 12
+	y := year + 4800 - a
+	m := month + 12*a - 3
+	return day + (153*m+2)/5 + 365*y + y/4 - y/100 + y/400 - 32045
+}
+
+func jdnToGregorian(jdn int) (int, int, int) {
+	a := jdn + 32044
+	b := (4*a + 3) / 146097
+	c := a - 146097*b/4
+	d := (4*c + 3) / 1461
+	e := c - 1461*d/4
+	m := (5*e + 2) / 153
+	day := e - (153*m+2)/5 + 1
+	month := m + 3 - 12*(m/10)
+	year := 100*b + d - 4800 + m/10
+	return year, month, day
+}
+
+func julianToJDN(year, month, day int) int {
+	a := (14 - month) / 12
+	y := year + 4800 - a
+	m := month + 12*a - 3
+	return day + (153*m+2)/5 + 365*y + y/4 - 32083
+}
@@ -0,0 +1,160 @@
+package coptic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestFromGregorian(t *testing.T) {
+	tests := []struct {
+		gregorian string
+		want      Date
+	}{
+		{"2023-09-10", Date{1739, 13, 5}},
+		{"2023-09-11", Date{1739, 13, 6}},
+		{"2023-09-12", Date{1740, 1, 1}},
+		{"2024-01-08", Date{1740, 4, 29}},
+		{"2024-03-01", Date{1740, 6, 22}},
+		{"2024-05-05", Date{1740, 8, 27}},
+		{"2024-09-10", Date{1740, 13, 5}},
+		{"2024-09-11", Date{1741, 1, 1}},
+		{"2025-01-07", Date{1741, 4, 29}},
+		{"1900-03-01", Date{1616, 6, 22}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.gregorian, func(t *testing.T) {
+			got := FromGregorian(day(tt.gregorian))
+			assert.Equal(t, tt.want, got)
+			assert.True(t, got.Valid())
+			assert.Equal(t, day(tt.gregorian), tt.want.Gregorian(time.UTC))
+		})
+	}
+}
+
+func TestFromGregorianUsesLocation(t *testing.T) {
+	cairo := time.FixedZone("Cairo", 3*60*60)
+	// 22:30 UTC on 11 September is already 12 September in Cairo.
+	assert.Equal(t, Date{1740, 1, 1}, FromGregorian(time.Date(2023, 9, 11, 22, 30, 0, 0, time.UTC).In(cairo)))
+	assert.Equal(t, Date{1739, 13, 6}, FromGregorian(time.Date(2023, 9, 11, 22, 30, 0, 0, time.UTC)))
+}
+
+func TestRoundTrip(t *testing.T) {
+	start := day("1999-01-01")
+	for d := start; d.Before(day("2031-01-01")); d = d.AddDate(0, 0, 1) {
+		c := FromGregorian(d)
+		if !c.Valid() || !c.Gregorian(time.UTC).Equal(d) {
+			t.Fatalf("%s converts to %v", d.Format("2006-01-02"), c)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		date Date
+		want bool
+	}{
+		{Date{1740, 1, 1}, true},
+		{Date{1740, 12, 30}, true},
+		{Date{1740, 12, 31}, false},
+		{Date{1740, 13, 5}, true},
+		{Date{1740, 13, 6}, false},
+		{Date{1739, 13, 6}, true},
+		{Date{1739, 13, 7}, false},
+		{Date{1740, 0, 1}, false},
+		{Date{1740, 14, 1}, false},
+		{Date{1740, 1, 0}, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.date.Valid(), tt.date.String())
+	}
+}
+
+func TestFormat(t *testing.T) {
+	d := Date{1743, 2, 12}
+	assert.Equal(t, "12 Paopi 1743", d.String())
+	assert.Equal(t, "12 بابه 1743", d.Arabic())
+}
+
+func TestEaster(t *testing.T) {
+	tests := []struct {
+		year int
+		want string
+	}{
+		{2019, "2019-04-28"},
+		{2020, "2020-04-19"},
+		{2021, "2021-05-02"},
+		{2022, "2022-04-24"},
+		{2023, "2023-04-16"},
+		{2024, "2024-05-05"},
+		{2025, "2025-04-20"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Easter(tt.year, time.UTC).Format("2006-01-02"), tt.year)
+	}
+}
+
+func TestFasts(t *testing.T) {
+	want := []struct {
+		name, start, end string
+	}{
+		{"Fast of Nineveh", "2024-02-26", "2024-02-28"},
+		{"Great Lent", "2024-03-11", "2024-05-04"},
+		{"Apostles' Fast", "2024-06-24", "2024-07-11"},
+		{"Fast of St. Mary", "2024-08-07", "2024-08-21"},
+		{"Nativity Fast", "2024-11-25", "2025-01-06"},
+	}
+	fasts := Fasts(2024, time.UTC)
+	if !assert.Len(t, fasts, len(want)) {
+		return
+	}
+	for i, w := range want {
+		assert.Equal(t, w.name, fasts[i].Name)
+		assert.Equal(t, w.start, fasts[i].Start.Format("2006-01-02"), w.name)
+		assert.Equal(t, w.end, fasts[i].End.Format("2006-01-02"), w.name)
+	}
+}
+
+func TestFeastsOn(t *testing.T) {
+	tests := []struct {
+		at   time.Time
+		want []string
+	}{
+		{time.Date(2024, 5, 5, 18, 0, 0, 0, time.UTC), []string{"Easter"}},
+		{day("2024-04-28"), []string{"Palm Sunday"}},
+		{day("2024-09-11"), []string{"Nayrouz"}},
+		{day("2024-09-27"), []string{"Feast of the Cross"}},
+		{day("2025-01-07"), []string{"Nativity"}},
+		{day("2024-05-06"), []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.at.Format("2006-01-02"), func(t *testing.T) {
+			got := []string{}
+			for _, f := range FeastsOn(tt.at, time.UTC) {
+				got = append(got, f.Name)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFeastsAreOrdered(t *testing.T) {
+	for year := 2020; year <= 2030; year++ {
+		feasts := Feasts(year, time.UTC)
+		for i := 1; i < len(feasts); i++ {
+			assert.False(t, feasts[i].Date.Before(feasts[i-1].Date), "%d: %s before %s", year, feasts[i].Name, feasts[i-1].Name)
+		}
+		for _, f := range feasts {
+			assert.Equal(t, year, f.Date.Year(), f.Name)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package coptic
+
+import (
+	"sort"
+	"time"
+)
+
+// Feast is a feast day of the church. Major marks the seven major Lordly
+// feasts.
+type Feast struct {
+	Name   string    `json:"name"`
+	NameAr string    `json:"nameAr"`
+	Date   time.Time `json:"date"`
+	Major  bool      `json:"major"`
+}
+
+// Fast is a fasting period, Start and End included.
+type Fast struct {
+	Name   string    `json:"name"`
+	NameAr string    `json:"nameAr"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+}
+
+// Easter returns the date of Pascha in the Gregorian year, computed with
+// the Alexandrian computus on the Julian calendar.
+func Easter(year int, loc *time.Location) time.Time {
+	a := year % 4
+	b := year % 7
+	c := year % 19
+	d := (19*c + 15) % 30
+	e := (2*a + 4*b - d + 34) % 7
+	month := (d + e + 114) / 31
+	day := (d+e+114)%31 + 1
+	y, m, dd := jdnToGregorian(julianToJDN(year, month, day))
+	return time.Date(y, time.Month(m), dd, 0, 0, 0, 0, loc)
+}
+
+type fixedFeast struct {
+	name   string
+	nameAr string
+	month  int
+	day    int
+	major  bool
+}
+
+var fixedFeasts = []fixedFeast{
+	{"Nayrouz", "عيد النيروز", 1, 1, false},
+	{"Feast of the Cross", "عيد الصليب", 1, 17, false},
+	{"Nativity", "عيد الميلاد المجيد", 4, 29, true},
+	{"Circumcision", "عيد الختان", 5, 6, false},
+	{"Theophany", "عيد الغطاس", 5, 11, true},
+	{"Wedding at Cana", "عيد عرس قانا الجليل", 5, 13, false},
+	{"Presentation in the Temple", "عيد دخول المسيح الهيكل", 6, 8, false},
+	{"Annunciation", "عيد البشارة", 7, 29, true},
+	{"Entry into Egypt", "عيد دخول المسيح أرض مصر", 9, 24, false},
+	{"Feast of the Apostles", "عيد الرسل", 11, 5, false},
+	{"Transfiguration", "عيد التجلي", 12, 13, false},
+	{"Assumption of St. Mary", "عيد صعود جسد السيدة العذراء", 12, 16, false},
+}
+
+type movableFeast struct {
+	name   string
+	nameAr string
+	offset int
+	major  bool
+}
+
+// movableFeasts are counted in days from Easter.
+var movableFeasts = []movableFeast{
+	{"Palm Sunday", "أحد الشعانين", -7, true},
+	{"Covenant Thursday", "خميس العهد", -3, false},
+	{"Good Friday", "الجمعة العظيمة", -2, false},
+	{"Easter", "عيد القيامة المجيد", 0, true},
+	{"Thomas Sunday", "أحد توما", 7, false},
+	{"Ascension", "عيد الصعود", 39, true},
+	{"Pentecost", "عيد العنصرة", 49, true},
+}
+
+// Feasts returns the feasts falling in the Gregorian year, ordered by date.
+func Feasts(year int, loc *time.Location) []Feast {
+	feasts := []Feast{}
+	for _, f := range fixedFeasts {
+		for _, d := range fixedInYear(year, f.month, f.day, loc) {
+			feasts = append(feasts, Feast{Name: f.name, NameAr: f.nameAr, Date: d, Major: f.major})
+		}
+	}
+	easter := Easter(year, loc)
+	for _, f := range movableFeasts {
+		feasts = append(feasts, Feast{Name: f.name, NameAr: f.nameAr, Date: easter.AddDate(0, 0, f.offset), Major: f.major})
+	}
+	sort.SliceStable(feasts, func(i, j int) bool { return feasts[i].Date.Before(feasts[j].Date) })
+	return feasts
+}
+
+// Fasts returns the fasting periods starting in the Gregorian year, ordered
+// by start. The weekly Wednesday and Friday fasts are not included.
+func Fasts(year int, loc *time.Location) []Fast {
+	easter := Easter(year, loc)
+	fasts := []Fast{
+		{"Fast of Nineveh", "صوم يونان", easter.AddDate(0, 0, -69), easter.AddDate(0, 0, -67)},
+		{"Great Lent", "الصوم الكبير", easter.AddDate(0, 0, -55), easter.AddDate(0, 0, -1)},
+	}
+	// The Apostles' fast runs from the day after Pentecost to the eve of
+	// their feast on 5 Epip.
+	for _, apostles := range fixedInYear(year, 11, 5, loc) {
+		if start := easter.AddDate(0, 0, 50); start.Before(apostles) {
+			fasts = append(fasts, Fast{"Apostles' Fast", "صوم الرسل", start, apostles.AddDate(0, 0, -1)})
+		}
+	}
+	for _, start := range fixedInYear(year, 12, 1, loc) {
+		fasts = append(fasts, Fast{"Fast of St. Mary", "صوم السيدة العذراء", start, start.AddDate(0, 0, 14)})
+	}
+	for _, start := range fixedInYear(year, 3, 16, loc) {
+		nativity := Date{Year: FromGregorian(start).Year, Month: 4, Day: 29}.Gregorian(loc)
+		fasts = append(fasts, Fast{"Nativity Fast", "صوم الميلاد", start, nativity.AddDate(0, 0, -1)})
+	}
+	sort.SliceStable(fasts, func(i, j int) bool { return fasts[i].Start.Before(fasts[j].Start) })
+	return fasts
+}
+
+// FeastsOn returns the feasts falling on t's calendar day in loc.
+func FeastsOn(t time.Time, loc *time.Location) []Feast {
+	t = t.In(loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	on := []Feast{}
+	for _, f := range Feasts(day.Year(), loc) {
+		if f.Date.Equal(day) {
+			on = append(on, f)
+		}
+	}
+	return on
+}
+
+// fixedInYear returns the Gregorian dates in year of the Coptic month and
+// day. A Coptic date falls in a Gregorian year once.
+func fixedInYear(year, month, day int, loc *time.Location) []time.Time {
+	dates := []time.Time{}
+	copticYear := FromGregorian(time.Date(year, 1, 1, 0, 0, 0, 0, loc)).Year
+	for _, y := range []int{copticYear, copticYear + 1} {
+		if d := (Date{Year: y, Month: month, Day: day}).Gregorian(loc); d.Year() == year {
+			dates = append(dates, d)
+		}
+	}
+	return dates
+}
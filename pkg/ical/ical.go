@@ -0,0 +1,124 @@
+// Package ical writes RFC 5545 iCalendar feeds.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event is a VEVENT. An event without End or Duration lasts no time.
+type Event struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	Categories  []string
+}
+
+// Todo is a VTODO with a due date that still needs action.
+type Todo struct {
+	UID         string
+	Due         time.Time
+	Summary     string
+	Description string
+}
+
+// Calendar is a VCALENDAR. Stamp is written as the DTSTAMP of every entry.
+type Calendar struct {
+	Name   string
+	Stamp  time.Time
+	Events []Event
+	Todos  []Todo
+}
+
+// Write writes the calendar with CRLF line endings, folding lines longer than
+// 75 octets.
+func (c *Calendar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+	stamp := formatTime(c.Stamp)
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//EKMS//Calendar//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escape(c.Name))
+	}
+	for _, e := range c.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", stamp)
+		line("DTSTART", formatTime(e.Start))
+		if !e.End.IsZero() {
+			line("DTEND", formatTime(e.End))
+		}
+		line("SUMMARY", escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escape(e.Description))
+		}
+		if e.Location != "" {
+			line("LOCATION", escape(e.Location))
+		}
+		if len(e.Categories) > 0 {
+			escaped := make([]string, len(e.Categories))
+			for i, cat := range e.Categories {
+				escaped[i] = escape(cat)
+			}
+			line("CATEGORIES", strings.Join(escaped, ","))
+		}
+		line("END", "VEVENT")
+	}
+	for _, t := range c.Todos {
+		line("BEGIN", "VTODO")
+		line("UID", t.UID)
+		line("DTSTAMP", stamp)
+		line("DUE", formatTime(t.Due))
+		line("SUMMARY", escape(t.Summary))
+		if t.Description != "" {
+			line("DESCRIPTION", escape(t.Description))
+		}
+		line("STATUS", "NEEDS-ACTION")
+		line("END", "VTODO")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// writeFolded writes a content line, continuing it on lines starting with a
+// space so that none is longer than 75 octets. Multi-byte characters are
+// never split.
+func writeFolded(w *bufio.Writer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// The leading space counts towards the continuation line.
+		limit = 74
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,118 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{`a\b`, `a\\b`},
+		{"a;b,c", `a\;b\,c`},
+		{"line\nnext", `line\nnext`},
+		{"line\r\nnext", `line\nnext`},
+		{"line\rnext", `line\nnext`},
+		{"مينا", "مينا"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, escape(tt.in), tt.in)
+	}
+}
+
+func TestWriteFolded(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"short", "SUMMARY:Meeting"},
+		{"exactly 75", "SUMMARY:" + strings.Repeat("a", 67)},
+		{"76", "SUMMARY:" + strings.Repeat("a", 68)},
+		{"long ascii", "DESCRIPTION:" + strings.Repeat("abcdefghij", 30)},
+		{"arabic", "SUMMARY:" + strings.Repeat("اجتماع الخدام ", 20)},
+		{"emoji", "SUMMARY:" + strings.Repeat("🙏", 50)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			writeFolded(w, tt.in)
+			require.NoError(t, w.Flush())
+
+			out := buf.String()
+			require.True(t, strings.HasSuffix(out, "\r\n"))
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			for i, line := range lines {
+				assert.LessOrEqual(t, len(line), 75, "line %d", i)
+				assert.True(t, utf8.ValidString(line), "line %d splits a character", i)
+				if i > 0 {
+					assert.True(t, strings.HasPrefix(line, " "), "line %d", i)
+				}
+			}
+			// Unfolding gives back the line.
+			assert.Equal(t, tt.in, strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""))
+			if len(tt.in) <= 75 {
+				assert.Len(t, lines, 1)
+			}
+		})
+	}
+}
+
+func TestCalendarWrite(t *testing.T) {
+	cal := Calendar{
+		Name:  "Youth; servants",
+		Stamp: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+		Events: []Event{{
+			UID:        "service-1@ekms",
+			Start:      time.Date(2024, 5, 3, 19, 0, 0, 0, time.FixedZone("EEST", 3*60*60)),
+			End:        time.Date(2024, 5, 3, 21, 0, 0, 0, time.FixedZone("EEST", 3*60*60)),
+			Summary:    "Meeting, week 1",
+			Location:   "Hall\nSecond floor",
+			Categories: []string{"Youth", "a,b"},
+		}},
+		Todos: []Todo{{
+			UID:     "assignment-1@ekms",
+			Due:     time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC),
+			Summary: "Read John 3",
+		}},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, cal.Write(&buf))
+
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//EKMS//Calendar//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		`X-WR-CALNAME:Youth\; servants`,
+		"BEGIN:VEVENT",
+		"UID:service-1@ekms",
+		"DTSTAMP:20240501T090000Z",
+		"DTSTART:20240503T160000Z",
+		"DTEND:20240503T180000Z",
+		`SUMMARY:Meeting\, week 1`,
+		`LOCATION:Hall\nSecond floor`,
+		`CATEGORIES:Youth,a\,b`,
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:assignment-1@ekms",
+		"DTSTAMP:20240501T090000Z",
+		"DUE:20240510T000000Z",
+		"SUMMARY:Read John 3",
+		"STATUS:NEEDS-ACTION",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n") + "\r\n"
+	assert.Equal(t, want, buf.String())
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AccessToken lets a person authenticate API requests. Only a hash of the
+// token is stored; the token itself is shown once, when it is issued.
+type AccessToken struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PersonID   primitive.ObjectID `json:"personId" bson:"personId,omitempty"`
+	Name       string             `json:"name" bson:"name,omitempty"`
+	Hash       string             `json:"-" bson:"hash,omitempty"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt,omitempty"`
+	LastUsedAt time.Time          `json:"lastUsedAt" bson:"lastUsedAt,omitempty"`
+	RevokedAt  time.Time          `json:"revokedAt" bson:"revokedAt,omitempty"`
+}
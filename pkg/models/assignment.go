@@ -6,15 +6,50 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Assignment is work given at a service. ReminderHours lists how many hours
+// before the deadline persons who have not submitted are reminded; the
+// thresholds already handled are kept in RemindersSent. Reminded records each
+// reminder and missing submissions notice sent, so a person is never sent the
+// same one twice.
 type Assignment struct {
-	ID          primitive.ObjectID     `json:"id" bson:"_id,omitempty"`
-	ServiceID   primitive.ObjectID     `json:"serviceId" bson:"serviceId,omitempty"`
-	Title       string                 `json:"title" bson:"title,omitempty"`
-	Deadline    time.Time              `json:"deadline" bson:"deadline,omitempty"`
-	Submissions []AssignmentSubmission `json:"submissions" bson:"submissions,omitempty"`
+	ID              primitive.ObjectID     `json:"id" bson:"_id,omitempty"`
+	ServiceID       primitive.ObjectID     `json:"serviceId" bson:"serviceId,omitempty"`
+	Title           string                 `json:"title" bson:"title,omitempty"`
+	Deadline        time.Time              `json:"deadline" bson:"deadline,omitempty"`
+	MaxScore        float64                `json:"maxScore" bson:"maxScore,omitempty"`
+	Attachments     []primitive.ObjectID   `json:"attachments" bson:"attachments,omitempty"`
+	Submissions     []AssignmentSubmission `json:"submissions" bson:"submissions,omitempty"`
+	ReminderHours   []int                  `json:"reminderHours" bson:"reminderHours,omitempty"`
+	RemindersSent   []int                  `json:"remindersSent" bson:"remindersSent,omitempty"`
+	MissingNoticeAt time.Time              `json:"missingNoticeAt" bson:"missingNoticeAt,omitempty"`
+	Reminded        []AssignmentReminder   `json:"reminded" bson:"reminded,omitempty"`
 }
 
-type AssignmentSubmission struct {
+// AssignmentReminder records that the person was sent the reminder for the
+// threshold Hours before the deadline. Hours is 0 for the missing submissions
+// notice sent to servants.
+type AssignmentReminder struct {
 	PersonID primitive.ObjectID `json:"personId" bson:"personId,omitempty"`
-	Time     time.Time          `json:"time" bson:"time,omitempty"`
+	Hours    int                `json:"hours" bson:"hours"`
+}
+
+const (
+	SubmissionSubmitted = "submitted"
+	SubmissionGraded    = "graded"
+	// SubmissionReturned marks a submission sent back to be redone.
+	SubmissionReturned = "returned"
+)
+
+// AssignmentSubmission is a person's answer to an assignment. Score is nil
+// until the submission is graded.
+type AssignmentSubmission struct {
+	PersonID    primitive.ObjectID   `json:"personId" bson:"personId,omitempty"`
+	Time        time.Time            `json:"time" bson:"time,omitempty"`
+	Answer      string               `json:"answer" bson:"answer,omitempty"`
+	Attachments []primitive.ObjectID `json:"attachments" bson:"attachments,omitempty"`
+	State       string               `json:"state" bson:"state,omitempty"`
+	Score       *float64             `json:"score" bson:"score,omitempty"`
+	Feedback    string               `json:"feedback" bson:"feedback,omitempty"`
+	GraderID    primitive.ObjectID   `json:"graderId" bson:"graderId,omitempty"`
+	GradedAt    time.Time            `json:"gradedAt" bson:"gradedAt,omitempty"`
 }
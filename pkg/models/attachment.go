@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	AttachmentOfAssignment = "assignment"
+	AttachmentOfSubmission = "submission"
+)
+
+// Attachment describes an uploaded file. It belongs to an assignment or, when
+// PersonID is set, to that person's submission to the assignment.
+type Attachment struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Parent       string             `json:"parent" bson:"parent,omitempty"`
+	AssignmentID primitive.ObjectID `json:"assignmentId" bson:"assignmentId,omitempty"`
+	PersonID     primitive.ObjectID `json:"personId" bson:"personId,omitempty"`
+	Filename     string             `json:"filename" bson:"filename,omitempty"`
+	ContentType  string             `json:"contentType" bson:"contentType,omitempty"`
+	Size         int64              `json:"size" bson:"size"`
+	UploadedAt   time.Time          `json:"uploadedAt" bson:"uploadedAt,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CalendarFeedToken grants read access to the calendar feed of a group or
+// person, Kind "group" or "person". Only a hash of the token is stored; a feed
+// has at most one token that is not revoked.
+type CalendarFeedToken struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Kind      string             `json:"kind" bson:"kind,omitempty"`
+	TargetID  primitive.ObjectID `json:"targetId" bson:"targetId,omitempty"`
+	Hash      string             `json:"-" bson:"hash,omitempty"`
+	CreatedBy primitive.ObjectID `json:"createdBy" bson:"createdBy,omitempty"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt,omitempty"`
+	RevokedAt time.Time          `json:"revokedAt" bson:"revokedAt,omitempty"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Curriculum is a series of lessons planned for a group's services, taught
+// in order. Lessons are given to the group's services from StartDate to
+// EndDate, optionally only those of ScheduleID.
+type Curriculum struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name        string             `json:"name" bson:"name,omitempty"`
+	Description string             `json:"description" bson:"description,omitempty"`
+	GroupID     primitive.ObjectID `json:"groupId" bson:"groupId,omitempty"`
+	ScheduleID  primitive.ObjectID `json:"scheduleId" bson:"scheduleId,omitempty"`
+	StartDate   time.Time          `json:"startDate" bson:"startDate,omitempty"`
+	EndDate     time.Time          `json:"endDate" bson:"endDate,omitempty"`
+	Lessons     []Lesson           `json:"lessons" bson:"lessons,omitempty"`
+}
+
+// Lesson is a lesson of a curriculum. ServiceID and Date are the service it
+// is planned for or was given at; DeliveredAt is set once that service took
+// place.
+type Lesson struct {
+	ID           primitive.ObjectID `json:"id" bson:"id,omitempty"`
+	Subject      string             `json:"subject" bson:"subject,omitempty"`
+	BibleChapter string             `json:"bibleChapter" bson:"bibleChapter,omitempty"`
+	Objectives   []string           `json:"objectives" bson:"objectives,omitempty"`
+	Materials    []string           `json:"materials" bson:"materials,omitempty"`
+	ServiceID    primitive.ObjectID `json:"serviceId" bson:"serviceId,omitempty"`
+	Date         time.Time          `json:"date" bson:"date,omitempty"`
+	DeliveredAt  time.Time          `json:"deliveredAt" bson:"deliveredAt,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Father is a confession father. Aliases hold the other spellings persons
+// used for him in the free-text Person.Fr field. PersonID links the priest's
+// own person record, which lets him read the notes kept for priests.
+type Father struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name     string             `json:"name" bson:"name,omitempty"`
+	Aliases  []string           `json:"aliases" bson:"aliases,omitempty"`
+	Church   string             `json:"church" bson:"church,omitempty"`
+	Phone    string             `json:"phone" bson:"phone,omitempty"`
+	PersonID primitive.ObjectID `json:"personId" bson:"personId,omitempty"`
+}
+
+type Confession struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PersonID primitive.ObjectID `json:"personId" bson:"personId,omitempty"`
+	FatherID primitive.ObjectID `json:"fatherId" bson:"fatherId,omitempty"`
+	Date     time.Time          `json:"date" bson:"date,omitempty"`
+}
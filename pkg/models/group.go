@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	GroupRoleMember  = "member"
+	GroupRoleServant = "servant"
+)
+
+// Group is a class or team of persons. BirthdayReminders records the
+// birthday reminders sent to the group's servants, so a servant is never sent
+// the same day twice.
+type Group struct {
+	ID                primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name              string             `json:"name" bson:"name,omitempty"`
+	Description       string             `json:"description" bson:"description,omitempty"`
+	Memberships       []GroupMembership  `json:"memberships" bson:"memberships,omitempty"`
+	BirthdayReminders []BirthdayReminder `json:"birthdayReminders" bson:"birthdayReminders,omitempty"`
+}
+
+// BirthdayReminder records that the servant was told about the birthdays of
+// the group on Day.
+type BirthdayReminder struct {
+	PersonID primitive.ObjectID `json:"personId" bson:"personId,omitempty"`
+	Day      time.Time          `json:"day" bson:"day,omitempty"`
+}
+
+type GroupMembership struct {
+	PersonID primitive.ObjectID `json:"personId" bson:"personId,omitempty"`
+	Role     string             `json:"role" bson:"role,omitempty"`
+	JoinedAt time.Time          `json:"joinedAt" bson:"joinedAt,omitempty"`
+	LeftAt   time.Time          `json:"leftAt" bson:"leftAt,omitempty"`
+}
+
+// ActiveAt reports whether the membership covers t.
+func (m GroupMembership) ActiveAt(t time.Time) bool {
+	if !m.JoinedAt.IsZero() && m.JoinedAt.After(t) {
+		return false
+	}
+	return m.LeftAt.IsZero() || m.LeftAt.After(t)
+}
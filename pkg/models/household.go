@@ -0,0 +1,30 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Household struct {
+	ID        primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	Name      string               `json:"name" bson:"name,omitempty"`
+	Address   string               `json:"address" bson:"address,omitempty"`
+	Phone     string               `json:"phone" bson:"phone,omitempty"`
+	MemberIDs []primitive.ObjectID `json:"memberIds" bson:"memberIds,omitempty"`
+}
+
+const (
+	RelationshipParent   = "parent"
+	RelationshipSibling  = "sibling"
+	RelationshipSpouse   = "spouse"
+	RelationshipGuardian = "guardian"
+)
+
+// Relationship is a directed edge between two persons: RelatedID is the
+// PersonID's parent, sibling, spouse or guardian. Sibling and spouse edges
+// hold both ways.
+type Relationship struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PersonID  primitive.ObjectID `json:"personId" bson:"personId,omitempty"`
+	RelatedID primitive.ObjectID `json:"relatedId" bson:"relatedId,omitempty"`
+	Type      string             `json:"type" bson:"type,omitempty"`
+}
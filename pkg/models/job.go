@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	// JobStatusExhausted marks a failed run that is not retried any more: the
+	// job waits for its next scheduled run.
+	JobStatusExhausted = "exhausted"
+)
+
+// Job is the persisted state of a scheduled job. Jobs are defined in code and
+// identified by name; the database holds when each one runs next and which
+// instance holds its lease while it runs. Failures counts the consecutive
+// failed runs, which are retried with backoff, until the next successful run.
+type Job struct {
+	Name         string    `json:"name" bson:"_id"`
+	Schedule     string    `json:"schedule" bson:"schedule,omitempty"`
+	Paused       bool      `json:"paused" bson:"paused"`
+	Triggered    bool      `json:"triggered" bson:"triggered"`
+	NextRun      time.Time `json:"nextRun" bson:"nextRun,omitempty"`
+	LastRun      time.Time `json:"lastRun" bson:"lastRun,omitempty"`
+	LastDuration string    `json:"lastDuration" bson:"lastDuration,omitempty"`
+	LastStatus   string    `json:"lastStatus" bson:"lastStatus,omitempty"`
+	LastError    string    `json:"lastError" bson:"lastError,omitempty"`
+	Failures     int       `json:"failures" bson:"failures"`
+	LeaseOwner   string    `json:"leaseOwner" bson:"leaseOwner,omitempty"`
+	LeaseUntil   time.Time `json:"leaseUntil" bson:"leaseUntil,omitempty"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	NoteCategoryHealth    = "health"
+	NoteCategoryFamily    = "family"
+	NoteCategorySpiritual = "spiritual"
+	NoteCategoryStudy     = "study"
+	NoteCategoryWork      = "work"
+	NoteCategoryOther     = "other"
+)
+
+// Note visibilities. Author only notes are read by their author alone, group
+// notes also by the servants of the person's groups and by priests, and
+// priest notes by the author and priests.
+const (
+	NoteVisibilityAuthor = "author"
+	NoteVisibilityGroup  = "group"
+	NoteVisibilityPriest = "priest"
+)
+
+// Note is a pastoral note a servant kept about a person after a call or a
+// visit. The body is stored encrypted.
+type Note struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PersonID   primitive.ObjectID `json:"personId" bson:"personId,omitempty"`
+	AuthorID   primitive.ObjectID `json:"authorId" bson:"authorId,omitempty"`
+	Category   string             `json:"category" bson:"category,omitempty"`
+	Visibility string             `json:"visibility" bson:"visibility,omitempty"`
+	Body       string             `json:"body" bson:"body,omitempty"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt,omitempty"`
+	UpdatedAt  time.Time          `json:"updatedAt" bson:"updatedAt,omitempty"`
+}
+
+// NoteRead records that a reader was shown a note.
+type NoteRead struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	NoteID   primitive.ObjectID `json:"noteId" bson:"noteId,omitempty"`
+	PersonID primitive.ObjectID `json:"personId" bson:"personId,omitempty"`
+	ReaderID primitive.ObjectID `json:"readerId" bson:"readerId,omitempty"`
+	ReadAt   time.Time          `json:"readAt" bson:"readAt,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
+
+// Notification is a message sent, or to be sent, to a person. Pending
+// notifications are retried from NextAttempt on. While a delivery attempt
+// runs the notification is leased until LeasedUntil, so nothing else
+// delivers it at the same time.
+type Notification struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PersonID    primitive.ObjectID `json:"personId" bson:"personId,omitempty"`
+	Name        string             `json:"name" bson:"name,omitempty"`
+	Channel     string             `json:"channel" bson:"channel,omitempty"`
+	To          string             `json:"to" bson:"to,omitempty"`
+	Template    string             `json:"template" bson:"template,omitempty"`
+	Language    string             `json:"language" bson:"language,omitempty"`
+	Subject     string             `json:"subject" bson:"subject,omitempty"`
+	Body        string             `json:"body" bson:"body,omitempty"`
+	Status      string             `json:"status" bson:"status,omitempty"`
+	Attempts    int                `json:"attempts" bson:"attempts"`
+	LastError   string             `json:"lastError" bson:"lastError,omitempty"`
+	NextAttempt time.Time          `json:"nextAttempt" bson:"nextAttempt,omitempty"`
+	LeasedUntil time.Time          `json:"leasedUntil" bson:"leasedUntil,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt,omitempty"`
+	SentAt      time.Time          `json:"sentAt" bson:"sentAt,omitempty"`
+}
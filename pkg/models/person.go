@@ -1,17 +1,53 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErasedPersonName replaces the name of persons whose personal data was
+// erased. ErasedAt is set on them.
+const ErasedPersonName = "Erased person"
+
 type Person struct {
 	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Name     string             `json:"name" bson:"name,omitempty"`
 	Birthday time.Time          `json:"birthday" bson:"birthday,omitempty"`
 	Phone    string             `json:"phone" bson:"phone,omitempty"`
+	Email    string             `json:"email" bson:"email,omitempty"`
 	Address  string             `json:"address" bson:"address,omitempty"`
 	Fr       string             `json:"fr" bson:"fr,omitempty"`
+	FatherID primitive.ObjectID `json:"fatherId" bson:"fatherId,omitempty"`
 	Degree   string             `json:"degree" bson:"degree,omitempty"`
+	Language string             `json:"language" bson:"language,omitempty"`
+	ErasedAt time.Time          `json:"erasedAt" bson:"erasedAt,omitempty"`
+}
+
+// String identifies the person without their phone, address and birthday,
+// so printing a person does not put them in the logs.
+func (p Person) String() string {
+	return fmt.Sprintf("Person(%s %s)", p.ID.Hex(), p.Name)
+}
+
+// NormalizePhone strips everything but digits from phone and rewrites the
+// Egyptian country code prefix to the local trunk prefix so "+20 120 603 2004"
+// and "01206032004" compare equal.
+func NormalizePhone(phone string) string {
+	var b strings.Builder
+	for _, ch := range phone {
+		if ch >= '0' && ch <= '9' {
+			b.WriteRune(ch)
+		} else if ch >= '٠' && ch <= '٩' {
+			b.WriteRune('0' + (ch - '٠'))
+		}
+	}
+	digits := b.String()
+	digits = strings.TrimPrefix(digits, "00")
+	if strings.HasPrefix(digits, "20") && len(digits) == 12 {
+		digits = "0" + digits[2:]
+	}
+	return digits
 }
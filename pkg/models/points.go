@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Events a points rule can be evaluated on.
+const (
+	// PointsOnAttendance awards persons attending a service.
+	PointsOnAttendance = "attendance"
+	// PointsOnTime awards persons arriving no later than GraceMinutes after
+	// the service starts.
+	PointsOnTime = "on_time"
+	// PointsOnSubmission awards every assignment submission.
+	PointsOnSubmission = "submission"
+	// PointsOnTimeSubmission awards submissions made before the deadline.
+	PointsOnTimeSubmission = "on_time_submission"
+	// PointsOnGrade awards graded submissions scoring at least MinPercent of
+	// the assignment's MaxScore.
+	PointsOnGrade = "grade"
+)
+
+const (
+	PointsKindRule       = "rule"
+	PointsKindAdjustment = "adjustment"
+	PointsKindRedemption = "redemption"
+)
+
+// PointsRule awards Points whenever its event happens. A rule with a GroupID
+// only applies to that group's services and their assignments.
+type PointsRule struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name         string             `json:"name" bson:"name,omitempty"`
+	Event        string             `json:"event" bson:"event,omitempty"`
+	Points       int                `json:"points" bson:"points"`
+	GroupID      primitive.ObjectID `json:"groupId" bson:"groupId,omitempty"`
+	GraceMinutes int                `json:"graceMinutes" bson:"graceMinutes,omitempty"`
+	MinPercent   float64            `json:"minPercent" bson:"minPercent,omitempty"`
+	Disabled     bool               `json:"disabled" bson:"disabled,omitempty"`
+}
+
+// PointsEntry is a line of the points ledger. Redemptions have negative
+// Points. Entries awarded by a rule carry a Key unique to the rule, the
+// service or assignment they were earned on and the person, so evaluating
+// the same event twice does not award points twice.
+type PointsEntry struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Key       string             `json:"-" bson:"key,omitempty"`
+	PersonID  primitive.ObjectID `json:"personId" bson:"personId,omitempty"`
+	Points    int                `json:"points" bson:"points"`
+	Kind      string             `json:"kind" bson:"kind,omitempty"`
+	RuleID    primitive.ObjectID `json:"ruleId" bson:"ruleId,omitempty"`
+	SourceID  primitive.ObjectID `json:"sourceId" bson:"sourceId,omitempty"`
+	GroupID   primitive.ObjectID `json:"groupId" bson:"groupId,omitempty"`
+	Reason    string             `json:"reason" bson:"reason,omitempty"`
+	ByID      primitive.ObjectID `json:"byId" bson:"byId,omitempty"`
+	Date      time.Time          `json:"date" bson:"date,omitempty"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt,omitempty"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	QuestionMultipleChoice = "multiple_choice"
+	QuestionTrueFalse      = "true_false"
+	QuestionShortAnswer    = "short_answer"
+)
+
+// DefaultQuizAttempts is how often a person may submit a quiz that sets no
+// MaxAttempts.
+const DefaultQuizAttempts = 3
+
+// Quiz is a set of auto-graded questions about a service or an assignment.
+// Persons can resubmit until ClosesAt, up to MaxAttempts times; their last
+// submission counts.
+type Quiz struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Title        string             `json:"title" bson:"title,omitempty"`
+	ServiceID    primitive.ObjectID `json:"serviceId" bson:"serviceId,omitempty"`
+	AssignmentID primitive.ObjectID `json:"assignmentId" bson:"assignmentId,omitempty"`
+	ClosesAt     time.Time          `json:"closesAt" bson:"closesAt,omitempty"`
+	MaxAttempts  int                `json:"maxAttempts" bson:"maxAttempts,omitempty"`
+	Questions    []QuizQuestion     `json:"questions" bson:"questions,omitempty"`
+	Submissions  []QuizSubmission   `json:"submissions" bson:"submissions,omitempty"`
+}
+
+// QuizQuestion is one question of a quiz. Correct holds the accepted
+// answers: the correct choices of a multiple choice question, "true" or
+// "false" for a true/false question, and every accepted spelling of a short
+// answer, which is compared ignoring case, punctuation and Arabic diacritics.
+type QuizQuestion struct {
+	ID      primitive.ObjectID `json:"id" bson:"id,omitempty"`
+	Type    string             `json:"type" bson:"type,omitempty"`
+	Text    string             `json:"text" bson:"text,omitempty"`
+	Choices []string           `json:"choices,omitempty" bson:"choices,omitempty"`
+	Correct []string           `json:"correct,omitempty" bson:"correct,omitempty"`
+	Points  float64            `json:"points" bson:"points,omitempty"`
+}
+
+// QuizSubmission is a person's last submission to a quiz. Attempts counts
+// their submissions so far.
+type QuizSubmission struct {
+	PersonID primitive.ObjectID `json:"personId" bson:"personId,omitempty"`
+	Time     time.Time          `json:"time" bson:"time,omitempty"`
+	Attempts int                `json:"attempts" bson:"attempts,omitempty"`
+	Answers  []QuizAnswer       `json:"answers" bson:"answers,omitempty"`
+	Score    float64            `json:"score" bson:"score"`
+	MaxScore float64            `json:"maxScore" bson:"maxScore"`
+}
+
+type QuizAnswer struct {
+	QuestionID primitive.ObjectID `json:"questionId" bson:"questionId,omitempty"`
+	Answer     string             `json:"answer" bson:"answer,omitempty"`
+	Correct    bool               `json:"correct" bson:"correct"`
+	Points     float64            `json:"points" bson:"points"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ScheduleWeekly  = "weekly"
+	ScheduleMonthly = "monthly"
+)
+
+const (
+	FeastSkip  = "skip"
+	FeastShift = "shift"
+)
+
+// ServiceSchedule generates a Service for every occurrence of a recurring
+// meeting. Weekly schedules meet on Weekdays (0 is Sunday) every Interval
+// weeks. Monthly schedules meet every Interval months, either on MonthDay or,
+// when Week is set, on the Week-th Weekdays[0] of the month (-1 for the
+// last). Time is the start time, "15:04", in TimeZone.
+//
+// OnFeast decides what happens to occurrences falling on a major feast of
+// the Coptic calendar: FeastSkip cancels them and FeastShift moves them by
+// FeastShiftDays. Empty meets as usual.
+type ServiceSchedule struct {
+	ID             primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Name           string              `json:"name" bson:"name,omitempty"`
+	Frequency      string              `json:"frequency" bson:"frequency,omitempty"`
+	Interval       int                 `json:"interval" bson:"interval,omitempty"`
+	Weekdays       []int               `json:"weekdays" bson:"weekdays,omitempty"`
+	MonthDay       int                 `json:"monthDay" bson:"monthDay,omitempty"`
+	Week           int                 `json:"week" bson:"week,omitempty"`
+	Time           string              `json:"time" bson:"time,omitempty"`
+	TimeZone       string              `json:"timeZone" bson:"timeZone,omitempty"`
+	StartDate      time.Time           `json:"startDate" bson:"startDate,omitempty"`
+	EndDate        time.Time           `json:"endDate" bson:"endDate,omitempty"`
+	Subject        string              `json:"subject" bson:"subject,omitempty"`
+	Speaker        string              `json:"speaker" bson:"speaker,omitempty"`
+	Location       string              `json:"location" bson:"location,omitempty"`
+	GroupID        primitive.ObjectID  `json:"groupId" bson:"groupId,omitempty"`
+	Skipped        []time.Time         `json:"skipped" bson:"skipped,omitempty"`
+	Exceptions     []ScheduleException `json:"exceptions" bson:"exceptions,omitempty"`
+	OnFeast        string              `json:"onFeast" bson:"onFeast,omitempty"`
+	FeastShiftDays int                 `json:"feastShiftDays" bson:"feastShiftDays,omitempty"`
+}
+
+// ScheduleException changes a single occurrence of a schedule. Empty fields
+// keep the schedule's value.
+type ScheduleException struct {
+	Occurrence time.Time `json:"occurrence" bson:"occurrence,omitempty"`
+	Date       time.Time `json:"date" bson:"date,omitempty"`
+	Subject    string    `json:"subject" bson:"subject,omitempty"`
+	Speaker    string    `json:"speaker" bson:"speaker,omitempty"`
+	Location   string    `json:"location" bson:"location,omitempty"`
+}
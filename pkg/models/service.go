@@ -1,19 +1,38 @@
 package models
 
 import (
+	"strings"
 	"time"
 
+	"github.com/Mario-Kamel/EKMS/pkg/bible"
+	"github.com/Mario-Kamel/EKMS/pkg/coptic"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Service is a meeting. Services generated from a ServiceSchedule carry its
+// ScheduleID and the Occurrence they were generated for, which stays the same
+// when the service is moved to another date. Speaker is the speaker's name,
+// also when SpeakerID links a Speaker. BibleRefs are parsed from
+// BibleChapter. Services a Curriculum lesson is planned for carry its
+// CurriculumID and LessonID. CopticDate is filled in for display and not
+// stored.
 type Service struct {
 	ID               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Date             time.Time          `json:"date" bson:"date,omitempty"`
 	Subject          string             `json:"subject" bson:"subject,omitempty"`
 	Speaker          string             `json:"speaker" bson:"speaker,omitempty"`
+	SpeakerID        primitive.ObjectID `json:"speakerId" bson:"speakerId,omitempty"`
 	BibleChapter     string             `json:"bibleChapter" bson:"bibleChapter,omitempty"`
+	BibleRefs        []bible.Reference  `json:"bibleRefs" bson:"bibleRefs,omitempty"`
+	Location         string             `json:"location" bson:"location,omitempty"`
 	AttendanceRecord []AttendanceRecord `json:"attendanceRecord" bson:"attendanceRecord,omitempty"`
 	AssignmentID     primitive.ObjectID `json:"assignmentId" bson:"assignmentId,omitempty"`
+	GroupID          primitive.ObjectID `json:"groupId" bson:"groupId,omitempty"`
+	ScheduleID       primitive.ObjectID `json:"scheduleId" bson:"scheduleId,omitempty"`
+	Occurrence       time.Time          `json:"occurrence" bson:"occurrence,omitempty"`
+	CurriculumID     primitive.ObjectID `json:"curriculumId" bson:"curriculumId,omitempty"`
+	LessonID         primitive.ObjectID `json:"lessonId" bson:"lessonId,omitempty"`
+	CopticDate       *coptic.Date       `json:"copticDate,omitempty" bson:"-"`
 }
 
 type AttendanceRecord struct {
@@ -21,3 +40,9 @@ type AttendanceRecord struct {
 	Time     time.Time          `json:"time" bson:"time,omitempty"`
 	Status   string             `json:"status" bson:"status,omitempty"`
 }
+
+// Attended reports whether the record counts as the person being there.
+// Records explicitly marked absent do not.
+func (ar AttendanceRecord) Attended() bool {
+	return !strings.EqualFold(strings.TrimSpace(ar.Status), "absent")
+}
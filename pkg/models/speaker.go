@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Speaker gives talks at services, possibly one of the persons. A speaker
+// with Available windows only speaks within them; Unavailable windows are
+// excluded either way. Inactive speakers are left out of planning.
+type Speaker struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name        string             `json:"name" bson:"name,omitempty"`
+	PersonID    primitive.ObjectID `json:"personId" bson:"personId,omitempty"`
+	Phone       string             `json:"phone" bson:"phone,omitempty"`
+	Topics      []string           `json:"topics" bson:"topics,omitempty"`
+	Available   []SpeakerWindow    `json:"available" bson:"available,omitempty"`
+	Unavailable []SpeakerWindow    `json:"unavailable" bson:"unavailable,omitempty"`
+	Inactive    bool               `json:"inactive" bson:"inactive,omitempty"`
+}
+
+// SpeakerWindow is a period, From and To included, optionally limited to
+// Weekdays (0 is Sunday). An unset From or To leaves that side open.
+type SpeakerWindow struct {
+	From     time.Time `json:"from" bson:"from,omitempty"`
+	To       time.Time `json:"to" bson:"to,omitempty"`
+	Weekdays []int     `json:"weekdays" bson:"weekdays,omitempty"`
+	Note     string    `json:"note" bson:"note,omitempty"`
+}
+
+// Covers reports whether t falls in the window.
+func (w SpeakerWindow) Covers(t time.Time) bool {
+	if !w.From.IsZero() && t.Before(w.From) {
+		return false
+	}
+	if !w.To.IsZero() && t.After(w.To) {
+		return false
+	}
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, d := range w.Weekdays {
+		if int(t.Weekday()) == d {
+			return true
+		}
+	}
+	return false
+}
+
+// AvailableAt reports whether the speaker can speak at t.
+func (s Speaker) AvailableAt(t time.Time) bool {
+	for _, w := range s.Unavailable {
+		if w.Covers(t) {
+			return false
+		}
+	}
+	if len(s.Available) == 0 {
+		return true
+	}
+	for _, w := range s.Available {
+		if w.Covers(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SMSNotifier sends text messages through an HTTP SMS gateway. Every message
+// is posted to URL as {"to": ..., "from": ..., "message": ...} with Token as
+// bearer token; any 2xx response counts as accepted.
+type SMSNotifier struct {
+	URL    string
+	Token  string
+	Sender string
+	Client *http.Client
+}
+
+func NewSMSNotifier(url, token, sender string) *SMSNotifier {
+	return &SMSNotifier{URL: url, Token: token, Sender: sender, Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (n *SMSNotifier) Notify(ctx context.Context, msg Message) error {
+	payload := map[string]string{
+		"to":      msg.To,
+		"from":    n.Sender,
+		"message": msg.Body,
+	}
+	return postJSON(ctx, n.Client, n.URL, n.Token, payload)
+}
+
+// DefaultWhatsAppURL is the WhatsApp Business Cloud API endpoint.
+const DefaultWhatsAppURL = "https://graph.facebook.com/v18.0"
+
+// WhatsAppNotifier sends text messages with the WhatsApp Business Cloud API
+// from the business phone number PhoneNumberID. WhatsApp only delivers free
+// form text within 24 hours of the recipient's last message; outside that
+// window the API rejects it and the message is retried or marked failed.
+type WhatsAppNotifier struct {
+	BaseURL       string
+	PhoneNumberID string
+	Token         string
+	Client        *http.Client
+}
+
+func NewWhatsAppNotifier(phoneNumberID, token string) *WhatsAppNotifier {
+	return &WhatsAppNotifier{
+		BaseURL:       DefaultWhatsAppURL,
+		PhoneNumberID: phoneNumberID,
+		Token:         token,
+		Client:        &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (n *WhatsAppNotifier) Notify(ctx context.Context, msg Message) error {
+	body := msg.Body
+	if msg.Subject != "" {
+		body = "*" + msg.Subject + "*\n\n" + body
+	}
+	payload := map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"to":                msg.To,
+		"type":              "text",
+		"text":              map[string]string{"body": body},
+	}
+	return postJSON(ctx, n.Client, n.BaseURL+"/"+n.PhoneNumberID+"/messages", n.Token, payload)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url, token string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("%s responded %s: %s", url, res.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Package notify delivers messages to people through pluggable drivers.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Channels a message can be delivered on. The address of a message is an
+// email address for ChannelEmail and a phone number in international format
+// for the others.
+const (
+	ChannelEmail    = "email"
+	ChannelSMS      = "sms"
+	ChannelWhatsApp = "whatsapp"
+)
+
+// Message is a single message to one recipient.
+type Message struct {
+	Channel string `json:"channel"`
+	To      string `json:"to"`
+	Name    string `json:"name,omitempty"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Notifier sends messages. Implementations must be safe for concurrent use.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// LogNotifier writes messages in a readable form to a writer, such as a file
+// or standard error, instead of sending them. It is meant for local use. Logs
+// are kept and shared more freely than the database, so addresses and phone
+// numbers are redacted.
+type LogNotifier struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLogNotifier(w io.Writer) *LogNotifier {
+	return &LogNotifier{w: w}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, msg Message) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s %s\n", time.Now().Format(time.RFC3339), msg.Channel)
+	fmt.Fprintf(&b, "To: %s <%s>\n", msg.Name, redactAddress(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\n\n%s\n", redactText(msg.Subject), redactText(strings.TrimRight(msg.Body, "\n")))
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err := io.WriteString(n.w, b.String())
+	return err
+}
+
+// phonePattern matches runs of digits and spaces that may be phone numbers,
+// in Latin or Arabic-Indic digits. Runs of fewer than phoneDigits digits,
+// like times and amounts, are left alone.
+var phonePattern = regexp.MustCompile(`\+?\p{Nd}[\p{Nd} ]*\p{Nd}`)
+
+const phoneDigits = 8
+
+var emailPattern = regexp.MustCompile(`[^\s<>@]+@[^\s<>@]+`)
+
+// redactAddress keeps the last two characters of a phone number and the
+// domain of an email address.
+func redactAddress(to string) string {
+	if _, domain, ok := strings.Cut(to, "@"); ok {
+		return "***@" + domain
+	}
+	runes := []rune(to)
+	if len(runes) <= 2 {
+		return "***"
+	}
+	return "***" + string(runes[len(runes)-2:])
+}
+
+// redactText hides the phone numbers and email addresses in s.
+func redactText(s string) string {
+	s = phonePattern.ReplaceAllStringFunc(s, func(run string) string {
+		digits := 0
+		for _, ch := range run {
+			if unicode.IsDigit(ch) {
+				digits++
+			}
+		}
+		if digits < phoneDigits {
+			return run
+		}
+		return "[phone]"
+	})
+	return emailPattern.ReplaceAllString(s, "[email]")
+}
+
+// FakeNotifier writes every message as a JSON file to a directory instead of
+// sending it, so tests and local setups can inspect what would have been sent.
+type FakeNotifier struct {
+	mu  sync.Mutex
+	dir string
+	n   int
+}
+
+func NewFakeNotifier(dir string) (*FakeNotifier, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FakeNotifier{dir: dir}, nil
+}
+
+func (n *FakeNotifier) Notify(ctx context.Context, msg Message) error {
+	data, err := json.MarshalIndent(msg, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.n++
+	name := fmt.Sprintf("%s-%04d-%s.json", time.Now().Format("20060102T150405.000"), n.n, msg.Channel)
+	return os.WriteFile(filepath.Join(n.dir, name), data, 0o644)
+}
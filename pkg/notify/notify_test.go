@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactAddress(t *testing.T) {
+	tests := []struct {
+		to   string
+		want string
+	}{
+		{"mina@example.com", "***@example.com"},
+		{"201001234567", "***67"},
+		{"+201001234567", "***67"},
+		{"٠١٠٠١٢٣٤٥٦٧", "***٦٧"},
+		{"12", "***"},
+		{"", "***"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, redactAddress(tt.to), tt.to)
+	}
+}
+
+func TestRedactText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Call 01001234567 today", "Call [phone] today"},
+		{"Call +20 100 123 4567.", "Call [phone]."},
+		{"اتصل ب ٠١٠٠١٢٣٤٥٦٧", "اتصل ب [phone]"},
+		{"Write to mina@example.com or call", "Write to [email] or call"},
+		{"<mina@example.com>", "<[email]>"},
+		// Times, dates and amounts are kept.
+		{"Meeting at 19:00 on 1/3, 150 EGP", "Meeting at 19:00 on 1/3, 150 EGP"},
+		{"Room 12 at 7 30", "Room 12 at 7 30"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, redactText(tt.in), tt.in)
+	}
+}
+
+func TestLogNotifierRedacts(t *testing.T) {
+	var buf bytes.Buffer
+	n := NewLogNotifier(&buf)
+	require.NoError(t, n.Notify(context.Background(), Message{
+		Channel: ChannelSMS,
+		To:      "201001234567",
+		Name:    "Mina",
+		Subject: "Reminder",
+		Body:    "Your servant's number is 01209876543.\n",
+	}))
+	out := buf.String()
+	assert.Contains(t, out, "To: Mina <***67>\n")
+	assert.Contains(t, out, "Your servant's number is [phone].\n")
+	assert.NotContains(t, out, "1001234567")
+	assert.NotContains(t, out, "01209876543")
+}
@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Languages messages can be written in.
+const (
+	LanguageArabic  = "ar"
+	LanguageEnglish = "en"
+)
+
+// Templates holds message templates by name and language. Subjects and
+// bodies are text/template sources rendered with the data passed to Render.
+type Templates struct {
+	mu        sync.RWMutex
+	templates map[string]map[string]*messageTemplate
+	fallback  string
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+// NewTemplates returns the built-in templates. Templates missing in a
+// language are rendered in fallback instead.
+func NewTemplates(fallback string) *Templates {
+	t := &Templates{templates: map[string]map[string]*messageTemplate{}, fallback: fallback}
+	for name, variants := range builtinTemplates {
+		for lang, src := range variants {
+			if err := t.Add(name, lang, src[0], src[1]); err != nil {
+				panic(fmt.Sprintf("notify: built-in template %s.%s: %v", name, lang, err))
+			}
+		}
+	}
+	return t
+}
+
+// Add adds or replaces the template name in lang.
+func (t *Templates) Add(name, lang, subject, body string) error {
+	mt := &messageTemplate{}
+	var err error
+	if mt.subject, err = template.New(name + ".subject").Parse(subject); err != nil {
+		return err
+	}
+	if mt.body, err = template.New(name + ".body").Parse(body); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.templates[name] == nil {
+		t.templates[name] = map[string]*messageTemplate{}
+	}
+	t.templates[name][lang] = mt
+	return nil
+}
+
+// Has reports whether a template called name exists in any language.
+func (t *Templates) Has(name string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.templates[name]) > 0
+}
+
+// Render renders the template name in lang, falling back to the default
+// language and then to any language the template exists in. It returns the
+// language actually used.
+func (t *Templates) Render(name, lang string, data interface{}) (subject, body, used string, err error) {
+	t.mu.RLock()
+	variants := t.templates[name]
+	mt, used := variants[lang], lang
+	if mt == nil {
+		mt, used = variants[t.fallback], t.fallback
+	}
+	if mt == nil {
+		for l, v := range variants {
+			mt, used = v, l
+			break
+		}
+	}
+	t.mu.RUnlock()
+	if mt == nil {
+		return "", "", "", fmt.Errorf("unknown template %q", name)
+	}
+
+	var s, b strings.Builder
+	if err := mt.subject.Execute(&s, data); err != nil {
+		return "", "", "", err
+	}
+	if err := mt.body.Execute(&b, data); err != nil {
+		return "", "", "", err
+	}
+	return strings.TrimSpace(s.String()), strings.TrimSpace(b.String()) + "\n", used, nil
+}
+
+// builtinTemplates maps template names to their subject and body per
+// language.
+var builtinTemplates = map[string]map[string][2]string{
+	"custom": {
+		LanguageEnglish: {`{{.Subject}}`, `{{.Body}}`},
+	},
+	"birthday_reminder": {
+		LanguageEnglish: {
+			`Birthdays today in {{.Group}}`,
+			`Hello {{.Recipient.Name}},
+Today is the birthday of these members of {{.Group}}:
+{{range .Birthdays}}- {{.Person.Name}} turns {{.Age}}{{if .Person.Phone}} ({{.Person.Phone}}){{end}}
+{{end}}`,
+		},
+		LanguageArabic: {
+			`أعياد ميلاد اليوم في {{.Group}}`,
+			`أهلاً {{.Recipient.Name}}،
+اليوم عيد ميلاد هؤلاء من {{.Group}}:
+{{range .Birthdays}}- {{.Person.Name}} ({{.Age}} سنة){{if .Person.Phone}} {{.Person.Phone}}{{end}}
+{{end}}`,
+		},
+	},
+	"assignment_reminder": {
+		LanguageEnglish: {
+			`Reminder: {{.Title}} is due {{.Deadline}}`,
+			`{{with .Guardian}}Hello {{.Name}},
+We have not received "{{$.Title}}" from {{$.Recipient.Name}} yet.{{else}}Hello {{.Recipient.Name}},
+We have not received your "{{.Title}}" yet.{{end}} It is due {{.Deadline}}, {{.HoursLeft}} hours from now.`,
+		},
+		LanguageArabic: {
+			`تذكير: موعد تسليم {{.Title}} {{.Deadline}}`,
+			`{{with .Guardian}}أهلاً {{.Name}}،
+لم نستلم "{{$.Title}}" من {{$.Recipient.Name}} بعد.{{else}}أهلاً {{.Recipient.Name}}،
+لم نستلم "{{.Title}}" منك بعد.{{end}} آخر موعد للتسليم {{.Deadline}}، بعد {{.HoursLeft}} ساعة.`,
+		},
+	},
+	"missing_submissions": {
+		LanguageEnglish: {
+			`Missing submissions for {{.Title}}`,
+			`Hello {{.Recipient.Name}},
+{{len .Missing}} of {{.Expected}} did not submit "{{.Title}}" by {{.Deadline}}:
+{{range .Missing}}- {{.Name}}{{if .Phone}} ({{.Phone}}){{end}}
+{{end}}`,
+		},
+		LanguageArabic: {
+			`لم يسلموا {{.Title}}`,
+			`أهلاً {{.Recipient.Name}}،
+{{len .Missing}} من {{.Expected}} لم يسلموا "{{.Title}}" حتى {{.Deadline}}:
+{{range .Missing}}- {{.Name}}{{if .Phone}} ({{.Phone}}){{end}}
+{{end}}`,
+		},
+	},
+}
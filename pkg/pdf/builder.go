@@ -0,0 +1,219 @@
+package pdf
+
+import (
+	"fmt"
+)
+
+const (
+	margin      = 40.0
+	lineSpacing = 1.4
+)
+
+// Builder lays out a document from top to bottom, starting a new page when
+// the current one is full. Every page gets a footer with its page number.
+type Builder struct {
+	doc    *Document
+	page   *Page
+	y      float64
+	footer string
+}
+
+// Column describes a table column. Width is in points.
+type Column struct {
+	Title string
+	Width float64
+}
+
+// NewBuilder creates a builder for a document drawn in fonts, see New.
+func NewBuilder(title, footer string, fonts *Fonts) *Builder {
+	b := &Builder{doc: New(title, fonts), footer: footer}
+	b.newPage()
+	return b
+}
+
+func (b *Builder) Document() *Document {
+	b.finishPage()
+	return b.doc
+}
+
+// Title writes a large bold heading.
+func (b *Builder) Title(s string) {
+	b.ensure(30)
+	b.y += 20
+	b.page.Text(margin, b.y, 18, true, s)
+	b.y += 12
+}
+
+// Heading writes a section heading followed by a rule.
+func (b *Builder) Heading(s string) {
+	b.ensure(40)
+	b.y += 18
+	b.page.Text(margin, b.y, 13, true, s)
+	b.y += 5
+	b.page.Line(margin, b.y, PageWidth-margin, b.y, 0.5)
+	b.y += 6
+}
+
+// Field writes a "label: value" line.
+func (b *Builder) Field(label, value string) {
+	b.ensure(16)
+	b.y += 14
+	b.page.Text(margin, b.y, 10, true, label+":")
+	b.text(margin+110, b.y, PageWidth-2*margin-110, 10, false, value)
+}
+
+// Text writes a line of plain text.
+func (b *Builder) Text(s string) {
+	b.ensure(16)
+	b.y += 14
+	b.text(margin, b.y, PageWidth-2*margin, 10, false, s)
+}
+
+// Space adds vertical space.
+func (b *Builder) Space(h float64) {
+	b.y += h
+}
+
+// Table draws a table with a shaded header row. Rows taller than minRowHeight
+// are useful for sign-in sheets where people write in the cells. The header
+// is repeated on every page the table spans.
+func (b *Builder) Table(columns []Column, rows [][]string, minRowHeight float64) {
+	const size = 9.0
+	rowHeight := size * lineSpacing * 1.5
+	if minRowHeight > rowHeight {
+		rowHeight = minRowHeight
+	}
+
+	header := func() {
+		b.page.FillRect(margin, b.y, tableWidth(columns), rowHeight, 0.85)
+		b.drawRow(columns, titles(columns), b.y, rowHeight, size, true)
+		b.y += rowHeight
+	}
+
+	b.ensure(2 * rowHeight)
+	b.y += 6
+	header()
+	for _, row := range rows {
+		if b.y+rowHeight > PageHeight-margin-20 {
+			b.newPage()
+			header()
+		}
+		b.drawRow(columns, row, b.y, rowHeight, size, false)
+		b.y += rowHeight
+	}
+}
+
+func (b *Builder) drawRow(columns []Column, values []string, y, h, size float64, bold bool) {
+	x := margin
+	for i, col := range columns {
+		b.page.Rect(x, y, col.Width, h, 0.5)
+		if i < len(values) {
+			b.text(x+4, y+h/2+size/3, col.Width-8, size, bold, values[i])
+		}
+		x += col.Width
+	}
+}
+
+// text draws s in a box of width w starting at x, shortened to fit. Text
+// read from right to left is aligned to the right of the box.
+func (b *Builder) text(x, y, w, size float64, bold bool, s string) {
+	s = b.truncate(s, size, bold, w)
+	if rtl(s) {
+		x += w - b.doc.TextWidth(s, size, bold)
+	}
+	b.page.Text(x, y, size, bold, s)
+}
+
+func (b *Builder) ensure(h float64) {
+	if b.y+h > PageHeight-margin-20 {
+		b.newPage()
+	}
+}
+
+func (b *Builder) newPage() {
+	b.finishPage()
+	b.page = b.doc.AddPage()
+	b.y = margin
+}
+
+func (b *Builder) finishPage() {
+	if b.page == nil {
+		return
+	}
+	label := fmt.Sprintf("Page %d", len(b.doc.pages))
+	if b.footer != "" {
+		label = b.footer + " - " + label
+	}
+	b.page.Text(margin, PageHeight-margin/2, 8, false, label)
+	b.page = nil
+}
+
+func tableWidth(columns []Column) float64 {
+	w := 0.0
+	for _, c := range columns {
+		w += c.Width
+	}
+	return w
+}
+
+func titles(columns []Column) []string {
+	t := make([]string, len(columns))
+	for i, c := range columns {
+		t[i] = c.Title
+	}
+	return t
+}
+
+// truncate shortens s with an ellipsis so it fits in maxWidth points.
+func (b *Builder) truncate(s string, size float64, bold bool, maxWidth float64) string {
+	if b.doc.TextWidth(s, size, bold) <= maxWidth {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := string(runes) + "..."
+		if b.doc.TextWidth(candidate, size, bold) <= maxWidth {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// standardTextWidth returns the width of s in points when drawn at size in
+// the standard fonts.
+func standardTextWidth(s string, size float64, bold bool) float64 {
+	widths := helveticaWidths
+	if bold {
+		widths = helveticaBoldWidths
+	}
+	total := 0
+	for _, c := range encode(s) {
+		if c >= 32 && c < 127 {
+			total += widths[c-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// Glyph widths of the printable ASCII characters (32 to 126) in thousandths of
+// the font size, from the Adobe font metrics of the standard fonts.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
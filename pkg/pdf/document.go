@@ -0,0 +1,379 @@
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// A4 page size in points.
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Document is a minimal PDF 1.4 writer supporting text, lines and
+// rectangles. Text is drawn in the given TrueType fonts, embedded with the
+// glyphs used, with Arabic shaped and right-to-left text put in reading
+// order. Without fonts it is drawn in the standard Helvetica fonts, which
+// only have the characters of the WinAnsi encoding; anything else is
+// replaced with "?".
+type Document struct {
+	pages []*Page
+	title string
+	fonts [2]*documentFont
+}
+
+// documentFont is a font as used by a document: the glyphs drawn, with the
+// text each stands for.
+type documentFont struct {
+	font *Font
+	used map[uint16][]rune
+}
+
+// glyph is a glyph to draw and the text it stands for.
+type glyph struct {
+	id   uint16
+	text []rune
+}
+
+// Page holds the content stream of a single page. Coordinates passed to its
+// methods are measured in points from the top left corner of the page.
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+}
+
+// New creates a document. With nil fonts, text is drawn in the standard
+// fonts. Without a bold font, bold text is drawn in the regular one.
+func New(title string, fonts *Fonts) *Document {
+	d := &Document{title: title}
+	if fonts != nil && fonts.Regular != nil {
+		d.fonts[0] = &documentFont{font: fonts.Regular, used: map[uint16][]rune{}}
+		d.fonts[1] = d.fonts[0]
+		if fonts.Bold != nil {
+			d.fonts[1] = &documentFont{font: fonts.Bold, used: map[uint16][]rune{}}
+		}
+	}
+	return d
+}
+
+func (d *Document) AddPage() *Page {
+	p := &Page{doc: d}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Text draws s with its baseline starting at x, y.
+func (p *Page) Text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	df := p.doc.font(bold)
+	if df == nil {
+		fmt.Fprintf(&p.content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, PageHeight-y, escape(encode(s)))
+		return
+	}
+	var ids strings.Builder
+	for _, g := range df.glyphs(s) {
+		if _, ok := df.used[g.id]; !ok {
+			df.used[g.id] = g.text
+		}
+		fmt.Fprintf(&ids, "%04X", g.id)
+	}
+	fmt.Fprintf(&p.content, "BT /%s %.2f Tf %.2f %.2f Td <%s> Tj ET\n", font, size, x, PageHeight-y, ids.String())
+}
+
+// TextWidth returns the width of s in points when drawn at size.
+func (d *Document) TextWidth(s string, size float64, bold bool) float64 {
+	df := d.font(bold)
+	if df == nil {
+		return standardTextWidth(s, size, bold)
+	}
+	total := 0.0
+	for _, g := range df.glyphs(s) {
+		total += df.font.advance(g.id)
+	}
+	return total * size / 1000
+}
+
+func (d *Document) font(bold bool) *documentFont {
+	if bold {
+		return d.fonts[1]
+	}
+	return d.fonts[0]
+}
+
+// glyphs returns the glyphs s is drawn with, from left to right. Letters
+// the font has no contextual form for are drawn in their plain form.
+func (df *documentFont) glyphs(s string) []glyph {
+	var glyphs []glyph
+	for _, c := range visual(s) {
+		switch {
+		case df.font.has(c.r) || len(c.text) == 0:
+			glyphs = append(glyphs, glyph{df.font.glyph(c.r), c.text[:len(c.text)-len(c.marks)]})
+		default:
+			// A missing lam-alef ligature is drawn as its letters, alef on
+			// the left.
+			letters := c.text[:len(c.text)-len(c.marks)]
+			for i := len(letters) - 1; i >= 0; i-- {
+				glyphs = append(glyphs, glyph{df.font.glyph(letters[i]), letters[i : i+1]})
+			}
+		}
+		for _, m := range c.marks {
+			glyphs = append(glyphs, glyph{df.font.glyph(m), []rune{m}})
+		}
+	}
+	return glyphs
+}
+
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+func (p *Page) Rect(x, y, w, h, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f %.2f %.2f re S\n", width, x, PageHeight-y-h, w, h)
+}
+
+// FillRect fills a rectangle with a gray level between 0 (black) and 1 (white).
+func (p *Page) FillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(&p.content, "q %.2f g %.2f %.2f %.2f %.2f re f Q\n", gray, x, PageHeight-y-h, w, h)
+}
+
+// WriteTo writes the whole document to w.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+	offsets := []int64{}
+	object := func(body string) {
+		offsets = append(offsets, cw.n)
+		fmt.Fprintf(cw, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	fmt.Fprint(cw, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1 to 5 are fixed, every page then takes a page object followed
+	// by its content stream. Embedded fonts come last, each taking four
+	// objects: the descendant font, its descriptor, the font file and the
+	// map back to text.
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	embedded := []*documentFont{}
+	firstFont := 6 + 2*len(d.pages)
+	fontObject := map[*documentFont]int{}
+	for _, df := range d.fonts {
+		if _, ok := fontObject[df]; df != nil && !ok {
+			fontObject[df] = firstFont + 4*len(embedded)
+			embedded = append(embedded, df)
+		}
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	if len(embedded) == 0 {
+		object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+		object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	} else {
+		for _, df := range d.fonts {
+			object(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", df.baseFont(), fontObject[df], fontObject[df]+3))
+		}
+	}
+	object(fmt.Sprintf("<< /Title %s /Producer (EKMS) >>", textString(d.title)))
+	for i, p := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", PageWidth, PageHeight, 7+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.content.Len(), p.content.String()))
+	}
+	for _, df := range embedded {
+		n, f := fontObject[df], df.font
+		object(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /CIDToGIDMap /Identity /W [%s] >>", df.baseFont(), n+1, df.widths()))
+		object(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%.0f %.0f %.0f %.0f] /ItalicAngle %.2f /Ascent %.0f /Descent %.0f /CapHeight %.0f /StemV 80 /FontFile2 %d 0 R >>",
+			df.baseFont(), f.bbox[0], f.bbox[1], f.bbox[2], f.bbox[3], f.italicAngle, f.ascent, f.descent, f.capHeight, n+2))
+		file := f.subset(df.usedGlyphs())
+		var packed bytes.Buffer
+		zw := zlib.NewWriter(&packed)
+		zw.Write(file)
+		zw.Close()
+		object(fmt.Sprintf("<< /Length %d /Length1 %d /Filter /FlateDecode >>\nstream\n%s\nendstream", packed.Len(), len(file), packed.String()))
+		cmap := df.toUnicode()
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(cmap), cmap))
+	}
+
+	xref := cw.n
+	fmt.Fprintf(cw, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(cw, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(cw, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, bw.Flush()
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+func (df *documentFont) usedGlyphs() map[uint16]bool {
+	used := map[uint16]bool{}
+	for g := range df.used {
+		used[g] = true
+	}
+	return used
+}
+
+func (df *documentFont) sortedGlyphs() []uint16 {
+	glyphs := make([]uint16, 0, len(df.used))
+	for g := range df.used {
+		glyphs = append(glyphs, g)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	return glyphs
+}
+
+// baseFont is the font's name prefixed with a tag telling which glyphs were
+// embedded, as subsets of the same font must have different names.
+func (df *documentFont) baseFont() string {
+	h := fnv.New32a()
+	for _, g := range df.sortedGlyphs() {
+		fmt.Fprintf(h, "%d,", g)
+	}
+	sum := h.Sum32()
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + byte(sum%26)
+		sum /= 26
+	}
+	return string(tag) + "+" + df.font.name
+}
+
+// widths returns the W array entries of the used glyphs.
+func (df *documentFont) widths() string {
+	var sb strings.Builder
+	for i, g := range df.sortedGlyphs() {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		fmt.Fprintf(&sb, "%d [%.0f]", g, df.font.advance(g))
+	}
+	return sb.String()
+}
+
+// toUnicode returns the CMap from the used glyphs back to the text they
+// stand for, so text can be searched and copied.
+func (df *documentFont) toUnicode() string {
+	var sb strings.Builder
+	sb.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	sb.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	sb.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	sb.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	glyphs := []uint16{}
+	for _, g := range df.sortedGlyphs() {
+		if len(df.used[g]) > 0 {
+			glyphs = append(glyphs, g)
+		}
+	}
+	// A bfchar block holds at most 100 entries.
+	for start := 0; start < len(glyphs); start += 100 {
+		block := glyphs[start:min(start+100, len(glyphs))]
+		fmt.Fprintf(&sb, "%d beginbfchar\n", len(block))
+		for _, g := range block {
+			fmt.Fprintf(&sb, "<%04X> <", g)
+			for _, u := range utf16.Encode(df.used[g]) {
+				fmt.Fprintf(&sb, "%04X", u)
+			}
+			sb.WriteString(">\n")
+		}
+		sb.WriteString("endbfchar\n")
+	}
+	sb.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return sb.String()
+}
+
+// textString returns s as a PDF text string, in UTF-16 when it has
+// characters outside ASCII.
+func textString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r >= 127 || r < 32 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return "(" + escape([]byte(s)) + ")"
+	}
+	var sb strings.Builder
+	sb.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&sb, "%04X", u)
+	}
+	sb.WriteString(">")
+	return sb.String()
+}
+
+// encode converts s to WinAnsi bytes.
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			out = append(out, ' ')
+		case r >= 32 && r < 127, r >= 160 && r <= 255:
+			out = append(out, byte(r))
+		case r == '€':
+			out = append(out, 128)
+		case r == '–':
+			out = append(out, 150)
+		case r == '—':
+			out = append(out, 151)
+		case r == '‘', r == '’':
+			out = append(out, '\'')
+		case r == '“', r == '”':
+			out = append(out, '"')
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+func escape(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		switch c {
+		case '(', ')', '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,111 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFont = "/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf"
+
+func loadTestFont(t *testing.T) *Font {
+	if _, err := os.Stat(testFont); err != nil {
+		t.Skipf("%s is not installed", testFont)
+	}
+	f, err := LoadFont(testFont)
+	require.NoError(t, err)
+	return f
+}
+
+func TestParseFontRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"otf", append([]byte("OTTO"), make([]byte, 12)...)},
+		{"no tables", []byte{0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"truncated directory", []byte{0, 1, 0, 0, 0, 5, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFont(tt.data)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFontSubset(t *testing.T) {
+	f := loadTestFont(t)
+	used := map[uint16]bool{}
+	for _, r := range "Aمح" + string([]rune{0xFEE3, 0xFEFB}) {
+		require.True(t, f.has(r), "font has %U", r)
+		used[f.glyph(r)] = true
+	}
+
+	file := f.subset(used)
+	assert.Equal(t, uint32(0xB1B0AFBA), checksum(file), "checksum adjustment")
+
+	sub := &Font{tables: map[string][]byte{}, longLoca: true, numGlyphs: f.numGlyphs}
+	for i := 0; i < int(binary.BigEndian.Uint16(file[4:])); i++ {
+		rec := file[12+16*i:]
+		offset, length := binary.BigEndian.Uint32(rec[8:]), binary.BigEndian.Uint32(rec[12:])
+		sub.tables[string(rec[:4])] = file[offset : offset+length]
+	}
+	for g := 0; g < f.numGlyphs; g++ {
+		if used[uint16(g)] || g == 0 {
+			assert.True(t, bytes.HasPrefix(sub.glyphData(uint16(g)), f.glyphData(uint16(g))), "glyph %d is kept", g)
+		} else if !usedComponent(f, used, uint16(g)) {
+			assert.Empty(t, sub.glyphData(uint16(g)), "glyph %d is dropped", g)
+		}
+	}
+}
+
+// usedComponent reports whether g is part of a used composite glyph.
+func usedComponent(f *Font, used map[uint16]bool, g uint16) bool {
+	for u := range used {
+		for _, c := range components(f.glyphData(u)) {
+			if c == g {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestDocumentEmbedsFont(t *testing.T) {
+	f := loadTestFont(t)
+	b := NewBuilder("تقرير", "footer", &Fonts{Regular: f})
+	b.Field("Name", "مينا")
+	var out bytes.Buffer
+	_, err := b.Document().WriteTo(&out)
+	require.NoError(t, err)
+
+	pdf := out.String()
+	assert.Contains(t, pdf, "/Encoding /Identity-H")
+	assert.Contains(t, pdf, "/CIDToGIDMap /Identity")
+	assert.Contains(t, pdf, "/FontFile2")
+	assert.Contains(t, pdf, "/Title <FEFF062A06420631064A0631>")
+	// The shaped letters map back to the plain ones.
+	assert.Contains(t, pdf, fmt.Sprintf("<%04X> <0645>", f.glyph(0xFEE3)))
+	assert.Contains(t, pdf, fmt.Sprintf("<%04X> <0627>", f.glyph(0xFE8E)))
+	assert.NotContains(t, pdf, "Helvetica")
+}
+
+func TestDocumentStandardFonts(t *testing.T) {
+	b := NewBuilder("Report", "", nil)
+	b.Text("Mina مينا")
+	var out bytes.Buffer
+	_, err := b.Document().WriteTo(&out)
+	require.NoError(t, err)
+
+	pdf := out.String()
+	assert.Contains(t, pdf, "/BaseFont /Helvetica")
+	assert.Contains(t, pdf, "(Mina ????) Tj")
+	assert.NotContains(t, pdf, "FontFile2")
+}
@@ -0,0 +1,455 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"unicode/utf16"
+)
+
+// Fonts are the TrueType fonts a document draws its text with. Unlike the
+// standard Helvetica fonts they cover any script the font files do, such as
+// Arabic.
+type Fonts struct {
+	Regular *Font
+	Bold    *Font
+}
+
+// Font is a parsed TrueType font. Documents embed only the glyphs they use.
+type Font struct {
+	name        string
+	tables      map[string][]byte
+	unitsPerEm  float64
+	numGlyphs   int
+	longLoca    bool
+	advances    []uint16
+	glyphs      map[rune]uint16
+	bbox        [4]float64
+	ascent      float64
+	descent     float64
+	capHeight   float64
+	italicAngle float64
+}
+
+// LoadFont reads and parses the TrueType font file at path.
+func LoadFont(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ParseFont(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// ParseFont parses a TrueType font. Fonts with PostScript outlines (OTF with
+// a CFF table) and font collections are not supported.
+func ParseFont(data []byte) (*Font, error) {
+	if len(data) < 12 {
+		return nil, errors.New("pdf: font is too short")
+	}
+	switch binary.BigEndian.Uint32(data) {
+	case 0x00010000, 0x74727565: // 1.0 and "true"
+	default:
+		return nil, errors.New("pdf: not a TrueType font")
+	}
+	f := &Font{tables: map[string][]byte{}}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 12+16*numTables {
+		return nil, errors.New("pdf: font table directory is truncated")
+	}
+	for i := 0; i < numTables; i++ {
+		rec := data[12+16*i:]
+		offset, length := binary.BigEndian.Uint32(rec[8:]), binary.BigEndian.Uint32(rec[12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil, fmt.Errorf("pdf: font table %q is truncated", rec[:4])
+		}
+		f.tables[string(rec[:4])] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "hmtx", "maxp", "loca", "glyf", "cmap"} {
+		if f.tables[tag] == nil {
+			return nil, fmt.Errorf("pdf: font has no %s table", tag)
+		}
+	}
+
+	head, hhea, maxp := f.tables["head"], f.tables["hhea"], f.tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errors.New("pdf: font header is truncated")
+	}
+	f.unitsPerEm = float64(binary.BigEndian.Uint16(head[18:]))
+	if f.unitsPerEm == 0 {
+		return nil, errors.New("pdf: font has no units per em")
+	}
+	for i := range f.bbox {
+		f.bbox[i] = f.scale(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+	f.longLoca = binary.BigEndian.Uint16(head[50:]) == 1
+	f.numGlyphs = int(binary.BigEndian.Uint16(maxp[4:]))
+	f.ascent = f.scale(int16(binary.BigEndian.Uint16(hhea[4:])))
+	f.descent = f.scale(int16(binary.BigEndian.Uint16(hhea[6:])))
+	f.capHeight = f.ascent
+	if os2 := f.tables["OS/2"]; len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		f.capHeight = f.scale(int16(binary.BigEndian.Uint16(os2[88:])))
+	}
+	if post := f.tables["post"]; len(post) >= 8 {
+		f.italicAngle = float64(int32(binary.BigEndian.Uint32(post[4:]))) / 65536
+	}
+
+	numMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	hmtx := f.tables["hmtx"]
+	if numMetrics == 0 || len(hmtx) < 4*numMetrics {
+		return nil, errors.New("pdf: font metrics are truncated")
+	}
+	f.advances = make([]uint16, f.numGlyphs)
+	for i := range f.advances {
+		f.advances[i] = binary.BigEndian.Uint16(hmtx[4*min(i, numMetrics-1):])
+	}
+	locaSize := 2
+	if f.longLoca {
+		locaSize = 4
+	}
+	if len(f.tables["loca"]) < locaSize*(f.numGlyphs+1) {
+		return nil, errors.New("pdf: font glyph locations are truncated")
+	}
+
+	glyphs, err := parseCmap(f.tables["cmap"])
+	if err != nil {
+		return nil, err
+	}
+	f.glyphs = glyphs
+	f.name = postScriptName(f.tables["name"])
+	return f, nil
+}
+
+// scale converts font units to thousandths of the font size.
+func (f *Font) scale(v int16) float64 {
+	return float64(v) * 1000 / f.unitsPerEm
+}
+
+// glyph returns the glyph of r, or 0 (the missing glyph) if the font does
+// not have one.
+func (f *Font) glyph(r rune) uint16 {
+	return f.glyphs[r]
+}
+
+func (f *Font) has(r rune) bool {
+	_, ok := f.glyphs[r]
+	return ok
+}
+
+// advance returns the advance width of glyph g in thousandths of the font
+// size.
+func (f *Font) advance(g uint16) float64 {
+	if int(g) >= len(f.advances) {
+		return 0
+	}
+	return float64(f.advances[g]) * 1000 / f.unitsPerEm
+}
+
+// parseCmap reads the Unicode character to glyph mapping, preferring the
+// full repertoire subtable (format 12) over the BMP one (format 4).
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	if len(cmap) < 4 {
+		return nil, errors.New("pdf: font cmap is truncated")
+	}
+	var bmp, full []byte
+	n := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < n && 4+8*i+8 <= len(cmap); i++ {
+		rec := cmap[4+8*i:]
+		platform, encoding := binary.BigEndian.Uint16(rec), binary.BigEndian.Uint16(rec[2:])
+		offset := binary.BigEndian.Uint32(rec[4:])
+		if uint64(offset)+4 > uint64(len(cmap)) {
+			continue
+		}
+		sub := cmap[offset:]
+		switch format := binary.BigEndian.Uint16(sub); {
+		case format == 12 && (platform == 0 || platform == 3 && encoding == 10):
+			full = sub
+		case format == 4 && (platform == 0 || platform == 3 && encoding == 1):
+			bmp = sub
+		}
+	}
+	switch {
+	case full != nil:
+		return parseCmap12(full)
+	case bmp != nil:
+		return parseCmap4(bmp)
+	}
+	return nil, errors.New("pdf: font has no Unicode cmap")
+}
+
+func parseCmap4(sub []byte) (map[rune]uint16, error) {
+	if len(sub) < 14 {
+		return nil, errors.New("pdf: font cmap is truncated")
+	}
+	segs := int(binary.BigEndian.Uint16(sub[6:])) / 2
+	ends, starts := 14, 16+2*segs
+	deltas, rangeOffsets := starts+2*segs, starts+4*segs
+	if len(sub) < rangeOffsets+2*segs {
+		return nil, errors.New("pdf: font cmap is truncated")
+	}
+	glyphs := map[rune]uint16{}
+	for s := 0; s < segs; s++ {
+		end := binary.BigEndian.Uint16(sub[ends+2*s:])
+		start := binary.BigEndian.Uint16(sub[starts+2*s:])
+		delta := binary.BigEndian.Uint16(sub[deltas+2*s:])
+		rangeOffset := int(binary.BigEndian.Uint16(sub[rangeOffsets+2*s:]))
+		for c := int(start); c <= int(end) && c != 0xFFFF; c++ {
+			var g uint16
+			if rangeOffset == 0 {
+				g = uint16(c) + delta
+			} else {
+				at := rangeOffsets + 2*s + rangeOffset + 2*(c-int(start))
+				if at+2 > len(sub) {
+					break
+				}
+				if g = binary.BigEndian.Uint16(sub[at:]); g != 0 {
+					g += delta
+				}
+			}
+			if g != 0 {
+				glyphs[rune(c)] = g
+			}
+		}
+	}
+	return glyphs, nil
+}
+
+func parseCmap12(sub []byte) (map[rune]uint16, error) {
+	if len(sub) < 16 {
+		return nil, errors.New("pdf: font cmap is truncated")
+	}
+	groups := int(binary.BigEndian.Uint32(sub[12:]))
+	if len(sub) < 16+12*groups {
+		return nil, errors.New("pdf: font cmap is truncated")
+	}
+	glyphs := map[rune]uint16{}
+	for i := 0; i < groups; i++ {
+		group := sub[16+12*i:]
+		start, end := binary.BigEndian.Uint32(group), binary.BigEndian.Uint32(group[4:])
+		g := binary.BigEndian.Uint32(group[8:])
+		for c := start; c <= end && c <= 0x10FFFF; c++ {
+			if g+c-start != 0 {
+				glyphs[rune(c)] = uint16(g + c - start)
+			}
+		}
+	}
+	return glyphs, nil
+}
+
+// postScriptName returns the PostScript name from the name table, which
+// becomes the font's name in the document.
+func postScriptName(table []byte) string {
+	const fallback = "EKMSFont"
+	if len(table) < 6 {
+		return fallback
+	}
+	count := int(binary.BigEndian.Uint16(table[2:]))
+	storage := int(binary.BigEndian.Uint16(table[4:]))
+	for i := 0; i < count && 6+12*i+12 <= len(table); i++ {
+		rec := table[6+12*i:]
+		platform, nameID := binary.BigEndian.Uint16(rec), binary.BigEndian.Uint16(rec[6:])
+		length, offset := int(binary.BigEndian.Uint16(rec[8:])), int(binary.BigEndian.Uint16(rec[10:]))
+		if nameID != 6 || storage+offset+length > len(table) {
+			continue
+		}
+		raw := table[storage+offset : storage+offset+length]
+		var name []rune
+		switch platform {
+		case 1:
+			for _, b := range raw {
+				name = append(name, rune(b))
+			}
+		case 0, 3:
+			units := make([]uint16, len(raw)/2)
+			for j := range units {
+				units[j] = binary.BigEndian.Uint16(raw[2*j:])
+			}
+			name = utf16.Decode(units)
+		default:
+			continue
+		}
+		// PDF names cannot hold every character a font name might.
+		clean := make([]rune, 0, len(name))
+		for _, r := range name {
+			if r > 32 && r < 127 && r != '/' && r != '[' && r != ']' && r != '(' && r != ')' && r != '<' && r != '>' && r != '{' && r != '}' && r != '%' && r != '#' {
+				clean = append(clean, r)
+			}
+		}
+		if len(clean) > 0 {
+			return string(clean)
+		}
+	}
+	return fallback
+}
+
+// glyphData returns the outline of glyph g.
+func (f *Font) glyphData(g uint16) []byte {
+	loca, glyf := f.tables["loca"], f.tables["glyf"]
+	var start, end uint32
+	if f.longLoca {
+		start, end = binary.BigEndian.Uint32(loca[4*int(g):]), binary.BigEndian.Uint32(loca[4*int(g)+4:])
+	} else {
+		start, end = 2*uint32(binary.BigEndian.Uint16(loca[2*int(g):])), 2*uint32(binary.BigEndian.Uint16(loca[2*int(g)+2:]))
+	}
+	if start >= end || end > uint32(len(glyf)) {
+		return nil
+	}
+	return glyf[start:end]
+}
+
+// components returns the glyphs a composite glyph is built from.
+func components(outline []byte) []uint16 {
+	const (
+		argsAreWords  = 0x0001
+		hasScale      = 0x0008
+		moreComponent = 0x0020
+		hasXYScale    = 0x0040
+		hasTwoByTwo   = 0x0080
+	)
+	if len(outline) < 10 || int16(binary.BigEndian.Uint16(outline)) >= 0 {
+		return nil
+	}
+	var glyphs []uint16
+	for at := 10; at+4 <= len(outline); {
+		flags := binary.BigEndian.Uint16(outline[at:])
+		glyphs = append(glyphs, binary.BigEndian.Uint16(outline[at+2:]))
+		at += 4
+		if flags&argsAreWords != 0 {
+			at += 4
+		} else {
+			at += 2
+		}
+		switch {
+		case flags&hasScale != 0:
+			at += 2
+		case flags&hasXYScale != 0:
+			at += 4
+		case flags&hasTwoByTwo != 0:
+			at += 8
+		}
+		if flags&moreComponent == 0 {
+			break
+		}
+	}
+	return glyphs
+}
+
+// subset returns a font file holding only the outlines of the used glyphs
+// and the glyphs they are composed of. Glyphs keep their numbers, so the
+// content streams can refer to them directly; the others are left empty.
+func (f *Font) subset(used map[uint16]bool) []byte {
+	keep := map[uint16]bool{0: true}
+	pending := []uint16{0}
+	for g := range used {
+		pending = append(pending, g)
+	}
+	for len(pending) > 0 {
+		g := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if int(g) >= f.numGlyphs {
+			continue
+		}
+		keep[g] = true
+		for _, c := range components(f.glyphData(g)) {
+			if !keep[c] {
+				pending = append(pending, c)
+			}
+		}
+	}
+
+	var glyf []byte
+	loca := make([]byte, 4*(f.numGlyphs+1))
+	for g := 0; g < f.numGlyphs; g++ {
+		binary.BigEndian.PutUint32(loca[4*g:], uint32(len(glyf)))
+		if keep[uint16(g)] {
+			glyf = append(glyf, f.glyphData(uint16(g))...)
+			for len(glyf)%4 != 0 {
+				glyf = append(glyf, 0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[4*f.numGlyphs:], uint32(len(glyf)))
+
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+
+	tables := map[string][]byte{
+		"head": head,
+		"hhea": f.tables["hhea"],
+		"hmtx": f.tables["hmtx"],
+		"maxp": f.tables["maxp"],
+		"loca": loca,
+		"glyf": glyf,
+	}
+	// The hinting programs are kept, as viewers run them on the outlines.
+	for _, tag := range []string{"cvt ", "fpgm", "prep"} {
+		if t := f.tables[tag]; t != nil {
+			tables[tag] = t
+		}
+	}
+	file := writeSfnt(tables)
+	binary.BigEndian.PutUint32(file[headOffset(file):][8:], 0xB1B0AFBA-checksum(file))
+	return file
+}
+
+func writeSfnt(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	n := len(tags)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= n {
+		entrySelector++
+	}
+	searchRange := 16 << entrySelector
+
+	file := make([]byte, 12+16*n)
+	binary.BigEndian.PutUint32(file, 0x00010000)
+	binary.BigEndian.PutUint16(file[4:], uint16(n))
+	binary.BigEndian.PutUint16(file[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(file[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(file[10:], uint16(16*n-searchRange))
+	for i, tag := range tags {
+		table := tables[tag]
+		rec := file[12+16*i:]
+		copy(rec, tag)
+		binary.BigEndian.PutUint32(rec[4:], checksum(table))
+		binary.BigEndian.PutUint32(rec[8:], uint32(len(file)))
+		binary.BigEndian.PutUint32(rec[12:], uint32(len(table)))
+		file = append(file, table...)
+		for len(file)%4 != 0 {
+			file = append(file, 0)
+		}
+	}
+	return file
+}
+
+func headOffset(file []byte) int {
+	n := int(binary.BigEndian.Uint16(file[4:]))
+	for i := 0; i < n; i++ {
+		rec := file[12+16*i:]
+		if string(rec[:4]) == "head" {
+			return int(binary.BigEndian.Uint32(rec[8:]))
+		}
+	}
+	return 0
+}
+
+func checksum(b []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(b); i += 4 {
+		var word [4]byte
+		copy(word[:], b[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
@@ -0,0 +1,311 @@
+package pdf
+
+import (
+	"unicode"
+)
+
+// cluster is what is drawn as one glyph: a character, in the contextual
+// form it takes, along with the combining marks drawn over it. text holds
+// the characters it stands for, so copying from the document gives back the
+// original text.
+type cluster struct {
+	r     rune
+	text  []rune
+	marks []rune
+}
+
+// arabicForms holds the isolated, final, initial and medial presentation
+// forms of the Arabic letters. Letters that only join to the letter before
+// them have no initial or medial form.
+var arabicForms = map[rune][4]rune{
+	0x0621: {0xFE80, 0, 0, 0},
+	0x0622: {0xFE81, 0xFE82, 0, 0},
+	0x0623: {0xFE83, 0xFE84, 0, 0},
+	0x0624: {0xFE85, 0xFE86, 0, 0},
+	0x0625: {0xFE87, 0xFE88, 0, 0},
+	0x0626: {0xFE89, 0xFE8A, 0xFE8B, 0xFE8C},
+	0x0627: {0xFE8D, 0xFE8E, 0, 0},
+	0x0628: {0xFE8F, 0xFE90, 0xFE91, 0xFE92},
+	0x0629: {0xFE93, 0xFE94, 0, 0},
+	0x062A: {0xFE95, 0xFE96, 0xFE97, 0xFE98},
+	0x062B: {0xFE99, 0xFE9A, 0xFE9B, 0xFE9C},
+	0x062C: {0xFE9D, 0xFE9E, 0xFE9F, 0xFEA0},
+	0x062D: {0xFEA1, 0xFEA2, 0xFEA3, 0xFEA4},
+	0x062E: {0xFEA5, 0xFEA6, 0xFEA7, 0xFEA8},
+	0x062F: {0xFEA9, 0xFEAA, 0, 0},
+	0x0630: {0xFEAB, 0xFEAC, 0, 0},
+	0x0631: {0xFEAD, 0xFEAE, 0, 0},
+	0x0632: {0xFEAF, 0xFEB0, 0, 0},
+	0x0633: {0xFEB1, 0xFEB2, 0xFEB3, 0xFEB4},
+	0x0634: {0xFEB5, 0xFEB6, 0xFEB7, 0xFEB8},
+	0x0635: {0xFEB9, 0xFEBA, 0xFEBB, 0xFEBC},
+	0x0636: {0xFEBD, 0xFEBE, 0xFEBF, 0xFEC0},
+	0x0637: {0xFEC1, 0xFEC2, 0xFEC3, 0xFEC4},
+	0x0638: {0xFEC5, 0xFEC6, 0xFEC7, 0xFEC8},
+	0x0639: {0xFEC9, 0xFECA, 0xFECB, 0xFECC},
+	0x063A: {0xFECD, 0xFECE, 0xFECF, 0xFED0},
+	0x0641: {0xFED1, 0xFED2, 0xFED3, 0xFED4},
+	0x0642: {0xFED5, 0xFED6, 0xFED7, 0xFED8},
+	0x0643: {0xFED9, 0xFEDA, 0xFEDB, 0xFEDC},
+	0x0644: {0xFEDD, 0xFEDE, 0xFEDF, 0xFEE0},
+	0x0645: {0xFEE1, 0xFEE2, 0xFEE3, 0xFEE4},
+	0x0646: {0xFEE5, 0xFEE6, 0xFEE7, 0xFEE8},
+	0x0647: {0xFEE9, 0xFEEA, 0xFEEB, 0xFEEC},
+	0x0648: {0xFEED, 0xFEEE, 0, 0},
+	0x0649: {0xFEEF, 0xFEF0, 0, 0},
+	0x064A: {0xFEF1, 0xFEF2, 0xFEF3, 0xFEF4},
+	0x067E: {0xFB56, 0xFB57, 0xFB58, 0xFB59},
+	0x0686: {0xFB7A, 0xFB7B, 0xFB7C, 0xFB7D},
+	0x0698: {0xFB8A, 0xFB8B, 0, 0},
+	0x06A9: {0xFB8E, 0xFB8F, 0xFB90, 0xFB91},
+	0x06AF: {0xFB92, 0xFB93, 0xFB94, 0xFB95},
+	0x06CC: {0xFBFC, 0xFBFD, 0xFBFE, 0xFBFF},
+}
+
+// lamAlef holds the isolated form of the ligature of lam with each alef; the
+// final form follows it.
+var lamAlef = map[rune]rune{
+	0x0622: 0xFEF5,
+	0x0623: 0xFEF7,
+	0x0625: 0xFEF9,
+	0x0627: 0xFEFB,
+}
+
+const (
+	lam     = 0x0644
+	tatweel = 0x0640
+)
+
+const (
+	isolated = iota
+	final
+	initial
+	medial
+)
+
+// transparent reports whether r is a mark that letters join across.
+func transparent(r rune) bool {
+	return unicode.Is(unicode.Mn, r)
+}
+
+// joinsBefore reports whether r connects to the letter that follows it.
+func joinsBefore(r rune) bool {
+	return r == tatweel || arabicForms[r][initial] != 0
+}
+
+// joinsAfter reports whether r connects to the letter that precedes it.
+func joinsAfter(r rune) bool {
+	return r == tatweel || arabicForms[r][final] != 0
+}
+
+// shape turns s into clusters in logical order, giving Arabic letters the
+// form that joins them to their neighbours and combining lam and alef. The
+// font has presentation forms for the letters, which viewers then draw
+// without shaping of their own.
+func shape(s string) []cluster {
+	runes := []rune(s)
+	// neighbour returns the letter before (step -1) or after (step 1) i,
+	// skipping marks, or 0.
+	neighbour := func(i, step int) rune {
+		for j := i + step; j >= 0 && j < len(runes); j += step {
+			if !transparent(runes[j]) {
+				return runes[j]
+			}
+		}
+		return 0
+	}
+
+	clusters := make([]cluster, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if transparent(r) && len(clusters) > 0 {
+			last := &clusters[len(clusters)-1]
+			last.marks = append(last.marks, r)
+			last.text = append(last.text, r)
+			continue
+		}
+		if r == '\t' {
+			r = ' '
+		}
+		before := joinsBefore(neighbour(i, -1))
+		if r == lam && i+1 < len(runes) && lamAlef[runes[i+1]] != 0 {
+			ligature := lamAlef[runes[i+1]]
+			if before {
+				ligature++
+			}
+			clusters = append(clusters, cluster{r: ligature, text: []rune{r, runes[i+1]}})
+			i++
+			continue
+		}
+		forms, ok := arabicForms[r]
+		if !ok {
+			clusters = append(clusters, cluster{r: r, text: []rune{r}})
+			continue
+		}
+		after := joinsBefore(r) && joinsAfter(neighbour(i, 1))
+		form := isolated
+		switch {
+		case before && joinsAfter(r) && after:
+			form = medial
+		case before && joinsAfter(r):
+			form = final
+		case after:
+			form = initial
+		}
+		clusters = append(clusters, cluster{r: forms[form], text: []rune{r}})
+	}
+	return clusters
+}
+
+// Bidirectional classes, a reduced form of those of the Unicode
+// bidirectional algorithm.
+const (
+	classL = iota
+	classR
+	classNumber
+	classNeutral
+)
+
+func bidiClass(r rune) int {
+	switch {
+	case r >= 0x0590 && r <= 0x08FF, r >= 0xFB1D && r <= 0xFDFF, r >= 0xFE70 && r <= 0xFEFF:
+		if unicode.IsDigit(r) {
+			return classNumber
+		}
+		return classR
+	case unicode.IsDigit(r):
+		return classNumber
+	case unicode.IsLetter(r), unicode.IsMark(r):
+		return classL
+	}
+	return classNeutral
+}
+
+// rtl reports whether s reads from right to left, which is decided by its
+// first letter.
+func rtl(s string) bool {
+	for _, r := range s {
+		switch bidiClass(r) {
+		case classR:
+			return true
+		case classL:
+			return false
+		}
+	}
+	return false
+}
+
+// numberSeparator reports whether r, between two digits, belongs to the
+// number, as in dates, times and decimals.
+func numberSeparator(r rune) bool {
+	switch r {
+	case '.', ',', ':', '/', '-', '+', 0x066B, 0x066C:
+		return true
+	}
+	return false
+}
+
+var mirrored = map[rune]rune{
+	'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{',
+	'<': '>', '>': '<', '«': '»', '»': '«',
+}
+
+// visual shapes s and puts the clusters in the order they are drawn from
+// left to right. Text that starts with a right-to-left letter reads from the
+// right, with runs of left-to-right words and numbers kept in their order.
+// Only a single line without explicit direction marks is supported.
+func visual(s string) []cluster {
+	clusters := shape(s)
+	if len(clusters) == 0 {
+		return clusters
+	}
+
+	base := classL
+	if rtl(s) {
+		base = classR
+	}
+	classes := make([]int, len(clusters))
+	for i, c := range clusters {
+		classes[i] = bidiClass(c.text[0])
+	}
+	for i := 1; i+1 < len(clusters); i++ {
+		if classes[i] == classNeutral && classes[i-1] == classNumber && classes[i+1] == classNumber && numberSeparator(clusters[i].r) {
+			classes[i] = classNumber
+		}
+	}
+	// Neutrals take the direction of the text around them when both sides
+	// agree, numbers counting as right to left, and the base direction
+	// otherwise.
+	strong := func(class int) int {
+		if class == classNumber {
+			return classR
+		}
+		return class
+	}
+	for i := 0; i < len(classes); {
+		if classes[i] != classNeutral {
+			i++
+			continue
+		}
+		j := i
+		for j < len(classes) && classes[j] == classNeutral {
+			j++
+		}
+		prev, next := base, base
+		if i > 0 {
+			prev = strong(classes[i-1])
+		}
+		if j < len(classes) {
+			next = strong(classes[j])
+		}
+		dir := base
+		if prev == next {
+			dir = prev
+		}
+		for k := i; k < j; k++ {
+			classes[k] = dir
+		}
+		i = j
+	}
+
+	levels := make([]int, len(clusters))
+	maxLevel := 0
+	for i, class := range classes {
+		switch {
+		case base == classL && class == classR:
+			levels[i] = 1
+		case base == classL && class == classNumber:
+			levels[i] = 2
+		case base == classR && class == classR:
+			levels[i] = 1
+		case base == classR:
+			levels[i] = 2
+		}
+		maxLevel = max(maxLevel, levels[i])
+		if levels[i]%2 == 1 {
+			if m, ok := mirrored[clusters[i].r]; ok {
+				clusters[i].r = m
+			}
+		}
+	}
+	// From the highest level down to the lowest odd one, every run at that
+	// level or above is reversed.
+	for level := maxLevel; level >= 1; level-- {
+		for i := 0; i < len(levels); {
+			if levels[i] < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(levels) && levels[j] >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				clusters[a], clusters[b] = clusters[b], clusters[a]
+				levels[a], levels[b] = levels[b], levels[a]
+			}
+			i = j
+		}
+	}
+	return clusters
+}
@@ -0,0 +1,71 @@
+package pdf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVisual(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []rune
+	}{
+		{"latin", "Mina 12", []rune("Mina 12")},
+		{"joined letters", "محمد", []rune{0xFEAA, 0xFEE4, 0xFEA4, 0xFEE3}},
+		{"right-joining letter breaks the word", "سلام", []rune{0xFEE1, 0xFEFC, 0xFEB3}},
+		{"lam alef", "لا", []rune{0xFEFB}},
+		{"isolated letters", "د و", []rune{0xFEED, ' ', 0xFEA9}},
+		{"marks stay on their letter", "مَ", []rune{0xFEE1}},
+		{"number in arabic", "مينا 12", []rune{'1', '2', ' ', 0xFE8E, 0xFEE8, 0xFEF4, 0xFEE3}},
+		{"date in arabic", "في 2024-05-01", []rune{'2', '0', '2', '4', '-', '0', '5', '-', '0', '1', ' ', 0xFEF2, 0xFED3}},
+		{"arabic in latin", "Mina (مينا)", append([]rune("Mina ("), 0xFE8E, 0xFEE8, 0xFEF4, 0xFEE3, ')')},
+		{"latin in arabic with mirrored brackets", "مينا (Mina)", append([]rune("(Mina) "), 0xFE8E, 0xFEE8, 0xFEF4, 0xFEE3)},
+		{"tab", "a\tb", []rune("a b")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []rune{}
+			for _, c := range visual(tt.in) {
+				got = append(got, c.r)
+			}
+			assert.Equal(t, string(tt.want), string(got))
+		})
+	}
+}
+
+func TestVisualKeepsText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"سلام", []string{"م", "لا", "س"}},
+		{"مَ", []string{"مَ"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got := []string{}
+			for _, c := range visual(tt.in) {
+				got = append(got, string(c.text))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRTL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"", false},
+		{"Mina", false},
+		{"مينا", true},
+		{"12 مينا", true},
+		{"(Mina) مينا", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, rtl(tt.in), tt.in)
+	}
+}
@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AccessTokenRepoInterface interface {
+	GetAccessTokensByPerson(ctx context.Context, personID primitive.ObjectID) ([]models.AccessToken, error)
+	GetAccessTokenById(ctx context.Context, id string) (*models.AccessToken, error)
+	GetActiveAccessToken(ctx context.Context, hash string) (*models.AccessToken, error)
+	CreateAccessToken(ctx context.Context, token models.AccessToken) (*models.AccessToken, error)
+	TouchAccessToken(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	RevokeAccessToken(ctx context.Context, id primitive.ObjectID, at time.Time) error
+}
+
+type AccessTokenRepo struct {
+	db *mongo.Client
+}
+
+func NewAccessTokenRepo(db *mongo.Client) *AccessTokenRepo {
+	return &AccessTokenRepo{
+		db: db,
+	}
+}
+
+func (m *AccessTokenRepo) GetAccessTokensByPerson(ctx context.Context, personID primitive.ObjectID) ([]models.AccessToken, error) {
+	tokens := []models.AccessToken{}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cur, err := m.db.Database("ekms").Collection("accessTokens").Find(ctx, bson.M{"personId": personID}, opts)
+	if err != nil {
+		fmt.Printf("Error while getting access tokens by person: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var token models.AccessToken
+		err := cur.Decode(&token)
+		if err != nil {
+			fmt.Printf("Error while decoding access token: %v\n", err)
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+func (m *AccessTokenRepo) GetAccessTokenById(ctx context.Context, id string) (*models.AccessToken, error) {
+	var token models.AccessToken
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("GetAccessTokenById", "AccessTokenRepo", err)
+	}
+
+	err = m.db.Database("ekms").Collection("accessTokens").FindOne(ctx, bson.M{"_id": oid}).Decode(&token)
+	if err != nil {
+		fmt.Printf("Error while getting access token by id: %v\n", err)
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// GetActiveAccessToken returns the token with the hash unless it was revoked.
+func (m *AccessTokenRepo) GetActiveAccessToken(ctx context.Context, hash string) (*models.AccessToken, error) {
+	var token models.AccessToken
+	err := m.db.Database("ekms").Collection("accessTokens").FindOne(ctx, bson.M{"hash": hash, "revokedAt": bson.M{"$exists": false}}).Decode(&token)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			fmt.Printf("Error while getting access token: %v\n", err)
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (m *AccessTokenRepo) CreateAccessToken(ctx context.Context, token models.AccessToken) (*models.AccessToken, error) {
+	token.ID = primitive.NewObjectID()
+	_, err := m.db.Database("ekms").Collection("accessTokens").InsertOne(ctx, token)
+	if err != nil {
+		fmt.Printf("Error while creating access token: %v\n", err)
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// TouchAccessToken records that the token was used at at.
+func (m *AccessTokenRepo) TouchAccessToken(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := m.db.Database("ekms").Collection("accessTokens").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"lastUsedAt": at}})
+	if err != nil {
+		fmt.Printf("Error while touching access token: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *AccessTokenRepo) RevokeAccessToken(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := m.db.Database("ekms").Collection("accessTokens").UpdateOne(ctx,
+		bson.M{"_id": id, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": at}})
+	if err != nil {
+		fmt.Printf("Error while revoking access token: %v\n", err)
+		return err
+	}
+
+	return nil
+}
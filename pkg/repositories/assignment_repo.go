@@ -3,11 +3,13 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Mario-Kamel/EKMS/pkg/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type AssignmentRepoInterface interface {
@@ -16,6 +18,47 @@ type AssignmentRepoInterface interface {
 	CreateAssignment(ctx context.Context, assignment models.Assignment) (*models.Assignment, error)
 	UpdateAssignment(ctx context.Context, id string, assignment models.Assignment) (*models.Assignment, error)
 	DeleteAssignment(ctx context.Context, id string) error
+
+	StreamAssignments(ctx context.Context, filter AssignmentFilter, fn func(models.Assignment) error) error
+	GetAssignmentsBySubmitter(ctx context.Context, personID primitive.ObjectID) ([]models.Assignment, error)
+	SetSubmission(ctx context.Context, id primitive.ObjectID, submission models.AssignmentSubmission) (*models.Assignment, error)
+	AddAttachment(ctx context.Context, id primitive.ObjectID, attachmentID primitive.ObjectID) error
+	AddSubmissionAttachment(ctx context.Context, id, personID, attachmentID primitive.ObjectID) error
+	RemoveAttachment(ctx context.Context, id primitive.ObjectID, attachmentID primitive.ObjectID) error
+
+	ClaimReminders(ctx context.Context, id primitive.ObjectID, hours []int) (bool, error)
+	ClaimMissingNotice(ctx context.Context, id primitive.ObjectID, at time.Time) (bool, error)
+	ClaimPersonReminder(ctx context.Context, id, personID primitive.ObjectID, hours int) (bool, error)
+	ReleasePersonReminder(ctx context.Context, id, personID primitive.ObjectID, hours int) error
+}
+
+// AssignmentFilter narrows down the assignments returned by StreamAssignments.
+// Zero values are ignored.
+type AssignmentFilter struct {
+	ServiceID    primitive.ObjectID
+	ServiceIDs   []primitive.ObjectID
+	DeadlineFrom time.Time
+	DeadlineTo   time.Time
+}
+
+func (f AssignmentFilter) query() bson.M {
+	query := bson.M{}
+	if !f.ServiceID.IsZero() {
+		query["serviceId"] = f.ServiceID
+	} else if len(f.ServiceIDs) > 0 {
+		query["serviceId"] = bson.M{"$in": f.ServiceIDs}
+	}
+	deadline := bson.M{}
+	if !f.DeadlineFrom.IsZero() {
+		deadline["$gte"] = f.DeadlineFrom
+	}
+	if !f.DeadlineTo.IsZero() {
+		deadline["$lte"] = f.DeadlineTo
+	}
+	if len(deadline) > 0 {
+		query["deadline"] = deadline
+	}
+	return query
 }
 
 type AssignmentRepo struct {
@@ -109,3 +152,188 @@ func (m *AssignmentRepo) DeleteAssignment(ctx context.Context, id string) error
 
 	return nil
 }
+
+// StreamAssignments calls fn for every assignment matching filter, ordered by
+// deadline, while iterating the cursor.
+func (m *AssignmentRepo) StreamAssignments(ctx context.Context, filter AssignmentFilter, fn func(models.Assignment) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "deadline", Value: 1}})
+	cur, err := m.db.Database("ekms").Collection("assignments").Find(ctx, filter.query(), opts)
+	if err != nil {
+		fmt.Printf("Error while streaming assignments: %v\n", err)
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var assignment models.Assignment
+		err := cur.Decode(&assignment)
+		if err != nil {
+			fmt.Printf("Error while decoding assignment: %v\n", err)
+			return err
+		}
+		if err := fn(assignment); err != nil {
+			return err
+		}
+	}
+
+	return cur.Err()
+}
+
+// SetSubmission replaces the person's submission to the assignment, or adds
+// it when the person has not submitted yet.
+func (m *AssignmentRepo) SetSubmission(ctx context.Context, id primitive.ObjectID, submission models.AssignmentSubmission) (*models.Assignment, error) {
+	assignments := m.db.Database("ekms").Collection("assignments")
+	res, err := assignments.UpdateOne(ctx,
+		bson.M{"_id": id, "submissions.personId": submission.PersonID},
+		bson.M{"$set": bson.M{"submissions.$": submission}})
+	if err != nil {
+		fmt.Printf("Error while replacing submission: %v\n", err)
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		_, err = assignments.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$push": bson.M{"submissions": submission}})
+		if err != nil {
+			fmt.Printf("Error while adding submission: %v\n", err)
+			return nil, err
+		}
+	}
+
+	return m.GetAssignmentById(ctx, id.Hex())
+}
+
+func (m *AssignmentRepo) AddAttachment(ctx context.Context, id primitive.ObjectID, attachmentID primitive.ObjectID) error {
+	_, err := m.db.Database("ekms").Collection("assignments").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$push": bson.M{"attachments": attachmentID}})
+	if err != nil {
+		fmt.Printf("Error while adding assignment attachment: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// AddSubmissionAttachment adds the attachment to the person's submission,
+// submitting the assignment on the person's behalf when there is no
+// submission yet.
+func (m *AssignmentRepo) AddSubmissionAttachment(ctx context.Context, id, personID, attachmentID primitive.ObjectID) error {
+	assignments := m.db.Database("ekms").Collection("assignments")
+	res, err := assignments.UpdateOne(ctx,
+		bson.M{"_id": id, "submissions.personId": personID},
+		bson.M{"$push": bson.M{"submissions.$.attachments": attachmentID}})
+	if err != nil {
+		fmt.Printf("Error while adding submission attachment: %v\n", err)
+		return err
+	}
+	if res.MatchedCount == 0 {
+		submission := models.AssignmentSubmission{
+			PersonID:    personID,
+			Time:        time.Now(),
+			State:       models.SubmissionSubmitted,
+			Attachments: []primitive.ObjectID{attachmentID},
+		}
+		_, err = assignments.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$push": bson.M{"submissions": submission}})
+		if err != nil {
+			fmt.Printf("Error while adding submission: %v\n", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveAttachment unlinks the attachment from the assignment and from every
+// submission to it.
+func (m *AssignmentRepo) RemoveAttachment(ctx context.Context, id primitive.ObjectID, attachmentID primitive.ObjectID) error {
+	_, err := m.db.Database("ekms").Collection("assignments").UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$pull": bson.M{"attachments": attachmentID, "submissions.$[].attachments": attachmentID}})
+	if err != nil {
+		fmt.Printf("Error while removing attachment: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// ClaimReminders records the reminder thresholds as handled once everyone was
+// reminded. It returns false when another caller already recorded the first
+// of them.
+func (m *AssignmentRepo) ClaimReminders(ctx context.Context, id primitive.ObjectID, hours []int) (bool, error) {
+	res, err := m.db.Database("ekms").Collection("assignments").UpdateOne(ctx,
+		bson.M{"_id": id, "remindersSent": bson.M{"$ne": hours[0]}},
+		bson.M{"$addToSet": bson.M{"remindersSent": bson.M{"$each": hours}}})
+	if err != nil {
+		fmt.Printf("Error while claiming assignment reminders: %v\n", err)
+		return false, err
+	}
+
+	return res.ModifiedCount == 1, nil
+}
+
+// ClaimMissingNotice records that the missing submissions notice of the
+// assignment was sent to everyone at at. It returns false when it was already
+// recorded.
+func (m *AssignmentRepo) ClaimMissingNotice(ctx context.Context, id primitive.ObjectID, at time.Time) (bool, error) {
+	res, err := m.db.Database("ekms").Collection("assignments").UpdateOne(ctx,
+		bson.M{"_id": id, "missingNoticeAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"missingNoticeAt": at}})
+	if err != nil {
+		fmt.Printf("Error while claiming missing submissions notice: %v\n", err)
+		return false, err
+	}
+
+	return res.ModifiedCount == 1, nil
+}
+
+// ClaimPersonReminder records that the person is sent the reminder for the
+// threshold hours, or the missing submissions notice when hours is 0. It
+// returns false when it was already recorded, so each person gets it once
+// even with several instances running.
+func (m *AssignmentRepo) ClaimPersonReminder(ctx context.Context, id, personID primitive.ObjectID, hours int) (bool, error) {
+	reminder := models.AssignmentReminder{PersonID: personID, Hours: hours}
+	res, err := m.db.Database("ekms").Collection("assignments").UpdateOne(ctx,
+		bson.M{"_id": id, "reminded": bson.M{"$not": bson.M{"$elemMatch": bson.M{"personId": personID, "hours": hours}}}},
+		bson.M{"$push": bson.M{"reminded": reminder}})
+	if err != nil {
+		fmt.Printf("Error while claiming assignment reminder: %v\n", err)
+		return false, err
+	}
+
+	return res.ModifiedCount == 1, nil
+}
+
+// ReleasePersonReminder removes a claim made by ClaimPersonReminder whose
+// reminder could not be sent, so the next run tries again.
+func (m *AssignmentRepo) ReleasePersonReminder(ctx context.Context, id, personID primitive.ObjectID, hours int) error {
+	_, err := m.db.Database("ekms").Collection("assignments").UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$pull": bson.M{"reminded": bson.M{"personId": personID, "hours": hours}}})
+	if err != nil {
+		fmt.Printf("Error while releasing assignment reminder: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *AssignmentRepo) GetAssignmentsBySubmitter(ctx context.Context, personID primitive.ObjectID) ([]models.Assignment, error) {
+	assignments := []models.Assignment{}
+	opts := options.Find().SetSort(bson.D{{Key: "deadline", Value: 1}})
+	cur, err := m.db.Database("ekms").Collection("assignments").Find(ctx, bson.M{"submissions.personId": personID}, opts)
+	if err != nil {
+		fmt.Printf("Error while getting assignments by submitter: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var assignment models.Assignment
+		err := cur.Decode(&assignment)
+		if err != nil {
+			fmt.Printf("Error while decoding assignment: %v\n", err)
+			return nil, err
+		}
+		assignments = append(assignments, assignment)
+	}
+
+	return assignments, nil
+}
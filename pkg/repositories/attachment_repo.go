@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AttachmentRepoInterface interface {
+	GetAttachmentById(ctx context.Context, id string) (*models.Attachment, error)
+	GetAttachmentsByAssignment(ctx context.Context, assignmentID primitive.ObjectID) ([]models.Attachment, error)
+	GetAttachmentsByPerson(ctx context.Context, personID primitive.ObjectID) ([]models.Attachment, error)
+	CreateAttachment(ctx context.Context, attachment models.Attachment) (*models.Attachment, error)
+	DeleteAttachment(ctx context.Context, id primitive.ObjectID) error
+	StreamAttachments(ctx context.Context, fn func(models.Attachment) error) error
+}
+
+type AttachmentRepo struct {
+	db *mongo.Client
+}
+
+func NewAttachmentRepo(db *mongo.Client) *AttachmentRepo {
+	return &AttachmentRepo{
+		db: db,
+	}
+}
+
+func (m *AttachmentRepo) GetAttachmentById(ctx context.Context, id string) (*models.Attachment, error) {
+	var attachment models.Attachment
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("GetAttachmentById", "AttachmentRepo", err)
+	}
+
+	err = m.db.Database("ekms").Collection("attachments").FindOne(ctx, bson.M{"_id": oid}).Decode(&attachment)
+	if err != nil {
+		fmt.Printf("Error while getting attachment by id: %v\n", err)
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+// GetAttachmentsByAssignment returns the attachments of the assignment and of
+// its submissions.
+func (m *AttachmentRepo) GetAttachmentsByAssignment(ctx context.Context, assignmentID primitive.ObjectID) ([]models.Attachment, error) {
+	attachments := []models.Attachment{}
+	opts := options.Find().SetSort(bson.D{{Key: "uploadedAt", Value: 1}})
+	cur, err := m.db.Database("ekms").Collection("attachments").Find(ctx, bson.M{"assignmentId": assignmentID}, opts)
+	if err != nil {
+		fmt.Printf("Error while getting attachments by assignment: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var attachment models.Attachment
+		err := cur.Decode(&attachment)
+		if err != nil {
+			fmt.Printf("Error while decoding attachment: %v\n", err)
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, nil
+}
+
+// GetAttachmentsByPerson returns the attachments of the person's
+// submissions.
+func (m *AttachmentRepo) GetAttachmentsByPerson(ctx context.Context, personID primitive.ObjectID) ([]models.Attachment, error) {
+	attachments := []models.Attachment{}
+	opts := options.Find().SetSort(bson.D{{Key: "uploadedAt", Value: 1}})
+	cur, err := m.db.Database("ekms").Collection("attachments").Find(ctx, bson.M{"personId": personID}, opts)
+	if err != nil {
+		fmt.Printf("Error while getting attachments by person: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var attachment models.Attachment
+		err := cur.Decode(&attachment)
+		if err != nil {
+			fmt.Printf("Error while decoding attachment: %v\n", err)
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, nil
+}
+
+func (m *AttachmentRepo) CreateAttachment(ctx context.Context, attachment models.Attachment) (*models.Attachment, error) {
+	if attachment.ID.IsZero() {
+		attachment.ID = primitive.NewObjectID()
+	}
+	_, err := m.db.Database("ekms").Collection("attachments").InsertOne(ctx, attachment)
+	if err != nil {
+		fmt.Printf("Error while creating attachment: %v\n", err)
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+func (m *AttachmentRepo) DeleteAttachment(ctx context.Context, id primitive.ObjectID) error {
+	_, err := m.db.Database("ekms").Collection("attachments").DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		fmt.Printf("Error while deleting attachment: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// StreamAttachments calls fn for every attachment while iterating the cursor.
+func (m *AttachmentRepo) StreamAttachments(ctx context.Context, fn func(models.Attachment) error) error {
+	cur, err := m.db.Database("ekms").Collection("attachments").Find(ctx, bson.D{})
+	if err != nil {
+		fmt.Printf("Error while streaming attachments: %v\n", err)
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var attachment models.Attachment
+		err := cur.Decode(&attachment)
+		if err != nil {
+			fmt.Printf("Error while decoding attachment: %v\n", err)
+			return err
+		}
+		if err := fn(attachment); err != nil {
+			return err
+		}
+	}
+
+	return cur.Err()
+}
@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CalendarFeedRepoInterface interface {
+	GetActiveFeedToken(ctx context.Context, kind string, targetID primitive.ObjectID, hash string) (*models.CalendarFeedToken, error)
+	CreateFeedToken(ctx context.Context, token models.CalendarFeedToken) (*models.CalendarFeedToken, error)
+	RevokeFeedTokens(ctx context.Context, kind string, targetID primitive.ObjectID, at time.Time) (int64, error)
+}
+
+type CalendarFeedRepo struct {
+	db *mongo.Client
+}
+
+func NewCalendarFeedRepo(db *mongo.Client) *CalendarFeedRepo {
+	return &CalendarFeedRepo{
+		db: db,
+	}
+}
+
+// GetActiveFeedToken returns the feed's token with the hash unless it was
+// revoked.
+func (m *CalendarFeedRepo) GetActiveFeedToken(ctx context.Context, kind string, targetID primitive.ObjectID, hash string) (*models.CalendarFeedToken, error) {
+	var token models.CalendarFeedToken
+	err := m.db.Database("ekms").Collection("calendarFeeds").FindOne(ctx, bson.M{
+		"kind":      kind,
+		"targetId":  targetID,
+		"hash":      hash,
+		"revokedAt": bson.M{"$exists": false},
+	}).Decode(&token)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			fmt.Printf("Error while getting calendar feed token: %v\n", err)
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (m *CalendarFeedRepo) CreateFeedToken(ctx context.Context, token models.CalendarFeedToken) (*models.CalendarFeedToken, error) {
+	token.ID = primitive.NewObjectID()
+	_, err := m.db.Database("ekms").Collection("calendarFeeds").InsertOne(ctx, token)
+	if err != nil {
+		fmt.Printf("Error while creating calendar feed token: %v\n", err)
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// RevokeFeedTokens revokes the tokens of the feed and returns how many were
+// still active.
+func (m *CalendarFeedRepo) RevokeFeedTokens(ctx context.Context, kind string, targetID primitive.ObjectID, at time.Time) (int64, error) {
+	res, err := m.db.Database("ekms").Collection("calendarFeeds").UpdateMany(ctx,
+		bson.M{"kind": kind, "targetId": targetID, "revokedAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedAt": at}})
+	if err != nil {
+		fmt.Printf("Error while revoking calendar feed tokens: %v\n", err)
+		return 0, err
+	}
+
+	return res.ModifiedCount, nil
+}
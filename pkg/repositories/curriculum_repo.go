@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CurriculumRepoInterface interface {
+	GetAllCurricula(ctx context.Context) ([]models.Curriculum, error)
+	GetCurriculumById(ctx context.Context, id string) (*models.Curriculum, error)
+	GetCurriculaFor(ctx context.Context, groupID, scheduleID primitive.ObjectID) ([]models.Curriculum, error)
+	CreateCurriculum(ctx context.Context, curriculum models.Curriculum) (*models.Curriculum, error)
+	ReplaceCurriculum(ctx context.Context, curriculum models.Curriculum) (*models.Curriculum, error)
+	DeleteCurriculum(ctx context.Context, id string) error
+}
+
+type CurriculumRepo struct {
+	db *mongo.Client
+}
+
+func NewCurriculumRepo(db *mongo.Client) *CurriculumRepo {
+	return &CurriculumRepo{
+		db: db,
+	}
+}
+
+func (m *CurriculumRepo) GetAllCurricula(ctx context.Context) ([]models.Curriculum, error) {
+	return m.findCurricula(ctx, bson.D{})
+}
+
+func (m *CurriculumRepo) GetCurriculumById(ctx context.Context, id string) (*models.Curriculum, error) {
+	var curriculum models.Curriculum
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("GetCurriculumById", "CurriculumRepo", err)
+	}
+
+	err = m.db.Database("ekms").Collection("curricula").FindOne(ctx, bson.M{"_id": oid}).Decode(&curriculum)
+	if err != nil {
+		fmt.Printf("Error while getting curriculum by id: %v\n", err)
+		return nil, err
+	}
+
+	return &curriculum, nil
+}
+
+// GetCurriculaFor returns the curricula planned for the services of the
+// group or of the schedule. Zero IDs are ignored.
+func (m *CurriculumRepo) GetCurriculaFor(ctx context.Context, groupID, scheduleID primitive.ObjectID) ([]models.Curriculum, error) {
+	or := bson.A{}
+	if !groupID.IsZero() {
+		or = append(or, bson.M{"groupId": groupID})
+	}
+	if !scheduleID.IsZero() {
+		or = append(or, bson.M{"scheduleId": scheduleID})
+	}
+	if len(or) == 0 {
+		return []models.Curriculum{}, nil
+	}
+	return m.findCurricula(ctx, bson.M{"$or": or})
+}
+
+func (m *CurriculumRepo) CreateCurriculum(ctx context.Context, curriculum models.Curriculum) (*models.Curriculum, error) {
+	curriculum.ID = primitive.NewObjectID()
+	_, err := m.db.Database("ekms").Collection("curricula").InsertOne(ctx, curriculum)
+	if err != nil {
+		fmt.Printf("Error while creating curriculum: %v\n", err)
+		return nil, err
+	}
+
+	return &curriculum, nil
+}
+
+// ReplaceCurriculum stores the whole curriculum, including where its lessons
+// are planned.
+func (m *CurriculumRepo) ReplaceCurriculum(ctx context.Context, curriculum models.Curriculum) (*models.Curriculum, error) {
+	res, err := m.db.Database("ekms").Collection("curricula").ReplaceOne(ctx, bson.M{"_id": curriculum.ID}, curriculum)
+	if err != nil {
+		fmt.Printf("Error while replacing curriculum: %v\n", err)
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return &curriculum, nil
+}
+
+func (m *CurriculumRepo) DeleteCurriculum(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return cerrors.NewInvalidIDError("DeleteCurriculum", "CurriculumRepo", err)
+	}
+
+	_, err = m.db.Database("ekms").Collection("curricula").DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		fmt.Printf("Error while deleting curriculum: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *CurriculumRepo) findCurricula(ctx context.Context, filter interface{}) ([]models.Curriculum, error) {
+	curricula := []models.Curriculum{}
+	cur, err := m.db.Database("ekms").Collection("curricula").Find(ctx, filter)
+	if err != nil {
+		fmt.Printf("Error while getting curricula: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var curriculum models.Curriculum
+		err := cur.Decode(&curriculum)
+		if err != nil {
+			fmt.Printf("Error while decoding curriculum: %v\n", err)
+			return nil, err
+		}
+		curricula = append(curricula, curriculum)
+	}
+
+	return curricula, nil
+}
@@ -0,0 +1,215 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type FatherRepoInterface interface {
+	GetAllFathers(ctx context.Context) ([]models.Father, error)
+	GetFatherById(ctx context.Context, id string) (*models.Father, error)
+	CreateFather(ctx context.Context, father models.Father) (*models.Father, error)
+	UpdateFather(ctx context.Context, id string, father models.Father) (*models.Father, error)
+	DeleteFather(ctx context.Context, id string) error
+	AddFatherAlias(ctx context.Context, id primitive.ObjectID, alias string) error
+	IsPriest(ctx context.Context, personID primitive.ObjectID) (bool, error)
+
+	AddConfession(ctx context.Context, confession models.Confession) (*models.Confession, error)
+	GetConfessionsByPerson(ctx context.Context, personID primitive.ObjectID) ([]models.Confession, error)
+	GetLastConfessions(ctx context.Context) (map[primitive.ObjectID]time.Time, error)
+}
+
+type FatherRepo struct {
+	db *mongo.Client
+}
+
+func NewFatherRepo(db *mongo.Client) *FatherRepo {
+	return &FatherRepo{
+		db: db,
+	}
+}
+
+func (m *FatherRepo) GetAllFathers(ctx context.Context) ([]models.Father, error) {
+	fathers := []models.Father{}
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cur, err := m.db.Database("ekms").Collection("fathers").Find(ctx, bson.D{}, opts)
+	if err != nil {
+		fmt.Printf("Error while getting all fathers: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var father models.Father
+		err := cur.Decode(&father)
+		if err != nil {
+			fmt.Printf("Error while decoding father: %v\n", err)
+			return nil, err
+		}
+		fathers = append(fathers, father)
+	}
+
+	return fathers, nil
+}
+
+func (m *FatherRepo) GetFatherById(ctx context.Context, id string) (*models.Father, error) {
+	var father models.Father
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("GetFatherById", "FatherRepo", err)
+	}
+
+	err = m.db.Database("ekms").Collection("fathers").FindOne(ctx, bson.M{"_id": oid}).Decode(&father)
+	if err != nil {
+		fmt.Printf("Error while getting father by id: %v\n", err)
+		return nil, err
+	}
+
+	return &father, nil
+}
+
+func (m *FatherRepo) CreateFather(ctx context.Context, father models.Father) (*models.Father, error) {
+	father.ID = primitive.NewObjectID()
+	_, err := m.db.Database("ekms").Collection("fathers").InsertOne(ctx, father)
+	if err != nil {
+		fmt.Printf("Error while creating father: %v\n", err)
+		return nil, err
+	}
+
+	return &father, nil
+}
+
+func (m *FatherRepo) UpdateFather(ctx context.Context, id string, father models.Father) (*models.Father, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("UpdateFather", "FatherRepo", err)
+	}
+	father.ID = oid
+
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "name", Value: father.Name},
+			{Key: "aliases", Value: father.Aliases},
+			{Key: "church", Value: father.Church},
+			{Key: "phone", Value: father.Phone},
+			{Key: "personId", Value: father.PersonID},
+		}},
+	}
+	_, err = m.db.Database("ekms").Collection("fathers").UpdateOne(ctx, bson.M{"_id": oid}, update)
+	if err != nil {
+		fmt.Printf("Error while updating father: %v\n", err)
+		return nil, err
+	}
+
+	return &father, nil
+}
+
+func (m *FatherRepo) DeleteFather(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return cerrors.NewInvalidIDError("DeleteFather", "FatherRepo", err)
+	}
+
+	_, err = m.db.Database("ekms").Collection("fathers").DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		fmt.Printf("Error while deleting father: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *FatherRepo) AddFatherAlias(ctx context.Context, id primitive.ObjectID, alias string) error {
+	_, err := m.db.Database("ekms").Collection("fathers").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$addToSet": bson.M{"aliases": alias}})
+	if err != nil {
+		fmt.Printf("Error while adding father alias: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// IsPriest reports whether the person is linked to a father.
+func (m *FatherRepo) IsPriest(ctx context.Context, personID primitive.ObjectID) (bool, error) {
+	n, err := m.db.Database("ekms").Collection("fathers").CountDocuments(ctx, bson.M{"personId": personID})
+	if err != nil {
+		fmt.Printf("Error while checking priest: %v\n", err)
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (m *FatherRepo) AddConfession(ctx context.Context, confession models.Confession) (*models.Confession, error) {
+	confession.ID = primitive.NewObjectID()
+	_, err := m.db.Database("ekms").Collection("confessions").InsertOne(ctx, confession)
+	if err != nil {
+		fmt.Printf("Error while adding confession: %v\n", err)
+		return nil, err
+	}
+
+	return &confession, nil
+}
+
+func (m *FatherRepo) GetConfessionsByPerson(ctx context.Context, personID primitive.ObjectID) ([]models.Confession, error) {
+	confessions := []models.Confession{}
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}})
+	cur, err := m.db.Database("ekms").Collection("confessions").Find(ctx, bson.M{"personId": personID}, opts)
+	if err != nil {
+		fmt.Printf("Error while getting confessions by person: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var confession models.Confession
+		err := cur.Decode(&confession)
+		if err != nil {
+			fmt.Printf("Error while decoding confession: %v\n", err)
+			return nil, err
+		}
+		confessions = append(confessions, confession)
+	}
+
+	return confessions, nil
+}
+
+// GetLastConfessions returns the date of every person's latest confession.
+func (m *FatherRepo) GetLastConfessions(ctx context.Context) (map[primitive.ObjectID]time.Time, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$personId", "last": bson.M{"$max": "$date"}}}},
+	}
+	cur, err := m.db.Database("ekms").Collection("confessions").Aggregate(ctx, pipeline)
+	if err != nil {
+		fmt.Printf("Error while getting last confessions: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	last := map[primitive.ObjectID]time.Time{}
+	for cur.Next(ctx) {
+		var row struct {
+			PersonID primitive.ObjectID `bson:"_id"`
+			Last     time.Time          `bson:"last"`
+		}
+		err := cur.Decode(&row)
+		if err != nil {
+			fmt.Printf("Error while decoding last confession: %v\n", err)
+			return nil, err
+		}
+		last[row.PersonID] = row.Last
+	}
+
+	return last, nil
+}
@@ -0,0 +1,215 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type GroupRepoInterface interface {
+	GetAllGroups(ctx context.Context) ([]models.Group, error)
+	GetGroupById(ctx context.Context, id string) (*models.Group, error)
+	CreateGroup(ctx context.Context, group models.Group) (*models.Group, error)
+	UpdateGroup(ctx context.Context, id string, group models.Group) (*models.Group, error)
+	DeleteGroup(ctx context.Context, id string) error
+
+	AddMembership(ctx context.Context, groupID primitive.ObjectID, membership models.GroupMembership) (*models.Group, error)
+	EndMembership(ctx context.Context, groupID, personID primitive.ObjectID, leftAt time.Time) (*models.Group, error)
+	GetGroupsByPerson(ctx context.Context, personID primitive.ObjectID) ([]models.Group, error)
+
+	ClaimBirthdayReminder(ctx context.Context, groupID, personID primitive.ObjectID, day time.Time) (bool, error)
+	ReleaseBirthdayReminder(ctx context.Context, groupID, personID primitive.ObjectID, day time.Time) error
+}
+
+// maxBirthdayReminders bounds how many birthday reminders a group keeps. Only
+// the claims of the current day matter, so older ones are dropped.
+const maxBirthdayReminders = 500
+
+type GroupRepo struct {
+	db *mongo.Client
+}
+
+func NewGroupRepo(db *mongo.Client) *GroupRepo {
+	return &GroupRepo{
+		db: db,
+	}
+}
+
+func (m *GroupRepo) GetAllGroups(ctx context.Context) ([]models.Group, error) {
+	return m.findGroups(ctx, bson.D{})
+}
+
+func (m *GroupRepo) GetGroupById(ctx context.Context, id string) (*models.Group, error) {
+	var group models.Group
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("GetGroupById", "GroupRepo", err)
+	}
+
+	err = m.db.Database("ekms").Collection("groups").FindOne(ctx, bson.M{"_id": oid}).Decode(&group)
+	if err != nil {
+		fmt.Printf("Error while getting group by id: %v\n", err)
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+func (m *GroupRepo) CreateGroup(ctx context.Context, group models.Group) (*models.Group, error) {
+	group.ID = primitive.NewObjectID()
+	_, err := m.db.Database("ekms").Collection("groups").InsertOne(ctx, group)
+	if err != nil {
+		fmt.Printf("Error while creating group: %v\n", err)
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+// UpdateGroup only updates the group's own fields, memberships are changed
+// through AddMembership and EndMembership so their history is kept.
+func (m *GroupRepo) UpdateGroup(ctx context.Context, id string, group models.Group) (*models.Group, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("UpdateGroup", "GroupRepo", err)
+	}
+
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "name", Value: group.Name},
+			{Key: "description", Value: group.Description},
+		}},
+	}
+	_, err = m.db.Database("ekms").Collection("groups").UpdateOne(ctx, bson.M{"_id": oid}, update)
+	if err != nil {
+		fmt.Printf("Error while updating group: %v\n", err)
+		return nil, err
+	}
+
+	return m.GetGroupById(ctx, id)
+}
+
+func (m *GroupRepo) DeleteGroup(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return cerrors.NewInvalidIDError("DeleteGroup", "GroupRepo", err)
+	}
+
+	_, err = m.db.Database("ekms").Collection("groups").DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		fmt.Printf("Error while deleting group: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// AddMembership adds the membership unless the person already has an open
+// membership in the group, in which case it returns mongo.ErrNoDocuments. The
+// check and the insert are a single update, so concurrent requests cannot
+// both add the person.
+func (m *GroupRepo) AddMembership(ctx context.Context, groupID primitive.ObjectID, membership models.GroupMembership) (*models.Group, error) {
+	filter := bson.M{
+		"_id": groupID,
+		"memberships": bson.M{"$not": bson.M{"$elemMatch": bson.M{
+			"personId": membership.PersonID,
+			"leftAt":   bson.M{"$exists": false},
+		}}},
+	}
+	res, err := m.db.Database("ekms").Collection("groups").UpdateOne(ctx, filter, bson.M{"$push": bson.M{"memberships": membership}})
+	if err != nil {
+		fmt.Printf("Error while adding group membership: %v\n", err)
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return m.GetGroupById(ctx, groupID.Hex())
+}
+
+// EndMembership sets the leave date of the person's open membership.
+func (m *GroupRepo) EndMembership(ctx context.Context, groupID, personID primitive.ObjectID, leftAt time.Time) (*models.Group, error) {
+	filter := bson.M{
+		"_id": groupID,
+		"memberships": bson.M{"$elemMatch": bson.M{
+			"personId": personID,
+			"leftAt":   bson.M{"$exists": false},
+		}},
+	}
+	res, err := m.db.Database("ekms").Collection("groups").UpdateOne(ctx, filter, bson.M{"$set": bson.M{"memberships.$.leftAt": leftAt}})
+	if err != nil {
+		fmt.Printf("Error while ending group membership: %v\n", err)
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return m.GetGroupById(ctx, groupID.Hex())
+}
+
+func (m *GroupRepo) GetGroupsByPerson(ctx context.Context, personID primitive.ObjectID) ([]models.Group, error) {
+	return m.findGroups(ctx, bson.M{"memberships.personId": personID})
+}
+
+// ClaimBirthdayReminder records that the servant is told about the group's
+// birthdays on day. It returns false when it was already recorded, so each
+// servant is told once even with several instances running.
+func (m *GroupRepo) ClaimBirthdayReminder(ctx context.Context, groupID, personID primitive.ObjectID, day time.Time) (bool, error) {
+	reminder := models.BirthdayReminder{PersonID: personID, Day: day}
+	res, err := m.db.Database("ekms").Collection("groups").UpdateOne(ctx,
+		bson.M{"_id": groupID, "birthdayReminders": bson.M{"$not": bson.M{"$elemMatch": bson.M{"personId": personID, "day": day}}}},
+		bson.M{"$push": bson.M{"birthdayReminders": bson.M{"$each": []models.BirthdayReminder{reminder}, "$slice": -maxBirthdayReminders}}})
+	if err != nil {
+		fmt.Printf("Error while claiming birthday reminder: %v\n", err)
+		return false, err
+	}
+
+	return res.ModifiedCount == 1, nil
+}
+
+// ReleaseBirthdayReminder removes a claim made by ClaimBirthdayReminder whose
+// reminder could not be sent, so the next run tries again.
+func (m *GroupRepo) ReleaseBirthdayReminder(ctx context.Context, groupID, personID primitive.ObjectID, day time.Time) error {
+	_, err := m.db.Database("ekms").Collection("groups").UpdateOne(ctx,
+		bson.M{"_id": groupID},
+		bson.M{"$pull": bson.M{"birthdayReminders": bson.M{"personId": personID, "day": day}}})
+	if err != nil {
+		fmt.Printf("Error while releasing birthday reminder: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *GroupRepo) findGroups(ctx context.Context, filter interface{}) ([]models.Group, error) {
+	groups := []models.Group{}
+	cur, err := m.db.Database("ekms").Collection("groups").Find(ctx, filter)
+	if err != nil {
+		fmt.Printf("Error while getting groups: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var group models.Group
+		err := cur.Decode(&group)
+		if err != nil {
+			fmt.Printf("Error while decoding group: %v\n", err)
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,213 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type HouseholdRepoInterface interface {
+	GetAllHouseholds(ctx context.Context) ([]models.Household, error)
+	GetHouseholdById(ctx context.Context, id string) (*models.Household, error)
+	CreateHousehold(ctx context.Context, household models.Household) (*models.Household, error)
+	UpdateHousehold(ctx context.Context, id string, household models.Household) (*models.Household, error)
+	DeleteHousehold(ctx context.Context, id string) error
+
+	AddHouseholdMember(ctx context.Context, householdID, personID primitive.ObjectID) (*models.Household, error)
+	RemoveHouseholdMember(ctx context.Context, householdID, personID primitive.ObjectID) (*models.Household, error)
+	GetHouseholdByMember(ctx context.Context, personID primitive.ObjectID) (*models.Household, error)
+
+	CreateRelationship(ctx context.Context, rel models.Relationship) (*models.Relationship, error)
+	DeleteRelationship(ctx context.Context, id string) error
+	GetRelationshipsByPerson(ctx context.Context, personID primitive.ObjectID) ([]models.Relationship, error)
+}
+
+type HouseholdRepo struct {
+	db *mongo.Client
+}
+
+func NewHouseholdRepo(db *mongo.Client) *HouseholdRepo {
+	return &HouseholdRepo{
+		db: db,
+	}
+}
+
+func (m *HouseholdRepo) GetAllHouseholds(ctx context.Context) ([]models.Household, error) {
+	households := []models.Household{}
+	cur, err := m.db.Database("ekms").Collection("households").Find(ctx, bson.D{})
+	if err != nil {
+		fmt.Printf("Error while getting all households: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var household models.Household
+		err := cur.Decode(&household)
+		if err != nil {
+			fmt.Printf("Error while decoding household: %v\n", err)
+			return nil, err
+		}
+		households = append(households, household)
+	}
+
+	return households, nil
+}
+
+func (m *HouseholdRepo) GetHouseholdById(ctx context.Context, id string) (*models.Household, error) {
+	var household models.Household
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("GetHouseholdById", "HouseholdRepo", err)
+	}
+
+	err = m.db.Database("ekms").Collection("households").FindOne(ctx, bson.M{"_id": oid}).Decode(&household)
+	if err != nil {
+		fmt.Printf("Error while getting household by id: %v\n", err)
+		return nil, err
+	}
+
+	return &household, nil
+}
+
+func (m *HouseholdRepo) CreateHousehold(ctx context.Context, household models.Household) (*models.Household, error) {
+	household.ID = primitive.NewObjectID()
+	_, err := m.db.Database("ekms").Collection("households").InsertOne(ctx, household)
+	if err != nil {
+		fmt.Printf("Error while creating household: %v\n", err)
+		return nil, err
+	}
+
+	return &household, nil
+}
+
+// UpdateHousehold updates the household's own fields, members are changed
+// through AddHouseholdMember and RemoveHouseholdMember.
+func (m *HouseholdRepo) UpdateHousehold(ctx context.Context, id string, household models.Household) (*models.Household, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("UpdateHousehold", "HouseholdRepo", err)
+	}
+
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "name", Value: household.Name},
+			{Key: "address", Value: household.Address},
+			{Key: "phone", Value: household.Phone},
+		}},
+	}
+	_, err = m.db.Database("ekms").Collection("households").UpdateOne(ctx, bson.M{"_id": oid}, update)
+	if err != nil {
+		fmt.Printf("Error while updating household: %v\n", err)
+		return nil, err
+	}
+
+	return m.GetHouseholdById(ctx, id)
+}
+
+func (m *HouseholdRepo) DeleteHousehold(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return cerrors.NewInvalidIDError("DeleteHousehold", "HouseholdRepo", err)
+	}
+
+	_, err = m.db.Database("ekms").Collection("households").DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		fmt.Printf("Error while deleting household: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *HouseholdRepo) AddHouseholdMember(ctx context.Context, householdID, personID primitive.ObjectID) (*models.Household, error) {
+	_, err := m.db.Database("ekms").Collection("households").UpdateOne(ctx, bson.M{"_id": householdID}, bson.M{"$addToSet": bson.M{"memberIds": personID}})
+	if err != nil {
+		fmt.Printf("Error while adding household member: %v\n", err)
+		return nil, err
+	}
+
+	return m.GetHouseholdById(ctx, householdID.Hex())
+}
+
+func (m *HouseholdRepo) RemoveHouseholdMember(ctx context.Context, householdID, personID primitive.ObjectID) (*models.Household, error) {
+	_, err := m.db.Database("ekms").Collection("households").UpdateOne(ctx, bson.M{"_id": householdID}, bson.M{"$pull": bson.M{"memberIds": personID}})
+	if err != nil {
+		fmt.Printf("Error while removing household member: %v\n", err)
+		return nil, err
+	}
+
+	return m.GetHouseholdById(ctx, householdID.Hex())
+}
+
+func (m *HouseholdRepo) GetHouseholdByMember(ctx context.Context, personID primitive.ObjectID) (*models.Household, error) {
+	var household models.Household
+	err := m.db.Database("ekms").Collection("households").FindOne(ctx, bson.M{"memberIds": personID}).Decode(&household)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			fmt.Printf("Error while getting household by member: %v\n", err)
+		}
+		return nil, err
+	}
+
+	return &household, nil
+}
+
+func (m *HouseholdRepo) CreateRelationship(ctx context.Context, rel models.Relationship) (*models.Relationship, error) {
+	rel.ID = primitive.NewObjectID()
+	_, err := m.db.Database("ekms").Collection("relationships").InsertOne(ctx, rel)
+	if err != nil {
+		fmt.Printf("Error while creating relationship: %v\n", err)
+		return nil, err
+	}
+
+	return &rel, nil
+}
+
+func (m *HouseholdRepo) DeleteRelationship(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return cerrors.NewInvalidIDError("DeleteRelationship", "HouseholdRepo", err)
+	}
+
+	_, err = m.db.Database("ekms").Collection("relationships").DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		fmt.Printf("Error while deleting relationship: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetRelationshipsByPerson returns the edges starting or ending at the person.
+func (m *HouseholdRepo) GetRelationshipsByPerson(ctx context.Context, personID primitive.ObjectID) ([]models.Relationship, error) {
+	rels := []models.Relationship{}
+	filter := bson.M{"$or": bson.A{bson.M{"personId": personID}, bson.M{"relatedId": personID}}}
+	cur, err := m.db.Database("ekms").Collection("relationships").Find(ctx, filter)
+	if err != nil {
+		fmt.Printf("Error while getting relationships by person: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var rel models.Relationship
+		err := cur.Decode(&rel)
+		if err != nil {
+			fmt.Printf("Error while decoding relationship: %v\n", err)
+			return nil, err
+		}
+		rels = append(rels, rel)
+	}
+
+	return rels, nil
+}
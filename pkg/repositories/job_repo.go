@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type JobRepoInterface interface {
+	GetAllJobs(ctx context.Context) ([]models.Job, error)
+	GetJob(ctx context.Context, name string) (*models.Job, error)
+	EnsureJob(ctx context.Context, name, schedule string, nextRun time.Time) (*models.Job, error)
+	SetPaused(ctx context.Context, name string, paused bool) (*models.Job, error)
+	Trigger(ctx context.Context, name string) (*models.Job, error)
+	AcquireLease(ctx context.Context, name, owner string, now, until time.Time) (*models.Job, error)
+	FinishRun(ctx context.Context, job models.Job, owner string) error
+}
+
+type JobRepo struct {
+	db *mongo.Client
+}
+
+func NewJobRepo(db *mongo.Client) *JobRepo {
+	return &JobRepo{
+		db: db,
+	}
+}
+
+func (m *JobRepo) GetAllJobs(ctx context.Context) ([]models.Job, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cur, err := m.db.Database("ekms").Collection("jobs").Find(ctx, bson.D{}, opts)
+	if err != nil {
+		fmt.Printf("Error while getting all jobs: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	jobs := []models.Job{}
+	for cur.Next(ctx) {
+		var job models.Job
+		err := cur.Decode(&job)
+		if err != nil {
+			fmt.Printf("Error while decoding job: %v\n", err)
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+func (m *JobRepo) GetJob(ctx context.Context, name string) (*models.Job, error) {
+	var job models.Job
+	err := m.db.Database("ekms").Collection("jobs").FindOne(ctx, bson.M{"_id": name}).Decode(&job)
+	if err != nil {
+		fmt.Printf("Error while getting job: %v\n", err)
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// EnsureJob creates the job if it does not exist yet. When its schedule
+// changed since it was stored, the new schedule and next run replace the old
+// ones; the paused flag and run history are kept either way.
+func (m *JobRepo) EnsureJob(ctx context.Context, name, schedule string, nextRun time.Time) (*models.Job, error) {
+	jobs := m.db.Database("ekms").Collection("jobs")
+	_, err := jobs.UpdateOne(ctx,
+		bson.M{"_id": name},
+		bson.M{"$setOnInsert": bson.M{"schedule": schedule, "nextRun": nextRun, "paused": false, "triggered": false, "failures": 0}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		fmt.Printf("Error while ensuring job: %v\n", err)
+		return nil, err
+	}
+	_, err = jobs.UpdateOne(ctx,
+		bson.M{"_id": name, "schedule": bson.M{"$ne": schedule}},
+		bson.M{"$set": bson.M{"schedule": schedule, "nextRun": nextRun}})
+	if err != nil {
+		fmt.Printf("Error while updating job schedule: %v\n", err)
+		return nil, err
+	}
+
+	return m.GetJob(ctx, name)
+}
+
+func (m *JobRepo) SetPaused(ctx context.Context, name string, paused bool) (*models.Job, error) {
+	return m.setJob(ctx, name, bson.M{"paused": paused})
+}
+
+// Trigger marks the job to run as soon as a scheduler instance picks it up,
+// even when it is paused.
+func (m *JobRepo) Trigger(ctx context.Context, name string) (*models.Job, error) {
+	return m.setJob(ctx, name, bson.M{"triggered": true})
+}
+
+func (m *JobRepo) setJob(ctx context.Context, name string, fields bson.M) (*models.Job, error) {
+	var job models.Job
+	err := m.db.Database("ekms").Collection("jobs").FindOneAndUpdate(ctx,
+		bson.M{"_id": name},
+		bson.M{"$set": fields},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&job)
+	if err != nil {
+		fmt.Printf("Error while updating job: %v\n", err)
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// AcquireLease takes the lease of a job that is due at now, or was triggered,
+// and whose previous lease expired. It returns mongo.ErrNoDocuments when the
+// job is not due or another instance holds the lease.
+func (m *JobRepo) AcquireLease(ctx context.Context, name, owner string, now, until time.Time) (*models.Job, error) {
+	var job models.Job
+	err := m.db.Database("ekms").Collection("jobs").FindOneAndUpdate(ctx,
+		bson.M{
+			"_id": name,
+			"$and": bson.A{
+				bson.M{"$or": bson.A{
+					bson.M{"triggered": true},
+					bson.M{"paused": false, "nextRun": bson.M{"$lte": now}},
+				}},
+				bson.M{"$or": bson.A{
+					bson.M{"leaseUntil": bson.M{"$exists": false}},
+					bson.M{"leaseUntil": bson.M{"$lt": now}},
+				}},
+			},
+		},
+		bson.M{"$set": bson.M{
+			"leaseOwner": owner,
+			"leaseUntil": until,
+			"lastStatus": models.JobStatusRunning,
+			"lastRun":    now,
+			"triggered":  false,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&job)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			fmt.Printf("Error while acquiring job lease: %v\n", err)
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// FinishRun stores the outcome of a run and releases the lease, provided
+// owner still holds it.
+func (m *JobRepo) FinishRun(ctx context.Context, job models.Job, owner string) error {
+	_, err := m.db.Database("ekms").Collection("jobs").UpdateOne(ctx,
+		bson.M{"_id": job.Name, "leaseOwner": owner},
+		bson.M{
+			"$set": bson.M{
+				"nextRun":      job.NextRun,
+				"lastDuration": job.LastDuration,
+				"lastStatus":   job.LastStatus,
+				"lastError":    job.LastError,
+				"failures":     job.Failures,
+			},
+			"$unset": bson.M{"leaseOwner": "", "leaseUntil": ""},
+		})
+	if err != nil {
+		fmt.Printf("Error while finishing job run: %v\n", err)
+		return err
+	}
+
+	return nil
+}
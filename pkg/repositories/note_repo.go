@@ -0,0 +1,255 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/vault"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type NoteRepoInterface interface {
+	GetNotesByPerson(ctx context.Context, personID primitive.ObjectID) ([]models.Note, error)
+	GetNoteById(ctx context.Context, id string) (*models.Note, error)
+	CreateNote(ctx context.Context, note models.Note) (*models.Note, error)
+	ReplaceNote(ctx context.Context, note models.Note) (*models.Note, error)
+	DeleteNote(ctx context.Context, id string) error
+
+	AddNoteReads(ctx context.Context, reads []models.NoteRead) error
+	GetNoteReads(ctx context.Context, filter NoteReadFilter) ([]models.NoteRead, error)
+}
+
+// NoteReadFilter narrows down the reads returned by GetNoteReads. Empty
+// fields are ignored.
+type NoteReadFilter struct {
+	NoteID   primitive.ObjectID
+	PersonID primitive.ObjectID
+	ReaderID primitive.ObjectID
+}
+
+func (f NoteReadFilter) query() bson.M {
+	query := bson.M{}
+	if !f.NoteID.IsZero() {
+		query["noteId"] = f.NoteID
+	}
+	if !f.PersonID.IsZero() {
+		query["personId"] = f.PersonID
+	}
+	if !f.ReaderID.IsZero() {
+		query["readerId"] = f.ReaderID
+	}
+	return query
+}
+
+// NoteRepo stores notes with their bodies sealed by keys. Notes are returned
+// with their bodies opened.
+type NoteRepo struct {
+	db   *mongo.Client
+	keys *vault.Keyring
+}
+
+func NewNoteRepo(db *mongo.Client, keys *vault.Keyring) *NoteRepo {
+	return &NoteRepo{
+		db:   db,
+		keys: keys,
+	}
+}
+
+func (m *NoteRepo) GetNotesByPerson(ctx context.Context, personID primitive.ObjectID) ([]models.Note, error) {
+	notes := []models.Note{}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cur, err := m.db.Database("ekms").Collection("notes").Find(ctx, bson.M{"personId": personID}, opts)
+	if err != nil {
+		fmt.Printf("Error while getting notes by person: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var note models.Note
+		err := cur.Decode(&note)
+		if err != nil {
+			fmt.Printf("Error while decoding note: %v\n", err)
+			return nil, err
+		}
+		if err := m.open(&note); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+
+	return notes, nil
+}
+
+func (m *NoteRepo) GetNoteById(ctx context.Context, id string) (*models.Note, error) {
+	var note models.Note
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("GetNoteById", "NoteRepo", err)
+	}
+
+	err = m.db.Database("ekms").Collection("notes").FindOne(ctx, bson.M{"_id": oid}).Decode(&note)
+	if err != nil {
+		fmt.Printf("Error while getting note by id: %v\n", err)
+		return nil, err
+	}
+	if err := m.open(&note); err != nil {
+		return nil, err
+	}
+
+	return &note, nil
+}
+
+func (m *NoteRepo) CreateNote(ctx context.Context, note models.Note) (*models.Note, error) {
+	note.ID = primitive.NewObjectID()
+	sealed, err := m.seal(note)
+	if err != nil {
+		return nil, err
+	}
+	_, err = m.db.Database("ekms").Collection("notes").InsertOne(ctx, sealed)
+	if err != nil {
+		fmt.Printf("Error while creating note: %v\n", err)
+		return nil, err
+	}
+
+	return &note, nil
+}
+
+func (m *NoteRepo) ReplaceNote(ctx context.Context, note models.Note) (*models.Note, error) {
+	sealed, err := m.seal(note)
+	if err != nil {
+		return nil, err
+	}
+	res, err := m.db.Database("ekms").Collection("notes").ReplaceOne(ctx, bson.M{"_id": note.ID}, sealed)
+	if err != nil {
+		fmt.Printf("Error while replacing note: %v\n", err)
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return &note, nil
+}
+
+func (m *NoteRepo) DeleteNote(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return cerrors.NewInvalidIDError("DeleteNote", "NoteRepo", err)
+	}
+
+	_, err = m.db.Database("ekms").Collection("notes").DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		fmt.Printf("Error while deleting note: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *NoteRepo) AddNoteReads(ctx context.Context, reads []models.NoteRead) error {
+	if len(reads) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(reads))
+	for i := range reads {
+		reads[i].ID = primitive.NewObjectID()
+		docs[i] = reads[i]
+	}
+	_, err := m.db.Database("ekms").Collection("note_reads").InsertMany(ctx, docs)
+	if err != nil {
+		fmt.Printf("Error while adding note reads: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *NoteRepo) GetNoteReads(ctx context.Context, filter NoteReadFilter) ([]models.NoteRead, error) {
+	reads := []models.NoteRead{}
+	opts := options.Find().SetSort(bson.D{{Key: "readAt", Value: -1}})
+	cur, err := m.db.Database("ekms").Collection("note_reads").Find(ctx, filter.query(), opts)
+	if err != nil {
+		fmt.Printf("Error while getting note reads: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var read models.NoteRead
+		err := cur.Decode(&read)
+		if err != nil {
+			fmt.Printf("Error while decoding note read: %v\n", err)
+			return nil, err
+		}
+		reads = append(reads, read)
+	}
+
+	return reads, nil
+}
+
+// ResealNotes seals again the bodies not sealed with the current key, after
+// the key was rotated. It returns the number of notes rewritten.
+func (m *NoteRepo) ResealNotes(ctx context.Context) (int, error) {
+	notes := m.db.Database("ekms").Collection("notes")
+	cur, err := notes.Find(ctx, bson.D{})
+	if err != nil {
+		fmt.Printf("Error while resealing notes: %v\n", err)
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	n := 0
+	for cur.Next(ctx) {
+		var note models.Note
+		if err := cur.Decode(&note); err != nil {
+			fmt.Printf("Error while decoding note: %v\n", err)
+			return n, err
+		}
+		if vault.KeyID(note.Body) == m.keys.Current() {
+			continue
+		}
+		if err := m.open(&note); err != nil {
+			return n, err
+		}
+		body, err := m.keys.Seal(note.Body)
+		if err != nil {
+			return n, err
+		}
+		_, err = notes.UpdateOne(ctx, bson.M{"_id": note.ID}, bson.M{"$set": bson.M{"body": body}})
+		if err != nil {
+			fmt.Printf("Error while resealing note %v: %v\n", note.ID.Hex(), err)
+			return n, err
+		}
+		n++
+	}
+
+	return n, cur.Err()
+}
+
+func (m *NoteRepo) seal(note models.Note) (models.Note, error) {
+	body, err := m.keys.Seal(note.Body)
+	if err != nil {
+		fmt.Printf("Error while sealing note: %v\n", err)
+		return note, err
+	}
+	note.Body = body
+	return note, nil
+}
+
+func (m *NoteRepo) open(note *models.Note) error {
+	body, err := m.keys.Open(note.Body)
+	if err != nil {
+		fmt.Printf("Error while opening note %v: %v\n", note.ID.Hex(), err)
+		return err
+	}
+	note.Body = body
+	return nil
+}
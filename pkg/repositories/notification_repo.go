@@ -0,0 +1,273 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/vault"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type NotificationRepoInterface interface {
+	GetNotifications(ctx context.Context, filter NotificationFilter) ([]models.Notification, error)
+	GetNotificationById(ctx context.Context, id string) (*models.Notification, error)
+	CreateNotification(ctx context.Context, notification models.Notification) (*models.Notification, error)
+	UpdateDelivery(ctx context.Context, notification models.Notification) error
+	ClaimDueNotification(ctx context.Context, now time.Time, lease time.Duration) (*models.Notification, error)
+	ClaimNotification(ctx context.Context, id primitive.ObjectID, now time.Time, lease time.Duration) (*models.Notification, error)
+}
+
+// NotificationFilter narrows GetNotifications. Zero fields are ignored.
+type NotificationFilter struct {
+	Status   string
+	PersonID primitive.ObjectID
+	Template string
+	Limit    int64
+}
+
+func (f NotificationFilter) query() bson.M {
+	query := bson.M{}
+	if f.Status != "" {
+		query["status"] = f.Status
+	}
+	if !f.PersonID.IsZero() {
+		query["personId"] = f.PersonID
+	}
+	if f.Template != "" {
+		query["template"] = f.Template
+	}
+	return query
+}
+
+// NotificationRepo stores notifications with their address and body sealed
+// by keys, since both carry phone numbers, also of other persons.
+// Notifications are returned opened.
+type NotificationRepo struct {
+	db   *mongo.Client
+	keys *vault.Keyring
+}
+
+func NewNotificationRepo(db *mongo.Client, keys *vault.Keyring) *NotificationRepo {
+	return &NotificationRepo{
+		db:   db,
+		keys: keys,
+	}
+}
+
+// GetNotifications returns the matching notifications, newest first.
+func (m *NotificationRepo) GetNotifications(ctx context.Context, filter NotificationFilter) ([]models.Notification, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(filter.Limit)
+	}
+	cur, err := m.db.Database("ekms").Collection("notifications").Find(ctx, filter.query(), opts)
+	if err != nil {
+		fmt.Printf("Error while getting notifications: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	notifications := []models.Notification{}
+	for cur.Next(ctx) {
+		var notification models.Notification
+		err := cur.Decode(&notification)
+		if err != nil {
+			fmt.Printf("Error while decoding notification: %v\n", err)
+			return nil, err
+		}
+		if err := m.open(&notification); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, nil
+}
+
+func (m *NotificationRepo) GetNotificationById(ctx context.Context, id string) (*models.Notification, error) {
+	var notification models.Notification
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("GetNotificationById", "NotificationRepo", err)
+	}
+
+	err = m.db.Database("ekms").Collection("notifications").FindOne(ctx, bson.M{"_id": oid}).Decode(&notification)
+	if err != nil {
+		fmt.Printf("Error while getting notification by id: %v\n", err)
+		return nil, err
+	}
+	if err := m.open(&notification); err != nil {
+		return nil, err
+	}
+
+	return &notification, nil
+}
+
+func (m *NotificationRepo) CreateNotification(ctx context.Context, notification models.Notification) (*models.Notification, error) {
+	notification.ID = primitive.NewObjectID()
+	sealed, err := m.seal(notification)
+	if err != nil {
+		return nil, err
+	}
+	_, err = m.db.Database("ekms").Collection("notifications").InsertOne(ctx, sealed)
+	if err != nil {
+		fmt.Printf("Error while creating notification: %v\n", err)
+		return nil, err
+	}
+
+	return &notification, nil
+}
+
+// UpdateDelivery stores the outcome of a delivery attempt and releases the
+// lease.
+func (m *NotificationRepo) UpdateDelivery(ctx context.Context, notification models.Notification) error {
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "status", Value: notification.Status},
+			{Key: "attempts", Value: notification.Attempts},
+			{Key: "lastError", Value: notification.LastError},
+			{Key: "nextAttempt", Value: notification.NextAttempt},
+			{Key: "sentAt", Value: notification.SentAt},
+		}},
+		{Key: "$unset", Value: bson.D{{Key: "leasedUntil", Value: ""}}},
+	}
+	_, err := m.db.Database("ekms").Collection("notifications").UpdateOne(ctx, bson.M{"_id": notification.ID}, update)
+	if err != nil {
+		fmt.Printf("Error while updating notification delivery: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// ClaimDueNotification picks a pending notification due at now that is not
+// leased and leases it, so no other instance or request delivers it at the
+// same time. It returns mongo.ErrNoDocuments when nothing is due.
+func (m *NotificationRepo) ClaimDueNotification(ctx context.Context, now time.Time, lease time.Duration) (*models.Notification, error) {
+	var notification models.Notification
+	err := m.db.Database("ekms").Collection("notifications").FindOneAndUpdate(ctx,
+		bson.M{"status": models.NotificationPending, "nextAttempt": bson.M{"$lte": now}, "leasedUntil": bson.M{"$not": bson.M{"$gt": now}}},
+		bson.M{"$set": bson.M{"leasedUntil": now.Add(lease)}},
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "nextAttempt", Value: 1}}).SetReturnDocument(options.After),
+	).Decode(&notification)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			fmt.Printf("Error while claiming due notification: %v\n", err)
+		}
+		return nil, err
+	}
+	if err := m.open(&notification); err != nil {
+		return nil, err
+	}
+
+	return &notification, nil
+}
+
+// ClaimNotification leases the notification for a delivery attempt unless it
+// was sent or is leased already, in which case it returns
+// mongo.ErrNoDocuments.
+func (m *NotificationRepo) ClaimNotification(ctx context.Context, id primitive.ObjectID, now time.Time, lease time.Duration) (*models.Notification, error) {
+	var notification models.Notification
+	err := m.db.Database("ekms").Collection("notifications").FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": bson.M{"$ne": models.NotificationSent}, "leasedUntil": bson.M{"$not": bson.M{"$gt": now}}},
+		bson.M{"$set": bson.M{"leasedUntil": now.Add(lease)}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&notification)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			fmt.Printf("Error while claiming notification: %v\n", err)
+		}
+		return nil, err
+	}
+	if err := m.open(&notification); err != nil {
+		return nil, err
+	}
+
+	return &notification, nil
+}
+
+// ResealNotifications seals again the addresses and bodies not sealed with
+// the current key, after the key was rotated or for notifications stored
+// before they were sealed. It returns the number of notifications rewritten.
+func (m *NotificationRepo) ResealNotifications(ctx context.Context) (int, error) {
+	notifications := m.db.Database("ekms").Collection("notifications")
+	cur, err := notifications.Find(ctx, bson.D{})
+	if err != nil {
+		fmt.Printf("Error while resealing notifications: %v\n", err)
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	n := 0
+	for cur.Next(ctx) {
+		var notification models.Notification
+		if err := cur.Decode(&notification); err != nil {
+			fmt.Printf("Error while decoding notification: %v\n", err)
+			return n, err
+		}
+		if m.sealedWithCurrent(notification.To) && m.sealedWithCurrent(notification.Body) {
+			continue
+		}
+		if err := m.open(&notification); err != nil {
+			return n, err
+		}
+		sealed, err := m.seal(notification)
+		if err != nil {
+			return n, err
+		}
+		_, err = notifications.UpdateOne(ctx, bson.M{"_id": notification.ID}, bson.M{"$set": bson.M{"to": sealed.To, "body": sealed.Body}})
+		if err != nil {
+			fmt.Printf("Error while resealing notification %v: %v\n", notification.ID.Hex(), err)
+			return n, err
+		}
+		n++
+	}
+
+	return n, cur.Err()
+}
+
+func (m *NotificationRepo) sealedWithCurrent(value string) bool {
+	return value == "" || vault.KeyID(value) == m.keys.Current()
+}
+
+func (m *NotificationRepo) seal(notification models.Notification) (models.Notification, error) {
+	to, err := m.keys.Seal(notification.To)
+	if err != nil {
+		fmt.Printf("Error while sealing notification: %v\n", err)
+		return notification, err
+	}
+	body, err := m.keys.Seal(notification.Body)
+	if err != nil {
+		fmt.Printf("Error while sealing notification: %v\n", err)
+		return notification, err
+	}
+	notification.To = to
+	notification.Body = body
+	return notification, nil
+}
+
+// open opens the address and body. Notifications stored before they were
+// sealed are returned as they are.
+func (m *NotificationRepo) open(notification *models.Notification) error {
+	var err error
+	if vault.IsSealed(notification.To) {
+		if notification.To, err = m.keys.Open(notification.To); err != nil {
+			fmt.Printf("Error while opening notification %v: %v\n", notification.ID.Hex(), err)
+			return err
+		}
+	}
+	if vault.IsSealed(notification.Body) {
+		if notification.Body, err = m.keys.Open(notification.Body); err != nil {
+			fmt.Printf("Error while opening notification %v: %v\n", notification.ID.Hex(), err)
+			return err
+		}
+	}
+	return nil
+}
@@ -3,12 +3,17 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"sort"
+	"time"
 
 	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
 	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/vault"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type PersonRepoInterface interface {
@@ -17,18 +22,73 @@ type PersonRepoInterface interface {
 	CreatePerson(ctx context.Context, person models.Person) (*models.Person, error)
 	UpdatePerson(ctx context.Context, id string, person models.Person) (*models.Person, error)
 	DeletePerson(ctx context.Context, id string) error
+
+	FindPersonsByPhoneOrName(ctx context.Context, phones []string, name string) ([]models.Person, error)
+	StreamPersons(ctx context.Context, filter PersonFilter, fn func(models.Person) error) error
+	MergePersons(ctx context.Context, survivor models.Person, duplicateIDs []primitive.ObjectID) error
+	ErasePerson(ctx context.Context, person models.Person) error
+	GetPersonsByIds(ctx context.Context, ids []primitive.ObjectID) ([]models.Person, error)
+	GetFrValues(ctx context.Context) (map[string]int, error)
+	SetFatherByFr(ctx context.Context, fr string, fatherID primitive.ObjectID) (int64, error)
+}
+
+// PersonFilter narrows down the persons returned by StreamPersons. Empty
+// fields are ignored.
+type PersonFilter struct {
+	Fr       string
+	FatherID primitive.ObjectID
+	Degree   string
 }
 
+func (f PersonFilter) query() bson.M {
+	query := bson.M{}
+	if f.Fr != "" {
+		query["fr"] = f.Fr
+	}
+	if !f.FatherID.IsZero() {
+		query["fatherId"] = f.FatherID
+	}
+	if f.Degree != "" {
+		query["degree"] = f.Degree
+	}
+	return query
+}
+
+// PersonRepo stores the phone, address and birthday of persons sealed by
+// keys, with a blind index of the phone made by index for lookups. Persons
+// are returned with those fields opened.
 type PersonRepo struct {
-	db *mongo.Client
+	db    *mongo.Client
+	keys  *vault.Keyring
+	index *vault.Index
 }
 
-func NewPersonRepo(db *mongo.Client) *PersonRepo {
+func NewPersonRepo(db *mongo.Client, keys *vault.Keyring, index *vault.Index) *PersonRepo {
 	return &PersonRepo{
-		db: db,
+		db:    db,
+		keys:  keys,
+		index: index,
 	}
 }
 
+// personDocument is a person as stored. Birthday holds the sealed date, or
+// the plain date of documents EncryptPersons has not rewritten yet; phone and
+// address of those are plain too.
+type personDocument struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	Name       string             `bson:"name,omitempty"`
+	Birthday   interface{}        `bson:"birthday,omitempty"`
+	Phone      string             `bson:"phone,omitempty"`
+	PhoneIndex string             `bson:"phoneIndex,omitempty"`
+	Email      string             `bson:"email,omitempty"`
+	Address    string             `bson:"address,omitempty"`
+	Fr         string             `bson:"fr,omitempty"`
+	FatherID   primitive.ObjectID `bson:"fatherId,omitempty"`
+	Degree     string             `bson:"degree,omitempty"`
+	Language   string             `bson:"language,omitempty"`
+	ErasedAt   time.Time          `bson:"erasedAt,omitempty"`
+}
+
 func (m *PersonRepo) GetAllPersons(ctx context.Context) ([]models.Person, error) {
 	persons := []models.Person{}
 	cur, err := m.db.Database("ekms").Collection("people").Find(ctx, bson.D{})
@@ -38,8 +98,7 @@ func (m *PersonRepo) GetAllPersons(ctx context.Context) ([]models.Person, error)
 	}
 
 	for cur.Next(ctx) {
-		var person models.Person
-		err := cur.Decode(&person)
+		person, err := m.decode(cur)
 
 		if err != nil {
 			fmt.Printf("Error while decoding person: %v\n", err)
@@ -59,7 +118,7 @@ func (m *PersonRepo) GetPersonById(ctx context.Context, id string) (*models.Pers
 		return nil, custErr
 	}
 
-	err = m.db.Database("ekms").Collection("people").FindOne(ctx, bson.M{"_id": oid}).Decode(&person)
+	person, err = m.decode(m.db.Database("ekms").Collection("people").FindOne(ctx, bson.M{"_id": oid}))
 	if err != nil {
 		fmt.Printf("Error while getting person by id: %v\n", err)
 		return nil, err
@@ -70,7 +129,11 @@ func (m *PersonRepo) GetPersonById(ctx context.Context, id string) (*models.Pers
 
 func (m *PersonRepo) CreatePerson(ctx context.Context, person models.Person) (*models.Person, error) {
 	person.ID = primitive.NewObjectID()
-	_, err := m.db.Database("ekms").Collection("people").InsertOne(ctx, person)
+	doc, err := m.seal(person)
+	if err != nil {
+		return nil, err
+	}
+	_, err = m.db.Database("ekms").Collection("people").InsertOne(ctx, doc)
 	if err != nil {
 		fmt.Printf("Error while creating person: %v\n", err)
 		return nil, err
@@ -87,14 +150,22 @@ func (m *PersonRepo) UpdatePerson(ctx context.Context, id string, person models.
 		custErr := cerrors.NewInvalidIDError("UpdatePerson", "PersonRepo", err)
 		return nil, custErr
 	}
+	doc, err := m.seal(person)
+	if err != nil {
+		return nil, err
+	}
 	update := bson.D{
 		{Key: "$set", Value: bson.D{
 			{Key: "name", Value: person.Name},
-			{Key: "birthday", Value: person.Birthday},
-			{Key: "phone", Value: person.Phone},
-			{Key: "address", Value: person.Address},
+			{Key: "birthday", Value: doc.Birthday},
+			{Key: "phone", Value: doc.Phone},
+			{Key: "phoneIndex", Value: doc.PhoneIndex},
+			{Key: "address", Value: doc.Address},
 			{Key: "fr", Value: person.Fr},
+			{Key: "fatherId", Value: person.FatherID},
 			{Key: "degree", Value: person.Degree},
+			{Key: "email", Value: person.Email},
+			{Key: "language", Value: person.Language},
 		}},
 	}
 	_, err = m.db.Database("ekms").Collection("people").UpdateOne(ctx, bson.M{"_id": oid}, update)
@@ -102,7 +173,6 @@ func (m *PersonRepo) UpdatePerson(ctx context.Context, id string, person models.
 		fmt.Printf("Error while updating person: %v\n", err)
 		return nil, err
 	}
-	fmt.Println("Person inside update: ", person)
 	return &person, nil
 }
 
@@ -121,3 +191,704 @@ func (m *PersonRepo) DeletePerson(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// FindPersonsByPhoneOrName finds persons by the blind index of their phone,
+// which is taken over the normalized phone so every spelling of a number
+// matches, or by the plain phone of documents not encrypted yet, or by name.
+func (m *PersonRepo) FindPersonsByPhoneOrName(ctx context.Context, phones []string, name string) ([]models.Person, error) {
+	persons := []models.Person{}
+	or := bson.A{}
+	if len(phones) > 0 {
+		indexes := make([]string, len(phones))
+		for i, phone := range phones {
+			indexes[i] = m.phoneIndex(phone)
+		}
+		or = append(or, bson.M{"phoneIndex": bson.M{"$in": indexes}}, bson.M{"phone": bson.M{"$in": phones}})
+	}
+	if name != "" {
+		or = append(or, bson.M{"name": bson.M{"$regex": "^" + regexp.QuoteMeta(name) + "$", "$options": "i"}})
+	}
+	if len(or) == 0 {
+		return persons, nil
+	}
+
+	cur, err := m.db.Database("ekms").Collection("people").Find(ctx, bson.M{"$or": or})
+	if err != nil {
+		fmt.Printf("Error while finding persons by phone or name: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		person, err := m.decode(cur)
+		if err != nil {
+			fmt.Printf("Error while decoding person: %v\n", err)
+			return nil, err
+		}
+		persons = append(persons, person)
+	}
+
+	return persons, nil
+}
+
+// StreamPersons calls fn for every person matching filter while iterating the
+// cursor, so callers never hold the whole collection in memory.
+func (m *PersonRepo) StreamPersons(ctx context.Context, filter PersonFilter, fn func(models.Person) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cur, err := m.db.Database("ekms").Collection("people").Find(ctx, filter.query(), opts)
+	if err != nil {
+		fmt.Printf("Error while streaming persons: %v\n", err)
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		person, err := m.decode(cur)
+		if err != nil {
+			fmt.Printf("Error while decoding person: %v\n", err)
+			return err
+		}
+		if err := fn(person); err != nil {
+			return err
+		}
+	}
+
+	return cur.Err()
+}
+
+// MergePersons updates survivor, points every attendance record, assignment
+// and quiz submission and grading, group membership, household, relationship,
+// confession, points entry and adjustment, attachment, speaker, father, note,
+// note read, notification, access token and calendar feed of the duplicates
+// at it and deletes the duplicates, all in one transaction. When the survivor
+// and a duplicate both have a record for the same service, assignment or
+// quiz, the survivor's record is kept; their memberships of the same group
+// are joined, see mergeMemberships. Transactions need MongoDB to run as a
+// replica set.
+func (m *PersonRepo) MergePersons(ctx context.Context, survivor models.Person, duplicateIDs []primitive.ObjectID) error {
+	session, err := m.db.StartSession()
+	if err != nil {
+		fmt.Printf("Error while starting merge session: %v\n", err)
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	db := m.db.Database("ekms")
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		_, err := m.UpdatePerson(sc, survivor.ID.Hex(), survivor)
+		if err != nil {
+			return nil, err
+		}
+
+		services := db.Collection("services")
+		_, err = services.UpdateMany(sc,
+			bson.M{"attendanceRecord.personId": survivor.ID},
+			bson.M{"$pull": bson.M{"attendanceRecord": bson.M{"personId": bson.M{"$in": duplicateIDs}}}})
+		if err != nil {
+			fmt.Printf("Error while removing duplicate attendance records: %v\n", err)
+			return nil, err
+		}
+		_, err = services.UpdateMany(sc,
+			bson.M{"attendanceRecord.personId": bson.M{"$in": duplicateIDs}},
+			bson.M{"$set": bson.M{"attendanceRecord.$[r].personId": survivor.ID}},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"r.personId": bson.M{"$in": duplicateIDs}}}}))
+		if err != nil {
+			fmt.Printf("Error while rewriting attendance records: %v\n", err)
+			return nil, err
+		}
+
+		assignments := db.Collection("assignments")
+		_, err = assignments.UpdateMany(sc,
+			bson.M{"submissions.personId": survivor.ID},
+			bson.M{"$pull": bson.M{"submissions": bson.M{"personId": bson.M{"$in": duplicateIDs}}}})
+		if err != nil {
+			fmt.Printf("Error while removing duplicate submissions: %v\n", err)
+			return nil, err
+		}
+		_, err = assignments.UpdateMany(sc,
+			bson.M{"submissions.personId": bson.M{"$in": duplicateIDs}},
+			bson.M{"$set": bson.M{"submissions.$[s].personId": survivor.ID}},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"s.personId": bson.M{"$in": duplicateIDs}}}}))
+		if err != nil {
+			fmt.Printf("Error while rewriting submissions: %v\n", err)
+			return nil, err
+		}
+
+		quizzes := db.Collection("quizzes")
+		_, err = quizzes.UpdateMany(sc,
+			bson.M{"submissions.personId": survivor.ID},
+			bson.M{"$pull": bson.M{"submissions": bson.M{"personId": bson.M{"$in": duplicateIDs}}}})
+		if err != nil {
+			fmt.Printf("Error while removing duplicate quiz submissions: %v\n", err)
+			return nil, err
+		}
+		_, err = quizzes.UpdateMany(sc,
+			bson.M{"submissions.personId": bson.M{"$in": duplicateIDs}},
+			bson.M{"$set": bson.M{"submissions.$[s].personId": survivor.ID}},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"s.personId": bson.M{"$in": duplicateIDs}}}}))
+		if err != nil {
+			fmt.Printf("Error while rewriting quiz submissions: %v\n", err)
+			return nil, err
+		}
+
+		_, err = assignments.UpdateMany(sc,
+			bson.M{"submissions.graderId": bson.M{"$in": duplicateIDs}},
+			bson.M{"$set": bson.M{"submissions.$[s].graderId": survivor.ID}},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"s.graderId": bson.M{"$in": duplicateIDs}}}}))
+		if err != nil {
+			fmt.Printf("Error while rewriting submission graders: %v\n", err)
+			return nil, err
+		}
+
+		groups := db.Collection("groups")
+		cur, err := groups.Find(sc, bson.M{"memberships.personId": bson.M{"$in": duplicateIDs}})
+		if err != nil {
+			fmt.Printf("Error while getting groups of merged persons: %v\n", err)
+			return nil, err
+		}
+		var shared []models.Group
+		if err := cur.All(sc, &shared); err != nil {
+			fmt.Printf("Error while decoding groups of merged persons: %v\n", err)
+			return nil, err
+		}
+		for _, g := range shared {
+			_, err = groups.UpdateOne(sc, bson.M{"_id": g.ID}, bson.M{"$set": bson.M{"memberships": mergeMemberships(g.Memberships, survivor.ID, duplicateIDs)}})
+			if err != nil {
+				fmt.Printf("Error while rewriting group memberships: %v\n", err)
+				return nil, err
+			}
+		}
+
+		households := db.Collection("households")
+		_, err = households.UpdateMany(sc,
+			bson.M{"memberIds": survivor.ID},
+			bson.M{"$pull": bson.M{"memberIds": bson.M{"$in": duplicateIDs}}})
+		if err != nil {
+			fmt.Printf("Error while removing duplicate household members: %v\n", err)
+			return nil, err
+		}
+		_, err = households.UpdateMany(sc,
+			bson.M{"memberIds": bson.M{"$in": duplicateIDs}},
+			bson.M{"$set": bson.M{"memberIds.$[h]": survivor.ID}},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"h": bson.M{"$in": duplicateIDs}}}}))
+		if err != nil {
+			fmt.Printf("Error while rewriting household members: %v\n", err)
+			return nil, err
+		}
+
+		relationships := db.Collection("relationships")
+		for _, field := range []string{"personId", "relatedId"} {
+			_, err = relationships.UpdateMany(sc, bson.M{field: bson.M{"$in": duplicateIDs}}, bson.M{"$set": bson.M{field: survivor.ID}})
+			if err != nil {
+				fmt.Printf("Error while rewriting relationships: %v\n", err)
+				return nil, err
+			}
+		}
+		_, err = relationships.DeleteMany(sc, bson.M{"$expr": bson.M{"$eq": bson.A{"$personId", "$relatedId"}}})
+		if err != nil {
+			fmt.Printf("Error while deleting self relationships: %v\n", err)
+			return nil, err
+		}
+
+		_, err = db.Collection("confessions").UpdateMany(sc,
+			bson.M{"personId": bson.M{"$in": duplicateIDs}},
+			bson.M{"$set": bson.M{"personId": survivor.ID}})
+		if err != nil {
+			fmt.Printf("Error while rewriting confessions: %v\n", err)
+			return nil, err
+		}
+
+		// Rule entries keep their keys, so duplicated awards disappear once
+		// the events are evaluated again.
+		for _, field := range []string{"personId", "byId"} {
+			_, err = db.Collection("points_ledger").UpdateMany(sc, bson.M{field: bson.M{"$in": duplicateIDs}}, bson.M{"$set": bson.M{field: survivor.ID}})
+			if err != nil {
+				fmt.Printf("Error while rewriting points entries: %v\n", err)
+				return nil, err
+			}
+		}
+
+		_, err = db.Collection("attachments").UpdateMany(sc,
+			bson.M{"personId": bson.M{"$in": duplicateIDs}},
+			bson.M{"$set": bson.M{"personId": survivor.ID}})
+		if err != nil {
+			fmt.Printf("Error while rewriting attachments: %v\n", err)
+			return nil, err
+		}
+
+		_, err = db.Collection("speakers").UpdateMany(sc,
+			bson.M{"personId": bson.M{"$in": duplicateIDs}},
+			bson.M{"$set": bson.M{"personId": survivor.ID}})
+		if err != nil {
+			fmt.Printf("Error while rewriting speakers: %v\n", err)
+			return nil, err
+		}
+
+		_, err = db.Collection("fathers").UpdateMany(sc,
+			bson.M{"personId": bson.M{"$in": duplicateIDs}},
+			bson.M{"$set": bson.M{"personId": survivor.ID}})
+		if err != nil {
+			fmt.Printf("Error while rewriting fathers: %v\n", err)
+			return nil, err
+		}
+
+		for _, field := range []string{"personId", "authorId"} {
+			_, err = db.Collection("notes").UpdateMany(sc, bson.M{field: bson.M{"$in": duplicateIDs}}, bson.M{"$set": bson.M{field: survivor.ID}})
+			if err != nil {
+				fmt.Printf("Error while rewriting notes: %v\n", err)
+				return nil, err
+			}
+		}
+		for _, field := range []string{"personId", "readerId"} {
+			_, err = db.Collection("note_reads").UpdateMany(sc, bson.M{field: bson.M{"$in": duplicateIDs}}, bson.M{"$set": bson.M{field: survivor.ID}})
+			if err != nil {
+				fmt.Printf("Error while rewriting note reads: %v\n", err)
+				return nil, err
+			}
+		}
+
+		_, err = db.Collection("notifications").UpdateMany(sc,
+			bson.M{"personId": bson.M{"$in": duplicateIDs}},
+			bson.M{"$set": bson.M{"personId": survivor.ID}})
+		if err != nil {
+			fmt.Printf("Error while rewriting notifications: %v\n", err)
+			return nil, err
+		}
+
+		_, err = db.Collection("accessTokens").UpdateMany(sc,
+			bson.M{"personId": bson.M{"$in": duplicateIDs}},
+			bson.M{"$set": bson.M{"personId": survivor.ID}})
+		if err != nil {
+			fmt.Printf("Error while rewriting access tokens: %v\n", err)
+			return nil, err
+		}
+
+		// A feed has at most one active token, so the duplicates' person feeds
+		// are revoked when the survivor already has one.
+		feeds := db.Collection("calendarFeeds")
+		active, err := feeds.CountDocuments(sc, bson.M{"kind": "person", "targetId": survivor.ID, "revokedAt": bson.M{"$exists": false}})
+		if err != nil {
+			fmt.Printf("Error while counting calendar feeds: %v\n", err)
+			return nil, err
+		}
+		if active > 0 {
+			_, err = feeds.UpdateMany(sc,
+				bson.M{"kind": "person", "targetId": bson.M{"$in": duplicateIDs}, "revokedAt": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"revokedAt": time.Now()}})
+			if err != nil {
+				fmt.Printf("Error while revoking calendar feeds: %v\n", err)
+				return nil, err
+			}
+		}
+		_, err = feeds.UpdateMany(sc,
+			bson.M{"kind": "person", "targetId": bson.M{"$in": duplicateIDs}},
+			bson.M{"$set": bson.M{"targetId": survivor.ID}})
+		if err != nil {
+			fmt.Printf("Error while rewriting calendar feeds: %v\n", err)
+			return nil, err
+		}
+		_, err = feeds.UpdateMany(sc,
+			bson.M{"createdBy": bson.M{"$in": duplicateIDs}},
+			bson.M{"$set": bson.M{"createdBy": survivor.ID}})
+		if err != nil {
+			fmt.Printf("Error while rewriting calendar feeds: %v\n", err)
+			return nil, err
+		}
+
+		_, err = db.Collection("people").DeleteMany(sc, bson.M{"_id": bson.M{"$in": duplicateIDs}})
+		if err != nil {
+			fmt.Printf("Error while deleting merged persons: %v\n", err)
+			return nil, err
+		}
+		return nil, nil
+	})
+	if err != nil {
+		fmt.Printf("Error while merging persons: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// mergeMemberships points the memberships of the duplicates at the survivor
+// and joins the survivor's memberships whose periods overlap, so a person who
+// was in the group twice over is in it once. Joined memberships keep the
+// survivor's own role when it has one.
+func mergeMemberships(memberships []models.GroupMembership, survivorID primitive.ObjectID, duplicateIDs []primitive.ObjectID) []models.GroupMembership {
+	type period struct {
+		models.GroupMembership
+		own bool
+	}
+	merged := []models.GroupMembership{}
+	periods := []period{}
+	for _, m := range memberships {
+		switch {
+		case m.PersonID == survivorID:
+			periods = append(periods, period{m, true})
+		case containsID(duplicateIDs, m.PersonID):
+			m.PersonID = survivorID
+			periods = append(periods, period{m, false})
+		default:
+			merged = append(merged, m)
+		}
+	}
+
+	sort.SliceStable(periods, func(i, j int) bool { return periods[i].JoinedAt.Before(periods[j].JoinedAt) })
+	joined := []period{}
+	for _, p := range periods {
+		last := len(joined) - 1
+		if last < 0 || !joined[last].LeftAt.IsZero() && p.JoinedAt.After(joined[last].LeftAt) {
+			joined = append(joined, p)
+			continue
+		}
+		if p.LeftAt.IsZero() || !joined[last].LeftAt.IsZero() && p.LeftAt.After(joined[last].LeftAt) {
+			joined[last].LeftAt = p.LeftAt
+		}
+		if p.Role != "" && (joined[last].Role == "" || p.own && !joined[last].own) {
+			joined[last].Role = p.Role
+			joined[last].own = p.own
+		}
+	}
+	for _, p := range joined {
+		merged = append(merged, p.GroupMembership)
+	}
+	return merged
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ErasePerson replaces the person with its anonymized version and, all in
+// one transaction, removes what else identifies them: the answers and
+// feedback of their assignment and quiz submissions, their notifications,
+// confessions, notes, relationships and household membership, the reasons of
+// their points entries and their links from speakers and fathers. Attendance
+// records, submission states and scores, points and group memberships stay,
+// so counts and totals do not change. Their access tokens are revoked.
+// Attachments are removed by the caller.
+func (m *PersonRepo) ErasePerson(ctx context.Context, person models.Person) error {
+	session, err := m.db.StartSession()
+	if err != nil {
+		fmt.Printf("Error while starting erase session: %v\n", err)
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	db := m.db.Database("ekms")
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		doc, err := m.seal(person)
+		if err != nil {
+			return nil, err
+		}
+		res, err := db.Collection("people").ReplaceOne(sc, bson.M{"_id": person.ID}, doc)
+		if err != nil {
+			fmt.Printf("Error while replacing erased person: %v\n", err)
+			return nil, err
+		}
+		if res.MatchedCount == 0 {
+			return nil, mongo.ErrNoDocuments
+		}
+
+		_, err = db.Collection("assignments").UpdateMany(sc,
+			bson.M{"submissions.personId": person.ID},
+			bson.M{"$unset": bson.M{"submissions.$[s].answer": "", "submissions.$[s].feedback": "", "submissions.$[s].attachments": ""}},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"s.personId": person.ID}}}))
+		if err != nil {
+			fmt.Printf("Error while erasing submissions: %v\n", err)
+			return nil, err
+		}
+
+		_, err = db.Collection("quizzes").UpdateMany(sc,
+			bson.M{"submissions.personId": person.ID},
+			bson.M{"$unset": bson.M{"submissions.$[s].answers": ""}},
+			options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"s.personId": person.ID}}}))
+		if err != nil {
+			fmt.Printf("Error while erasing quiz submissions: %v\n", err)
+			return nil, err
+		}
+
+		for _, collection := range []string{"notifications", "confessions", "notes", "note_reads"} {
+			_, err = db.Collection(collection).DeleteMany(sc, bson.M{"personId": person.ID})
+			if err != nil {
+				fmt.Printf("Error while erasing %v: %v\n", collection, err)
+				return nil, err
+			}
+		}
+
+		_, err = db.Collection("relationships").DeleteMany(sc, bson.M{"$or": bson.A{bson.M{"personId": person.ID}, bson.M{"relatedId": person.ID}}})
+		if err != nil {
+			fmt.Printf("Error while erasing relationships: %v\n", err)
+			return nil, err
+		}
+		_, err = db.Collection("households").UpdateMany(sc, bson.M{"memberIds": person.ID}, bson.M{"$pull": bson.M{"memberIds": person.ID}})
+		if err != nil {
+			fmt.Printf("Error while erasing household members: %v\n", err)
+			return nil, err
+		}
+
+		_, err = db.Collection("points_ledger").UpdateMany(sc, bson.M{"personId": person.ID}, bson.M{"$unset": bson.M{"reason": ""}})
+		if err != nil {
+			fmt.Printf("Error while erasing points reasons: %v\n", err)
+			return nil, err
+		}
+
+		_, err = db.Collection("accessTokens").UpdateMany(sc,
+			bson.M{"personId": person.ID, "revokedAt": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"revokedAt": person.ErasedAt}})
+		if err != nil {
+			fmt.Printf("Error while revoking access tokens: %v\n", err)
+			return nil, err
+		}
+
+		for _, collection := range []string{"speakers", "fathers"} {
+			_, err = db.Collection(collection).UpdateMany(sc, bson.M{"personId": person.ID}, bson.M{"$unset": bson.M{"personId": ""}})
+			if err != nil {
+				fmt.Printf("Error while unlinking erased person from %v: %v\n", collection, err)
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		fmt.Printf("Error while erasing person: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (m *PersonRepo) GetPersonsByIds(ctx context.Context, ids []primitive.ObjectID) ([]models.Person, error) {
+	persons := []models.Person{}
+	if len(ids) == 0 {
+		return persons, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cur, err := m.db.Database("ekms").Collection("people").Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		fmt.Printf("Error while getting persons by ids: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		person, err := m.decode(cur)
+		if err != nil {
+			fmt.Printf("Error while decoding person: %v\n", err)
+			return nil, err
+		}
+		persons = append(persons, person)
+	}
+
+	return persons, nil
+}
+
+// GetFrValues returns the distinct free-text confession fathers of persons not
+// linked to a Father yet, with how many persons use each.
+func (m *PersonRepo) GetFrValues(ctx context.Context) (map[string]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"fr": bson.M{"$nin": bson.A{"", nil}}, "fatherId": bson.M{"$in": bson.A{nil, primitive.NilObjectID}}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$fr", "count": bson.M{"$sum": 1}}}},
+	}
+	cur, err := m.db.Database("ekms").Collection("people").Aggregate(ctx, pipeline)
+	if err != nil {
+		fmt.Printf("Error while getting fr values: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	values := map[string]int{}
+	for cur.Next(ctx) {
+		var row struct {
+			Fr    string `bson:"_id"`
+			Count int    `bson:"count"`
+		}
+		err := cur.Decode(&row)
+		if err != nil {
+			fmt.Printf("Error while decoding fr value: %v\n", err)
+			return nil, err
+		}
+		values[row.Fr] = row.Count
+	}
+
+	return values, nil
+}
+
+// SetFatherByFr links every person whose free-text confession father is fr to
+// fatherID. Persons already linked to a father are left alone.
+func (m *PersonRepo) SetFatherByFr(ctx context.Context, fr string, fatherID primitive.ObjectID) (int64, error) {
+	res, err := m.db.Database("ekms").Collection("people").UpdateMany(ctx,
+		bson.M{"fr": fr, "fatherId": bson.M{"$in": bson.A{nil, primitive.NilObjectID}}},
+		bson.M{"$set": bson.M{"fatherId": fatherID}})
+	if err != nil {
+		fmt.Printf("Error while setting father by fr: %v\n", err)
+		return 0, err
+	}
+
+	return res.ModifiedCount, nil
+}
+
+// EncryptPersons seals the phone, address and birthday of every person not
+// sealed with the current key yet, which encrypts the documents written
+// before encryption and moves the others to the current key after it was
+// rotated, and fixes stale phone indexes. It returns the number of persons
+// rewritten.
+func (m *PersonRepo) EncryptPersons(ctx context.Context) (int, error) {
+	people := m.db.Database("ekms").Collection("people")
+	_, err := people.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "phoneIndex", Value: 1}}})
+	if err != nil {
+		fmt.Printf("Error while creating phone index: %v\n", err)
+		return 0, err
+	}
+
+	cur, err := people.Find(ctx, bson.D{})
+	if err != nil {
+		fmt.Printf("Error while encrypting persons: %v\n", err)
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	n := 0
+	for cur.Next(ctx) {
+		var stored personDocument
+		if err := cur.Decode(&stored); err != nil {
+			fmt.Printf("Error while decoding person: %v\n", err)
+			return n, err
+		}
+		if m.current(stored) {
+			continue
+		}
+		person, err := m.open(stored)
+		if err != nil {
+			return n, err
+		}
+		doc, err := m.seal(person)
+		if err != nil {
+			return n, err
+		}
+		_, err = people.UpdateOne(ctx, bson.M{"_id": stored.ID}, bson.M{"$set": bson.M{
+			"birthday":   doc.Birthday,
+			"phone":      doc.Phone,
+			"phoneIndex": doc.PhoneIndex,
+			"address":    doc.Address,
+		}})
+		if err != nil {
+			fmt.Printf("Error while encrypting person %v: %v\n", stored.ID.Hex(), err)
+			return n, err
+		}
+		n++
+	}
+
+	return n, cur.Err()
+}
+
+// current reports whether the stored person is sealed with the current key
+// and indexed.
+func (m *PersonRepo) current(doc personDocument) bool {
+	sealed := func(value string) bool {
+		return value == "" || vault.KeyID(value) == m.keys.Current()
+	}
+	birthday, ok := doc.Birthday.(string)
+	if doc.Birthday != nil && !ok {
+		return false
+	}
+	if !sealed(birthday) || !sealed(doc.Phone) || !sealed(doc.Address) {
+		return false
+	}
+	phone, err := m.keys.Open(doc.Phone)
+	return err == nil && doc.PhoneIndex == m.phoneIndex(phone)
+}
+
+// phoneIndex returns the blind index of the phone in its normalized form.
+func (m *PersonRepo) phoneIndex(phone string) string {
+	return m.index.Of(models.NormalizePhone(phone))
+}
+
+// decoder is a cursor or a single result.
+type decoder interface {
+	Decode(v interface{}) error
+}
+
+func (m *PersonRepo) decode(d decoder) (models.Person, error) {
+	var doc personDocument
+	if err := d.Decode(&doc); err != nil {
+		return models.Person{}, err
+	}
+	return m.open(doc)
+}
+
+func (m *PersonRepo) seal(person models.Person) (personDocument, error) {
+	doc := personDocument{
+		ID:         person.ID,
+		Name:       person.Name,
+		PhoneIndex: m.phoneIndex(person.Phone),
+		Email:      person.Email,
+		Fr:         person.Fr,
+		FatherID:   person.FatherID,
+		Degree:     person.Degree,
+		Language:   person.Language,
+		ErasedAt:   person.ErasedAt,
+	}
+	var err error
+	if doc.Phone, err = m.keys.Seal(person.Phone); err != nil {
+		return doc, err
+	}
+	if doc.Address, err = m.keys.Seal(person.Address); err != nil {
+		return doc, err
+	}
+	if !person.Birthday.IsZero() {
+		if doc.Birthday, err = m.keys.Seal(person.Birthday.UTC().Format(time.RFC3339Nano)); err != nil {
+			return doc, err
+		}
+	}
+	return doc, nil
+}
+
+// open returns the person with its fields opened. Plain fields of documents
+// not encrypted yet are returned as they are.
+func (m *PersonRepo) open(doc personDocument) (models.Person, error) {
+	person := models.Person{
+		ID:       doc.ID,
+		Name:     doc.Name,
+		Phone:    doc.Phone,
+		Email:    doc.Email,
+		Address:  doc.Address,
+		Fr:       doc.Fr,
+		FatherID: doc.FatherID,
+		Degree:   doc.Degree,
+		Language: doc.Language,
+		ErasedAt: doc.ErasedAt,
+	}
+	var err error
+	if vault.IsSealed(doc.Phone) {
+		if person.Phone, err = m.keys.Open(doc.Phone); err != nil {
+			return person, fmt.Errorf("opening phone of person %v: %w", doc.ID.Hex(), err)
+		}
+	}
+	if vault.IsSealed(doc.Address) {
+		if person.Address, err = m.keys.Open(doc.Address); err != nil {
+			return person, fmt.Errorf("opening address of person %v: %w", doc.ID.Hex(), err)
+		}
+	}
+	switch birthday := doc.Birthday.(type) {
+	case primitive.DateTime:
+		person.Birthday = birthday.Time().UTC()
+	case string:
+		value, err := m.keys.Open(birthday)
+		if err != nil {
+			return person, fmt.Errorf("opening birthday of person %v: %w", doc.ID.Hex(), err)
+		}
+		if value != "" {
+			if person.Birthday, err = time.Parse(time.RFC3339Nano, value); err != nil {
+				return person, err
+			}
+		}
+	}
+	return person, nil
+}
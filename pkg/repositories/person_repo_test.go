@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMergeMemberships(t *testing.T) {
+	survivor, duplicate, other := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name        string
+		memberships []models.GroupMembership
+		want        []models.GroupMembership
+	}{
+		{
+			name: "both active in the same group",
+			memberships: []models.GroupMembership{
+				{PersonID: duplicate, Role: "member", JoinedAt: day(1)},
+				{PersonID: other, Role: "member", JoinedAt: day(2)},
+				{PersonID: survivor, Role: "servant", JoinedAt: day(5)},
+			},
+			want: []models.GroupMembership{
+				{PersonID: other, Role: "member", JoinedAt: day(2)},
+				{PersonID: survivor, Role: "servant", JoinedAt: day(1)},
+			},
+		},
+		{
+			name: "only the duplicate is a member",
+			memberships: []models.GroupMembership{
+				{PersonID: duplicate, Role: "member", JoinedAt: day(1), LeftAt: day(3)},
+			},
+			want: []models.GroupMembership{
+				{PersonID: survivor, Role: "member", JoinedAt: day(1), LeftAt: day(3)},
+			},
+		},
+		{
+			name: "overlapping periods are joined",
+			memberships: []models.GroupMembership{
+				{PersonID: survivor, JoinedAt: day(1), LeftAt: day(10)},
+				{PersonID: duplicate, Role: "member", JoinedAt: day(5), LeftAt: day(20)},
+			},
+			want: []models.GroupMembership{
+				{PersonID: survivor, Role: "member", JoinedAt: day(1), LeftAt: day(20)},
+			},
+		},
+		{
+			name: "separate periods are kept",
+			memberships: []models.GroupMembership{
+				{PersonID: survivor, Role: "member", JoinedAt: day(15)},
+				{PersonID: duplicate, Role: "member", JoinedAt: day(1), LeftAt: day(10)},
+			},
+			want: []models.GroupMembership{
+				{PersonID: survivor, Role: "member", JoinedAt: day(1), LeftAt: day(10)},
+				{PersonID: survivor, Role: "member", JoinedAt: day(15)},
+			},
+		},
+		{
+			name: "other persons are untouched",
+			memberships: []models.GroupMembership{
+				{PersonID: other, Role: "member", JoinedAt: day(1)},
+			},
+			want: []models.GroupMembership{
+				{PersonID: other, Role: "member", JoinedAt: day(1)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mergeMemberships(tt.memberships, survivor, []primitive.ObjectID{duplicate}))
+		})
+	}
+}
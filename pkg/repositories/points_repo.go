@@ -0,0 +1,306 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PointsRepoInterface interface {
+	GetPointsRules(ctx context.Context) ([]models.PointsRule, error)
+	GetPointsRuleById(ctx context.Context, id string) (*models.PointsRule, error)
+	CreatePointsRule(ctx context.Context, rule models.PointsRule) (*models.PointsRule, error)
+	UpdatePointsRule(ctx context.Context, id string, rule models.PointsRule) (*models.PointsRule, error)
+	DeletePointsRule(ctx context.Context, id string) error
+
+	GetEntries(ctx context.Context, filter PointsFilter) ([]models.PointsEntry, error)
+	AddEntry(ctx context.Context, entry models.PointsEntry) (*models.PointsEntry, error)
+	RedeemPoints(ctx context.Context, entry models.PointsEntry) (*models.PointsEntry, error)
+	SettleRuleEntries(ctx context.Context, sourceID, personID primitive.ObjectID, entries []models.PointsEntry) error
+	SumPoints(ctx context.Context, filter PointsFilter) (map[primitive.ObjectID]int, error)
+}
+
+// PointsFilter narrows down ledger entries. Zero values are ignored.
+type PointsFilter struct {
+	PersonIDs []primitive.ObjectID
+	Kinds     []string
+	From      time.Time
+	To        time.Time
+	Limit     int64
+}
+
+func (f PointsFilter) query() bson.M {
+	query := bson.M{}
+	if len(f.PersonIDs) > 0 {
+		query["personId"] = bson.M{"$in": f.PersonIDs}
+	}
+	if len(f.Kinds) > 0 {
+		query["kind"] = bson.M{"$in": f.Kinds}
+	}
+	date := bson.M{}
+	if !f.From.IsZero() {
+		date["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		date["$lte"] = f.To
+	}
+	if len(date) > 0 {
+		query["date"] = date
+	}
+	return query
+}
+
+type PointsRepo struct {
+	db *mongo.Client
+}
+
+func NewPointsRepo(db *mongo.Client) *PointsRepo {
+	return &PointsRepo{
+		db: db,
+	}
+}
+
+func (m *PointsRepo) GetPointsRules(ctx context.Context) ([]models.PointsRule, error) {
+	rules := []models.PointsRule{}
+	cur, err := m.db.Database("ekms").Collection("points_rules").Find(ctx, bson.D{})
+	if err != nil {
+		fmt.Printf("Error while getting points rules: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var rule models.PointsRule
+		err := cur.Decode(&rule)
+		if err != nil {
+			fmt.Printf("Error while decoding points rule: %v\n", err)
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func (m *PointsRepo) GetPointsRuleById(ctx context.Context, id string) (*models.PointsRule, error) {
+	var rule models.PointsRule
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("GetPointsRuleById", "PointsRepo", err)
+	}
+
+	err = m.db.Database("ekms").Collection("points_rules").FindOne(ctx, bson.M{"_id": oid}).Decode(&rule)
+	if err != nil {
+		fmt.Printf("Error while getting points rule by id: %v\n", err)
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+func (m *PointsRepo) CreatePointsRule(ctx context.Context, rule models.PointsRule) (*models.PointsRule, error) {
+	rule.ID = primitive.NewObjectID()
+	_, err := m.db.Database("ekms").Collection("points_rules").InsertOne(ctx, rule)
+	if err != nil {
+		fmt.Printf("Error while creating points rule: %v\n", err)
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+func (m *PointsRepo) UpdatePointsRule(ctx context.Context, id string, rule models.PointsRule) (*models.PointsRule, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("UpdatePointsRule", "PointsRepo", err)
+	}
+
+	rule.ID = oid
+	res, err := m.db.Database("ekms").Collection("points_rules").ReplaceOne(ctx, bson.M{"_id": oid}, rule)
+	if err != nil {
+		fmt.Printf("Error while updating points rule: %v\n", err)
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return &rule, nil
+}
+
+func (m *PointsRepo) DeletePointsRule(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return cerrors.NewInvalidIDError("DeletePointsRule", "PointsRepo", err)
+	}
+
+	_, err = m.db.Database("ekms").Collection("points_rules").DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		fmt.Printf("Error while deleting points rule: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetEntries returns the matching ledger entries, newest first.
+func (m *PointsRepo) GetEntries(ctx context.Context, filter PointsFilter) ([]models.PointsEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: -1}, {Key: "_id", Value: -1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(filter.Limit)
+	}
+	cur, err := m.db.Database("ekms").Collection("points_ledger").Find(ctx, filter.query(), opts)
+	if err != nil {
+		fmt.Printf("Error while getting points entries: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	entries := []models.PointsEntry{}
+	for cur.Next(ctx) {
+		var entry models.PointsEntry
+		err := cur.Decode(&entry)
+		if err != nil {
+			fmt.Printf("Error while decoding points entry: %v\n", err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (m *PointsRepo) AddEntry(ctx context.Context, entry models.PointsEntry) (*models.PointsEntry, error) {
+	entry.ID = primitive.NewObjectID()
+	_, err := m.db.Database("ekms").Collection("points_ledger").InsertOne(ctx, entry)
+	if err != nil {
+		fmt.Printf("Error while adding points entry: %v\n", err)
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// RedeemPoints adds the redemption entry if the person's balance covers it.
+// Concurrent redemptions of the same person all write the person's document
+// in points_locks, so their transactions conflict and are retried one after
+// the other instead of each spending the same balance. Transactions need
+// MongoDB to run as a replica set.
+func (m *PointsRepo) RedeemPoints(ctx context.Context, entry models.PointsEntry) (*models.PointsEntry, error) {
+	session, err := m.db.StartSession()
+	if err != nil {
+		fmt.Printf("Error while starting redemption session: %v\n", err)
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	db := m.db.Database("ekms")
+	entry.ID = primitive.NewObjectID()
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		_, err := db.Collection("points_locks").UpdateOne(sc,
+			bson.M{"_id": entry.PersonID},
+			bson.M{"$inc": bson.M{"redemptions": 1}},
+			options.Update().SetUpsert(true))
+		if err != nil {
+			fmt.Printf("Error while locking points balance: %v\n", err)
+			return nil, err
+		}
+		totals, err := m.SumPoints(sc, PointsFilter{PersonIDs: []primitive.ObjectID{entry.PersonID}})
+		if err != nil {
+			return nil, err
+		}
+		if totals[entry.PersonID] < -entry.Points {
+			return nil, cerrors.NewBadRequestError("RedeemPoints", "PointsRepo", fmt.Errorf("balance of %d points is not enough", totals[entry.PersonID]))
+		}
+		_, err = db.Collection("points_ledger").InsertOne(sc, entry)
+		if err != nil {
+			fmt.Printf("Error while adding points entry: %v\n", err)
+			return nil, err
+		}
+		return nil, nil
+	})
+	if err != nil {
+		var badRequest *cerrors.BadRequestError
+		if !errors.As(err, &badRequest) {
+			fmt.Printf("Error while redeeming points: %v\n", err)
+		}
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// SettleRuleEntries makes the rule entries of the person for the source
+// match entries: entries whose key is new are added and rule entries no
+// longer earned are removed. Manual adjustments and redemptions are left
+// alone.
+func (m *PointsRepo) SettleRuleEntries(ctx context.Context, sourceID, personID primitive.ObjectID, entries []models.PointsEntry) error {
+	ledger := m.db.Database("ekms").Collection("points_ledger")
+	keys := []string{}
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+		entry.ID = primitive.NewObjectID()
+		_, err := ledger.UpdateOne(ctx,
+			bson.M{"key": entry.Key},
+			bson.M{"$setOnInsert": entry},
+			options.Update().SetUpsert(true))
+		if err != nil {
+			fmt.Printf("Error while adding points entry: %v\n", err)
+			return err
+		}
+	}
+
+	_, err := ledger.DeleteMany(ctx, bson.M{
+		"kind":     models.PointsKindRule,
+		"sourceId": sourceID,
+		"personId": personID,
+		"key":      bson.M{"$nin": keys},
+	})
+	if err != nil {
+		fmt.Printf("Error while removing points entries: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// SumPoints totals the points of the matching entries per person.
+func (m *PointsRepo) SumPoints(ctx context.Context, filter PointsFilter) (map[primitive.ObjectID]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter.query()}},
+		{{Key: "$group", Value: bson.M{"_id": "$personId", "points": bson.M{"$sum": "$points"}}}},
+	}
+	cur, err := m.db.Database("ekms").Collection("points_ledger").Aggregate(ctx, pipeline)
+	if err != nil {
+		fmt.Printf("Error while summing points: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	totals := map[primitive.ObjectID]int{}
+	for cur.Next(ctx) {
+		var row struct {
+			PersonID primitive.ObjectID `bson:"_id"`
+			Points   int                `bson:"points"`
+		}
+		err := cur.Decode(&row)
+		if err != nil {
+			fmt.Printf("Error while decoding points total: %v\n", err)
+			return nil, err
+		}
+		totals[row.PersonID] = row.Points
+	}
+
+	return totals, nil
+}
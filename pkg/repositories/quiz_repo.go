@@ -0,0 +1,202 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type QuizRepoInterface interface {
+	GetQuizzes(ctx context.Context, filter QuizFilter) ([]models.Quiz, error)
+	GetQuizById(ctx context.Context, id string) (*models.Quiz, error)
+	CreateQuiz(ctx context.Context, quiz models.Quiz) (*models.Quiz, error)
+	UpdateQuiz(ctx context.Context, id string, quiz models.Quiz) (*models.Quiz, error)
+	DeleteQuiz(ctx context.Context, id string) error
+
+	SetQuizSubmission(ctx context.Context, id primitive.ObjectID, submission models.QuizSubmission) (*models.Quiz, error)
+	ReplaceQuizSubmission(ctx context.Context, id primitive.ObjectID, previous *models.QuizSubmission, submission models.QuizSubmission) error
+}
+
+// QuizFilter narrows down the quizzes returned by GetQuizzes. Zero values are
+// ignored. PersonID selects the quizzes the person submitted.
+type QuizFilter struct {
+	ServiceID    primitive.ObjectID
+	AssignmentID primitive.ObjectID
+	PersonID     primitive.ObjectID
+}
+
+func (f QuizFilter) query() bson.M {
+	query := bson.M{}
+	if !f.ServiceID.IsZero() {
+		query["serviceId"] = f.ServiceID
+	}
+	if !f.AssignmentID.IsZero() {
+		query["assignmentId"] = f.AssignmentID
+	}
+	if !f.PersonID.IsZero() {
+		query["submissions.personId"] = f.PersonID
+	}
+	return query
+}
+
+type QuizRepo struct {
+	db *mongo.Client
+}
+
+func NewQuizRepo(db *mongo.Client) *QuizRepo {
+	return &QuizRepo{
+		db: db,
+	}
+}
+
+func (m *QuizRepo) GetQuizzes(ctx context.Context, filter QuizFilter) ([]models.Quiz, error) {
+	quizzes := []models.Quiz{}
+	cur, err := m.db.Database("ekms").Collection("quizzes").Find(ctx, filter.query())
+	if err != nil {
+		fmt.Printf("Error while getting quizzes: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var quiz models.Quiz
+		err := cur.Decode(&quiz)
+		if err != nil {
+			fmt.Printf("Error while decoding quiz: %v\n", err)
+			return nil, err
+		}
+		quizzes = append(quizzes, quiz)
+	}
+
+	return quizzes, nil
+}
+
+func (m *QuizRepo) GetQuizById(ctx context.Context, id string) (*models.Quiz, error) {
+	var quiz models.Quiz
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("GetQuizById", "QuizRepo", err)
+	}
+
+	err = m.db.Database("ekms").Collection("quizzes").FindOne(ctx, bson.M{"_id": oid}).Decode(&quiz)
+	if err != nil {
+		fmt.Printf("Error while getting quiz by id: %v\n", err)
+		return nil, err
+	}
+
+	return &quiz, nil
+}
+
+func (m *QuizRepo) CreateQuiz(ctx context.Context, quiz models.Quiz) (*models.Quiz, error) {
+	quiz.ID = primitive.NewObjectID()
+	_, err := m.db.Database("ekms").Collection("quizzes").InsertOne(ctx, quiz)
+	if err != nil {
+		fmt.Printf("Error while creating quiz: %v\n", err)
+		return nil, err
+	}
+
+	return &quiz, nil
+}
+
+// UpdateQuiz replaces the quiz's own fields and questions. Submissions are
+// only changed through SetQuizSubmission.
+func (m *QuizRepo) UpdateQuiz(ctx context.Context, id string, quiz models.Quiz) (*models.Quiz, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("UpdateQuiz", "QuizRepo", err)
+	}
+
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "title", Value: quiz.Title},
+			{Key: "serviceId", Value: quiz.ServiceID},
+			{Key: "assignmentId", Value: quiz.AssignmentID},
+			{Key: "closesAt", Value: quiz.ClosesAt},
+			{Key: "maxAttempts", Value: quiz.MaxAttempts},
+			{Key: "questions", Value: quiz.Questions},
+		}},
+	}
+	res, err := m.db.Database("ekms").Collection("quizzes").UpdateOne(ctx, bson.M{"_id": oid}, update)
+	if err != nil {
+		fmt.Printf("Error while updating quiz: %v\n", err)
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return m.GetQuizById(ctx, id)
+}
+
+func (m *QuizRepo) DeleteQuiz(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return cerrors.NewInvalidIDError("DeleteQuiz", "QuizRepo", err)
+	}
+
+	_, err = m.db.Database("ekms").Collection("quizzes").DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		fmt.Printf("Error while deleting quiz: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// SetQuizSubmission replaces the person's submission to the quiz, or adds it
+// when the person has not submitted yet.
+func (m *QuizRepo) SetQuizSubmission(ctx context.Context, id primitive.ObjectID, submission models.QuizSubmission) (*models.Quiz, error) {
+	quizzes := m.db.Database("ekms").Collection("quizzes")
+	res, err := quizzes.UpdateOne(ctx,
+		bson.M{"_id": id, "submissions.personId": submission.PersonID},
+		bson.M{"$set": bson.M{"submissions.$": submission}})
+	if err != nil {
+		fmt.Printf("Error while replacing quiz submission: %v\n", err)
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		_, err = quizzes.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$push": bson.M{"submissions": submission}})
+		if err != nil {
+			fmt.Printf("Error while adding quiz submission: %v\n", err)
+			return nil, err
+		}
+	}
+
+	return m.GetQuizById(ctx, id.Hex())
+}
+
+// ReplaceQuizSubmission replaces previous, the person's submission as last
+// read, with submission, or adds it when previous is nil. It returns
+// mongo.ErrNoDocuments when the person submitted again in the meantime, so
+// concurrent submissions cannot get around the attempt limit.
+func (m *QuizRepo) ReplaceQuizSubmission(ctx context.Context, id primitive.ObjectID, previous *models.QuizSubmission, submission models.QuizSubmission) error {
+	quizzes := m.db.Database("ekms").Collection("quizzes")
+	var res *mongo.UpdateResult
+	var err error
+	if previous == nil {
+		res, err = quizzes.UpdateOne(ctx,
+			bson.M{"_id": id, "submissions.personId": bson.M{"$ne": submission.PersonID}},
+			bson.M{"$push": bson.M{"submissions": submission}})
+	} else {
+		res, err = quizzes.UpdateOne(ctx,
+			bson.M{"_id": id, "submissions": bson.M{"$elemMatch": bson.M{"personId": submission.PersonID, "time": previous.Time}}},
+			bson.M{"$set": bson.M{"submissions.$": submission}})
+	}
+	if err != nil {
+		fmt.Printf("Error while replacing quiz submission: %v\n", err)
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
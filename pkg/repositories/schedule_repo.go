@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ScheduleRepoInterface interface {
+	GetAllSchedules(ctx context.Context) ([]models.ServiceSchedule, error)
+	GetScheduleById(ctx context.Context, id string) (*models.ServiceSchedule, error)
+	CreateSchedule(ctx context.Context, schedule models.ServiceSchedule) (*models.ServiceSchedule, error)
+	ReplaceSchedule(ctx context.Context, schedule models.ServiceSchedule) (*models.ServiceSchedule, error)
+	DeleteSchedule(ctx context.Context, id string) error
+}
+
+type ScheduleRepo struct {
+	db *mongo.Client
+}
+
+func NewScheduleRepo(db *mongo.Client) *ScheduleRepo {
+	return &ScheduleRepo{
+		db: db,
+	}
+}
+
+func (m *ScheduleRepo) GetAllSchedules(ctx context.Context) ([]models.ServiceSchedule, error) {
+	schedules := []models.ServiceSchedule{}
+	cur, err := m.db.Database("ekms").Collection("schedules").Find(ctx, bson.D{})
+	if err != nil {
+		fmt.Printf("Error while getting all schedules: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var schedule models.ServiceSchedule
+		err := cur.Decode(&schedule)
+		if err != nil {
+			fmt.Printf("Error while decoding schedule: %v\n", err)
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, nil
+}
+
+func (m *ScheduleRepo) GetScheduleById(ctx context.Context, id string) (*models.ServiceSchedule, error) {
+	var schedule models.ServiceSchedule
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("GetScheduleById", "ScheduleRepo", err)
+	}
+
+	err = m.db.Database("ekms").Collection("schedules").FindOne(ctx, bson.M{"_id": oid}).Decode(&schedule)
+	if err != nil {
+		fmt.Printf("Error while getting schedule by id: %v\n", err)
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+func (m *ScheduleRepo) CreateSchedule(ctx context.Context, schedule models.ServiceSchedule) (*models.ServiceSchedule, error) {
+	schedule.ID = primitive.NewObjectID()
+	_, err := m.db.Database("ekms").Collection("schedules").InsertOne(ctx, schedule)
+	if err != nil {
+		fmt.Printf("Error while creating schedule: %v\n", err)
+		return nil, err
+	}
+
+	return &schedule, nil
+}
+
+// ReplaceSchedule stores the whole schedule, including its skipped dates and
+// exceptions.
+func (m *ScheduleRepo) ReplaceSchedule(ctx context.Context, schedule models.ServiceSchedule) (*models.ServiceSchedule, error) {
+	res, err := m.db.Database("ekms").Collection("schedules").ReplaceOne(ctx, bson.M{"_id": schedule.ID}, schedule)
+	if err != nil {
+		fmt.Printf("Error while replacing schedule: %v\n", err)
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return &schedule, nil
+}
+
+func (m *ScheduleRepo) DeleteSchedule(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return cerrors.NewInvalidIDError("DeleteSchedule", "ScheduleRepo", err)
+	}
+
+	_, err = m.db.Database("ekms").Collection("schedules").DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		fmt.Printf("Error while deleting schedule: %v\n", err)
+		return err
+	}
+
+	return nil
+}
@@ -3,11 +3,14 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/Mario-Kamel/EKMS/pkg/bible"
 	"github.com/Mario-Kamel/EKMS/pkg/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ServiceRepoInterface interface {
@@ -20,6 +23,71 @@ type ServiceRepoInterface interface {
 	AddAttendanceRecord(ctx context.Context, serviceID primitive.ObjectID, ar models.AttendanceRecord) (*models.Service, error)
 	EditAttendanceRecord(ctx context.Context, serviceID primitive.ObjectID, ar models.AttendanceRecord) (*models.Service, error)
 	DeleteAttendanceRecord(ctx context.Context, serviceID primitive.ObjectID, ar models.AttendanceRecord) (*models.Service, error)
+
+	StreamServices(ctx context.Context, filter ServiceFilter, fn func(models.Service) error) error
+	GetServicesByAttendee(ctx context.Context, personID primitive.ObjectID) ([]models.Service, error)
+
+	GetOccurrence(ctx context.Context, scheduleID primitive.ObjectID, occurrence time.Time) (*models.Service, error)
+	GetOccurrencesFrom(ctx context.Context, scheduleID primitive.ObjectID, from time.Time) ([]models.Service, error)
+	CreateOccurrence(ctx context.Context, service models.Service) (bool, error)
+	UpdateOccurrence(ctx context.Context, service models.Service) error
+	DeleteOccurrences(ctx context.Context, scheduleID primitive.ObjectID, from time.Time) (int64, error)
+
+	SetSpeaker(ctx context.Context, id, speakerID primitive.ObjectID, name string) error
+	SetBibleRefs(ctx context.Context, id primitive.ObjectID, refs []bible.Reference) error
+	SetLesson(ctx context.Context, service models.Service) error
+	ClearLesson(ctx context.Context, id primitive.ObjectID) error
+}
+
+// ServiceFilter narrows down the services returned by StreamServices. Zero
+// values are ignored. References matches services reading any chapter of
+// the references.
+type ServiceFilter struct {
+	From       time.Time
+	To         time.Time
+	Speaker    string
+	SpeakerID  primitive.ObjectID
+	GroupID    primitive.ObjectID
+	ScheduleID primitive.ObjectID
+	References []bible.Reference
+}
+
+func (f ServiceFilter) query() bson.M {
+	query := bson.M{}
+	date := bson.M{}
+	if !f.From.IsZero() {
+		date["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		date["$lte"] = f.To
+	}
+	if len(date) > 0 {
+		query["date"] = date
+	}
+	if f.Speaker != "" {
+		query["speaker"] = f.Speaker
+	}
+	if !f.SpeakerID.IsZero() {
+		query["speakerId"] = f.SpeakerID
+	}
+	if !f.GroupID.IsZero() {
+		query["groupId"] = f.GroupID
+	}
+	if !f.ScheduleID.IsZero() {
+		query["scheduleId"] = f.ScheduleID
+	}
+	if len(f.References) > 0 {
+		refs := bson.A{}
+		for _, r := range f.References {
+			refs = append(refs, bson.M{"bibleRefs": bson.M{"$elemMatch": bson.M{
+				"book":         r.Book,
+				"startChapter": bson.M{"$lte": r.EndChapter},
+				"endChapter":   bson.M{"$gte": r.StartChapter},
+			}}})
+		}
+		query["$or"] = refs
+	}
+	return query
 }
 
 type ServiceRepo struct {
@@ -93,8 +161,12 @@ func (m *ServiceRepo) UpdateService(ctx context.Context, id string, service mode
 			{Key: "date", Value: service.Date},
 			{Key: "subject", Value: service.Subject},
 			{Key: "speaker", Value: service.Speaker},
+			{Key: "speakerId", Value: service.SpeakerID},
 			{Key: "bibleChapter", Value: service.BibleChapter},
+			{Key: "bibleRefs", Value: service.BibleRefs},
+			{Key: "location", Value: service.Location},
 			{Key: "assignmentId", Value: service.AssignmentID},
+			{Key: "groupId", Value: service.GroupID},
 		}},
 	}
 
@@ -173,3 +245,223 @@ func (m *ServiceRepo) DeleteAttendanceRecord(ctx context.Context, serviceID prim
 
 	return service, nil
 }
+
+// StreamServices calls fn for every service matching filter, ordered by date,
+// while iterating the cursor.
+func (m *ServiceRepo) StreamServices(ctx context.Context, filter ServiceFilter, fn func(models.Service) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}})
+	cur, err := m.db.Database("ekms").Collection("services").Find(ctx, filter.query(), opts)
+	if err != nil {
+		fmt.Printf("Error while streaming services: %v\n", err)
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var service models.Service
+		err := cur.Decode(&service)
+		if err != nil {
+			fmt.Printf("Error while decoding service: %v\n", err)
+			return err
+		}
+		if err := fn(service); err != nil {
+			return err
+		}
+	}
+
+	return cur.Err()
+}
+
+func (m *ServiceRepo) GetServicesByAttendee(ctx context.Context, personID primitive.ObjectID) ([]models.Service, error) {
+	services := []models.Service{}
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}})
+	cur, err := m.db.Database("ekms").Collection("services").Find(ctx, bson.M{"attendanceRecord.personId": personID}, opts)
+	if err != nil {
+		fmt.Printf("Error while getting services by attendee: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var service models.Service
+		err := cur.Decode(&service)
+		if err != nil {
+			fmt.Printf("Error while decoding service: %v\n", err)
+			return nil, err
+		}
+		services = append(services, service)
+	}
+
+	return services, nil
+}
+
+// GetOccurrence returns the service generated for the occurrence of the
+// schedule.
+func (m *ServiceRepo) GetOccurrence(ctx context.Context, scheduleID primitive.ObjectID, occurrence time.Time) (*models.Service, error) {
+	var service models.Service
+	err := m.db.Database("ekms").Collection("services").FindOne(ctx, bson.M{"scheduleId": scheduleID, "occurrence": occurrence}).Decode(&service)
+	if err != nil {
+		fmt.Printf("Error while getting service occurrence: %v\n", err)
+		return nil, err
+	}
+
+	return &service, nil
+}
+
+// GetOccurrencesFrom returns the services generated by the schedule for
+// occurrences from from on.
+func (m *ServiceRepo) GetOccurrencesFrom(ctx context.Context, scheduleID primitive.ObjectID, from time.Time) ([]models.Service, error) {
+	services := []models.Service{}
+	opts := options.Find().SetSort(bson.D{{Key: "occurrence", Value: 1}})
+	cur, err := m.db.Database("ekms").Collection("services").Find(ctx, bson.M{"scheduleId": scheduleID, "occurrence": bson.M{"$gte": from}}, opts)
+	if err != nil {
+		fmt.Printf("Error while getting service occurrences: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var service models.Service
+		err := cur.Decode(&service)
+		if err != nil {
+			fmt.Printf("Error while decoding service: %v\n", err)
+			return nil, err
+		}
+		services = append(services, service)
+	}
+
+	return services, nil
+}
+
+// CreateOccurrence inserts the service generated for an occurrence unless
+// one was already generated for it. It reports whether it inserted one.
+func (m *ServiceRepo) CreateOccurrence(ctx context.Context, service models.Service) (bool, error) {
+	service.ID = primitive.NewObjectID()
+	res, err := m.db.Database("ekms").Collection("services").UpdateOne(ctx,
+		bson.M{"scheduleId": service.ScheduleID, "occurrence": service.Occurrence},
+		bson.M{"$setOnInsert": service},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		fmt.Printf("Error while creating service occurrence: %v\n", err)
+		return false, err
+	}
+
+	return res.UpsertedCount == 1, nil
+}
+
+// UpdateOccurrence stores the fields a schedule generates on the service,
+// along with the schedule and occurrence it now belongs to. Everything else,
+// like attendance, the linked speaker and lessons, is left alone.
+func (m *ServiceRepo) UpdateOccurrence(ctx context.Context, service models.Service) error {
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "date", Value: service.Date},
+			{Key: "subject", Value: service.Subject},
+			{Key: "speaker", Value: service.Speaker},
+			{Key: "location", Value: service.Location},
+			{Key: "groupId", Value: service.GroupID},
+			{Key: "scheduleId", Value: service.ScheduleID},
+			{Key: "occurrence", Value: service.Occurrence},
+		}},
+	}
+	_, err := m.db.Database("ekms").Collection("services").UpdateOne(ctx, bson.M{"_id": service.ID}, update)
+	if err != nil {
+		fmt.Printf("Error while updating service occurrence: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteOccurrences deletes the services generated by the schedule for
+// occurrences from from on. Services with attendance records are kept.
+func (m *ServiceRepo) DeleteOccurrences(ctx context.Context, scheduleID primitive.ObjectID, from time.Time) (int64, error) {
+	res, err := m.db.Database("ekms").Collection("services").DeleteMany(ctx, bson.M{
+		"scheduleId":       scheduleID,
+		"occurrence":       bson.M{"$gte": from},
+		"attendanceRecord": bson.M{"$in": bson.A{nil, bson.A{}}},
+	})
+	if err != nil {
+		fmt.Printf("Error while deleting service occurrences: %v\n", err)
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
+
+// SetSpeaker sets the speaker of the service. A zero speakerID clears the
+// link and keeps name as a free-text speaker.
+func (m *ServiceRepo) SetSpeaker(ctx context.Context, id, speakerID primitive.ObjectID, name string) error {
+	set := bson.M{"speaker": name}
+	update := bson.M{"$set": set}
+	if speakerID.IsZero() {
+		update["$unset"] = bson.M{"speakerId": ""}
+	} else {
+		set["speakerId"] = speakerID
+	}
+	res, err := m.db.Database("ekms").Collection("services").UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		fmt.Printf("Error while setting service speaker: %v\n", err)
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (m *ServiceRepo) SetBibleRefs(ctx context.Context, id primitive.ObjectID, refs []bible.Reference) error {
+	update := bson.M{"$set": bson.M{"bibleRefs": refs}}
+	if len(refs) == 0 {
+		update = bson.M{"$unset": bson.M{"bibleRefs": ""}}
+	}
+	_, err := m.db.Database("ekms").Collection("services").UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		fmt.Printf("Error while setting service bible references: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+// SetLesson stores the curriculum lesson planned for the service with its
+// subject and chapter.
+func (m *ServiceRepo) SetLesson(ctx context.Context, service models.Service) error {
+	update := bson.M{"$set": bson.M{
+		"curriculumId": service.CurriculumID,
+		"lessonId":     service.LessonID,
+		"subject":      service.Subject,
+		"bibleChapter": service.BibleChapter,
+		"bibleRefs":    service.BibleRefs,
+	}}
+	res, err := m.db.Database("ekms").Collection("services").UpdateOne(ctx, bson.M{"_id": service.ID}, update)
+	if err != nil {
+		fmt.Printf("Error while setting service lesson: %v\n", err)
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// ClearLesson removes the lesson planned for the service, with the subject
+// and chapter it brought.
+func (m *ServiceRepo) ClearLesson(ctx context.Context, id primitive.ObjectID) error {
+	update := bson.M{"$unset": bson.M{
+		"curriculumId": "",
+		"lessonId":     "",
+		"subject":      "",
+		"bibleChapter": "",
+		"bibleRefs":    "",
+	}}
+	_, err := m.db.Database("ekms").Collection("services").UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		fmt.Printf("Error while clearing service lesson: %v\n", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SpeakerRepoInterface interface {
+	GetAllSpeakers(ctx context.Context) ([]models.Speaker, error)
+	GetSpeakerById(ctx context.Context, id string) (*models.Speaker, error)
+	CreateSpeaker(ctx context.Context, speaker models.Speaker) (*models.Speaker, error)
+	ReplaceSpeaker(ctx context.Context, speaker models.Speaker) (*models.Speaker, error)
+	DeleteSpeaker(ctx context.Context, id string) error
+}
+
+type SpeakerRepo struct {
+	db *mongo.Client
+}
+
+func NewSpeakerRepo(db *mongo.Client) *SpeakerRepo {
+	return &SpeakerRepo{
+		db: db,
+	}
+}
+
+func (m *SpeakerRepo) GetAllSpeakers(ctx context.Context) ([]models.Speaker, error) {
+	speakers := []models.Speaker{}
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cur, err := m.db.Database("ekms").Collection("speakers").Find(ctx, bson.D{}, opts)
+	if err != nil {
+		fmt.Printf("Error while getting all speakers: %v\n", err)
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var speaker models.Speaker
+		err := cur.Decode(&speaker)
+		if err != nil {
+			fmt.Printf("Error while decoding speaker: %v\n", err)
+			return nil, err
+		}
+		speakers = append(speakers, speaker)
+	}
+
+	return speakers, nil
+}
+
+func (m *SpeakerRepo) GetSpeakerById(ctx context.Context, id string) (*models.Speaker, error) {
+	var speaker models.Speaker
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return nil, cerrors.NewInvalidIDError("GetSpeakerById", "SpeakerRepo", err)
+	}
+
+	err = m.db.Database("ekms").Collection("speakers").FindOne(ctx, bson.M{"_id": oid}).Decode(&speaker)
+	if err != nil {
+		fmt.Printf("Error while getting speaker by id: %v\n", err)
+		return nil, err
+	}
+
+	return &speaker, nil
+}
+
+func (m *SpeakerRepo) CreateSpeaker(ctx context.Context, speaker models.Speaker) (*models.Speaker, error) {
+	speaker.ID = primitive.NewObjectID()
+	_, err := m.db.Database("ekms").Collection("speakers").InsertOne(ctx, speaker)
+	if err != nil {
+		fmt.Printf("Error while creating speaker: %v\n", err)
+		return nil, err
+	}
+
+	return &speaker, nil
+}
+
+// ReplaceSpeaker stores the whole speaker, including its availability.
+func (m *SpeakerRepo) ReplaceSpeaker(ctx context.Context, speaker models.Speaker) (*models.Speaker, error) {
+	res, err := m.db.Database("ekms").Collection("speakers").ReplaceOne(ctx, bson.M{"_id": speaker.ID}, speaker)
+	if err != nil {
+		fmt.Printf("Error while replacing speaker: %v\n", err)
+		return nil, err
+	}
+	if res.MatchedCount == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return &speaker, nil
+}
+
+func (m *SpeakerRepo) DeleteSpeaker(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		fmt.Printf("Error while converting id to object id %v: %v\n", id, err)
+		return cerrors.NewInvalidIDError("DeleteSpeaker", "SpeakerRepo", err)
+	}
+
+	_, err = m.db.Database("ekms").Collection("speakers").DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		fmt.Printf("Error while deleting speaker: %v\n", err)
+		return err
+	}
+
+	return nil
+}
@@ -8,12 +8,18 @@ import (
 )
 
 type AssignmentService struct {
-	repo repositories.AssignmentRepoInterface
+	repo        repositories.AssignmentRepoInterface
+	personRepo  repositories.PersonRepoInterface
+	attachments *AttachmentService
+	points      *PointsService
 }
 
-func NewAssignmentService(repo repositories.AssignmentRepoInterface) *AssignmentService {
+func NewAssignmentService(repo repositories.AssignmentRepoInterface, personRepo repositories.PersonRepoInterface, attachments *AttachmentService, points *PointsService) *AssignmentService {
 	return &AssignmentService{
-		repo: repo,
+		repo:        repo,
+		personRepo:  personRepo,
+		attachments: attachments,
+		points:      points,
 	}
 }
 
@@ -49,8 +55,17 @@ func (s *AssignmentService) UpdateAssignment(ctx context.Context, id string, ass
 	return a, nil
 }
 
+// DeleteAssignment deletes the assignment together with the attachments of
+// the assignment and of its submissions.
 func (s *AssignmentService) DeleteAssignment(ctx context.Context, id string) error {
-	err := s.repo.DeleteAssignment(ctx, id)
+	a, err := s.repo.GetAssignmentById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.attachments.DeleteByAssignment(ctx, a.ID); err != nil {
+		return err
+	}
+	err = s.repo.DeleteAssignment(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Grade is the body of a grading request. State defaults to graded; a
+// returned submission may be resubmitted.
+type Grade struct {
+	Score    *float64           `json:"score"`
+	Feedback string             `json:"feedback"`
+	GraderID primitive.ObjectID `json:"graderId"`
+	State    string             `json:"state"`
+}
+
+type SubmissionGrade struct {
+	PersonID primitive.ObjectID `json:"personId"`
+	Name     string             `json:"name"`
+	State    string             `json:"state"`
+	Late     bool               `json:"late"`
+	Score    *float64           `json:"score"`
+	Percent  *float64           `json:"percent,omitempty"`
+	Feedback string             `json:"feedback"`
+	GraderID primitive.ObjectID `json:"graderId"`
+	GradedAt time.Time          `json:"gradedAt"`
+}
+
+type GradeSummary struct {
+	AssignmentID primitive.ObjectID `json:"assignmentId"`
+	Title        string             `json:"title"`
+	MaxScore     float64            `json:"maxScore"`
+	Submitted    int                `json:"submitted"`
+	Graded       int                `json:"graded"`
+	Returned     int                `json:"returned"`
+	Ungraded     int                `json:"ungraded"`
+	Average      float64            `json:"average"`
+	Median       float64            `json:"median"`
+	Min          float64            `json:"min"`
+	Max          float64            `json:"max"`
+	Grades       []SubmissionGrade  `json:"grades"`
+}
+
+// Submit records the person's submission. Submitting again replaces the
+// previous answer unless it was already graded.
+func (s *AssignmentService) Submit(ctx context.Context, assignmentID string, submission models.AssignmentSubmission) (*models.Assignment, error) {
+	a, err := s.repo.GetAssignmentById(ctx, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.personRepo.GetPersonById(ctx, submission.PersonID.Hex()); err != nil {
+		return nil, err
+	}
+	existing := findSubmission(a, submission.PersonID)
+	if existing != nil && existing.State == models.SubmissionGraded {
+		return nil, cerrors.NewBadRequestError("Submit", "AssignmentService", errors.New("submission was already graded"))
+	}
+	// Attachments are uploaded separately, keep them when resubmitting.
+	if existing != nil {
+		submission.Attachments = existing.Attachments
+	}
+
+	if submission.Time.IsZero() {
+		submission.Time = time.Now()
+	}
+	submission.State = models.SubmissionSubmitted
+	submission.Score = nil
+	submission.Feedback = ""
+	submission.GraderID = primitive.NilObjectID
+	submission.GradedAt = time.Time{}
+	updated, err := s.repo.SetSubmission(ctx, a.ID, submission)
+	if err != nil {
+		return nil, err
+	}
+	s.evaluatePoints(ctx, updated, submission.PersonID)
+	return updated, nil
+}
+
+// GradeSubmission scores the person's submission. The score must lie between
+// zero and the assignment's MaxScore when it has one.
+func (s *AssignmentService) GradeSubmission(ctx context.Context, assignmentID, personID string, grade Grade) (*models.Assignment, error) {
+	a, err := s.repo.GetAssignmentById(ctx, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	pid, err := primitive.ObjectIDFromHex(personID)
+	if err != nil {
+		return nil, cerrors.NewInvalidIDError("GradeSubmission", "AssignmentService", err)
+	}
+	submission := findSubmission(a, pid)
+	if submission == nil {
+		return nil, cerrors.NewBadRequestError("GradeSubmission", "AssignmentService", errors.New("person has not submitted this assignment"))
+	}
+
+	switch grade.State {
+	case "":
+		grade.State = models.SubmissionGraded
+	case models.SubmissionGraded, models.SubmissionReturned:
+	default:
+		return nil, cerrors.NewBadRequestError("GradeSubmission", "AssignmentService", fmt.Errorf("unknown state %q", grade.State))
+	}
+	if grade.State == models.SubmissionGraded && grade.Score == nil {
+		return nil, cerrors.NewBadRequestError("GradeSubmission", "AssignmentService", errors.New("score is required"))
+	}
+	if grade.Score != nil && (*grade.Score < 0 || (a.MaxScore > 0 && *grade.Score > a.MaxScore)) {
+		return nil, cerrors.NewBadRequestError("GradeSubmission", "AssignmentService", fmt.Errorf("score must be between 0 and %v", a.MaxScore))
+	}
+	if !grade.GraderID.IsZero() {
+		if _, err := s.personRepo.GetPersonById(ctx, grade.GraderID.Hex()); err != nil {
+			return nil, err
+		}
+	}
+
+	submission.State = grade.State
+	submission.Score = grade.Score
+	submission.Feedback = grade.Feedback
+	submission.GraderID = grade.GraderID
+	submission.GradedAt = time.Now()
+	updated, err := s.repo.SetSubmission(ctx, a.ID, *submission)
+	if err != nil {
+		return nil, err
+	}
+	s.evaluatePoints(ctx, updated, pid)
+	return updated, nil
+}
+
+// evaluatePoints updates the points the person earned by the submission. The
+// submission is already saved, so failures are only logged.
+func (s *AssignmentService) evaluatePoints(ctx context.Context, a *models.Assignment, personID primitive.ObjectID) {
+	if err := s.points.EvaluateSubmission(ctx, a, personID); err != nil {
+		fmt.Printf("Error while evaluating submission points: %v\n", err)
+	}
+}
+
+// GradeSummary lists the grades of an assignment with statistics over the
+// graded submissions.
+func (s *AssignmentService) GradeSummary(ctx context.Context, assignmentID string) (*GradeSummary, error) {
+	a, err := s.repo.GetAssignmentById(ctx, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(a.Submissions))
+	for i, sub := range a.Submissions {
+		ids[i] = sub.PersonID
+	}
+	persons, err := s.personRepo.GetPersonsByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	names := map[primitive.ObjectID]string{}
+	for _, p := range persons {
+		names[p.ID] = p.Name
+	}
+
+	summary := &GradeSummary{AssignmentID: a.ID, Title: a.Title, MaxScore: a.MaxScore, Grades: []SubmissionGrade{}}
+	scores := []float64{}
+	for _, sub := range a.Submissions {
+		g := SubmissionGrade{
+			PersonID: sub.PersonID,
+			Name:     names[sub.PersonID],
+			State:    sub.State,
+			Late:     !a.Deadline.IsZero() && sub.Time.After(a.Deadline),
+			Score:    sub.Score,
+			Feedback: sub.Feedback,
+			GraderID: sub.GraderID,
+			GradedAt: sub.GradedAt,
+		}
+		if g.State == "" {
+			g.State = models.SubmissionSubmitted
+		}
+		summary.Submitted++
+		switch g.State {
+		case models.SubmissionGraded:
+			summary.Graded++
+		case models.SubmissionReturned:
+			summary.Returned++
+		default:
+			summary.Ungraded++
+		}
+		if g.State == models.SubmissionGraded && sub.Score != nil {
+			scores = append(scores, *sub.Score)
+			if a.MaxScore > 0 {
+				percent := *sub.Score / a.MaxScore * 100
+				g.Percent = &percent
+			}
+		}
+		summary.Grades = append(summary.Grades, g)
+	}
+
+	if len(scores) > 0 {
+		sort.Float64s(scores)
+		total := 0.0
+		for _, score := range scores {
+			total += score
+		}
+		summary.Average = total / float64(len(scores))
+		summary.Min = scores[0]
+		summary.Max = scores[len(scores)-1]
+		if n := len(scores); n%2 == 1 {
+			summary.Median = scores[n/2]
+		} else {
+			summary.Median = (scores[n/2-1] + scores[n/2]) / 2
+		}
+	}
+	sort.SliceStable(summary.Grades, func(i, j int) bool {
+		return summary.Grades[i].Name < summary.Grades[j].Name
+	})
+	return summary, nil
+}
+
+func findSubmission(a *models.Assignment, personID primitive.ObjectID) *models.AssignmentSubmission {
+	for i := range a.Submissions {
+		if a.Submissions[i].PersonID == personID {
+			return &a.Submissions[i]
+		}
+	}
+	return nil
+}
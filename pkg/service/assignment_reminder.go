@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// missingNoticeWindow bounds how long after its deadline an assignment still
+// gets a missing submissions notice, so old assignments are not announced
+// when reminders are first switched on.
+const missingNoticeWindow = 7 * 24 * time.Hour
+
+const reminderDateLayout = "Mon 02 Jan 15:04"
+
+type AssignmentReminderService struct {
+	assignmentRepo repositories.AssignmentRepoInterface
+	serviceRepo    repositories.ServiceRepoInterface
+	personRepo     repositories.PersonRepoInterface
+	groupRepo      repositories.GroupRepoInterface
+	notifier       *NotificationService
+	defaultHours   []int
+	loc            *time.Location
+}
+
+// NewAssignmentReminderService creates an AssignmentReminderService. Persons
+// are reminded defaultHours before the deadline of assignments that have no
+// reminder schedule of their own. Deadlines are written in loc.
+func NewAssignmentReminderService(assignmentRepo repositories.AssignmentRepoInterface, serviceRepo repositories.ServiceRepoInterface, personRepo repositories.PersonRepoInterface, groupRepo repositories.GroupRepoInterface, notifier *NotificationService, defaultHours []int, loc *time.Location) *AssignmentReminderService {
+	return &AssignmentReminderService{
+		assignmentRepo: assignmentRepo,
+		serviceRepo:    serviceRepo,
+		personRepo:     personRepo,
+		groupRepo:      groupRepo,
+		notifier:       notifier,
+		defaultHours:   defaultHours,
+		loc:            loc,
+	}
+}
+
+// MissingSubmissions lists who has not submitted an assignment. NoGroup is
+// set when the assignment's service has no group and NoService when the
+// service was deleted: there is no roster then, so nobody is expected and
+// nobody is reminded.
+type MissingSubmissions struct {
+	AssignmentID primitive.ObjectID `json:"assignmentId"`
+	Title        string             `json:"title"`
+	Deadline     time.Time          `json:"deadline"`
+	Expected     int                `json:"expected"`
+	Missing      []models.Person    `json:"missing"`
+	NoGroup      bool               `json:"noGroup,omitempty"`
+	NoService    bool               `json:"noService,omitempty"`
+}
+
+// ReminderRun is the outcome of SendDue. Skipped lists the assignments due
+// for reminders whose service has no group or was deleted.
+type ReminderRun struct {
+	Sent    int                  `json:"sent"`
+	Skipped []primitive.ObjectID `json:"skipped"`
+}
+
+// GetMissingSubmissions lists the persons expected to submit the assignment
+// who have not.
+func (s *AssignmentReminderService) GetMissingSubmissions(ctx context.Context, assignmentID string) (*MissingSubmissions, error) {
+	a, err := s.assignmentRepo.GetAssignmentById(ctx, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	return s.missingSubmissions(ctx, *a)
+}
+
+// MissingSubmissionsReport lists the missing submissions of every assignment
+// whose deadline is between from and to, leaving out assignments everyone
+// submitted. Assignments whose service has no group or was deleted are
+// listed with NoGroup or NoService set.
+func (s *AssignmentReminderService) MissingSubmissionsReport(ctx context.Context, from, to time.Time) ([]MissingSubmissions, error) {
+	if to.IsZero() || to.After(time.Now()) {
+		to = time.Now()
+	}
+	assignments := []models.Assignment{}
+	err := s.assignmentRepo.StreamAssignments(ctx, repositories.AssignmentFilter{DeadlineFrom: from, DeadlineTo: to}, func(a models.Assignment) error {
+		if !a.Deadline.IsZero() {
+			assignments = append(assignments, a)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := []MissingSubmissions{}
+	for _, a := range assignments {
+		missing, err := s.missingSubmissions(ctx, a)
+		if err != nil {
+			return nil, err
+		}
+		if len(missing.Missing) > 0 || missing.NoGroup || missing.NoService {
+			report = append(report, *missing)
+		}
+	}
+	return report, nil
+}
+
+// SendDue sends the reminders and missing submissions notices due at now and
+// reports how many notifications it created. Reminders go to the persons who
+// have not submitted yet; when several thresholds passed since the last run
+// only one reminder is sent. After the deadline the servants of the
+// assignment's group get the list of missing submissions. Assignments whose
+// service has no group or was deleted are skipped and reported, rather than
+// reminding everyone.
+func (s *AssignmentReminderService) SendDue(ctx context.Context, now time.Time) (*ReminderRun, error) {
+	assignments := []models.Assignment{}
+	err := s.assignmentRepo.StreamAssignments(ctx, repositories.AssignmentFilter{DeadlineFrom: now.Add(-missingNoticeWindow)}, func(a models.Assignment) error {
+		if a.Deadline.IsZero() || a.ServiceID.IsZero() {
+			return nil
+		}
+		if a.Deadline.After(now) {
+			if len(s.pendingReminders(a, now)) > 0 {
+				assignments = append(assignments, a)
+			}
+		} else if a.MissingNoticeAt.IsZero() {
+			assignments = append(assignments, a)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	run := &ReminderRun{Skipped: []primitive.ObjectID{}}
+	for _, a := range assignments {
+		missing, err := s.missingSubmissions(ctx, a)
+		if err != nil {
+			return run, err
+		}
+		if missing.NoGroup || missing.NoService {
+			run.Skipped = append(run.Skipped, a.ID)
+			continue
+		}
+		var n int
+		if a.Deadline.After(now) {
+			n, err = s.sendReminders(ctx, a, missing, now)
+		} else {
+			n, err = s.sendMissingNotice(ctx, a, missing, now)
+		}
+		run.Sent += n
+		if err != nil {
+			return run, err
+		}
+	}
+	return run, nil
+}
+
+// sendReminders reminds the persons who have not submitted of the latest
+// pending threshold and then records the pending thresholds as handled. A
+// run that fails half way leaves them pending, and the next run only
+// reminds the persons that were not reminded yet.
+func (s *AssignmentReminderService) sendReminders(ctx context.Context, a models.Assignment, missing *MissingSubmissions, now time.Time) (int, error) {
+	pending := s.pendingReminders(a, now)
+	sent := 0
+	for _, p := range missing.Missing {
+		ok, err := s.notifyOnce(ctx, a, p, pending[0], "assignment_reminder", map[string]interface{}{
+			"Title":     a.Title,
+			"Deadline":  a.Deadline.In(s.loc).Format(reminderDateLayout),
+			"HoursLeft": int(a.Deadline.Sub(now).Hours()),
+		})
+		if err != nil {
+			return sent, err
+		}
+		if ok {
+			sent++
+		}
+	}
+	_, err := s.assignmentRepo.ClaimReminders(ctx, a.ID, pending)
+	return sent, err
+}
+
+// sendMissingNotice sends the servants the list of missing submissions and
+// then records the notice as sent, the same way as sendReminders.
+func (s *AssignmentReminderService) sendMissingNotice(ctx context.Context, a models.Assignment, missing *MissingSubmissions, now time.Time) (int, error) {
+	sent := 0
+	if len(missing.Missing) > 0 {
+		servants, err := s.assignmentServants(ctx, a)
+		if err != nil {
+			return 0, err
+		}
+		for _, servant := range servants {
+			ok, err := s.notifyOnce(ctx, a, servant, 0, "missing_submissions", map[string]interface{}{
+				"Title":    a.Title,
+				"Deadline": a.Deadline.In(s.loc).Format(reminderDateLayout),
+				"Expected": missing.Expected,
+				"Missing":  missing.Missing,
+			})
+			if err != nil {
+				return sent, err
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+	_, err := s.assignmentRepo.ClaimMissingNotice(ctx, a.ID, now)
+	return sent, err
+}
+
+// notifyOnce claims the reminder for the threshold hours for the person and
+// sends it. It returns false when the person was already sent it. The claim
+// is released when the notification could not be created, so the next run
+// tries again.
+func (s *AssignmentReminderService) notifyOnce(ctx context.Context, a models.Assignment, p models.Person, hours int, template string, data map[string]interface{}) (bool, error) {
+	claimed, err := s.assignmentRepo.ClaimPersonReminder(ctx, a.ID, p.ID, hours)
+	if err != nil || !claimed {
+		return false, err
+	}
+	if _, err := s.notifier.NotifyPerson(ctx, p, template, data); err != nil {
+		if releaseErr := s.assignmentRepo.ReleasePersonReminder(ctx, a.ID, p.ID, hours); releaseErr != nil {
+			return false, releaseErr
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// pendingReminders returns the reminder thresholds of a that passed by now
+// and were not handled yet, latest first.
+func (s *AssignmentReminderService) pendingReminders(a models.Assignment, now time.Time) []int {
+	hours := a.ReminderHours
+	if len(hours) == 0 {
+		hours = s.defaultHours
+	}
+	sent := map[int]bool{}
+	for _, h := range a.RemindersSent {
+		sent[h] = true
+	}
+	pending := []int{}
+	for _, h := range hours {
+		if h > 0 && !sent[h] && !now.Before(a.Deadline.Add(-time.Duration(h)*time.Hour)) {
+			pending = append(pending, h)
+		}
+	}
+	sort.Ints(pending)
+	return pending
+}
+
+// missingSubmissions compares the roster of the assignment's service with its
+// submissions. Assignments without a service, or whose service has no group
+// or was deleted, have no roster: unlike the sign-in sheets, reminders never
+// fall back to everyone.
+func (s *AssignmentReminderService) missingSubmissions(ctx context.Context, a models.Assignment) (*MissingSubmissions, error) {
+	result := &MissingSubmissions{AssignmentID: a.ID, Title: a.Title, Deadline: a.Deadline, Missing: []models.Person{}}
+	if a.ServiceID.IsZero() {
+		return result, nil
+	}
+	serv, err := s.serviceRepo.GetServiceById(ctx, a.ServiceID.Hex())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		result.NoService = true
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if serv.GroupID.IsZero() {
+		result.NoGroup = true
+		return result, nil
+	}
+	roster, err := serviceRoster(ctx, s.personRepo, s.groupRepo, serv)
+	if err != nil {
+		return nil, err
+	}
+
+	submitted := map[primitive.ObjectID]bool{}
+	for _, sub := range a.Submissions {
+		submitted[sub.PersonID] = true
+	}
+	result.Expected = len(roster)
+	for _, p := range roster {
+		if !submitted[p.ID] {
+			result.Missing = append(result.Missing, p)
+		}
+	}
+	sortPersonsByName(result.Missing)
+	return result, nil
+}
+
+// assignmentServants returns the servants of the group of the assignment's
+// service at its deadline.
+func (s *AssignmentReminderService) assignmentServants(ctx context.Context, a models.Assignment) ([]models.Person, error) {
+	serv, err := s.serviceRepo.GetServiceById(ctx, a.ServiceID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if serv.GroupID.IsZero() {
+		return []models.Person{}, nil
+	}
+	group, err := s.groupRepo.GetGroupById(ctx, serv.GroupID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return s.personRepo.GetPersonsByIds(ctx, groupRosterIDs(group, a.Deadline, models.GroupRoleServant))
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeAssignmentRepo holds assignments in memory and claims reminders with the
+// same conditions as the MongoDB updates. Other methods are not used by the
+// reminders and panic.
+type fakeAssignmentRepo struct {
+	repositories.AssignmentRepoInterface
+	assignments []*models.Assignment
+}
+
+func (r *fakeAssignmentRepo) StreamAssignments(ctx context.Context, filter repositories.AssignmentFilter, fn func(models.Assignment) error) error {
+	for _, a := range r.assignments {
+		if !filter.DeadlineFrom.IsZero() && a.Deadline.Before(filter.DeadlineFrom) {
+			continue
+		}
+		if err := fn(*a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fakeAssignmentRepo) get(id primitive.ObjectID) *models.Assignment {
+	for _, a := range r.assignments {
+		if a.ID == id {
+			return a
+		}
+	}
+	panic("unknown assignment " + id.Hex())
+}
+
+func (r *fakeAssignmentRepo) ClaimReminders(ctx context.Context, id primitive.ObjectID, hours []int) (bool, error) {
+	a := r.get(id)
+	for _, h := range a.RemindersSent {
+		if h == hours[0] {
+			return false, nil
+		}
+	}
+	for _, h := range hours {
+		if !containsInt(a.RemindersSent, h) {
+			a.RemindersSent = append(a.RemindersSent, h)
+		}
+	}
+	return true, nil
+}
+
+func (r *fakeAssignmentRepo) ClaimMissingNotice(ctx context.Context, id primitive.ObjectID, at time.Time) (bool, error) {
+	a := r.get(id)
+	if !a.MissingNoticeAt.IsZero() {
+		return false, nil
+	}
+	a.MissingNoticeAt = at
+	return true, nil
+}
+
+func (r *fakeAssignmentRepo) ClaimPersonReminder(ctx context.Context, id, personID primitive.ObjectID, hours int) (bool, error) {
+	a := r.get(id)
+	reminder := models.AssignmentReminder{PersonID: personID, Hours: hours}
+	for _, rem := range a.Reminded {
+		if rem == reminder {
+			return false, nil
+		}
+	}
+	a.Reminded = append(a.Reminded, reminder)
+	return true, nil
+}
+
+func (r *fakeAssignmentRepo) ReleasePersonReminder(ctx context.Context, id, personID primitive.ObjectID, hours int) error {
+	a := r.get(id)
+	kept := []models.AssignmentReminder{}
+	for _, rem := range a.Reminded {
+		if rem != (models.AssignmentReminder{PersonID: personID, Hours: hours}) {
+			kept = append(kept, rem)
+		}
+	}
+	a.Reminded = kept
+	return nil
+}
+
+// fakeServiceRepo looks services up in memory. Other methods are not used and
+// panic.
+type fakeServiceRepo struct {
+	repositories.ServiceRepoInterface
+	services []models.Service
+}
+
+func (r *fakeServiceRepo) GetServiceById(ctx context.Context, id string) (*models.Service, error) {
+	for _, s := range r.services {
+		if s.ID.Hex() == id {
+			found := s
+			return &found, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+// fakeGroupRepo looks groups up in memory and adds memberships and claims
+// birthday reminders with the same conditions as the MongoDB updates. Other methods are not used and
+// panic.
+type fakeGroupRepo struct {
+	repositories.GroupRepoInterface
+	groups []models.Group
+}
+
+func (r *fakeGroupRepo) GetAllGroups(ctx context.Context) ([]models.Group, error) {
+	return append([]models.Group{}, r.groups...), nil
+}
+
+func (r *fakeGroupRepo) AddMembership(ctx context.Context, groupID primitive.ObjectID, membership models.GroupMembership) (*models.Group, error) {
+	for i := range r.groups {
+		if r.groups[i].ID != groupID {
+			continue
+		}
+		for _, m := range r.groups[i].Memberships {
+			if m.PersonID == membership.PersonID && m.LeftAt.IsZero() {
+				return nil, mongo.ErrNoDocuments
+			}
+		}
+		r.groups[i].Memberships = append(r.groups[i].Memberships, membership)
+		found := r.groups[i]
+		return &found, nil
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeGroupRepo) ClaimBirthdayReminder(ctx context.Context, groupID, personID primitive.ObjectID, day time.Time) (bool, error) {
+	for i := range r.groups {
+		if r.groups[i].ID != groupID {
+			continue
+		}
+		reminder := models.BirthdayReminder{PersonID: personID, Day: day}
+		for _, rem := range r.groups[i].BirthdayReminders {
+			if rem.PersonID == personID && rem.Day.Equal(day) {
+				return false, nil
+			}
+		}
+		r.groups[i].BirthdayReminders = append(r.groups[i].BirthdayReminders, reminder)
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *fakeGroupRepo) ReleaseBirthdayReminder(ctx context.Context, groupID, personID primitive.ObjectID, day time.Time) error {
+	for i := range r.groups {
+		if r.groups[i].ID != groupID {
+			continue
+		}
+		kept := []models.BirthdayReminder{}
+		for _, rem := range r.groups[i].BirthdayReminders {
+			if rem.PersonID != personID || !rem.Day.Equal(day) {
+				kept = append(kept, rem)
+			}
+		}
+		r.groups[i].BirthdayReminders = kept
+	}
+	return nil
+}
+
+func (r *fakeGroupRepo) GetGroupById(ctx context.Context, id string) (*models.Group, error) {
+	for _, g := range r.groups {
+		if g.ID.Hex() == id {
+			found := g
+			return &found, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+// reminderFixture is a group with a servant and two members, a service of the
+// group and an assignment of the service due in a day.
+type reminderFixture struct {
+	servant, mina, youssef models.Person
+	assignment             *models.Assignment
+	assignments            *fakeAssignmentRepo
+	notifications          *fakeNotificationRepo
+	notifier               *fakeNotifier
+	service                *AssignmentReminderService
+}
+
+func newReminderFixture(now time.Time, loc *time.Location) *reminderFixture {
+	f := &reminderFixture{
+		servant: models.Person{ID: primitive.NewObjectID(), Name: "Abanoub", Email: "abanoub@example.com"},
+		mina:    models.Person{ID: primitive.NewObjectID(), Name: "Mina", Email: "mina@example.com"},
+		youssef: models.Person{ID: primitive.NewObjectID(), Name: "Youssef", Email: "youssef@example.com"},
+	}
+	group := models.Group{ID: primitive.NewObjectID(), Memberships: []models.GroupMembership{
+		{PersonID: f.servant.ID, Role: models.GroupRoleServant},
+		{PersonID: f.mina.ID, Role: models.GroupRoleMember},
+		{PersonID: f.youssef.ID, Role: models.GroupRoleMember},
+	}}
+	serv := models.Service{ID: primitive.NewObjectID(), GroupID: group.ID, Date: now}
+	f.assignment = &models.Assignment{ID: primitive.NewObjectID(), ServiceID: serv.ID, Title: "Homework", Deadline: now.Add(20 * time.Hour), ReminderHours: []int{24}}
+	f.assignments = &fakeAssignmentRepo{assignments: []*models.Assignment{f.assignment}}
+
+	persons := &fakePersonRepo{persons: []models.Person{f.servant, f.mina, f.youssef}}
+	var notifications *NotificationService
+	notifications, f.notifications, f.notifier = newTestNotificationService(persons, &fakeHouseholdRepo{})
+	f.service = NewAssignmentReminderService(f.assignments, &fakeServiceRepo{services: []models.Service{serv}}, persons,
+		&fakeGroupRepo{groups: []models.Group{group}}, notifications, []int{24}, loc)
+	return f
+}
+
+func (f *reminderFixture) recipients() []string {
+	to := []string{}
+	for _, msg := range f.notifier.sent {
+		to = append(to, msg.To)
+	}
+	return to
+}
+
+func TestSendDueRemindsEachPersonOnce(t *testing.T) {
+	now := time.Now()
+	f := newReminderFixture(now, time.UTC)
+
+	run, err := f.service.SendDue(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, run.Sent)
+	assert.ElementsMatch(t, []string{"mina@example.com", "youssef@example.com"}, f.recipients())
+	assert.Equal(t, []int{24}, f.assignment.RemindersSent)
+
+	run, err = f.service.SendDue(context.Background(), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, run.Sent)
+	assert.Len(t, f.notifier.sent, 2)
+}
+
+func TestSendDueResumesAfterAFailedRun(t *testing.T) {
+	now := time.Now()
+	f := newReminderFixture(now, time.UTC)
+	// An earlier run reminded Mina and stopped before everyone was reminded.
+	f.assignment.Reminded = []models.AssignmentReminder{{PersonID: f.mina.ID, Hours: 24}}
+
+	f.notifications.createErr = errors.New("database down")
+	_, err := f.service.SendDue(context.Background(), now)
+	require.Error(t, err)
+	assert.Equal(t, []models.AssignmentReminder{{PersonID: f.mina.ID, Hours: 24}}, f.assignment.Reminded, "failed claim released")
+	assert.Empty(t, f.assignment.RemindersSent)
+
+	f.notifications.createErr = nil
+	run, err := f.service.SendDue(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, run.Sent)
+	assert.Equal(t, []string{"youssef@example.com"}, f.recipients())
+	assert.Equal(t, []int{24}, f.assignment.RemindersSent)
+}
+
+func TestSendDueSendsMissingNoticeOnce(t *testing.T) {
+	now := time.Now()
+	f := newReminderFixture(now, time.UTC)
+	f.assignment.Deadline = now.Add(-time.Hour)
+	f.assignment.Submissions = []models.AssignmentSubmission{{PersonID: f.youssef.ID, Time: now.Add(-2 * time.Hour)}}
+
+	run, err := f.service.SendDue(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, run.Sent)
+	require.Equal(t, []string{"abanoub@example.com"}, f.recipients())
+	assert.Contains(t, f.notifier.sent[0].Body, "Mina")
+	assert.NotContains(t, f.notifier.sent[0].Body, "Youssef")
+	assert.False(t, f.assignment.MissingNoticeAt.IsZero())
+
+	run, err = f.service.SendDue(context.Background(), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, run.Sent)
+}
+
+func TestSendDueSkipsDeletedServices(t *testing.T) {
+	now := time.Now()
+	f := newReminderFixture(now, time.UTC)
+	orphan := &models.Assignment{ID: primitive.NewObjectID(), ServiceID: primitive.NewObjectID(), Title: "Orphan", Deadline: now.Add(time.Hour)}
+	f.assignments.assignments = append([]*models.Assignment{orphan}, f.assignments.assignments...)
+
+	run, err := f.service.SendDue(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, []primitive.ObjectID{orphan.ID}, run.Skipped)
+	assert.Equal(t, 2, run.Sent)
+
+	missing, err := f.service.missingSubmissions(context.Background(), *orphan)
+	require.NoError(t, err)
+	assert.True(t, missing.NoService)
+}
+
+func TestSendDueWritesDeadlinesInTheScheduleLocation(t *testing.T) {
+	now := time.Date(2026, 10, 19, 16, 0, 0, 0, time.UTC)
+	f := newReminderFixture(now, time.FixedZone("EET", 3*60*60))
+
+	_, err := f.service.SendDue(context.Background(), now)
+	require.NoError(t, err)
+	require.NotEmpty(t, f.notifier.sent)
+	assert.Contains(t, f.notifier.sent[0].Subject, "Tue 20 Oct 15:00")
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"github.com/Mario-Kamel/EKMS/pkg/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DefaultAttachmentTypes are the content types accepted when no list is
+// configured.
+var DefaultAttachmentTypes = []string{
+	"image/jpeg",
+	"image/png",
+	"image/webp",
+	"image/gif",
+	"application/pdf",
+	"text/plain",
+}
+
+const DefaultAttachmentMaxSize = 10 << 20
+
+type AttachmentService struct {
+	repo           repositories.AttachmentRepoInterface
+	assignmentRepo repositories.AssignmentRepoInterface
+	store          storage.Store
+	maxSize        int64
+	allowedTypes   []string
+}
+
+func NewAttachmentService(repo repositories.AttachmentRepoInterface, assignmentRepo repositories.AssignmentRepoInterface, store storage.Store, maxSize int64, allowedTypes []string) *AttachmentService {
+	if maxSize <= 0 {
+		maxSize = DefaultAttachmentMaxSize
+	}
+	if len(allowedTypes) == 0 {
+		allowedTypes = DefaultAttachmentTypes
+	}
+	return &AttachmentService{
+		repo:           repo,
+		assignmentRepo: assignmentRepo,
+		store:          store,
+		maxSize:        maxSize,
+		allowedTypes:   allowedTypes,
+	}
+}
+
+// MaxSize is the largest file accepted by Upload, in bytes.
+func (s *AttachmentService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// UploadAssignmentAttachment stores a file attached to the assignment itself.
+func (s *AttachmentService) UploadAssignmentAttachment(ctx context.Context, assignmentID, filename string, r io.Reader) (*models.Attachment, error) {
+	a, err := s.assignmentRepo.GetAssignmentById(ctx, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	attachment, err := s.upload(ctx, models.Attachment{
+		Parent:       models.AttachmentOfAssignment,
+		AssignmentID: a.ID,
+		Filename:     filename,
+	}, r)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.assignmentRepo.AddAttachment(ctx, a.ID, attachment.ID); err != nil {
+		s.remove(ctx, *attachment)
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// UploadSubmissionAttachment stores a file attached to the person's
+// submission to the assignment. Graded submissions can no longer be changed.
+func (s *AttachmentService) UploadSubmissionAttachment(ctx context.Context, assignmentID, personID, filename string, r io.Reader) (*models.Attachment, error) {
+	a, err := s.assignmentRepo.GetAssignmentById(ctx, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	pid, err := primitive.ObjectIDFromHex(personID)
+	if err != nil {
+		return nil, cerrors.NewInvalidIDError("UploadSubmissionAttachment", "AttachmentService", err)
+	}
+	if existing := findSubmission(a, pid); existing != nil && existing.State == models.SubmissionGraded {
+		return nil, cerrors.NewBadRequestError("UploadSubmissionAttachment", "AttachmentService", errors.New("submission was already graded"))
+	}
+
+	attachment, err := s.upload(ctx, models.Attachment{
+		Parent:       models.AttachmentOfSubmission,
+		AssignmentID: a.ID,
+		PersonID:     pid,
+		Filename:     filename,
+	}, r)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.assignmentRepo.AddSubmissionAttachment(ctx, a.ID, pid, attachment.ID); err != nil {
+		s.remove(ctx, *attachment)
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// upload checks the content type sniffed from the first bytes of r against
+// the allowed types and stores at most maxSize bytes of it. Files that turn
+// out larger are deleted again.
+func (s *AttachmentService) upload(ctx context.Context, attachment models.Attachment, r io.Reader) (*models.Attachment, error) {
+	attachment.Filename = filepath.Base(strings.ReplaceAll(attachment.Filename, "\\", "/"))
+	if attachment.Filename == "." || attachment.Filename == "/" {
+		return nil, cerrors.NewBadRequestError("Upload", "AttachmentService", errors.New("filename is required"))
+	}
+
+	br := bufio.NewReaderSize(r, 512)
+	head, err := br.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	if len(head) == 0 {
+		return nil, cerrors.NewBadRequestError("Upload", "AttachmentService", errors.New("file is empty"))
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !containsString(s.allowedTypes, contentType) {
+		return nil, cerrors.NewBadRequestError("Upload", "AttachmentService", fmt.Errorf("files of type %s are not allowed", contentType))
+	}
+
+	attachment.ID = primitive.NewObjectID()
+	attachment.ContentType = contentType
+	attachment.UploadedAt = time.Now()
+	counter := &countingReader{r: io.LimitReader(br, s.maxSize+1)}
+	if err := s.store.Save(ctx, attachment.ID, attachment.Filename, counter); err != nil {
+		fmt.Printf("Error while storing attachment: %v\n", err)
+		s.store.Delete(ctx, attachment.ID)
+		return nil, err
+	}
+	if counter.n > s.maxSize {
+		s.store.Delete(ctx, attachment.ID)
+		return nil, cerrors.NewBadRequestError("Upload", "AttachmentService", fmt.Errorf("file is larger than %d bytes", s.maxSize))
+	}
+	attachment.Size = counter.n
+
+	a, err := s.repo.CreateAttachment(ctx, attachment)
+	if err != nil {
+		s.store.Delete(ctx, attachment.ID)
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *AttachmentService) GetAttachmentById(ctx context.Context, id string) (*models.Attachment, error) {
+	return s.repo.GetAttachmentById(ctx, id)
+}
+
+func (s *AttachmentService) GetAssignmentAttachments(ctx context.Context, assignmentID string) ([]models.Attachment, error) {
+	a, err := s.assignmentRepo.GetAssignmentById(ctx, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetAttachmentsByAssignment(ctx, a.ID)
+}
+
+// OpenAttachment returns the attachment and a reader of its contents, which
+// the caller must close.
+func (s *AttachmentService) OpenAttachment(ctx context.Context, id string) (*models.Attachment, io.ReadCloser, error) {
+	attachment, err := s.repo.GetAttachmentById(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, err := s.store.Open(ctx, attachment.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, mongo.ErrNoDocuments
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, rc, nil
+}
+
+// DeleteAttachment removes the attachment and unlinks it from its assignment
+// or submission.
+func (s *AttachmentService) DeleteAttachment(ctx context.Context, id string) error {
+	attachment, err := s.repo.GetAttachmentById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.assignmentRepo.RemoveAttachment(ctx, attachment.AssignmentID, attachment.ID); err != nil {
+		return err
+	}
+	return s.remove(ctx, *attachment)
+}
+
+// DeleteByAssignment removes every attachment of the assignment and of its
+// submissions. It is called when the assignment is deleted.
+func (s *AttachmentService) DeleteByAssignment(ctx context.Context, assignmentID primitive.ObjectID) error {
+	attachments, err := s.repo.GetAttachmentsByAssignment(ctx, assignmentID)
+	if err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		if err := s.remove(ctx, attachment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPersonAttachments returns the attachments of the person's submissions.
+func (s *AttachmentService) GetPersonAttachments(ctx context.Context, personID primitive.ObjectID) ([]models.Attachment, error) {
+	return s.repo.GetAttachmentsByPerson(ctx, personID)
+}
+
+// DeleteByPerson removes every attachment of the person's submissions. The
+// submissions keep their links, which the caller clears.
+func (s *AttachmentService) DeleteByPerson(ctx context.Context, personID primitive.ObjectID) error {
+	attachments, err := s.repo.GetAttachmentsByPerson(ctx, personID)
+	if err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		if err := s.remove(ctx, attachment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CleanupOrphans removes the attachments whose assignment no longer exists,
+// for example when deleting it was interrupted half way. It returns the number
+// of attachments removed.
+func (s *AttachmentService) CleanupOrphans(ctx context.Context) (int, error) {
+	orphans := []models.Attachment{}
+	exists := map[primitive.ObjectID]bool{}
+	err := s.repo.StreamAttachments(ctx, func(attachment models.Attachment) error {
+		ok, seen := exists[attachment.AssignmentID]
+		if !seen {
+			_, err := s.assignmentRepo.GetAssignmentById(ctx, attachment.AssignmentID.Hex())
+			if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				return err
+			}
+			ok = err == nil
+			exists[attachment.AssignmentID] = ok
+		}
+		if !ok {
+			orphans = append(orphans, attachment)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, attachment := range orphans {
+		if err := s.remove(ctx, attachment); err != nil {
+			return 0, err
+		}
+	}
+	return len(orphans), nil
+}
+
+// remove deletes the attachment's contents and its record. Contents that are
+// already gone are not an error.
+func (s *AttachmentService) remove(ctx context.Context, attachment models.Attachment) error {
+	if err := s.store.Delete(ctx, attachment.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		fmt.Printf("Error while deleting attachment contents: %v\n", err)
+		return err
+	}
+	return s.repo.DeleteAttachment(ctx, attachment.ID)
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
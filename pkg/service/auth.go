@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// accessTokenPrefix marks the tokens issued here, so they are easy to spot
+// when they leak into logs or repositories.
+const accessTokenPrefix = "ekms_"
+
+// Caller is who made a request, established from its access token. Admin
+// callers authenticated with the admin token and act for no person.
+type Caller struct {
+	PersonID primitive.ObjectID
+	Admin    bool
+}
+
+// IssuedAccessToken is a newly issued token along with the token itself,
+// which is not stored and cannot be shown again.
+type IssuedAccessToken struct {
+	models.AccessToken
+	Token string `json:"token"`
+}
+
+type AuthService struct {
+	repo       repositories.AccessTokenRepoInterface
+	personRepo repositories.PersonRepoInterface
+	adminHash  []byte
+}
+
+// NewAuthService creates an AuthService. adminToken authenticates admins,
+// who issue access tokens to persons; with an empty adminToken nobody is an
+// admin.
+func NewAuthService(repo repositories.AccessTokenRepoInterface, personRepo repositories.PersonRepoInterface, adminToken string) *AuthService {
+	s := &AuthService{
+		repo:       repo,
+		personRepo: personRepo,
+	}
+	if adminToken != "" {
+		sum := sha256.Sum256([]byte(adminToken))
+		s.adminHash = sum[:]
+	}
+	return s
+}
+
+// Authenticate returns the caller the token belongs to. Revoked tokens and
+// tokens of erased persons are refused.
+func (s *AuthService) Authenticate(ctx context.Context, token string, now time.Time) (*Caller, error) {
+	refused := cerrors.NewUnauthorizedError("Authenticate", "AuthService", errors.New("invalid access token"))
+	if token == "" {
+		return nil, cerrors.NewUnauthorizedError("Authenticate", "AuthService", errors.New("missing access token"))
+	}
+	sum := sha256.Sum256([]byte(token))
+	if s.adminHash != nil && subtle.ConstantTimeCompare(sum[:], s.adminHash) == 1 {
+		return &Caller{Admin: true}, nil
+	}
+	if !strings.HasPrefix(token, accessTokenPrefix) {
+		return nil, refused
+	}
+
+	stored, err := s.repo.GetActiveAccessToken(ctx, hex.EncodeToString(sum[:]))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, refused
+	}
+	if err != nil {
+		return nil, err
+	}
+	person, err := s.personRepo.GetPersonById(ctx, stored.PersonID.Hex())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, refused
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !person.ErasedAt.IsZero() {
+		return nil, refused
+	}
+	// Only used to show when a token was last used, so a failure to record
+	// it does not fail the request.
+	s.repo.TouchAccessToken(ctx, stored.ID, now)
+	return &Caller{PersonID: stored.PersonID}, nil
+}
+
+// IssueToken creates an access token for the person. Only admins issue
+// tokens.
+func (s *AuthService) IssueToken(ctx context.Context, caller Caller, personID, name string, now time.Time) (*IssuedAccessToken, error) {
+	if !caller.Admin {
+		return nil, cerrors.NewForbiddenError("IssueToken", "AuthService", errors.New("only admins issue access tokens"))
+	}
+	person, err := s.personRepo.GetPersonById(ctx, personID)
+	if err != nil {
+		return nil, err
+	}
+	if !person.ErasedAt.IsZero() {
+		return nil, cerrors.NewBadRequestError("IssueToken", "AuthService", errors.New("person was erased"))
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	token := accessTokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	sum := sha256.Sum256([]byte(token))
+	created, err := s.repo.CreateAccessToken(ctx, models.AccessToken{
+		PersonID:  person.ID,
+		Name:      strings.TrimSpace(name),
+		Hash:      hex.EncodeToString(sum[:]),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &IssuedAccessToken{AccessToken: *created, Token: token}, nil
+}
+
+// GetPersonTokens lists the person's tokens to admins and to the person.
+func (s *AuthService) GetPersonTokens(ctx context.Context, caller Caller, personID string) ([]models.AccessToken, error) {
+	oid, err := primitive.ObjectIDFromHex(personID)
+	if err != nil {
+		return nil, cerrors.NewInvalidIDError("GetPersonTokens", "AuthService", err)
+	}
+	if !caller.Admin && caller.PersonID != oid {
+		return nil, cerrors.NewForbiddenError("GetPersonTokens", "AuthService", errors.New("only admins list the tokens of others"))
+	}
+	return s.repo.GetAccessTokensByPerson(ctx, oid)
+}
+
+// RevokeToken revokes a token. Admins revoke any token, persons their own.
+func (s *AuthService) RevokeToken(ctx context.Context, caller Caller, id string, now time.Time) error {
+	token, err := s.repo.GetAccessTokenById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !caller.Admin && caller.PersonID != token.PersonID {
+		return cerrors.NewForbiddenError("RevokeToken", "AuthService", errors.New("only admins revoke the tokens of others"))
+	}
+	return s.repo.RevokeAccessToken(ctx, token.ID, now)
+}
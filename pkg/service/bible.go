@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/bible"
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChapterCoverage is how often a chapter was read and when.
+type ChapterCoverage struct {
+	Chapter  int       `json:"chapter"`
+	Services int       `json:"services"`
+	First    time.Time `json:"first"`
+	Last     time.Time `json:"last"`
+}
+
+// BookCoverage is the part of a book the services read.
+type BookCoverage struct {
+	Book      string            `json:"book"`
+	Name      string            `json:"name"`
+	NameAr    string            `json:"nameAr"`
+	Testament string            `json:"testament"`
+	Chapters  int               `json:"chapters"`
+	Covered   int               `json:"covered"`
+	Percent   float64           `json:"percent"`
+	Coverage  []ChapterCoverage `json:"coverage"`
+}
+
+type BibleService struct {
+	serviceRepo repositories.ServiceRepoInterface
+}
+
+func NewBibleService(serviceRepo repositories.ServiceRepoInterface) *BibleService {
+	return &BibleService{
+		serviceRepo: serviceRepo,
+	}
+}
+
+// Parse parses text as Bible references.
+func (s *BibleService) Parse(text string) ([]bible.Reference, error) {
+	refs, err := bible.Parse(text)
+	if err != nil {
+		return nil, cerrors.NewBadRequestError("Parse", "BibleService", err)
+	}
+	return refs, nil
+}
+
+// Coverage lists, in canonical order, the books read by the services
+// between from and to, optionally of one group, with the chapters covered
+// and when they were first and last read. A passage counts for every
+// chapter it touches.
+func (s *BibleService) Coverage(ctx context.Context, from, to time.Time, groupID string) ([]BookCoverage, error) {
+	filter := repositories.ServiceFilter{From: from, To: to}
+	if groupID != "" {
+		oid, err := primitive.ObjectIDFromHex(groupID)
+		if err != nil {
+			return nil, cerrors.NewInvalidIDError("Coverage", "BibleService", err)
+		}
+		filter.GroupID = oid
+	}
+
+	chapters := map[string]map[int]*ChapterCoverage{}
+	err := s.serviceRepo.StreamServices(ctx, filter, func(serv models.Service) error {
+		// A service reading two passages of a chapter counts once for it.
+		seen := map[string]map[int]bool{}
+		for _, r := range serv.BibleRefs {
+			if chapters[r.Book] == nil {
+				chapters[r.Book] = map[int]*ChapterCoverage{}
+			}
+			if seen[r.Book] == nil {
+				seen[r.Book] = map[int]bool{}
+			}
+			for c := r.StartChapter; c <= r.EndChapter; c++ {
+				if seen[r.Book][c] {
+					continue
+				}
+				seen[r.Book][c] = true
+				cov := chapters[r.Book][c]
+				if cov == nil {
+					cov = &ChapterCoverage{Chapter: c, First: serv.Date}
+					chapters[r.Book][c] = cov
+				}
+				cov.Services++
+				if serv.Date.Before(cov.First) {
+					cov.First = serv.Date
+				}
+				if serv.Date.After(cov.Last) {
+					cov.Last = serv.Date
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	coverage := []BookCoverage{}
+	for _, b := range bible.Books() {
+		read := chapters[b.Code]
+		if len(read) == 0 {
+			continue
+		}
+		book := BookCoverage{
+			Book:      b.Code,
+			Name:      b.Name,
+			NameAr:    b.NameAr,
+			Testament: b.Testament,
+			Chapters:  b.Chapters,
+			Covered:   len(read),
+			Percent:   float64(len(read)) / float64(b.Chapters) * 100,
+			Coverage:  []ChapterCoverage{},
+		}
+		for c := 1; c <= b.Chapters; c++ {
+			if cov := read[c]; cov != nil {
+				book.Coverage = append(book.Coverage, *cov)
+			}
+		}
+		coverage = append(coverage, book)
+	}
+	return coverage, nil
+}
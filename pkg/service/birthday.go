@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultBirthdayDays is the length of the birthdays feed when no end date is
+// given.
+const DefaultBirthdayDays = 7
+
+type BirthdayService struct {
+	personRepo repositories.PersonRepoInterface
+	groupRepo  repositories.GroupRepoInterface
+	notifier   *NotificationService
+}
+
+func NewBirthdayService(personRepo repositories.PersonRepoInterface, groupRepo repositories.GroupRepoInterface, notifier *NotificationService) *BirthdayService {
+	return &BirthdayService{
+		personRepo: personRepo,
+		groupRepo:  groupRepo,
+		notifier:   notifier,
+	}
+}
+
+type UpcomingBirthday struct {
+	Person models.Person `json:"person"`
+	Date   time.Time     `json:"date"`
+	Age    int           `json:"age"`
+}
+
+// UpcomingBirthdays lists the birthdays falling between from and to, both
+// inclusive, ordered by date. The range may span the end of a year. Persons
+// born on February 29 celebrate on February 28 in common years.
+func (s *BirthdayService) UpcomingBirthdays(ctx context.Context, from, to time.Time) ([]UpcomingBirthday, error) {
+	if from.IsZero() {
+		from = time.Now()
+	}
+	from = truncateDay(from)
+	if to.IsZero() {
+		to = from.AddDate(0, 0, DefaultBirthdayDays-1)
+	}
+	to = truncateDay(to)
+
+	birthdays := []UpcomingBirthday{}
+	err := s.personRepo.StreamPersons(ctx, repositories.PersonFilter{}, func(p models.Person) error {
+		if p.Birthday.IsZero() {
+			return nil
+		}
+		for year := from.Year(); year <= to.Year(); year++ {
+			date := birthdayIn(p.Birthday, year)
+			if date.Before(from) || date.After(to) {
+				continue
+			}
+			birthdays = append(birthdays, UpcomingBirthday{Person: p, Date: date, Age: year - p.Birthday.Year()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(birthdays, func(i, j int) bool {
+		if !birthdays[i].Date.Equal(birthdays[j].Date) {
+			return birthdays[i].Date.Before(birthdays[j].Date)
+		}
+		return birthdays[i].Person.Name < birthdays[j].Person.Name
+	})
+	return birthdays, nil
+}
+
+// SendReminders notifies the servants of every group of the birthdays of the
+// group's members on day. Servants get one message per group and are not told
+// about their own birthday. Each message is claimed on the group before it is
+// sent, so running it again on the same day only reaches the servants that
+// were not told yet. It returns the number of notifications created.
+func (s *BirthdayService) SendReminders(ctx context.Context, day time.Time) (int, error) {
+	day = truncateDay(day)
+	birthdays, err := s.UpcomingBirthdays(ctx, day, day)
+	if err != nil {
+		return 0, err
+	}
+	if len(birthdays) == 0 {
+		return 0, nil
+	}
+	byPerson := map[primitive.ObjectID]UpcomingBirthday{}
+	for _, b := range birthdays {
+		byPerson[b.Person.ID] = b
+	}
+
+	groups, err := s.groupRepo.GetAllGroups(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range groups {
+		group := &groups[i]
+		celebrating := []UpcomingBirthday{}
+		for _, id := range groupRosterIDs(group, day, "") {
+			if b, ok := byPerson[id]; ok {
+				celebrating = append(celebrating, b)
+			}
+		}
+		if len(celebrating) == 0 {
+			continue
+		}
+		servants, err := s.personRepo.GetPersonsByIds(ctx, groupRosterIDs(group, day, models.GroupRoleServant))
+		if err != nil {
+			return sent, err
+		}
+		for _, servant := range servants {
+			others := []UpcomingBirthday{}
+			for _, b := range celebrating {
+				if b.Person.ID != servant.ID {
+					others = append(others, b)
+				}
+			}
+			if len(others) == 0 {
+				continue
+			}
+			claimed, err := s.groupRepo.ClaimBirthdayReminder(ctx, group.ID, servant.ID, day)
+			if err != nil {
+				return sent, err
+			}
+			if !claimed {
+				continue
+			}
+			_, err = s.notifier.NotifyPerson(ctx, servant, "birthday_reminder", map[string]interface{}{
+				"Group":     group.Name,
+				"Birthdays": others,
+			})
+			if err != nil {
+				if releaseErr := s.groupRepo.ReleaseBirthdayReminder(ctx, group.ID, servant.ID, day); releaseErr != nil {
+					return sent, releaseErr
+				}
+				return sent, err
+			}
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// birthdayIn returns the birthday of someone born on birthday in year,
+// moving February 29 to February 28 in common years.
+func birthdayIn(birthday time.Time, year int) time.Time {
+	month, day := birthday.Month(), birthday.Day()
+	if month == time.February && day == 29 && !isLeapYear(year) {
+		day = 28
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// truncateDay returns the calendar date of t as midnight UTC, the way
+// birthdays are stored.
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
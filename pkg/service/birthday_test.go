@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBirthdayIn(t *testing.T) {
+	tests := []struct {
+		name     string
+		birthday string
+		year     int
+		want     string
+	}{
+		{"ordinary day", "2001-06-15", 2026, "2026-06-15"},
+		{"Feb 29 in a leap year", "2004-02-29", 2028, "2028-02-29"},
+		{"Feb 29 in a common year", "2004-02-29", 2026, "2026-02-28"},
+		{"Feb 29 in a century year", "1996-02-29", 2100, "2100-02-28"},
+		{"Feb 29 in a 400th year", "1996-02-29", 2000, "2000-02-29"},
+		{"Feb 28 stays", "2005-02-28", 2028, "2028-02-28"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, birthday(tt.want), birthdayIn(birthday(tt.birthday), tt.year))
+		})
+	}
+}
+
+func TestUpcomingBirthdays(t *testing.T) {
+	persons := []models.Person{
+		{ID: primitive.NewObjectID(), Name: "Before", Birthday: birthday("2000-12-27")},
+		{ID: primitive.NewObjectID(), Name: "First", Birthday: birthday("2000-12-28")},
+		{ID: primitive.NewObjectID(), Name: "New Year's Eve", Birthday: birthday("2010-12-31")},
+		{ID: primitive.NewObjectID(), Name: "New Year", Birthday: birthday("2012-01-01")},
+		{ID: primitive.NewObjectID(), Name: "Last", Birthday: birthday("1990-01-03")},
+		{ID: primitive.NewObjectID(), Name: "After", Birthday: birthday("1990-01-04")},
+		{ID: primitive.NewObjectID(), Name: "Leap", Birthday: birthday("2004-02-29")},
+		{ID: primitive.NewObjectID(), Name: "Unknown"},
+	}
+
+	type want struct {
+		name string
+		date string
+		age  int
+	}
+	tests := []struct {
+		name     string
+		from, to string
+		want     []want
+	}{
+		{"across the new year", "2025-12-28", "2026-01-03", []want{
+			{"First", "2025-12-28", 25},
+			{"New Year's Eve", "2025-12-31", 15},
+			{"New Year", "2026-01-01", 14},
+			{"Last", "2026-01-03", 36},
+		}},
+		{"Feb 29 in a common year", "2025-02-28", "2025-02-28", []want{
+			{"Leap", "2025-02-28", 21},
+		}},
+		{"Feb 29 not moved to March", "2025-03-01", "2025-03-01", []want{}},
+		{"Feb 29 in a leap year", "2028-02-28", "2028-03-01", []want{
+			{"Leap", "2028-02-29", 24},
+		}},
+		{"Feb 28 of a leap year", "2028-02-28", "2028-02-28", []want{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewBirthdayService(&fakePersonRepo{persons: persons}, &fakeGroupRepo{}, nil)
+			got, err := s.UpcomingBirthdays(context.Background(), birthday(tt.from), birthday(tt.to))
+			require.NoError(t, err)
+
+			gotWant := []want{}
+			for _, b := range got {
+				gotWant = append(gotWant, want{b.Person.Name, b.Date.Format("2006-01-02"), b.Age})
+			}
+			assert.Equal(t, tt.want, gotWant)
+		})
+	}
+}
+
+func TestSendBirthdayRemindersOnce(t *testing.T) {
+	day := birthday("2026-10-19")
+	servant := models.Person{ID: primitive.NewObjectID(), Name: "Abanoub", Email: "abanoub@example.com"}
+	other := models.Person{ID: primitive.NewObjectID(), Name: "Bishoy", Email: "bishoy@example.com"}
+	mina := models.Person{ID: primitive.NewObjectID(), Name: "Mina", Birthday: birthday("2012-10-19")}
+	group := models.Group{ID: primitive.NewObjectID(), Name: "Grade 6", Memberships: []models.GroupMembership{
+		{PersonID: servant.ID, Role: models.GroupRoleServant},
+		{PersonID: other.ID, Role: models.GroupRoleServant},
+		{PersonID: mina.ID, Role: models.GroupRoleMember},
+	}}
+	persons := &fakePersonRepo{persons: []models.Person{servant, other, mina}}
+	groups := &fakeGroupRepo{groups: []models.Group{group}}
+	notifications, repo, notifier := newTestNotificationService(persons, &fakeHouseholdRepo{})
+	s := NewBirthdayService(persons, groups, notifications)
+	// An earlier run today told Abanoub and stopped before telling Bishoy.
+	groups.groups[0].BirthdayReminders = []models.BirthdayReminder{{PersonID: servant.ID, Day: day}}
+
+	repo.createErr = errors.New("database down")
+	_, err := s.SendReminders(context.Background(), day.Add(9*time.Hour))
+	require.Error(t, err)
+	assert.Equal(t, []models.BirthdayReminder{{PersonID: servant.ID, Day: day}}, groups.groups[0].BirthdayReminders, "failed claim released")
+
+	repo.createErr = nil
+	sent, err := s.SendReminders(context.Background(), day.Add(9*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, notifier.sent, 1)
+	assert.Equal(t, "bishoy@example.com", notifier.sent[0].To)
+	assert.Contains(t, notifier.sent[0].Body, "Mina turns 14")
+
+	sent, err = s.SendReminders(context.Background(), day.Add(10*time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+
+	sent, err = s.SendReminders(context.Background(), day.AddDate(1, 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent, "a new day is sent again")
+}
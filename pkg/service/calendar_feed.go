@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/ical"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	FeedGroup  = "group"
+	FeedPerson = "person"
+)
+
+// feedPast is how far back feeds reach; older services and deadlines are
+// left out to keep feeds small.
+const feedPast = 90 * 24 * time.Hour
+
+// serviceDuration is the length given to services in feeds, which have no
+// end time of their own.
+const serviceDuration = 2 * time.Hour
+
+type CalendarFeedService struct {
+	repo           repositories.CalendarFeedRepoInterface
+	serviceRepo    repositories.ServiceRepoInterface
+	assignmentRepo repositories.AssignmentRepoInterface
+	groupRepo      repositories.GroupRepoInterface
+	personRepo     repositories.PersonRepoInterface
+}
+
+// NewCalendarFeedService creates a CalendarFeedService. Group and person
+// feeds are read with random tokens stored in repo, one per feed.
+func NewCalendarFeedService(repo repositories.CalendarFeedRepoInterface, serviceRepo repositories.ServiceRepoInterface, assignmentRepo repositories.AssignmentRepoInterface, groupRepo repositories.GroupRepoInterface, personRepo repositories.PersonRepoInterface) *CalendarFeedService {
+	return &CalendarFeedService{
+		repo:           repo,
+		serviceRepo:    serviceRepo,
+		assignmentRepo: assignmentRepo,
+		groupRepo:      groupRepo,
+		personRepo:     personRepo,
+	}
+}
+
+// IssueFeedToken creates a new token for the feed of kind, FeedGroup or
+// FeedPerson, of the group or person id, revoking the one issued before. It
+// returns the token, which is not stored and cannot be shown again. Admins
+// issue tokens for every feed, persons for their own feed and for the feeds
+// of the groups they serve.
+func (s *CalendarFeedService) IssueFeedToken(ctx context.Context, caller Caller, kind, id string, now time.Time) (string, error) {
+	targetID, err := s.authorizeFeed(ctx, "IssueFeedToken", caller, kind, id, now)
+	if err != nil {
+		return "", err
+	}
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	if _, err := s.repo.RevokeFeedTokens(ctx, kind, targetID, now); err != nil {
+		return "", err
+	}
+	_, err = s.repo.CreateFeedToken(ctx, models.CalendarFeedToken{
+		Kind:      kind,
+		TargetID:  targetID,
+		Hash:      feedTokenHash(token),
+		CreatedBy: caller.PersonID,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RevokeFeedToken revokes the token of the feed, so its link stops working.
+// The same callers as for IssueFeedToken may revoke it.
+func (s *CalendarFeedService) RevokeFeedToken(ctx context.Context, caller Caller, kind, id string, now time.Time) error {
+	targetID, err := s.authorizeFeed(ctx, "RevokeFeedToken", caller, kind, id, now)
+	if err != nil {
+		return err
+	}
+	_, err = s.repo.RevokeFeedTokens(ctx, kind, targetID, now)
+	return err
+}
+
+// authorizeFeed checks that the caller may manage the feed and returns the
+// ID of its group or person.
+func (s *CalendarFeedService) authorizeFeed(ctx context.Context, method string, caller Caller, kind, id string, now time.Time) (primitive.ObjectID, error) {
+	forbidden := cerrors.NewForbiddenError(method, "CalendarFeedService", errors.New("only admins, the person and the servants of the group manage its feed"))
+	switch kind {
+	case FeedGroup:
+		group, err := s.groupRepo.GetGroupById(ctx, id)
+		if err != nil {
+			return primitive.NilObjectID, err
+		}
+		if caller.Admin {
+			return group.ID, nil
+		}
+		for _, m := range group.Memberships {
+			if m.PersonID == caller.PersonID && m.Role == models.GroupRoleServant && m.ActiveAt(now) {
+				return group.ID, nil
+			}
+		}
+		return primitive.NilObjectID, forbidden
+	case FeedPerson:
+		person, err := s.personRepo.GetPersonById(ctx, id)
+		if err != nil {
+			return primitive.NilObjectID, err
+		}
+		if !caller.Admin && caller.PersonID != person.ID {
+			return primitive.NilObjectID, forbidden
+		}
+		return person.ID, nil
+	default:
+		return primitive.NilObjectID, cerrors.NewBadRequestError(method, "CalendarFeedService", fmt.Errorf("unknown feed %q", kind))
+	}
+}
+
+// checkToken fails with an InvalidIDError, so a wrong token looks like an
+// unknown feed, unless token is the feed's current one.
+func (s *CalendarFeedService) checkToken(ctx context.Context, method, kind, id, token string) error {
+	invalid := cerrors.NewInvalidIDError(method, "CalendarFeedService", errors.New("invalid feed token"))
+	targetID, err := primitive.ObjectIDFromHex(id)
+	if err != nil || token == "" {
+		return invalid
+	}
+	_, err = s.repo.GetActiveFeedToken(ctx, kind, targetID, feedTokenHash(token))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return invalid
+	}
+	return err
+}
+
+func feedTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Feed returns every service and assignment deadline.
+func (s *CalendarFeedService) Feed(ctx context.Context, now time.Time) (*ical.Calendar, error) {
+	cal := &ical.Calendar{Name: "EKMS", Stamp: now}
+	err := s.serviceRepo.StreamServices(ctx, repositories.ServiceFilter{From: now.Add(-feedPast)}, func(serv models.Service) error {
+		cal.Events = append(cal.Events, serviceEvent(serv))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.assignmentRepo.StreamAssignments(ctx, repositories.AssignmentFilter{DeadlineFrom: now.Add(-feedPast)}, func(a models.Assignment) error {
+		cal.Events = append(cal.Events, deadlineEvent(a))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cal, nil
+}
+
+// GroupFeed returns the services of the group and their assignment
+// deadlines.
+func (s *CalendarFeedService) GroupFeed(ctx context.Context, id, token string, now time.Time) (*ical.Calendar, error) {
+	if err := s.checkToken(ctx, "GroupFeed", FeedGroup, id, token); err != nil {
+		return nil, err
+	}
+	group, err := s.groupRepo.GetGroupById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	cal := &ical.Calendar{Name: group.Name, Stamp: now}
+	ids := []primitive.ObjectID{}
+	err = s.serviceRepo.StreamServices(ctx, repositories.ServiceFilter{From: now.Add(-feedPast), GroupID: group.ID}, func(serv models.Service) error {
+		cal.Events = append(cal.Events, serviceEvent(serv))
+		ids = append(ids, serv.ID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.streamAssignments(ctx, ids, now, func(a models.Assignment) {
+		cal.Events = append(cal.Events, deadlineEvent(a))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cal, nil
+}
+
+// PersonFeed returns the services of the groups the person belonged to at
+// the time and the services of no group, their assignment deadlines, and a
+// to-do for every assignment the person still has to submit.
+func (s *CalendarFeedService) PersonFeed(ctx context.Context, id, token string, now time.Time) (*ical.Calendar, error) {
+	if err := s.checkToken(ctx, "PersonFeed", FeedPerson, id, token); err != nil {
+		return nil, err
+	}
+	person, err := s.personRepo.GetPersonById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.groupRepo.GetGroupsByPerson(ctx, person.ID)
+	if err != nil {
+		return nil, err
+	}
+	memberships := map[primitive.ObjectID][]models.GroupMembership{}
+	for _, g := range groups {
+		for _, m := range g.Memberships {
+			if m.PersonID == person.ID {
+				memberships[g.ID] = append(memberships[g.ID], m)
+			}
+		}
+	}
+
+	cal := &ical.Calendar{Name: person.Name, Stamp: now}
+	ids := []primitive.ObjectID{}
+	// expected holds the services whose assignments the person has to
+	// submit, those they attend as a member.
+	expected := map[primitive.ObjectID]bool{}
+	err = s.serviceRepo.StreamServices(ctx, repositories.ServiceFilter{From: now.Add(-feedPast)}, func(serv models.Service) error {
+		role, ok := feedRole(serv, memberships)
+		if !ok {
+			return nil
+		}
+		cal.Events = append(cal.Events, serviceEvent(serv))
+		ids = append(ids, serv.ID)
+		expected[serv.ID] = role == models.GroupRoleMember
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.streamAssignments(ctx, ids, now, func(a models.Assignment) {
+		cal.Events = append(cal.Events, deadlineEvent(a))
+		if expected[a.ServiceID] && submissionPending(a, person.ID) {
+			cal.Todos = append(cal.Todos, ical.Todo{
+				UID:         fmt.Sprintf("assignment-%s-%s@ekms", a.ID.Hex(), person.ID.Hex()),
+				Due:         a.Deadline,
+				Summary:     a.Title,
+				Description: "Assignment to submit",
+			})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cal, nil
+}
+
+func (s *CalendarFeedService) streamAssignments(ctx context.Context, serviceIDs []primitive.ObjectID, now time.Time, fn func(models.Assignment)) error {
+	if len(serviceIDs) == 0 {
+		return nil
+	}
+	return s.assignmentRepo.StreamAssignments(ctx, repositories.AssignmentFilter{ServiceIDs: serviceIDs, DeadlineFrom: now.Add(-feedPast)}, func(a models.Assignment) error {
+		fn(a)
+		return nil
+	})
+}
+
+// feedRole returns the role the person had at the service. Services of no
+// group are for everyone, as members.
+func feedRole(serv models.Service, memberships map[primitive.ObjectID][]models.GroupMembership) (string, bool) {
+	if serv.GroupID.IsZero() {
+		return models.GroupRoleMember, true
+	}
+	for _, m := range memberships[serv.GroupID] {
+		if m.ActiveAt(serv.Date) {
+			return m.Role, true
+		}
+	}
+	return "", false
+}
+
+// submissionPending reports whether the person has not submitted the
+// assignment or had the submission returned.
+func submissionPending(a models.Assignment, personID primitive.ObjectID) bool {
+	sub := findSubmission(&a, personID)
+	return sub == nil || sub.State == models.SubmissionReturned
+}
+
+func serviceEvent(serv models.Service) ical.Event {
+	summary := serv.Subject
+	if summary == "" {
+		summary = "Service"
+	}
+	details := []string{}
+	if serv.Speaker != "" {
+		details = append(details, "Speaker: "+serv.Speaker)
+	}
+	if serv.BibleChapter != "" {
+		details = append(details, "Reading: "+serv.BibleChapter)
+	}
+	return ical.Event{
+		UID:         fmt.Sprintf("service-%s@ekms", serv.ID.Hex()),
+		Start:       serv.Date,
+		End:         serv.Date.Add(serviceDuration),
+		Summary:     summary,
+		Description: strings.Join(details, "\n"),
+		Location:    serv.Location,
+		Categories:  []string{"Service"},
+	}
+}
+
+func deadlineEvent(a models.Assignment) ical.Event {
+	return ical.Event{
+		UID:        fmt.Sprintf("assignment-%s@ekms", a.ID.Hex()),
+		Start:      a.Deadline,
+		Summary:    "Deadline: " + a.Title,
+		Categories: []string{"Assignment"},
+	}
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	LessonDelivered   = "delivered"
+	LessonScheduled   = "scheduled"
+	LessonUnscheduled = "unscheduled"
+)
+
+type LessonProgress struct {
+	LessonID  primitive.ObjectID `json:"lessonId"`
+	Number    int                `json:"number"`
+	Subject   string             `json:"subject"`
+	Status    string             `json:"status"`
+	ServiceID primitive.ObjectID `json:"serviceId,omitempty"`
+	Date      time.Time          `json:"date,omitempty"`
+}
+
+type CurriculumProgress struct {
+	CurriculumID primitive.ObjectID `json:"curriculumId"`
+	Name         string             `json:"name"`
+	Planned      int                `json:"planned"`
+	Delivered    int                `json:"delivered"`
+	Scheduled    int                `json:"scheduled"`
+	Unscheduled  int                `json:"unscheduled"`
+	Percent      float64            `json:"percent"`
+	Lessons      []LessonProgress   `json:"lessons"`
+}
+
+type CurriculumService struct {
+	repo        repositories.CurriculumRepoInterface
+	serviceRepo repositories.ServiceRepoInterface
+}
+
+func NewCurriculumService(repo repositories.CurriculumRepoInterface, serviceRepo repositories.ServiceRepoInterface) *CurriculumService {
+	return &CurriculumService{
+		repo:        repo,
+		serviceRepo: serviceRepo,
+	}
+}
+
+func (s *CurriculumService) GetAllCurricula(ctx context.Context) ([]models.Curriculum, error) {
+	return s.repo.GetAllCurricula(ctx)
+}
+
+func (s *CurriculumService) GetCurriculumById(ctx context.Context, id string) (*models.Curriculum, error) {
+	return s.repo.GetCurriculumById(ctx, id)
+}
+
+// CreateCurriculum creates the curriculum and plans its lessons on the
+// upcoming services.
+func (s *CurriculumService) CreateCurriculum(ctx context.Context, curriculum models.Curriculum) (*models.Curriculum, error) {
+	for i := range curriculum.Lessons {
+		curriculum.Lessons[i].ID = primitive.NilObjectID
+	}
+	if err := validateCurriculum("CreateCurriculum", &curriculum, nil); err != nil {
+		return nil, err
+	}
+	created, err := s.repo.CreateCurriculum(ctx, curriculum)
+	if err != nil {
+		return nil, err
+	}
+	return created, s.reschedule(ctx, created, time.Now())
+}
+
+// UpdateCurriculum replaces the curriculum. Lessons keep where they were
+// planned and delivered by ID; the lessons not delivered yet are planned
+// again in their new order.
+func (s *CurriculumService) UpdateCurriculum(ctx context.Context, id string, curriculum models.Curriculum) (*models.Curriculum, error) {
+	existing, err := s.repo.GetCurriculumById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	curriculum.ID = existing.ID
+	if err := validateCurriculum("UpdateCurriculum", &curriculum, existing); err != nil {
+		return nil, err
+	}
+
+	// Removed lessons, and all of them when the curriculum moves to other
+	// services, give their upcoming services back.
+	moved := curriculum.GroupID != existing.GroupID || curriculum.ScheduleID != existing.ScheduleID
+	for _, old := range existing.Lessons {
+		if old.ServiceID.IsZero() || !old.DeliveredAt.IsZero() {
+			continue
+		}
+		if moved || findLesson(curriculum.Lessons, old.ID) == nil {
+			if err := s.serviceRepo.ClearLesson(ctx, old.ServiceID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if moved {
+		for i := range curriculum.Lessons {
+			if curriculum.Lessons[i].DeliveredAt.IsZero() {
+				curriculum.Lessons[i].ServiceID = primitive.NilObjectID
+				curriculum.Lessons[i].Date = time.Time{}
+			}
+		}
+	}
+
+	updated, err := s.repo.ReplaceCurriculum(ctx, curriculum)
+	if err != nil {
+		return nil, err
+	}
+	return updated, s.reschedule(ctx, updated, time.Now())
+}
+
+// DeleteCurriculum deletes the curriculum and frees the services its
+// lessons were planned for. Delivered lessons stay on their services.
+func (s *CurriculumService) DeleteCurriculum(ctx context.Context, id string) error {
+	curriculum, err := s.repo.GetCurriculumById(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, l := range curriculum.Lessons {
+		if !l.ServiceID.IsZero() && l.DeliveredAt.IsZero() {
+			if err := s.serviceRepo.ClearLesson(ctx, l.ServiceID); err != nil {
+				return err
+			}
+		}
+	}
+	return s.repo.DeleteCurriculum(ctx, id)
+}
+
+// Reschedule plans the curriculum's lessons again, see reschedule.
+func (s *CurriculumService) Reschedule(ctx context.Context, id string) (*models.Curriculum, error) {
+	curriculum, err := s.repo.GetCurriculumById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.reschedule(ctx, curriculum, time.Now()); err != nil {
+		return nil, err
+	}
+	return curriculum, nil
+}
+
+// RescheduleFor plans again the curricula of the group's or schedule's
+// services, after some of them were cancelled or generated again.
+func (s *CurriculumService) RescheduleFor(ctx context.Context, groupID, scheduleID primitive.ObjectID, now time.Time) error {
+	curricula, err := s.repo.GetCurriculaFor(ctx, groupID, scheduleID)
+	if err != nil {
+		return err
+	}
+	for i := range curricula {
+		if err := s.reschedule(ctx, &curricula[i], now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RescheduleAll plans every curriculum again and returns how many there
+// are. It runs after services are generated, so lessons that did not fit
+// get the new services.
+func (s *CurriculumService) RescheduleAll(ctx context.Context, now time.Time) (int, error) {
+	curricula, err := s.repo.GetAllCurricula(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i := range curricula {
+		if err := s.reschedule(ctx, &curricula[i], now); err != nil {
+			return i, err
+		}
+	}
+	return len(curricula), nil
+}
+
+// reschedule marks the lessons whose service took place as delivered and
+// gives the remaining lessons, in order, to the curriculum's upcoming
+// services that no other curriculum uses. Lessons whose service was
+// cancelled carry over to the next free service, moving the following
+// lessons along; lessons beyond the last service stay unscheduled.
+func (s *CurriculumService) reschedule(ctx context.Context, curriculum *models.Curriculum, now time.Time) error {
+	from := now
+	if curriculum.StartDate.After(from) {
+		from = curriculum.StartDate
+	}
+	free := []models.Service{}
+	planned := map[primitive.ObjectID]bool{}
+	err := s.serviceRepo.StreamServices(ctx, repositories.ServiceFilter{
+		From:       from,
+		To:         curriculum.EndDate,
+		GroupID:    curriculum.GroupID,
+		ScheduleID: curriculum.ScheduleID,
+	}, func(serv models.Service) error {
+		if serv.CurriculumID.IsZero() || serv.CurriculumID == curriculum.ID {
+			free = append(free, serv)
+			planned[serv.ID] = serv.CurriculumID == curriculum.ID
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	next := 0
+	for i := range curriculum.Lessons {
+		l := &curriculum.Lessons[i]
+		if !l.DeliveredAt.IsZero() {
+			continue
+		}
+		if !l.ServiceID.IsZero() && !l.Date.After(now) {
+			serv, err := s.serviceRepo.GetServiceById(ctx, l.ServiceID.Hex())
+			if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				return err
+			}
+			if serv != nil && !serv.Date.After(now) {
+				l.DeliveredAt = serv.Date
+				l.Date = serv.Date
+				continue
+			}
+		}
+		if next == len(free) {
+			l.ServiceID = primitive.NilObjectID
+			l.Date = time.Time{}
+			continue
+		}
+		serv := free[next]
+		next++
+		planned[serv.ID] = false
+		if serv.LessonID != l.ID || serv.Subject != l.Subject || serv.BibleChapter != l.BibleChapter {
+			serv.CurriculumID = curriculum.ID
+			serv.LessonID = l.ID
+			serv.Subject = l.Subject
+			serv.BibleChapter = l.BibleChapter
+			setBibleRefs(&serv)
+			if err := s.serviceRepo.SetLesson(ctx, serv); err != nil {
+				return err
+			}
+		}
+		l.ServiceID = serv.ID
+		l.Date = serv.Date
+	}
+	// Services left over from an earlier plan are freed.
+	for id, stale := range planned {
+		if stale {
+			if err := s.serviceRepo.ClearLesson(ctx, id); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = s.repo.ReplaceCurriculum(ctx, *curriculum)
+	return err
+}
+
+// Progress compares the curriculum's delivered lessons with the planned
+// ones.
+func (s *CurriculumService) Progress(ctx context.Context, id string, now time.Time) (*CurriculumProgress, error) {
+	curriculum, err := s.repo.GetCurriculumById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	progress := &CurriculumProgress{
+		CurriculumID: curriculum.ID,
+		Name:         curriculum.Name,
+		Planned:      len(curriculum.Lessons),
+		Lessons:      []LessonProgress{},
+	}
+	for i, l := range curriculum.Lessons {
+		item := LessonProgress{LessonID: l.ID, Number: i + 1, Subject: l.Subject, ServiceID: l.ServiceID, Date: l.Date}
+		switch {
+		case !l.DeliveredAt.IsZero() || (!l.ServiceID.IsZero() && !l.Date.After(now)):
+			item.Status = LessonDelivered
+			progress.Delivered++
+		case !l.ServiceID.IsZero():
+			item.Status = LessonScheduled
+			progress.Scheduled++
+		default:
+			item.Status = LessonUnscheduled
+			progress.Unscheduled++
+		}
+		progress.Lessons = append(progress.Lessons, item)
+	}
+	if progress.Planned > 0 {
+		progress.Percent = float64(progress.Delivered) / float64(progress.Planned) * 100
+	}
+	return progress, nil
+}
+
+// validateCurriculum checks the curriculum and gives new lessons IDs.
+// Lessons of existing keep where they were planned and delivered.
+func validateCurriculum(method string, curriculum *models.Curriculum, existing *models.Curriculum) error {
+	bad := func(format string, args ...interface{}) error {
+		return cerrors.NewBadRequestError(method, "CurriculumService", fmt.Errorf(format, args...))
+	}
+	if curriculum.Name == "" {
+		return bad("name is required")
+	}
+	if curriculum.GroupID.IsZero() && curriculum.ScheduleID.IsZero() {
+		return bad("a curriculum needs a groupId or a scheduleId")
+	}
+	if !curriculum.EndDate.IsZero() && curriculum.EndDate.Before(curriculum.StartDate) {
+		return bad("endDate is before startDate")
+	}
+	for i := range curriculum.Lessons {
+		l := &curriculum.Lessons[i]
+		if l.Subject == "" {
+			return bad("lesson %d has no subject", i+1)
+		}
+		var old *models.Lesson
+		if existing != nil && !l.ID.IsZero() {
+			old = findLesson(existing.Lessons, l.ID)
+		}
+		if old == nil {
+			l.ID = primitive.NewObjectID()
+			l.ServiceID = primitive.NilObjectID
+			l.Date = time.Time{}
+			l.DeliveredAt = time.Time{}
+			continue
+		}
+		l.ServiceID = old.ServiceID
+		l.Date = old.Date
+		l.DeliveredAt = old.DeliveredAt
+	}
+	return nil
+}
+
+func findLesson(lessons []models.Lesson, id primitive.ObjectID) *models.Lesson {
+	for i := range lessons {
+		if lessons[i].ID == id {
+			return &lessons[i]
+		}
+	}
+	return nil
+}
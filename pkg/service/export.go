@@ -0,0 +1,306 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"github.com/Mario-Kamel/EKMS/pkg/xlsx"
+)
+
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatXLSX   = "xlsx"
+	ExportFormatNDJSON = "ndjson"
+)
+
+// PersonExportColumns, ServiceExportColumns and AssignmentExportColumns list
+// the columns available for each export, in their default order. Services are
+// flattened to one row per attendance record and assignments to one row per
+// submission.
+var (
+	PersonExportColumns     = []string{"id", "name", "birthday", "phone", "address", "fr", "fatherId", "degree", "email", "language"}
+	ServiceExportColumns    = []string{"id", "date", "subject", "speaker", "bibleChapter", "location", "assignmentId", "personId", "attendanceTime", "attendanceStatus"}
+	AssignmentExportColumns = []string{"id", "serviceId", "title", "deadline", "personId", "submissionTime", "state", "score", "maxScore", "feedback"}
+)
+
+type ExportService struct {
+	personRepo     repositories.PersonRepoInterface
+	serviceRepo    repositories.ServiceRepoInterface
+	assignmentRepo repositories.AssignmentRepoInterface
+}
+
+func NewExportService(personRepo repositories.PersonRepoInterface, serviceRepo repositories.ServiceRepoInterface, assignmentRepo repositories.AssignmentRepoInterface) *ExportService {
+	return &ExportService{
+		personRepo:     personRepo,
+		serviceRepo:    serviceRepo,
+		assignmentRepo: assignmentRepo,
+	}
+}
+
+// ExportContentType returns the MIME type of an export format.
+func ExportContentType(format string) string {
+	switch format {
+	case ExportFormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case ExportFormatNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+func (s *ExportService) ExportPersons(ctx context.Context, w io.Writer, format string, columns []string, filter repositories.PersonFilter) error {
+	columns, err := resolveColumns("ExportPersons", columns, PersonExportColumns)
+	if err != nil {
+		return err
+	}
+	out, err := newRecordWriter("ExportPersons", w, format, "Persons", columns)
+	if err != nil {
+		return err
+	}
+
+	err = s.personRepo.StreamPersons(ctx, filter, func(p models.Person) error {
+		values := map[string]string{
+			"id":       p.ID.Hex(),
+			"name":     p.Name,
+			"birthday": formatExportDate(p.Birthday),
+			"phone":    p.Phone,
+			"address":  p.Address,
+			"fr":       p.Fr,
+			"fatherId": hexOrEmpty(p.FatherID.IsZero(), p.FatherID.Hex()),
+			"degree":   p.Degree,
+			"email":    p.Email,
+			"language": p.Language,
+		}
+		return out.Write(values)
+	})
+	if err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func (s *ExportService) ExportServices(ctx context.Context, w io.Writer, format string, columns []string, filter repositories.ServiceFilter) error {
+	columns, err := resolveColumns("ExportServices", columns, ServiceExportColumns)
+	if err != nil {
+		return err
+	}
+	out, err := newRecordWriter("ExportServices", w, format, "Services", columns)
+	if err != nil {
+		return err
+	}
+
+	err = s.serviceRepo.StreamServices(ctx, filter, func(serv models.Service) error {
+		values := map[string]string{
+			"id":           serv.ID.Hex(),
+			"date":         formatExportDate(serv.Date),
+			"subject":      serv.Subject,
+			"speaker":      serv.Speaker,
+			"bibleChapter": serv.BibleChapter,
+			"location":     serv.Location,
+			"assignmentId": hexOrEmpty(serv.AssignmentID.IsZero(), serv.AssignmentID.Hex()),
+		}
+		if len(serv.AttendanceRecord) == 0 {
+			return out.Write(values)
+		}
+		for _, ar := range serv.AttendanceRecord {
+			values["personId"] = ar.PersonID.Hex()
+			values["attendanceTime"] = formatExportDate(ar.Time)
+			values["attendanceStatus"] = ar.Status
+			if err := out.Write(values); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func (s *ExportService) ExportAssignments(ctx context.Context, w io.Writer, format string, columns []string, filter repositories.AssignmentFilter) error {
+	columns, err := resolveColumns("ExportAssignments", columns, AssignmentExportColumns)
+	if err != nil {
+		return err
+	}
+	out, err := newRecordWriter("ExportAssignments", w, format, "Assignments", columns)
+	if err != nil {
+		return err
+	}
+
+	err = s.assignmentRepo.StreamAssignments(ctx, filter, func(a models.Assignment) error {
+		values := map[string]string{
+			"id":        a.ID.Hex(),
+			"serviceId": hexOrEmpty(a.ServiceID.IsZero(), a.ServiceID.Hex()),
+			"title":     a.Title,
+			"deadline":  formatExportDate(a.Deadline),
+			"maxScore":  "",
+		}
+		if a.MaxScore > 0 {
+			values["maxScore"] = strconv.FormatFloat(a.MaxScore, 'f', -1, 64)
+		}
+		if len(a.Submissions) == 0 {
+			return out.Write(values)
+		}
+		for _, sub := range a.Submissions {
+			values["personId"] = sub.PersonID.Hex()
+			values["submissionTime"] = formatExportDate(sub.Time)
+			values["state"] = sub.State
+			values["score"] = ""
+			if sub.Score != nil {
+				values["score"] = strconv.FormatFloat(*sub.Score, 'f', -1, 64)
+			}
+			values["feedback"] = sub.Feedback
+			if err := out.Write(values); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// recordWriter writes rows made of the selected columns in one of the export
+// formats.
+type recordWriter interface {
+	Write(values map[string]string) error
+	Close() error
+}
+
+func newRecordWriter(method string, w io.Writer, format, sheet string, columns []string) (recordWriter, error) {
+	switch format {
+	case "", ExportFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, err
+		}
+		return &csvRecordWriter{w: cw, columns: columns}, nil
+	case ExportFormatXLSX:
+		xw, err := xlsx.NewWriter(w, sheet)
+		if err != nil {
+			return nil, err
+		}
+		if err := xw.Write(columns); err != nil {
+			return nil, err
+		}
+		return &xlsxRecordWriter{w: xw, columns: columns}, nil
+	case ExportFormatNDJSON:
+		bw := bufio.NewWriter(w)
+		return &ndjsonRecordWriter{w: bw, enc: json.NewEncoder(bw), columns: columns}, nil
+	default:
+		return nil, cerrors.NewBadRequestError(method, "ExportService", fmt.Errorf("unknown export format %q", format))
+	}
+}
+
+type csvRecordWriter struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func (c *csvRecordWriter) Write(values map[string]string) error {
+	record := selectColumns(values, c.columns)
+	for i, v := range record {
+		record[i] = escapeFormula(v)
+	}
+	return c.w.Write(record)
+}
+
+// escapeFormula keeps spreadsheets from running a cell as a formula by
+// prefixing values that start like one with a quote.
+func escapeFormula(v string) string {
+	if v != "" && strings.ContainsRune("=+-@", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+func (c *csvRecordWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type xlsxRecordWriter struct {
+	w       *xlsx.Writer
+	columns []string
+}
+
+func (x *xlsxRecordWriter) Write(values map[string]string) error {
+	return x.w.Write(selectColumns(values, x.columns))
+}
+
+func (x *xlsxRecordWriter) Close() error {
+	return x.w.Close()
+}
+
+type ndjsonRecordWriter struct {
+	w       *bufio.Writer
+	enc     *json.Encoder
+	columns []string
+}
+
+func (n *ndjsonRecordWriter) Write(values map[string]string) error {
+	record := make(map[string]string, len(n.columns))
+	for _, col := range n.columns {
+		record[col] = values[col]
+	}
+	return n.enc.Encode(record)
+}
+
+func (n *ndjsonRecordWriter) Close() error {
+	return n.w.Flush()
+}
+
+func resolveColumns(method string, requested, available []string) ([]string, error) {
+	if len(requested) == 0 {
+		return available, nil
+	}
+	for _, col := range requested {
+		found := false
+		for _, a := range available {
+			if a == col {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, cerrors.NewBadRequestError(method, "ExportService", fmt.Errorf("unknown column %q", col))
+		}
+	}
+	return requested, nil
+}
+
+func selectColumns(values map[string]string, columns []string) []string {
+	row := make([]string, len(columns))
+	for i, col := range columns {
+		row[i] = values[col]
+	}
+	return row
+}
+
+func formatExportDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func hexOrEmpty(zero bool, hex string) string {
+	if zero {
+		return ""
+	}
+	return hex
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVEscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	out, err := newRecordWriter("Export", &buf, ExportFormatCSV, "Sheet", []string{"name", "phone"})
+	require.NoError(t, err)
+	rows := []map[string]string{
+		{"name": "Mina", "phone": "01001234567"},
+		{"name": "=HYPERLINK(\"http://x\")", "phone": "+201001234567"},
+		{"name": "-2+3", "phone": "@SUM(A1)"},
+		{"name": "", "phone": "a=b"},
+	}
+	for _, row := range rows {
+		require.NoError(t, out.Write(row))
+	}
+	require.NoError(t, out.Close())
+
+	assert.Equal(t, "name,phone\n"+
+		"Mina,01001234567\n"+
+		"\"'=HYPERLINK(\"\"http://x\"\")\",'+201001234567\n"+
+		"'-2+3,'@SUM(A1)\n"+
+		",a=b\n", buf.String())
+}
+
+func TestOtherFormatsAreNotEscaped(t *testing.T) {
+	var buf bytes.Buffer
+	out, err := newRecordWriter("Export", &buf, ExportFormatNDJSON, "Sheet", []string{"name"})
+	require.NoError(t, err)
+	require.NoError(t, out.Write(map[string]string{"name": "=1+1"}))
+	require.NoError(t, out.Close())
+	assert.Equal(t, "{\"name\":\"=1+1\"}\n", buf.String())
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultFatherMatchScore is the name similarity from which a free-text
+// confession father is matched to a Father.
+const DefaultFatherMatchScore = 0.85
+
+const (
+	FrMatchMatched   = "matched"
+	FrMatchCreated   = "created"
+	FrMatchUnmatched = "unmatched"
+)
+
+// clergyTitles are dropped before comparing names, after NormalizeName.
+var clergyTitles = map[string]bool{
+	"fr": true, "father": true, "abouna": true, "abuna": true, "hegumen": true,
+	"rev": true, "reverend": true, "priest": true,
+	"ابونا": true, "القمص": true, "القس": true, "الاب": true, "الراهب": true,
+}
+
+type FatherService struct {
+	repo             repositories.FatherRepoInterface
+	personRepo       repositories.PersonRepoInterface
+	confessionPeriod time.Duration
+}
+
+// NewFatherService creates a FatherService. confessionPeriod is how long a
+// person may go without confession before showing up in the report.
+func NewFatherService(repo repositories.FatherRepoInterface, personRepo repositories.PersonRepoInterface, confessionPeriod time.Duration) *FatherService {
+	return &FatherService{
+		repo:             repo,
+		personRepo:       personRepo,
+		confessionPeriod: confessionPeriod,
+	}
+}
+
+type FrMatch struct {
+	Fr         string             `json:"fr"`
+	Persons    int                `json:"persons"`
+	Status     string             `json:"status"`
+	FatherID   primitive.ObjectID `json:"fatherId,omitempty"`
+	FatherName string             `json:"fatherName,omitempty"`
+	Score      float64            `json:"score,omitempty"`
+	Updated    int64              `json:"updated"`
+}
+
+type ConfessionStatus struct {
+	Person         models.Person `json:"person"`
+	LastConfession time.Time     `json:"lastConfession,omitempty"`
+}
+
+type FatherReport struct {
+	Since             time.Time          `json:"since"`
+	WithoutFather     []models.Person    `json:"withoutFather"`
+	WithoutConfession []ConfessionStatus `json:"withoutConfession"`
+}
+
+func (s *FatherService) GetAllFathers(ctx context.Context) ([]models.Father, error) {
+	fathers, err := s.repo.GetAllFathers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fathers, nil
+}
+
+func (s *FatherService) GetFatherById(ctx context.Context, id string) (*models.Father, error) {
+	father, err := s.repo.GetFatherById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return father, nil
+}
+
+func (s *FatherService) CreateFather(ctx context.Context, father models.Father) (*models.Father, error) {
+	if strings.TrimSpace(father.Name) == "" {
+		return nil, cerrors.NewBadRequestError("CreateFather", "FatherService", errors.New("name is required"))
+	}
+	f, err := s.repo.CreateFather(ctx, father)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *FatherService) UpdateFather(ctx context.Context, id string, father models.Father) (*models.Father, error) {
+	f, err := s.repo.UpdateFather(ctx, id, father)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// DeleteFather refuses to delete a father persons are still assigned to.
+func (s *FatherService) DeleteFather(ctx context.Context, id string) error {
+	persons, err := s.GetFatherPersons(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(persons) > 0 {
+		return cerrors.NewBadRequestError("DeleteFather", "FatherService", fmt.Errorf("%d persons are still assigned to this father", len(persons)))
+	}
+	err = s.repo.DeleteFather(ctx, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *FatherService) GetFatherPersons(ctx context.Context, id string) ([]models.Person, error) {
+	father, err := s.repo.GetFatherById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	persons := []models.Person{}
+	err = s.personRepo.StreamPersons(ctx, repositories.PersonFilter{FatherID: father.ID}, func(p models.Person) error {
+		persons = append(persons, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return persons, nil
+}
+
+// AddConfession records a confession of a person. The father defaults to the
+// person's assigned father and the date to now.
+func (s *FatherService) AddConfession(ctx context.Context, personID string, confession models.Confession) (*models.Confession, error) {
+	person, err := s.personRepo.GetPersonById(ctx, personID)
+	if err != nil {
+		return nil, err
+	}
+	confession.PersonID = person.ID
+	if confession.FatherID.IsZero() {
+		confession.FatherID = person.FatherID
+	}
+	if !confession.FatherID.IsZero() {
+		if _, err := s.repo.GetFatherById(ctx, confession.FatherID.Hex()); err != nil {
+			return nil, err
+		}
+	}
+	if confession.Date.IsZero() {
+		confession.Date = time.Now()
+	}
+	c, err := s.repo.AddConfession(ctx, confession)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *FatherService) GetPersonConfessions(ctx context.Context, personID string) ([]models.Confession, error) {
+	pid, err := primitive.ObjectIDFromHex(personID)
+	if err != nil {
+		return nil, cerrors.NewInvalidIDError("GetPersonConfessions", "FatherService", err)
+	}
+	return s.repo.GetConfessionsByPerson(ctx, pid)
+}
+
+// MatchFr matches the free-text confession fathers of persons without a
+// FatherID to the known fathers by name and aliases. With apply set the
+// matched persons are linked and the spelling is added to the father's
+// aliases; with createMissing set a father is created for every spelling
+// that matched nothing, grouping spellings that differ only by a title.
+func (s *FatherService) MatchFr(ctx context.Context, apply, createMissing bool, minScore float64) ([]FrMatch, error) {
+	if minScore <= 0 {
+		minScore = DefaultFatherMatchScore
+	}
+	values, err := s.personRepo.GetFrValues(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fathers, err := s.repo.GetAllFathers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	frs := make([]string, 0, len(values))
+	for fr := range values {
+		frs = append(frs, fr)
+	}
+	// Most used spellings first, so they name the fathers created for them.
+	sort.Slice(frs, func(i, j int) bool {
+		if values[frs[i]] != values[frs[j]] {
+			return values[frs[i]] > values[frs[j]]
+		}
+		return frs[i] < frs[j]
+	})
+
+	matches := []FrMatch{}
+	for _, fr := range frs {
+		match := FrMatch{Fr: fr, Persons: values[fr], Status: FrMatchUnmatched}
+
+		father, score := bestFather(fr, fathers)
+		if father != nil && score >= minScore {
+			match.Status = FrMatchMatched
+		} else if createMissing && apply && stripClergyTitle(fr) != "" {
+			created, err := s.repo.CreateFather(ctx, models.Father{Name: displayFatherName(fr)})
+			if err != nil {
+				return nil, err
+			}
+			fathers = append(fathers, *created)
+			father, score = created, 1
+			match.Status = FrMatchCreated
+		}
+
+		if match.Status != FrMatchUnmatched {
+			match.FatherID = father.ID
+			match.FatherName = father.Name
+			match.Score = score
+			if apply {
+				match.Updated, err = s.personRepo.SetFatherByFr(ctx, fr, father.ID)
+				if err != nil {
+					return nil, err
+				}
+				if fr != father.Name && !containsString(father.Aliases, fr) {
+					if err := s.repo.AddFatherAlias(ctx, father.ID, fr); err != nil {
+						return nil, err
+					}
+					for i := range fathers {
+						if fathers[i].ID == father.ID {
+							fathers[i].Aliases = append(fathers[i].Aliases, fr)
+						}
+					}
+				}
+			}
+		}
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+// Report lists the persons without an assigned father and those with no
+// confession recorded since the given time, leaving out erased persons. A
+// zero since uses the configured confession period.
+func (s *FatherService) Report(ctx context.Context, since time.Time) (*FatherReport, error) {
+	if since.IsZero() {
+		since = time.Now().Add(-s.confessionPeriod)
+	}
+	last, err := s.repo.GetLastConfessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FatherReport{Since: since, WithoutFather: []models.Person{}, WithoutConfession: []ConfessionStatus{}}
+	err = s.personRepo.StreamPersons(ctx, repositories.PersonFilter{}, func(p models.Person) error {
+		if !p.ErasedAt.IsZero() {
+			return nil
+		}
+		if p.FatherID.IsZero() {
+			report.WithoutFather = append(report.WithoutFather, p)
+		}
+		if l, ok := last[p.ID]; !ok || l.Before(since) {
+			report.WithoutConfession = append(report.WithoutConfession, ConfessionStatus{Person: p, LastConfession: l})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func bestFather(fr string, fathers []models.Father) (*models.Father, float64) {
+	key := stripClergyTitle(fr)
+	if key == "" {
+		return nil, 0
+	}
+	var best *models.Father
+	bestScore := 0.0
+	for i := range fathers {
+		for _, name := range append([]string{fathers[i].Name}, fathers[i].Aliases...) {
+			candidate := stripClergyTitle(name)
+			score := 0.0
+			if candidate == key {
+				score = 1
+			} else if candidate != "" {
+				score = NameSimilarity(key, candidate)
+			}
+			if score > bestScore {
+				best, bestScore = &fathers[i], score
+			}
+		}
+	}
+	return best, bestScore
+}
+
+// stripClergyTitle normalizes a father's name and drops titles such as "Fr."
+// or "القمص" so "Fr. Timo" and "Timo" compare equal.
+func stripClergyTitle(name string) string {
+	tokens := []string{}
+	for _, token := range strings.Fields(NormalizeName(name)) {
+		if !clergyTitles[token] {
+			tokens = append(tokens, token)
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
+// displayFatherName trims a free-text value for use as a new father's name,
+// keeping its original spelling.
+func displayFatherName(fr string) string {
+	return strings.Join(strings.Fields(fr), " ")
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
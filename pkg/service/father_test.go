@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeFatherRepo serves fathers and last confessions from memory. Other
+// methods are not used and panic.
+type fakeFatherRepo struct {
+	repositories.FatherRepoInterface
+	fathers     []models.Father
+	confessions map[primitive.ObjectID]time.Time
+}
+
+func (r *fakeFatherRepo) GetAllFathers(ctx context.Context) ([]models.Father, error) {
+	return r.fathers, nil
+}
+
+func (r *fakeFatherRepo) GetFatherById(ctx context.Context, id string) (*models.Father, error) {
+	for _, f := range r.fathers {
+		if f.ID.Hex() == id {
+			found := f
+			return &found, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeFatherRepo) GetLastConfessions(ctx context.Context) (map[primitive.ObjectID]time.Time, error) {
+	return r.confessions, nil
+}
+
+func TestPersonWritesResolveFather(t *testing.T) {
+	timo := models.Father{ID: primitive.NewObjectID(), Name: "Fr. Timotheos", Aliases: []string{"Abouna Timo"}}
+	tests := []struct {
+		name       string
+		person     models.Person
+		wantFather primitive.ObjectID
+		wantErr    bool
+	}{
+		{"no father", models.Person{Name: "Mina"}, primitive.NilObjectID, false},
+		{"known father", models.Person{Name: "Mina", FatherID: timo.ID}, timo.ID, false},
+		{"unknown father", models.Person{Name: "Mina", FatherID: primitive.NewObjectID()}, primitive.NilObjectID, true},
+		{"name with another title", models.Person{Name: "Mina", Fr: "القمص Timotheos"}, timo.ID, false},
+		{"alias", models.Person{Name: "Mina", Fr: "Timo"}, timo.ID, false},
+		{"unmatched name", models.Person{Name: "Mina", Fr: "Fr. Bishoy"}, primitive.NilObjectID, true},
+		{"only a title", models.Person{Name: "Mina", Fr: "Abouna"}, primitive.NilObjectID, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, write := range []string{"create", "update"} {
+				repo := &fakeImportRepo{persons: []models.Person{{ID: primitive.NewObjectID(), Name: "Mina"}}}
+				s := NewPersonService(repo, &fakeFatherRepo{fathers: []models.Father{timo}})
+
+				var p *models.Person
+				var err error
+				if write == "create" {
+					p, err = s.CreatePerson(context.Background(), tt.person)
+				} else {
+					p, err = s.UpdatePerson(context.Background(), repo.persons[0].ID.Hex(), tt.person)
+				}
+				if tt.wantErr {
+					var badRequest *cerrors.BadRequestError
+					assert.ErrorAs(t, err, &badRequest, write)
+					continue
+				}
+				require.NoError(t, err, write)
+				assert.Equal(t, tt.wantFather, p.FatherID, write)
+				assert.Equal(t, tt.person.Fr, p.Fr, write)
+			}
+		})
+	}
+}
+
+func TestFatherReportLeavesOutErasedPersons(t *testing.T) {
+	father := models.Father{ID: primitive.NewObjectID(), Name: "Fr. Timotheos"}
+	confessed := models.Person{ID: primitive.NewObjectID(), Name: "Confessed", FatherID: father.ID}
+	overdue := models.Person{ID: primitive.NewObjectID(), Name: "Overdue", FatherID: father.ID}
+	fatherless := models.Person{ID: primitive.NewObjectID(), Name: "Fatherless"}
+	erased := models.Person{ID: primitive.NewObjectID(), Name: models.ErasedPersonName, ErasedAt: time.Now()}
+
+	now := time.Now()
+	fathers := &fakeFatherRepo{fathers: []models.Father{father}, confessions: map[primitive.ObjectID]time.Time{
+		confessed.ID:  now.AddDate(0, 0, -10),
+		overdue.ID:    now.AddDate(0, 0, -100),
+		fatherless.ID: now.AddDate(0, 0, -5),
+	}}
+	s := NewFatherService(fathers, &fakePersonRepo{persons: []models.Person{confessed, overdue, fatherless, erased}}, 90*24*time.Hour)
+
+	report, err := s.Report(context.Background(), time.Time{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []models.Person{fatherless}, report.WithoutFather)
+	require.Len(t, report.WithoutConfession, 1)
+	assert.Equal(t, overdue.ID, report.WithoutConfession[0].Person.ID)
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type GroupService struct {
+	repo        repositories.GroupRepoInterface
+	personRepo  repositories.PersonRepoInterface
+	serviceRepo repositories.ServiceRepoInterface
+}
+
+func NewGroupService(repo repositories.GroupRepoInterface, personRepo repositories.PersonRepoInterface, serviceRepo repositories.ServiceRepoInterface) *GroupService {
+	return &GroupService{
+		repo:        repo,
+		personRepo:  personRepo,
+		serviceRepo: serviceRepo,
+	}
+}
+
+func (s *GroupService) GetAllGroups(ctx context.Context) ([]models.Group, error) {
+	groups, err := s.repo.GetAllGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (s *GroupService) GetGroupById(ctx context.Context, id string) (*models.Group, error) {
+	group, err := s.repo.GetGroupById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (s *GroupService) CreateGroup(ctx context.Context, group models.Group) (*models.Group, error) {
+	if group.Name == "" {
+		return nil, cerrors.NewBadRequestError("CreateGroup", "GroupService", errors.New("name is required"))
+	}
+	// Memberships are added one by one so every one is validated.
+	group.Memberships = nil
+	g, err := s.repo.CreateGroup(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (s *GroupService) UpdateGroup(ctx context.Context, id string, group models.Group) (*models.Group, error) {
+	g, err := s.repo.UpdateGroup(ctx, id, group)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (s *GroupService) DeleteGroup(ctx context.Context, id string) error {
+	err := s.repo.DeleteGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// AddMember opens a membership for a person. A person can only have one open
+// membership per group; JoinedAt defaults to now and Role to member.
+func (s *GroupService) AddMember(ctx context.Context, groupID string, membership models.GroupMembership) (*models.Group, error) {
+	group, err := s.repo.GetGroupById(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if membership.Role == "" {
+		membership.Role = models.GroupRoleMember
+	}
+	if membership.Role != models.GroupRoleMember && membership.Role != models.GroupRoleServant {
+		return nil, cerrors.NewBadRequestError("AddMember", "GroupService", fmt.Errorf("unknown role %q", membership.Role))
+	}
+	if membership.JoinedAt.IsZero() {
+		membership.JoinedAt = time.Now()
+	}
+	membership.LeftAt = time.Time{}
+
+	if _, err := s.personRepo.GetPersonById(ctx, membership.PersonID.Hex()); err != nil {
+		return nil, err
+	}
+
+	g, err := s.repo.AddMembership(ctx, group.ID, membership)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, cerrors.NewBadRequestError("AddMember", "GroupService", fmt.Errorf("person %v is already in the group", membership.PersonID.Hex()))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// RemoveMember closes the person's open membership at leftAt, or now when
+// leftAt is zero. The membership stays in the group's history.
+func (s *GroupService) RemoveMember(ctx context.Context, groupID, personID string, leftAt time.Time) (*models.Group, error) {
+	gid, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return nil, cerrors.NewInvalidIDError("RemoveMember", "GroupService", err)
+	}
+	pid, err := primitive.ObjectIDFromHex(personID)
+	if err != nil {
+		return nil, cerrors.NewInvalidIDError("RemoveMember", "GroupService", err)
+	}
+	if leftAt.IsZero() {
+		leftAt = time.Now()
+	}
+	g, err := s.repo.EndMembership(ctx, gid, pid, leftAt)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// GetRoster returns the persons holding role in the group at the given time.
+// An empty role returns members and servants alike.
+func (s *GroupService) GetRoster(ctx context.Context, groupID string, at time.Time, role string) ([]models.Person, error) {
+	group, err := s.repo.GetGroupById(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return s.personRepo.GetPersonsByIds(ctx, groupRosterIDs(group, at, role))
+}
+
+func (s *GroupService) GetGroupServices(ctx context.Context, groupID string) ([]models.Service, error) {
+	gid, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return nil, cerrors.NewInvalidIDError("GetGroupServices", "GroupService", err)
+	}
+	services := []models.Service{}
+	err = s.serviceRepo.StreamServices(ctx, repositories.ServiceFilter{GroupID: gid}, func(serv models.Service) error {
+		services = append(services, serv)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+func (s *GroupService) GetPersonGroups(ctx context.Context, personID string) ([]models.Group, error) {
+	pid, err := primitive.ObjectIDFromHex(personID)
+	if err != nil {
+		return nil, cerrors.NewInvalidIDError("GetPersonGroups", "GroupService", err)
+	}
+	return s.repo.GetGroupsByPerson(ctx, pid)
+}
+
+// GetServiceAbsentees returns the persons expected at a service who have no
+// attendance record for it or are recorded as absent.
+func (s *GroupService) GetServiceAbsentees(ctx context.Context, serviceID string) ([]models.Person, error) {
+	serv, err := s.serviceRepo.GetServiceById(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	roster, err := serviceRoster(ctx, s.personRepo, s.repo, serv)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded := map[primitive.ObjectID]bool{}
+	for _, ar := range serv.AttendanceRecord {
+		if ar.Attended() {
+			recorded[ar.PersonID] = true
+		}
+	}
+	absentees := []models.Person{}
+	for _, p := range roster {
+		if !recorded[p.ID] {
+			absentees = append(absentees, p)
+		}
+	}
+	return absentees, nil
+}
+
+// serviceRoster returns the persons expected at a service: the members of its
+// group on the day of the service, or everyone when it has no group.
+func serviceRoster(ctx context.Context, personRepo repositories.PersonRepoInterface, groupRepo repositories.GroupRepoInterface, serv *models.Service) ([]models.Person, error) {
+	if serv.GroupID.IsZero() {
+		return personRepo.GetAllPersons(ctx)
+	}
+
+	group, err := groupRepo.GetGroupById(ctx, serv.GroupID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	at := serv.Date
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return personRepo.GetPersonsByIds(ctx, groupRosterIDs(group, at, models.GroupRoleMember))
+}
+
+func groupRosterIDs(group *models.Group, at time.Time, role string) []primitive.ObjectID {
+	ids := []primitive.ObjectID{}
+	seen := map[primitive.ObjectID]bool{}
+	for _, m := range group.Memberships {
+		if role != "" && m.Role != role {
+			continue
+		}
+		if !m.ActiveAt(at) || seen[m.PersonID] {
+			continue
+		}
+		seen[m.PersonID] = true
+		ids = append(ids, m.PersonID)
+	}
+	return ids
+}
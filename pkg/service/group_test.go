@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAddMember(t *testing.T) {
+	mina := models.Person{ID: primitive.NewObjectID(), Name: "Mina"}
+	left := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		memberships []models.GroupMembership
+		membership  models.GroupMembership
+		wantErr     bool
+	}{
+		{"new member", nil, models.GroupMembership{PersonID: mina.ID}, false},
+		{"rejoins after leaving", []models.GroupMembership{{PersonID: mina.ID, Role: models.GroupRoleMember, LeftAt: left}}, models.GroupMembership{PersonID: mina.ID}, false},
+		{"already a member", []models.GroupMembership{{PersonID: mina.ID, Role: models.GroupRoleMember}}, models.GroupMembership{PersonID: mina.ID}, true},
+		{"already a servant", []models.GroupMembership{{PersonID: mina.ID, Role: models.GroupRoleServant}}, models.GroupMembership{PersonID: mina.ID, Role: models.GroupRoleMember}, true},
+		{"unknown role", nil, models.GroupMembership{PersonID: mina.ID, Role: "leader"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := models.Group{ID: primitive.NewObjectID(), Name: "Grade 6", Memberships: tt.memberships}
+			groups := &fakeGroupRepo{groups: []models.Group{group}}
+			s := NewGroupService(groups, &fakePersonRepo{persons: []models.Person{mina}}, nil)
+
+			g, err := s.AddMember(context.Background(), group.ID.Hex(), tt.membership)
+			if tt.wantErr {
+				var badRequest *cerrors.BadRequestError
+				assert.ErrorAs(t, err, &badRequest)
+				assert.Equal(t, tt.memberships, groups.groups[0].Memberships)
+				return
+			}
+			require.NoError(t, err)
+			added := g.Memberships[len(g.Memberships)-1]
+			assert.Equal(t, mina.ID, added.PersonID)
+			assert.Equal(t, models.GroupRoleMember, added.Role)
+			assert.False(t, added.JoinedAt.IsZero())
+			assert.True(t, added.LeftAt.IsZero())
+		})
+	}
+}
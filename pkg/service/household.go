@@ -0,0 +1,404 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AdultAge is the age from which a person no longer needs a guardian
+// contacted on their behalf.
+const AdultAge = 18
+
+type HouseholdService struct {
+	repo        repositories.HouseholdRepoInterface
+	personRepo  repositories.PersonRepoInterface
+	serviceRepo repositories.ServiceRepoInterface
+	groupRepo   repositories.GroupRepoInterface
+}
+
+func NewHouseholdService(repo repositories.HouseholdRepoInterface, personRepo repositories.PersonRepoInterface, serviceRepo repositories.ServiceRepoInterface, groupRepo repositories.GroupRepoInterface) *HouseholdService {
+	return &HouseholdService{
+		repo:        repo,
+		personRepo:  personRepo,
+		serviceRepo: serviceRepo,
+		groupRepo:   groupRepo,
+	}
+}
+
+// PersonRelation is a relationship seen from one person: Relation says what
+// Person is to them (parent, child, sibling, spouse, guardian or ward).
+type PersonRelation struct {
+	RelationshipID primitive.ObjectID `json:"relationshipId"`
+	Relation       string             `json:"relation"`
+	Person         models.Person      `json:"person"`
+}
+
+type GuardianContact struct {
+	PersonID primitive.ObjectID `json:"personId,omitempty"`
+	Name     string             `json:"name"`
+	Relation string             `json:"relation"`
+	Phone    string             `json:"phone"`
+	Email    string             `json:"email,omitempty"`
+	Language string             `json:"language,omitempty"`
+}
+
+type MemberAttendance struct {
+	Person       models.Person `json:"person"`
+	Expected     int           `json:"expected"`
+	Attended     int           `json:"attended"`
+	LastAttended time.Time     `json:"lastAttended,omitempty"`
+}
+
+type HouseholdAttendance struct {
+	Household models.Household   `json:"household"`
+	From      time.Time          `json:"from"`
+	To        time.Time          `json:"to"`
+	Expected  int                `json:"expected"`
+	Attended  int                `json:"attended"`
+	Rate      float64            `json:"rate"`
+	Members   []MemberAttendance `json:"members"`
+}
+
+func (s *HouseholdService) GetAllHouseholds(ctx context.Context) ([]models.Household, error) {
+	households, err := s.repo.GetAllHouseholds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return households, nil
+}
+
+func (s *HouseholdService) GetHouseholdById(ctx context.Context, id string) (*models.Household, error) {
+	household, err := s.repo.GetHouseholdById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return household, nil
+}
+
+func (s *HouseholdService) CreateHousehold(ctx context.Context, household models.Household) (*models.Household, error) {
+	members := household.MemberIDs
+	household.MemberIDs = nil
+	h, err := s.repo.CreateHousehold(ctx, household)
+	if err != nil {
+		return nil, err
+	}
+	for _, personID := range members {
+		h, err = s.AddMember(ctx, h.ID.Hex(), personID.Hex())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+func (s *HouseholdService) UpdateHousehold(ctx context.Context, id string, household models.Household) (*models.Household, error) {
+	h, err := s.repo.UpdateHousehold(ctx, id, household)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (s *HouseholdService) DeleteHousehold(ctx context.Context, id string) error {
+	err := s.repo.DeleteHousehold(ctx, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// AddMember adds a person to a household. A person belongs to at most one
+// household.
+func (s *HouseholdService) AddMember(ctx context.Context, householdID, personID string) (*models.Household, error) {
+	household, err := s.repo.GetHouseholdById(ctx, householdID)
+	if err != nil {
+		return nil, err
+	}
+	person, err := s.personRepo.GetPersonById(ctx, personID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.GetHouseholdByMember(ctx, person.ID)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+	if current != nil && current.ID != household.ID {
+		return nil, cerrors.NewBadRequestError("AddMember", "HouseholdService", fmt.Errorf("person %v already belongs to household %v", personID, current.ID.Hex()))
+	}
+
+	h, err := s.repo.AddHouseholdMember(ctx, household.ID, person.ID)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (s *HouseholdService) RemoveMember(ctx context.Context, householdID, personID string) (*models.Household, error) {
+	hid, err := primitive.ObjectIDFromHex(householdID)
+	if err != nil {
+		return nil, cerrors.NewInvalidIDError("RemoveMember", "HouseholdService", err)
+	}
+	pid, err := primitive.ObjectIDFromHex(personID)
+	if err != nil {
+		return nil, cerrors.NewInvalidIDError("RemoveMember", "HouseholdService", err)
+	}
+	h, err := s.repo.RemoveHouseholdMember(ctx, hid, pid)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (s *HouseholdService) CreateRelationship(ctx context.Context, rel models.Relationship) (*models.Relationship, error) {
+	switch rel.Type {
+	case models.RelationshipParent, models.RelationshipSibling, models.RelationshipSpouse, models.RelationshipGuardian:
+	default:
+		return nil, cerrors.NewBadRequestError("CreateRelationship", "HouseholdService", fmt.Errorf("unknown relationship type %q", rel.Type))
+	}
+	if rel.PersonID == rel.RelatedID {
+		return nil, cerrors.NewBadRequestError("CreateRelationship", "HouseholdService", errors.New("a person cannot be related to themselves"))
+	}
+	for _, id := range []primitive.ObjectID{rel.PersonID, rel.RelatedID} {
+		if _, err := s.personRepo.GetPersonById(ctx, id.Hex()); err != nil {
+			return nil, err
+		}
+	}
+
+	existing, err := s.repo.GetRelationshipsByPerson(ctx, rel.PersonID)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range existing {
+		sameDirection := e.PersonID == rel.PersonID && e.RelatedID == rel.RelatedID
+		reversed := e.PersonID == rel.RelatedID && e.RelatedID == rel.PersonID
+		symmetric := rel.Type == models.RelationshipSibling || rel.Type == models.RelationshipSpouse
+		if e.Type == rel.Type && (sameDirection || (symmetric && reversed)) {
+			return nil, cerrors.NewBadRequestError("CreateRelationship", "HouseholdService", errors.New("relationship already exists"))
+		}
+	}
+
+	r, err := s.repo.CreateRelationship(ctx, rel)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *HouseholdService) DeleteRelationship(ctx context.Context, id string) error {
+	err := s.repo.DeleteRelationship(ctx, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetPersonRelations lists the relatives of a person.
+func (s *HouseholdService) GetPersonRelations(ctx context.Context, personID string) ([]PersonRelation, error) {
+	person, err := s.personRepo.GetPersonById(ctx, personID)
+	if err != nil {
+		return nil, err
+	}
+	rels, err := s.repo.GetRelationshipsByPerson(ctx, person.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	others := []primitive.ObjectID{}
+	for _, rel := range rels {
+		others = append(others, otherEnd(rel, person.ID))
+	}
+	persons, err := s.personRepo.GetPersonsByIds(ctx, others)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[primitive.ObjectID]models.Person{}
+	for _, p := range persons {
+		byID[p.ID] = p
+	}
+
+	relations := []PersonRelation{}
+	for _, rel := range rels {
+		other, ok := byID[otherEnd(rel, person.ID)]
+		if !ok {
+			continue
+		}
+		relations = append(relations, PersonRelation{
+			RelationshipID: rel.ID,
+			Relation:       relationFrom(rel, person.ID),
+			Person:         other,
+		})
+	}
+	return relations, nil
+}
+
+// GuardianContacts returns who to contact about a minor: their parents and
+// guardians with a phone number, falling back to the household phone. Adults
+// are contacted directly and get their own phone back.
+func (s *HouseholdService) GuardianContacts(ctx context.Context, personID string) ([]GuardianContact, error) {
+	person, err := s.personRepo.GetPersonById(ctx, personID)
+	if err != nil {
+		return nil, err
+	}
+	if !IsMinor(*person, time.Now()) {
+		return []GuardianContact{{PersonID: person.ID, Name: person.Name, Relation: "self", Phone: person.Phone, Email: person.Email, Language: person.Language}}, nil
+	}
+
+	relations, err := s.GetPersonRelations(ctx, personID)
+	if err != nil {
+		return nil, err
+	}
+	contacts := []GuardianContact{}
+	for _, rel := range relations {
+		if rel.Relation != models.RelationshipParent && rel.Relation != models.RelationshipGuardian {
+			continue
+		}
+		if rel.Person.Phone == "" && rel.Person.Email == "" {
+			continue
+		}
+		contacts = append(contacts, GuardianContact{
+			PersonID: rel.Person.ID,
+			Name:     rel.Person.Name,
+			Relation: rel.Relation,
+			Phone:    rel.Person.Phone,
+			Email:    rel.Person.Email,
+			Language: rel.Person.Language,
+		})
+	}
+
+	if len(contacts) == 0 {
+		household, err := s.repo.GetHouseholdByMember(ctx, person.ID)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		if household != nil && household.Phone != "" {
+			contacts = append(contacts, GuardianContact{Name: household.Name, Relation: "household", Phone: household.Phone})
+		}
+	}
+	return contacts, nil
+}
+
+// HouseholdAttendance summarizes the attendance of a household's members
+// between from and to. A member is expected at a service when it has no group
+// or when they were a member of its group on that day.
+func (s *HouseholdService) HouseholdAttendance(ctx context.Context, householdID string, from, to time.Time) (*HouseholdAttendance, error) {
+	household, err := s.repo.GetHouseholdById(ctx, householdID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.personRepo.GetPersonsByIds(ctx, household.MemberIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]MemberAttendance, len(members))
+	memberships := make([][]personGroupMembership, len(members))
+	for i, p := range members {
+		summaries[i].Person = p
+		groups, err := s.groupRepo.GetGroupsByPerson(ctx, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			for _, m := range g.Memberships {
+				if m.PersonID == p.ID && m.Role == models.GroupRoleMember {
+					memberships[i] = append(memberships[i], personGroupMembership{groupID: g.ID, membership: m})
+				}
+			}
+		}
+	}
+
+	err = s.serviceRepo.StreamServices(ctx, repositories.ServiceFilter{From: from, To: to}, func(serv models.Service) error {
+		for i, p := range members {
+			attended := false
+			for _, ar := range serv.AttendanceRecord {
+				if ar.PersonID == p.ID && ar.Attended() {
+					attended = true
+					break
+				}
+			}
+			if !attended && !expectedAt(serv, memberships[i]) {
+				continue
+			}
+			summaries[i].Expected++
+			if attended {
+				summaries[i].Attended++
+				if serv.Date.After(summaries[i].LastAttended) {
+					summaries[i].LastAttended = serv.Date
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &HouseholdAttendance{Household: *household, From: from, To: to, Members: summaries}
+	for _, m := range summaries {
+		summary.Expected += m.Expected
+		summary.Attended += m.Attended
+	}
+	if summary.Expected > 0 {
+		summary.Rate = float64(summary.Attended) / float64(summary.Expected)
+	}
+	return summary, nil
+}
+
+type personGroupMembership struct {
+	groupID    primitive.ObjectID
+	membership models.GroupMembership
+}
+
+// expectedAt reports whether a person with the given group memberships is
+// expected at serv.
+func expectedAt(serv models.Service, memberships []personGroupMembership) bool {
+	if serv.GroupID.IsZero() {
+		return true
+	}
+	for _, m := range memberships {
+		if m.groupID == serv.GroupID && m.membership.ActiveAt(serv.Date) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMinor reports whether the person is younger than AdultAge at t. Persons
+// without a birthday are treated as adults.
+func IsMinor(p models.Person, t time.Time) bool {
+	if p.Birthday.IsZero() {
+		return false
+	}
+	return p.Birthday.AddDate(AdultAge, 0, 0).After(t)
+}
+
+func otherEnd(rel models.Relationship, personID primitive.ObjectID) primitive.ObjectID {
+	if rel.PersonID == personID {
+		return rel.RelatedID
+	}
+	return rel.PersonID
+}
+
+// relationFrom names what the other end of rel is to personID.
+func relationFrom(rel models.Relationship, personID primitive.ObjectID) string {
+	if rel.PersonID == personID {
+		return rel.Type
+	}
+	switch rel.Type {
+	case models.RelationshipParent:
+		return "child"
+	case models.RelationshipGuardian:
+		return "ward"
+	default:
+		return rel.Type
+	}
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+)
+
+// InternationalPhone returns phone as digits with the country code and no
+// leading plus, the form SMS gateways and WhatsApp expect. Numbers without a
+// country code are taken to be Egyptian.
+func InternationalPhone(phone string) string {
+	digits := models.NormalizePhone(phone)
+	if strings.HasPrefix(digits, "0") {
+		return "20" + digits[1:]
+	}
+	return digits
+}
+
+// arabicLetterVariants folds letters that are commonly written
+// interchangeably in names.
+var arabicLetterVariants = map[rune]rune{
+	'أ': 'ا',
+	'إ': 'ا',
+	'آ': 'ا',
+	'ٱ': 'ا',
+	'ة': 'ه',
+	'ى': 'ي',
+	'ؤ': 'و',
+	'ئ': 'ي',
+}
+
+// latinAccents folds the accented letters that show up in transliterated
+// names.
+var latinAccents = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ä': 'a', 'ã': 'a',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'ö': 'o', 'õ': 'o',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u',
+	'ç': 'c', 'ñ': 'n',
+}
+
+// NormalizeName lower cases name, removes Arabic diacritics and tatweel,
+// folds Arabic letter variants (أ/إ/آ to ا, ة to ه, ى to ي) and Latin accents,
+// replaces punctuation with spaces and collapses whitespace.
+func NormalizeName(name string) string {
+	var b strings.Builder
+	space := true
+	for _, ch := range strings.ToLower(name) {
+		if isArabicDiacritic(ch) || ch == 'ـ' {
+			continue
+		}
+		if v, ok := arabicLetterVariants[ch]; ok {
+			ch = v
+		}
+		if v, ok := latinAccents[ch]; ok {
+			ch = v
+		}
+		if !unicode.IsLetter(ch) && !unicode.IsDigit(ch) {
+			if !space {
+				b.WriteRune(' ')
+				space = true
+			}
+			continue
+		}
+		b.WriteRune(ch)
+		space = false
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func isArabicDiacritic(ch rune) bool {
+	return (ch >= 'ً' && ch <= 'ٟ') || ch == 'ٰ'
+}
+
+func isArabic(s string) bool {
+	for _, ch := range s {
+		if ch >= '؀' && ch <= 'ۿ' {
+			return true
+		}
+	}
+	return false
+}
+
+// arabicSkeleton maps Arabic consonants to the Latin letters they are usually
+// transliterated with in Egyptian names. Letters mapped to 0 are dropped as
+// they are written as vowels, or not at all, in Latin script.
+var arabicSkeleton = map[rune]rune{
+	'ب': 'b', 'ت': 't', 'ث': 't', 'ج': 'g', 'ح': 'h', 'خ': 'k', 'د': 'd',
+	'ذ': 'z', 'ر': 'r', 'ز': 'z', 'س': 's', 'ش': 's', 'ص': 's', 'ض': 'd',
+	'ط': 't', 'ظ': 'z', 'غ': 'g', 'ف': 'f', 'ق': 'k', 'ك': 'k', 'ل': 'l',
+	'م': 'm', 'ن': 'n', 'ه': 'h', 'ا': 0, 'و': 0, 'ي': 0, 'ع': 0, 'ء': 0,
+}
+
+// latinSkeleton maps Latin letters onto the same consonant classes as
+// arabicSkeleton.
+var latinSkeleton = map[rune]rune{
+	'a': 0, 'e': 0, 'i': 0, 'o': 0, 'u': 0, 'y': 0, 'w': 0,
+	'c': 'k', 'q': 'k', 'j': 'g', 'p': 'b', 'v': 'f', 'x': 'k',
+}
+
+// nameSkeleton reduces a normalized name token to its consonants so names
+// can be compared across Arabic and Latin script, e.g. "kyrillos" and "كيرلس"
+// both become "krls".
+func nameSkeleton(token string) string {
+	token = strings.NewReplacer("sh", "s", "th", "t", "kh", "k", "gh", "g", "ph", "f").Replace(token)
+	var b strings.Builder
+	var last rune
+	for _, ch := range token {
+		mapped, ok := arabicSkeleton[ch]
+		if !ok {
+			mapped, ok = latinSkeleton[ch]
+		}
+		if !ok {
+			mapped = ch
+		}
+		if mapped == 0 || mapped == last {
+			continue
+		}
+		b.WriteRune(mapped)
+		last = mapped
+	}
+	return b.String()
+}
+
+// NameSimilarity returns a score between 0 and 1 of how alike two names are.
+// Names in the same script are compared token by token with Jaro-Winkler on
+// their normalized form, names in different scripts on their consonant
+// skeletons with a small penalty.
+func NameSimilarity(a, b string) float64 {
+	na, nb := NormalizeName(a), NormalizeName(b)
+	if na == "" || nb == "" {
+		return 0
+	}
+	if na == nb {
+		return 1
+	}
+
+	ta, tb := strings.Fields(na), strings.Fields(nb)
+	penalty := 1.0
+	if isArabic(na) != isArabic(nb) {
+		for i := range ta {
+			ta[i] = nameSkeleton(ta[i])
+		}
+		for i := range tb {
+			tb[i] = nameSkeleton(tb[i])
+		}
+		penalty = 0.9
+	}
+	if len(ta) > len(tb) {
+		ta, tb = tb, ta
+	}
+
+	// Match every token of the shorter name with its best counterpart in the
+	// longer one, then discount names with many unmatched tokens. Names with
+	// as many tokens are matched both ways so the score does not depend on
+	// the order of a and b.
+	score := bestTokenMatches(ta, tb)
+	if len(ta) == len(tb) {
+		score = (score + bestTokenMatches(tb, ta)) / 2
+	}
+	coverage := float64(len(ta)) / float64(len(tb))
+	return penalty * score * (0.8 + 0.2*coverage)
+}
+
+// bestTokenMatches averages the best Jaro-Winkler score of every token of ta
+// against the tokens of tb.
+func bestTokenMatches(ta, tb []string) float64 {
+	total := 0.0
+	for _, x := range ta {
+		best := 0.0
+		for _, y := range tb {
+			if s := jaroWinkler(x, y); s > best {
+				best = s
+			}
+		}
+		total += best
+	}
+	return total / float64(len(ta))
+}
+
+func jaroWinkler(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 || len(rb) == 0 {
+		if len(ra) == len(rb) {
+			return 1
+		}
+		return 0
+	}
+
+	window := max(len(ra), len(rb))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i := range ra {
+		lo, hi := max(0, i-window), min(len(rb), i+window+1)
+		for j := lo; j < hi; j++ {
+			if matchedB[j] || ra[i] != rb[j] {
+				continue
+			}
+			matchedA[i], matchedB[j] = true, true
+			matches++
+			break
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"  Mina   GIRGIS ", "mina girgis"},
+		{"Mina-Girgis.", "mina girgis"},
+		{"José Müller", "jose muller"},
+		{"أحمد", "احمد"},
+		{"إيمان", "ايمان"},
+		{"مَرْيَمُ", "مريم"},
+		{"فاطمة", "فاطمه"},
+		{"مـــينا", "مينا"},
+		{"هدى", "هدي"},
+		{"St. Mary 2", "st mary 2"},
+		{"", ""},
+		{"...", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, NormalizeName(tt.in), tt.in)
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"Mina", "mina", 1, 1},
+		{"أحمد", "احمد", 1, 1},
+		{"", "", 0, 0},
+		{"Mina Girgis", "Mina Gerges", 0.85, 1},
+		{"Marina Adel", "Marian Adel", 0.95, 1},
+		{"John", "Jon", 0.9, 1},
+		// Transliterations match their Arabic spelling.
+		{"Kyrillos Nabil", "كيرلس نبيل", 0.85, 1},
+		{"Mina Girgis", "مينا جرجس", 0.85, 1},
+		// Different persons stay well below the duplicate threshold.
+		{"Mina Girgis", "Peter Samir", 0, 0.5},
+		{"مينا جرجس", "بيتر سمير", 0, 0.5},
+	}
+	for _, tt := range tests {
+		got := NameSimilarity(tt.a, tt.b)
+		assert.GreaterOrEqual(t, got, tt.min, "%s / %s", tt.a, tt.b)
+		assert.LessOrEqual(t, got, tt.max, "%s / %s", tt.a, tt.b)
+		assert.InDelta(t, got, NameSimilarity(tt.b, tt.a), 1e-9, "%s / %s is not symmetric", tt.a, tt.b)
+	}
+}
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"martha", "marhta", 0.9611},
+		{"dwayne", "duane", 0.84},
+		{"dixon", "dicksonx", 0.8133},
+		{"same", "same", 1},
+		{"abc", "xyz", 0},
+	}
+	for _, tt := range tests {
+		assert.InDelta(t, tt.want, jaroWinkler(tt.a, tt.b), 0.0001, "%s / %s", tt.a, tt.b)
+	}
+}
+
+func TestNameSkeleton(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"kyrillos", "krls"},
+		{"كيرلس", "krls"},
+		{"shenouda", "snd"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, nameSkeleton(tt.in), tt.in)
+	}
+}
+
+func TestInternationalPhone(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"01001234567", "201001234567"},
+		{"+20 100 123 4567", "201001234567"},
+		{"00201001234567", "201001234567"},
+		{"٠١٠٠١٢٣٤٥٦٧", "201001234567"},
+		{"+44 20 7946 0958", "442079460958"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, InternationalPhone(tt.in), tt.in)
+	}
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var noteCategories = []string{
+	models.NoteCategoryHealth,
+	models.NoteCategoryFamily,
+	models.NoteCategorySpiritual,
+	models.NoteCategoryStudy,
+	models.NoteCategoryWork,
+	models.NoteCategoryOther,
+}
+
+// NoteService keeps the pastoral notes of persons. Every method takes the
+// ID of the person asking, the reader: notes are only shown to the readers
+// their visibility allows, and every note shown is recorded in the audit.
+type NoteService struct {
+	repo       repositories.NoteRepoInterface
+	personRepo repositories.PersonRepoInterface
+	groupRepo  repositories.GroupRepoInterface
+	fatherRepo repositories.FatherRepoInterface
+}
+
+func NewNoteService(repo repositories.NoteRepoInterface, personRepo repositories.PersonRepoInterface, groupRepo repositories.GroupRepoInterface, fatherRepo repositories.FatherRepoInterface) *NoteService {
+	return &NoteService{
+		repo:       repo,
+		personRepo: personRepo,
+		groupRepo:  groupRepo,
+		fatherRepo: fatherRepo,
+	}
+}
+
+// noteReader is what a reader may see of one person's notes.
+type noteReader struct {
+	id      primitive.ObjectID
+	priest  bool
+	servant bool
+}
+
+func (r *noteReader) canRead(note models.Note) bool {
+	switch {
+	case note.AuthorID == r.id:
+		return true
+	case note.Visibility == models.NoteVisibilityGroup:
+		return r.servant || r.priest
+	case note.Visibility == models.NoteVisibilityPriest:
+		return r.priest
+	}
+	return false
+}
+
+// GetPersonNotes returns the notes about the person the reader may see,
+// newest first.
+func (s *NoteService) GetPersonNotes(ctx context.Context, personID, reader string, now time.Time) ([]models.Note, error) {
+	person, err := s.personRepo.GetPersonById(ctx, personID)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.reader(ctx, "GetPersonNotes", reader, person.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := s.repo.GetNotesByPerson(ctx, person.ID)
+	if err != nil {
+		return nil, err
+	}
+	visible := []models.Note{}
+	for _, note := range notes {
+		if r.canRead(note) {
+			visible = append(visible, note)
+		}
+	}
+	if err := s.audit(ctx, r, visible, now); err != nil {
+		return nil, err
+	}
+	return visible, nil
+}
+
+func (s *NoteService) GetNoteById(ctx context.Context, id, reader string, now time.Time) (*models.Note, error) {
+	note, err := s.repo.GetNoteById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.reader(ctx, "GetNoteById", reader, note.PersonID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !r.canRead(*note) {
+		return nil, cerrors.NewForbiddenError("GetNoteById", "NoteService", errors.New("the note is not visible to the reader"))
+	}
+	if err := s.audit(ctx, r, []models.Note{*note}, now); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// CreateNote adds a note about the person written by the reader, who must
+// be a servant of one of the person's groups or a priest.
+func (s *NoteService) CreateNote(ctx context.Context, personID, reader string, note models.Note, now time.Time) (*models.Note, error) {
+	person, err := s.personRepo.GetPersonById(ctx, personID)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.reader(ctx, "CreateNote", reader, person.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !r.servant && !r.priest {
+		return nil, cerrors.NewForbiddenError("CreateNote", "NoteService", errors.New("only the person's servants and priests can add notes"))
+	}
+	if err := validateNote("CreateNote", &note); err != nil {
+		return nil, err
+	}
+	note.PersonID = person.ID
+	note.AuthorID = r.id
+	note.CreatedAt = now
+	note.UpdatedAt = now
+	return s.repo.CreateNote(ctx, note)
+}
+
+// UpdateNote changes the category, visibility and body of the note. Only
+// its author can.
+func (s *NoteService) UpdateNote(ctx context.Context, id, reader string, note models.Note, now time.Time) (*models.Note, error) {
+	existing, err := s.authored(ctx, "UpdateNote", id, reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateNote("UpdateNote", &note); err != nil {
+		return nil, err
+	}
+	existing.Category = note.Category
+	existing.Visibility = note.Visibility
+	existing.Body = note.Body
+	existing.UpdatedAt = now
+	return s.repo.ReplaceNote(ctx, *existing)
+}
+
+// DeleteNote deletes the note. Only its author can. The audit of the note
+// is kept.
+func (s *NoteService) DeleteNote(ctx context.Context, id, reader string) error {
+	if _, err := s.authored(ctx, "DeleteNote", id, reader); err != nil {
+		return err
+	}
+	return s.repo.DeleteNote(ctx, id)
+}
+
+// GetNoteReads returns who read the note, for its author and priests.
+func (s *NoteService) GetNoteReads(ctx context.Context, id, reader string, now time.Time) ([]models.NoteRead, error) {
+	note, err := s.repo.GetNoteById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.reader(ctx, "GetNoteReads", reader, note.PersonID, now)
+	if err != nil {
+		return nil, err
+	}
+	if note.AuthorID != r.id && !r.priest {
+		return nil, cerrors.NewForbiddenError("GetNoteReads", "NoteService", errors.New("only the author and priests can see who read the note"))
+	}
+	return s.repo.GetNoteReads(ctx, repositories.NoteReadFilter{NoteID: note.ID})
+}
+
+// GetPersonNoteReads returns who read the notes about the person, for
+// priests.
+func (s *NoteService) GetPersonNoteReads(ctx context.Context, personID, reader string, now time.Time) ([]models.NoteRead, error) {
+	person, err := s.personRepo.GetPersonById(ctx, personID)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.reader(ctx, "GetPersonNoteReads", reader, person.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !r.priest {
+		return nil, cerrors.NewForbiddenError("GetPersonNoteReads", "NoteService", errors.New("only priests can see who read the notes"))
+	}
+	return s.repo.GetNoteReads(ctx, repositories.NoteReadFilter{PersonID: person.ID})
+}
+
+// reader resolves what the reader may see of the person's notes. A reader
+// is a servant when they serve one of the person's groups at now.
+func (s *NoteService) reader(ctx context.Context, method, reader string, personID primitive.ObjectID, now time.Time) (*noteReader, error) {
+	oid, err := primitive.ObjectIDFromHex(reader)
+	if err != nil {
+		return nil, cerrors.NewBadRequestError(method, "NoteService", errors.New("a valid reader id is required"))
+	}
+	if _, err := s.personRepo.GetPersonById(ctx, reader); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, cerrors.NewForbiddenError(method, "NoteService", fmt.Errorf("unknown reader %s", reader))
+		}
+		return nil, err
+	}
+	r := &noteReader{id: oid}
+	r.priest, err = s.fatherRepo.IsPriest(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.groupRepo.GetGroupsByPerson(ctx, personID)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		for _, m := range g.Memberships {
+			if m.PersonID == oid && m.Role == models.GroupRoleServant && m.ActiveAt(now) {
+				r.servant = true
+			}
+		}
+	}
+	return r, nil
+}
+
+// authored returns the note if the reader wrote it.
+func (s *NoteService) authored(ctx context.Context, method, id, reader string) (*models.Note, error) {
+	note, err := s.repo.GetNoteById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if note.AuthorID.Hex() != reader {
+		return nil, cerrors.NewForbiddenError(method, "NoteService", errors.New("only the author can change the note"))
+	}
+	return note, nil
+}
+
+// audit records that the reader was shown the notes. Notes are not shown
+// unless the reads were recorded.
+func (s *NoteService) audit(ctx context.Context, r *noteReader, notes []models.Note, now time.Time) error {
+	reads := make([]models.NoteRead, len(notes))
+	for i, note := range notes {
+		reads[i] = models.NoteRead{NoteID: note.ID, PersonID: note.PersonID, ReaderID: r.id, ReadAt: now}
+	}
+	return s.repo.AddNoteReads(ctx, reads)
+}
+
+func validateNote(method string, note *models.Note) error {
+	note.Body = strings.TrimSpace(note.Body)
+	if note.Body == "" {
+		return cerrors.NewBadRequestError(method, "NoteService", errors.New("body is required"))
+	}
+	if note.Category == "" {
+		note.Category = models.NoteCategoryOther
+	}
+	if !containsString(noteCategories, note.Category) {
+		return cerrors.NewBadRequestError(method, "NoteService", fmt.Errorf("unknown category %q, want one of %s", note.Category, strings.Join(noteCategories, ", ")))
+	}
+	switch note.Visibility {
+	case "":
+		note.Visibility = models.NoteVisibilityAuthor
+	case models.NoteVisibilityAuthor, models.NoteVisibilityGroup, models.NoteVisibilityPriest:
+	default:
+		return cerrors.NewBadRequestError(method, "NoteService", fmt.Errorf("unknown visibility %q", note.Visibility))
+	}
+	return nil
+}
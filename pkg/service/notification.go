@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/notify"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MaxNotificationAttempts is how often delivery of a notification is tried
+// before it is marked failed.
+const MaxNotificationAttempts = 5
+
+const (
+	// notificationRetryDelay is the wait after the first failed attempt. It
+	// doubles with every further attempt.
+	notificationRetryDelay = time.Minute
+	// notificationLease is how long a claimed notification is hidden from
+	// other instances and requests while it is being delivered.
+	notificationLease = 5 * time.Minute
+)
+
+type NotificationService struct {
+	repo       repositories.NotificationRepoInterface
+	personRepo repositories.PersonRepoInterface
+	households *HouseholdService
+	drivers    map[string]notify.Notifier
+	channels   []string
+	templates  *notify.Templates
+}
+
+// NewNotificationService creates a NotificationService delivering through
+// drivers, keyed by channel. Persons are contacted on the first channel of
+// channels that has a driver and that they have an address for. Minors are
+// never contacted directly: their notifications go to the first of their
+// guardians, as listed by households, that can be reached.
+func NewNotificationService(repo repositories.NotificationRepoInterface, personRepo repositories.PersonRepoInterface, households *HouseholdService, drivers map[string]notify.Notifier, channels []string, templates *notify.Templates) *NotificationService {
+	return &NotificationService{
+		repo:       repo,
+		personRepo: personRepo,
+		households: households,
+		drivers:    drivers,
+		channels:   channels,
+		templates:  templates,
+	}
+}
+
+func (s *NotificationService) GetNotifications(ctx context.Context, filter repositories.NotificationFilter) ([]models.Notification, error) {
+	return s.repo.GetNotifications(ctx, filter)
+}
+
+func (s *NotificationService) GetNotificationById(ctx context.Context, id string) (*models.Notification, error) {
+	return s.repo.GetNotificationById(ctx, id)
+}
+
+// NotifyPerson renders the template in the language of whoever receives it
+// and tries to deliver it right away. Messages to a guardian whose language
+// is unknown are rendered in the person's language. The notification is stored leased, so the retry job
+// leaves it alone while it is delivered here. Failed deliveries are stored
+// and retried, so the returned error only reports an unknown template or a
+// storage failure. data is made available to the template along with the
+// person as .Recipient and, when the person is a minor, the guardian it is
+// sent to as .Guardian.
+func (s *NotificationService) NotifyPerson(ctx context.Context, person models.Person, template string, data map[string]interface{}) (*models.Notification, error) {
+	if !s.templates.Has(template) {
+		return nil, cerrors.NewBadRequestError("NotifyPerson", "NotificationService", fmt.Errorf("unknown template %q", template))
+	}
+	values := map[string]interface{}{}
+	for k, v := range data {
+		values[k] = v
+	}
+	values["Recipient"] = person
+
+	now := time.Now()
+	contact := GuardianContact{PersonID: person.ID, Name: person.Name, Relation: "self", Phone: person.Phone, Email: person.Email, Language: person.Language}
+	minor := IsMinor(person, now)
+	if minor {
+		guardian, err := s.guardian(ctx, person)
+		if err != nil {
+			return nil, err
+		}
+		contact = guardian
+		values["Guardian"] = guardian
+	}
+
+	language := contact.Language
+	if language == "" {
+		language = person.Language
+	}
+	subject, body, lang, err := s.templates.Render(template, language, values)
+	if err != nil {
+		return nil, cerrors.NewBadRequestError("NotifyPerson", "NotificationService", err)
+	}
+
+	n := models.Notification{
+		PersonID:    person.ID,
+		Name:        contact.Name,
+		Template:    template,
+		Language:    lang,
+		Subject:     subject,
+		Body:        body,
+		Status:      models.NotificationPending,
+		NextAttempt: now,
+		LeasedUntil: now.Add(notificationLease),
+		CreatedAt:   now,
+	}
+	n.Channel, n.To = s.route(contact)
+	if n.Channel == "" {
+		n.Status = models.NotificationFailed
+		n.LastError = "no channel to reach the person on"
+		if minor {
+			n.LastError = "no guardian to reach the minor through"
+		}
+		n.LeasedUntil = time.Time{}
+	}
+
+	created, err := s.repo.CreateNotification(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	if created.Status == models.NotificationPending {
+		if err := s.deliver(ctx, created); err != nil {
+			return nil, err
+		}
+	}
+	return created, nil
+}
+
+// NotifyPersonById is NotifyPerson for a person looked up by id.
+func (s *NotificationService) NotifyPersonById(ctx context.Context, personID, template string, data map[string]interface{}) (*models.Notification, error) {
+	person, err := s.personRepo.GetPersonById(ctx, personID)
+	if err != nil {
+		return nil, err
+	}
+	return s.NotifyPerson(ctx, *person, template, data)
+}
+
+// Retry immediately tries to deliver a pending or failed notification again.
+// It claims the notification first, like the retry job, so a notification
+// being delivered elsewhere is not sent twice.
+func (s *NotificationService) Retry(ctx context.Context, id string) (*models.Notification, error) {
+	n, err := s.repo.GetNotificationById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if n.Status == models.NotificationSent {
+		return nil, cerrors.NewBadRequestError("Retry", "NotificationService", errors.New("notification was already sent"))
+	}
+	if n.Channel == "" {
+		return nil, cerrors.NewBadRequestError("Retry", "NotificationService", errors.New("notification has no channel"))
+	}
+	n, err = s.repo.ClaimNotification(ctx, n.ID, time.Now(), notificationLease)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, cerrors.NewBadRequestError("Retry", "NotificationService", errors.New("notification is being delivered or was already sent"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	// A manual retry of a failed notification gets one more attempt.
+	if n.Attempts >= MaxNotificationAttempts {
+		n.Attempts = MaxNotificationAttempts - 1
+	}
+	if err := s.deliver(ctx, n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// RetryDue delivers the pending notifications whose next attempt is due and
+// returns how many it tried.
+func (s *NotificationService) RetryDue(ctx context.Context) (int, error) {
+	tried := 0
+	for {
+		n, err := s.repo.ClaimDueNotification(ctx, time.Now(), notificationLease)
+		if err == mongo.ErrNoDocuments {
+			return tried, nil
+		}
+		if err != nil {
+			return tried, err
+		}
+		if err := s.deliver(ctx, n); err != nil {
+			return tried, err
+		}
+		tried++
+	}
+}
+
+// deliver makes one delivery attempt and stores its outcome. Only failing to
+// store the outcome is returned as an error.
+func (s *NotificationService) deliver(ctx context.Context, n *models.Notification) error {
+	driver, ok := s.drivers[n.Channel]
+	var err error
+	if !ok {
+		err = fmt.Errorf("no driver for channel %q", n.Channel)
+	} else {
+		err = driver.Notify(ctx, notify.Message{
+			Channel: n.Channel,
+			To:      n.To,
+			Name:    n.Name,
+			Subject: n.Subject,
+			Body:    n.Body,
+		})
+	}
+
+	now := time.Now()
+	n.Attempts++
+	if err == nil {
+		n.Status = models.NotificationSent
+		n.SentAt = now
+		n.LastError = ""
+		n.NextAttempt = time.Time{}
+	} else {
+		fmt.Printf("Error while delivering notification %v: %v\n", n.ID.Hex(), err)
+		n.LastError = err.Error()
+		if n.Attempts >= MaxNotificationAttempts {
+			n.Status = models.NotificationFailed
+			n.NextAttempt = time.Time{}
+		} else {
+			n.Status = models.NotificationPending
+			n.NextAttempt = now.Add(notificationRetryDelay << (n.Attempts - 1))
+		}
+	}
+	return s.repo.UpdateDelivery(ctx, *n)
+}
+
+// guardian returns the first guardian of the minor that can be reached on a
+// configured channel, or an empty contact when there is none.
+func (s *NotificationService) guardian(ctx context.Context, minor models.Person) (GuardianContact, error) {
+	contacts, err := s.households.GuardianContacts(ctx, minor.ID.Hex())
+	if err != nil {
+		return GuardianContact{}, err
+	}
+	for _, c := range contacts {
+		if channel, _ := s.route(c); channel != "" {
+			return c, nil
+		}
+	}
+	return GuardianContact{}, nil
+}
+
+// route returns the first configured channel the contact can be reached on
+// and their address on it.
+func (s *NotificationService) route(contact GuardianContact) (string, string) {
+	for _, channel := range s.channels {
+		if _, ok := s.drivers[channel]; !ok {
+			continue
+		}
+		switch channel {
+		case notify.ChannelEmail:
+			if contact.Email != "" {
+				return channel, contact.Email
+			}
+		case notify.ChannelSMS, notify.ChannelWhatsApp:
+			if phone := InternationalPhone(contact.Phone); phone != "" {
+				return channel, phone
+			}
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/notify"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeNotificationRepo holds notifications in memory and claims them with the
+// same conditions as the MongoDB queries. Creating notifications fails while
+// createErr is set. Other methods are not used by the delivery and panic.
+type fakeNotificationRepo struct {
+	repositories.NotificationRepoInterface
+	notifications []*models.Notification
+	createErr     error
+}
+
+func (r *fakeNotificationRepo) CreateNotification(ctx context.Context, n models.Notification) (*models.Notification, error) {
+	if r.createErr != nil {
+		return nil, r.createErr
+	}
+	n.ID = primitive.NewObjectID()
+	stored := n
+	r.notifications = append(r.notifications, &stored)
+	return &n, nil
+}
+
+func (r *fakeNotificationRepo) GetNotificationById(ctx context.Context, id string) (*models.Notification, error) {
+	for _, n := range r.notifications {
+		if n.ID.Hex() == id {
+			found := *n
+			return &found, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeNotificationRepo) UpdateDelivery(ctx context.Context, n models.Notification) error {
+	for _, stored := range r.notifications {
+		if stored.ID == n.ID {
+			stored.Status = n.Status
+			stored.Attempts = n.Attempts
+			stored.LastError = n.LastError
+			stored.NextAttempt = n.NextAttempt
+			stored.SentAt = n.SentAt
+			stored.LeasedUntil = time.Time{}
+			return nil
+		}
+	}
+	panic("unknown notification " + n.ID.Hex())
+}
+
+func (r *fakeNotificationRepo) ClaimDueNotification(ctx context.Context, now time.Time, lease time.Duration) (*models.Notification, error) {
+	for _, n := range r.notifications {
+		if n.Status == models.NotificationPending && !n.NextAttempt.After(now) && !n.LeasedUntil.After(now) {
+			n.LeasedUntil = now.Add(lease)
+			claimed := *n
+			return &claimed, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakeNotificationRepo) ClaimNotification(ctx context.Context, id primitive.ObjectID, now time.Time, lease time.Duration) (*models.Notification, error) {
+	for _, n := range r.notifications {
+		if n.ID == id && n.Status != models.NotificationSent && !n.LeasedUntil.After(now) {
+			n.LeasedUntil = now.Add(lease)
+			claimed := *n
+			return &claimed, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+// fakeNotifier records the messages it is given and fails while err is set.
+type fakeNotifier struct {
+	sent []notify.Message
+	err  error
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, msg notify.Message) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+// fakePersonRepo looks persons up in memory. Other methods are not used and
+// panic.
+type fakePersonRepo struct {
+	repositories.PersonRepoInterface
+	persons []models.Person
+}
+
+func (r *fakePersonRepo) GetPersonById(ctx context.Context, id string) (*models.Person, error) {
+	for _, p := range r.persons {
+		if p.ID.Hex() == id {
+			found := p
+			return &found, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *fakePersonRepo) StreamPersons(ctx context.Context, filter repositories.PersonFilter, fn func(models.Person) error) error {
+	for _, p := range r.persons {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fakePersonRepo) GetPersonsByIds(ctx context.Context, ids []primitive.ObjectID) ([]models.Person, error) {
+	found := []models.Person{}
+	for _, p := range r.persons {
+		for _, id := range ids {
+			if p.ID == id {
+				found = append(found, p)
+			}
+		}
+	}
+	return found, nil
+}
+
+// fakeHouseholdRepo serves relationships and households from memory. Other
+// methods are not used and panic.
+type fakeHouseholdRepo struct {
+	repositories.HouseholdRepoInterface
+	relationships []models.Relationship
+	households    []models.Household
+}
+
+func (r *fakeHouseholdRepo) GetRelationshipsByPerson(ctx context.Context, personID primitive.ObjectID) ([]models.Relationship, error) {
+	found := []models.Relationship{}
+	for _, rel := range r.relationships {
+		if rel.PersonID == personID || rel.RelatedID == personID {
+			found = append(found, rel)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeHouseholdRepo) GetHouseholdByMember(ctx context.Context, personID primitive.ObjectID) (*models.Household, error) {
+	for _, h := range r.households {
+		for _, id := range h.MemberIDs {
+			if id == personID {
+				found := h
+				return &found, nil
+			}
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+// newTestNotificationService returns a NotificationService delivering email
+// through a fakeNotifier.
+func newTestNotificationService(persons *fakePersonRepo, households *fakeHouseholdRepo) (*NotificationService, *fakeNotificationRepo, *fakeNotifier) {
+	repo := &fakeNotificationRepo{}
+	notifier := &fakeNotifier{}
+	s := NewNotificationService(repo, persons, NewHouseholdService(households, persons, nil, nil),
+		map[string]notify.Notifier{notify.ChannelEmail: notifier}, []string{notify.ChannelEmail}, notify.NewTemplates(notify.LanguageEnglish))
+	return s, repo, notifier
+}
+
+func TestDeliverBacksOff(t *testing.T) {
+	s, repo, notifier := newTestNotificationService(&fakePersonRepo{}, &fakeHouseholdRepo{})
+	notifier.err = errors.New("mail server down")
+	person := models.Person{ID: primitive.NewObjectID(), Name: "Mina", Email: "mina@example.com"}
+
+	_, err := s.NotifyPerson(context.Background(), person, "custom", map[string]interface{}{"Subject": "Hi", "Body": "Hello"})
+	require.NoError(t, err)
+	require.Len(t, repo.notifications, 1)
+	stored := repo.notifications[0]
+
+	for attempt, wait := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute} {
+		assert.Equal(t, attempt+1, stored.Attempts)
+		assert.Equal(t, models.NotificationPending, stored.Status)
+		assert.WithinDuration(t, time.Now().Add(wait), stored.NextAttempt, 5*time.Second, "attempt %d", attempt+1)
+		assert.True(t, stored.LeasedUntil.IsZero(), "lease released after attempt %d", attempt+1)
+
+		claimed := *stored
+		require.NoError(t, s.deliver(context.Background(), &claimed))
+	}
+
+	assert.Equal(t, MaxNotificationAttempts, stored.Attempts)
+	assert.Equal(t, models.NotificationFailed, stored.Status)
+	assert.True(t, stored.NextAttempt.IsZero())
+	assert.Equal(t, "mail server down", stored.LastError)
+}
+
+func TestRetryDueSkipsLeasedNotifications(t *testing.T) {
+	s, repo, notifier := newTestNotificationService(&fakePersonRepo{}, &fakeHouseholdRepo{})
+	now := time.Now()
+	due := &models.Notification{ID: primitive.NewObjectID(), Channel: notify.ChannelEmail, To: "a@example.com", Status: models.NotificationPending, Attempts: 1, NextAttempt: now.Add(-time.Minute)}
+	leased := &models.Notification{ID: primitive.NewObjectID(), Channel: notify.ChannelEmail, To: "b@example.com", Status: models.NotificationPending, Attempts: 1, NextAttempt: now.Add(-time.Minute), LeasedUntil: now.Add(time.Minute)}
+	expired := &models.Notification{ID: primitive.NewObjectID(), Channel: notify.ChannelEmail, To: "c@example.com", Status: models.NotificationPending, Attempts: 1, NextAttempt: now.Add(-time.Minute), LeasedUntil: now.Add(-time.Second)}
+	later := &models.Notification{ID: primitive.NewObjectID(), Channel: notify.ChannelEmail, To: "d@example.com", Status: models.NotificationPending, Attempts: 1, NextAttempt: now.Add(time.Minute)}
+	repo.notifications = []*models.Notification{due, leased, expired, later}
+
+	tried, err := s.RetryDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, tried)
+
+	to := []string{}
+	for _, msg := range notifier.sent {
+		to = append(to, msg.To)
+	}
+	assert.ElementsMatch(t, []string{"a@example.com", "c@example.com"}, to)
+	assert.Equal(t, models.NotificationSent, due.Status)
+	assert.Equal(t, models.NotificationSent, expired.Status)
+	assert.Equal(t, models.NotificationPending, leased.Status)
+	assert.Equal(t, models.NotificationPending, later.Status)
+}
+
+func TestNotifyPersonLeasesUntilDelivered(t *testing.T) {
+	s, repo, notifier := newTestNotificationService(&fakePersonRepo{}, &fakeHouseholdRepo{})
+	notifier.err = errors.New("mail server down")
+	person := models.Person{ID: primitive.NewObjectID(), Name: "Mina", Email: "mina@example.com"}
+
+	// While NotifyPerson delivers, the stored notification must be leased so
+	// the retry job does not pick it up as well.
+	var leasedWhileDelivering bool
+	s.drivers[notify.ChannelEmail] = notifierFunc(func(ctx context.Context, msg notify.Message) error {
+		leasedWhileDelivering = repo.notifications[0].LeasedUntil.After(time.Now().Add(notificationLease - time.Minute))
+		tried, err := s.RetryDue(ctx)
+		assert.NoError(t, err)
+		assert.Zero(t, tried)
+		return notifier.err
+	})
+
+	_, err := s.NotifyPerson(context.Background(), person, "custom", map[string]interface{}{"Subject": "Hi", "Body": "Hello"})
+	require.NoError(t, err)
+	assert.True(t, leasedWhileDelivering)
+	assert.Equal(t, 1, repo.notifications[0].Attempts)
+}
+
+func TestRetry(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name         string
+		notification models.Notification
+		wantErr      bool
+		wantAttempts int
+	}{
+		{"failed gets one more attempt", models.Notification{Status: models.NotificationFailed, Channel: notify.ChannelEmail, Attempts: MaxNotificationAttempts}, false, MaxNotificationAttempts},
+		{"pending", models.Notification{Status: models.NotificationPending, Channel: notify.ChannelEmail, Attempts: 2}, false, 3},
+		{"sent", models.Notification{Status: models.NotificationSent, Channel: notify.ChannelEmail, Attempts: 1}, true, 1},
+		{"no channel", models.Notification{Status: models.NotificationFailed, Attempts: 0}, true, 0},
+		{"being delivered", models.Notification{Status: models.NotificationPending, Channel: notify.ChannelEmail, Attempts: 1, LeasedUntil: now.Add(time.Minute)}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo, _ := newTestNotificationService(&fakePersonRepo{}, &fakeHouseholdRepo{})
+			n := tt.notification
+			n.ID = primitive.NewObjectID()
+			n.To = "mina@example.com"
+			repo.notifications = []*models.Notification{&n}
+
+			_, err := s.Retry(context.Background(), n.ID.Hex())
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, models.NotificationSent, n.Status)
+			}
+			assert.Equal(t, tt.wantAttempts, n.Attempts)
+		})
+	}
+}
+
+func TestNotifyPersonRoutesMinorsToGuardians(t *testing.T) {
+	minorBirthday := time.Now().AddDate(-10, 0, 0)
+	minor := models.Person{ID: primitive.NewObjectID(), Name: "Mina", Birthday: minorBirthday, Email: "mina@example.com", Language: notify.LanguageArabic}
+	father := models.Person{ID: primitive.NewObjectID(), Name: "Youssef", Email: "youssef@example.com", Language: notify.LanguageEnglish}
+	mother := models.Person{ID: primitive.NewObjectID(), Name: "Mariam", Email: "mariam@example.com"}
+	unreachable := models.Person{ID: primitive.NewObjectID(), Name: "Girgis", Phone: "01001234567"}
+	adult := models.Person{ID: primitive.NewObjectID(), Name: "Bishoy", Email: "bishoy@example.com", Language: notify.LanguageArabic}
+
+	tests := []struct {
+		name          string
+		person        models.Person
+		relationships []models.Relationship
+		wantTo        string
+		wantName      string
+		wantLanguage  string
+		wantStatus    string
+	}{
+		{
+			name:   "guardian in their own language",
+			person: minor,
+			relationships: []models.Relationship{
+				{ID: primitive.NewObjectID(), PersonID: minor.ID, RelatedID: father.ID, Type: models.RelationshipParent},
+			},
+			wantTo: "youssef@example.com", wantName: "Youssef", wantLanguage: notify.LanguageEnglish, wantStatus: models.NotificationSent,
+		},
+		{
+			name:   "guardian without a language gets the minor's",
+			person: minor,
+			relationships: []models.Relationship{
+				{ID: primitive.NewObjectID(), PersonID: minor.ID, RelatedID: mother.ID, Type: models.RelationshipParent},
+			},
+			wantTo: "mariam@example.com", wantName: "Mariam", wantLanguage: notify.LanguageArabic, wantStatus: models.NotificationSent,
+		},
+		{
+			name:   "skips guardians that cannot be reached",
+			person: minor,
+			relationships: []models.Relationship{
+				{ID: primitive.NewObjectID(), PersonID: minor.ID, RelatedID: unreachable.ID, Type: models.RelationshipGuardian},
+				{ID: primitive.NewObjectID(), PersonID: minor.ID, RelatedID: father.ID, Type: models.RelationshipParent},
+			},
+			wantTo: "youssef@example.com", wantName: "Youssef", wantLanguage: notify.LanguageEnglish, wantStatus: models.NotificationSent,
+		},
+		{
+			name:   "never the minor directly",
+			person: minor,
+			relationships: []models.Relationship{
+				{ID: primitive.NewObjectID(), PersonID: minor.ID, RelatedID: unreachable.ID, Type: models.RelationshipGuardian},
+			},
+			wantTo: "", wantName: "", wantLanguage: notify.LanguageArabic, wantStatus: models.NotificationFailed,
+		},
+		{
+			name:   "adults directly",
+			person: adult,
+			wantTo: "bishoy@example.com", wantName: "Bishoy", wantLanguage: notify.LanguageArabic, wantStatus: models.NotificationSent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			persons := &fakePersonRepo{persons: []models.Person{minor, father, mother, unreachable, adult}}
+			s, _, notifier := newTestNotificationService(persons, &fakeHouseholdRepo{relationships: tt.relationships})
+
+			n, err := s.NotifyPerson(context.Background(), tt.person, "assignment_reminder", map[string]interface{}{"Title": "Homework", "Deadline": "Friday", "HoursLeft": 24})
+			require.NoError(t, err)
+			assert.Equal(t, tt.person.ID, n.PersonID)
+			assert.Equal(t, tt.wantTo, n.To)
+			assert.Equal(t, tt.wantName, n.Name)
+			assert.Equal(t, tt.wantLanguage, n.Language)
+			assert.Equal(t, tt.wantStatus, n.Status)
+			if tt.wantTo == "" {
+				assert.Empty(t, notifier.sent)
+			} else {
+				require.Len(t, notifier.sent, 1)
+				assert.Equal(t, tt.wantTo, notifier.sent[0].To)
+			}
+		})
+	}
+}
+
+type notifierFunc func(ctx context.Context, msg notify.Message) error
+
+func (f notifierFunc) Notify(ctx context.Context, msg notify.Message) error {
+	return f(ctx, msg)
+}
@@ -2,18 +2,26 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
 	"github.com/Mario-Kamel/EKMS/pkg/models"
 	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type PersonService struct {
-	repo repositories.PersonRepoInterface
+	repo       repositories.PersonRepoInterface
+	fatherRepo repositories.FatherRepoInterface
+	index      *personIndex
 }
 
-func NewPersonService(repo repositories.PersonRepoInterface) *PersonService {
+func NewPersonService(repo repositories.PersonRepoInterface, fatherRepo repositories.FatherRepoInterface) *PersonService {
 	return &PersonService{
-		repo: repo,
+		repo:       repo,
+		fatherRepo: fatherRepo,
+		index:      newPersonIndex(),
 	}
 }
 
@@ -34,25 +42,67 @@ func (s *PersonService) GetPersonById(ctx context.Context, id string) (*models.P
 }
 
 func (s *PersonService) CreatePerson(ctx context.Context, person models.Person) (*models.Person, error) {
+	if err := s.resolveFather(ctx, "CreatePerson", &person); err != nil {
+		return nil, err
+	}
 	p, err := s.repo.CreatePerson(ctx, person)
 	if err != nil {
 		return nil, err
 	}
+	s.index.invalidate()
 	return p, nil
 }
 
 func (s *PersonService) UpdatePerson(ctx context.Context, id string, person models.Person) (*models.Person, error) {
+	if err := s.resolveFather(ctx, "UpdatePerson", &person); err != nil {
+		return nil, err
+	}
 	p, err := s.repo.UpdatePerson(ctx, id, person)
 	if err != nil {
 		return nil, err
 	}
+	s.index.invalidate()
 	return p, nil
 }
 
+// resolveFather checks that the person's FatherID is a known father. A
+// confession father given only as free text is matched to the fathers by name
+// and aliases, like MatchFr does, and rejected when it matches none, so new
+// persons are never left with a spelling the roster does not know.
+func (s *PersonService) resolveFather(ctx context.Context, method string, person *models.Person) error {
+	if !person.FatherID.IsZero() {
+		_, err := s.fatherRepo.GetFatherById(ctx, person.FatherID.Hex())
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return cerrors.NewBadRequestError(method, "PersonService", fmt.Errorf("father %s does not exist", person.FatherID.Hex()))
+		}
+		return err
+	}
+	if stripClergyTitle(person.Fr) == "" {
+		return nil
+	}
+	fathers, err := s.fatherRepo.GetAllFathers(ctx)
+	if err != nil {
+		return err
+	}
+	father, score := bestFather(person.Fr, fathers)
+	if father == nil || score < DefaultFatherMatchScore {
+		return cerrors.NewBadRequestError(method, "PersonService", fmt.Errorf("confession father %q matches no father; add the father first or set fatherId", person.Fr))
+	}
+	person.FatherID = father.ID
+	return nil
+}
+
+// InvalidateSearchIndex makes the next search see persons changed outside
+// this service, like erased ones, without waiting for personIndexTTL.
+func (s *PersonService) InvalidateSearchIndex() {
+	s.index.invalidate()
+}
+
 func (s *PersonService) DeletePerson(ctx context.Context, id string) error {
 	err := s.repo.DeletePerson(ctx, id)
 	if err != nil {
 		return err
 	}
+	s.index.invalidate()
 	return nil
 }
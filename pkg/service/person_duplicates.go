@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultDuplicateScore is the score from which a pair of persons is reported
+// as a likely duplicate.
+const DefaultDuplicateScore = 0.5
+
+const (
+	phoneWeight    = 0.4
+	nameWeight     = 0.5
+	birthdayWeight = 0.1
+)
+
+type DuplicateCandidate struct {
+	Person    models.Person `json:"person"`
+	Duplicate models.Person `json:"duplicate"`
+	Score     float64       `json:"score"`
+	Reasons   []string      `json:"reasons"`
+}
+
+type MergeRequest struct {
+	SurvivorID   string   `json:"survivorId"`
+	DuplicateIDs []string `json:"duplicateIds"`
+	// Person optionally overrides fields of the merged record.
+	Person *models.Person `json:"person,omitempty"`
+}
+
+// FindDuplicates scores pairs of persons sharing a phone number, a birthday
+// or the start of a name token and returns the pairs scoring at least
+// minScore, best first.
+func (s *PersonService) FindDuplicates(ctx context.Context, minScore float64) ([]DuplicateCandidate, error) {
+	persons, err := s.repo.GetAllPersons(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Blocking keeps the comparison count close to linear: only persons that
+	// share at least one key are compared. Erased persons all share a name
+	// and are never compared.
+	blocks := map[string][]int{}
+	for i, p := range persons {
+		if !p.ErasedAt.IsZero() {
+			continue
+		}
+		for _, key := range duplicateBlockingKeys(p) {
+			blocks[key] = append(blocks[key], i)
+		}
+	}
+
+	seen := map[[2]int]bool{}
+	candidates := []DuplicateCandidate{}
+	for _, members := range blocks {
+		for x := 0; x < len(members); x++ {
+			for y := x + 1; y < len(members); y++ {
+				pair := [2]int{members[x], members[y]}
+				if seen[pair] {
+					continue
+				}
+				seen[pair] = true
+
+				a, b := persons[pair[0]], persons[pair[1]]
+				score, reasons := scoreDuplicate(a, b)
+				if score >= minScore {
+					candidates = append(candidates, DuplicateCandidate{Person: a, Duplicate: b, Score: score, Reasons: reasons})
+				}
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates, nil
+}
+
+// MergePersons folds the duplicates into the survivor. Empty fields of the
+// survivor are filled from the duplicates in the given order, then the
+// request's Person overrides are applied.
+func (s *PersonService) MergePersons(ctx context.Context, req MergeRequest) (*models.Person, error) {
+	if req.SurvivorID == "" || len(req.DuplicateIDs) == 0 {
+		return nil, cerrors.NewBadRequestError("MergePersons", "PersonService", errors.New("survivorId and duplicateIds are required"))
+	}
+
+	survivor, err := s.repo.GetPersonById(ctx, req.SurvivorID)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := *survivor
+	duplicateIDs := []primitive.ObjectID{}
+	for _, id := range req.DuplicateIDs {
+		if id == req.SurvivorID {
+			return nil, cerrors.NewBadRequestError("MergePersons", "PersonService", fmt.Errorf("person %v cannot be merged into itself", id))
+		}
+		dup, err := s.repo.GetPersonById(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		merged = fillEmptyPersonFields(merged, *dup)
+		duplicateIDs = append(duplicateIDs, dup.ID)
+	}
+	if req.Person != nil {
+		merged = mergeImportedPerson(merged, *req.Person)
+	}
+
+	err = s.repo.MergePersons(ctx, merged, duplicateIDs)
+	if err != nil {
+		return nil, err
+	}
+	s.index.invalidate()
+	return &merged, nil
+}
+
+func scoreDuplicate(a, b models.Person) (float64, []string) {
+	score := 0.0
+	reasons := []string{}
+
+	phoneA, phoneB := models.NormalizePhone(a.Phone), models.NormalizePhone(b.Phone)
+	if phoneA != "" && phoneA == phoneB {
+		score += phoneWeight
+		reasons = append(reasons, "same phone")
+	}
+
+	if sim := NameSimilarity(a.Name, b.Name); sim > 0.7 {
+		score += nameWeight * sim
+		reasons = append(reasons, fmt.Sprintf("similar name (%.0f%%)", sim*100))
+	}
+
+	if !a.Birthday.IsZero() && !b.Birthday.IsZero() {
+		if a.Birthday.Format("2006-01-02") == b.Birthday.Format("2006-01-02") {
+			score += birthdayWeight
+			reasons = append(reasons, "same birthday")
+		} else {
+			score -= birthdayWeight
+			reasons = append(reasons, "different birthday")
+		}
+	}
+
+	if score < 0 {
+		score = 0
+	}
+	return score, reasons
+}
+
+func duplicateBlockingKeys(p models.Person) []string {
+	keys := []string{}
+	if phone := models.NormalizePhone(p.Phone); phone != "" {
+		keys = append(keys, "phone:"+phone)
+	}
+	if !p.Birthday.IsZero() {
+		keys = append(keys, "birthday:"+p.Birthday.Format("2006-01-02"))
+	}
+	for _, token := range strings.Fields(NormalizeName(p.Name)) {
+		skeleton := []rune(nameSkeleton(token))
+		if len(skeleton) > 2 {
+			skeleton = skeleton[:2]
+		}
+		if len(skeleton) > 0 {
+			keys = append(keys, "name:"+string(skeleton))
+		}
+	}
+	return keys
+}
+
+func fillEmptyPersonFields(p, from models.Person) models.Person {
+	if p.Name == "" {
+		p.Name = from.Name
+	}
+	if p.Birthday.IsZero() {
+		p.Birthday = from.Birthday
+	}
+	if p.Phone == "" {
+		p.Phone = from.Phone
+	}
+	if p.Address == "" {
+		p.Address = from.Address
+	}
+	if p.Fr == "" {
+		p.Fr = from.Fr
+	}
+	if p.FatherID.IsZero() {
+		p.FatherID = from.FatherID
+	}
+	if p.Degree == "" {
+		p.Degree = from.Degree
+	}
+	if p.Email == "" {
+		p.Email = from.Email
+	}
+	if p.Language == "" {
+		p.Language = from.Language
+	}
+	return p
+}
@@ -0,0 +1,443 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+)
+
+// RowReader is satisfied by both csv.Reader and xlsx.Reader so imports can
+// stream rows without caring about the file format.
+type RowReader interface {
+	Read() ([]string, error)
+}
+
+const (
+	ImportModeInsert = "insert"
+	ImportModeUpsert = "upsert"
+	ImportModeSkip   = "skip"
+)
+
+const (
+	ImportRowCreated   = "created"
+	ImportRowUpdated   = "updated"
+	ImportRowSkipped   = "skipped"
+	ImportRowDuplicate = "duplicate"
+	ImportRowConflict  = "conflict"
+	ImportRowInvalid   = "invalid"
+	ImportRowFailed    = "failed"
+)
+
+// ImportOptions controls how ImportPersons treats the incoming rows.
+// Mapping maps a person field (name, birthday, phone, address, fr, degree)
+// to the header of the column holding it. Fields missing from the mapping are
+// looked up by their own name in the header row.
+type ImportOptions struct {
+	Mapping map[string]string `json:"mapping"`
+	DryRun  bool              `json:"dryRun"`
+	Mode    string            `json:"mode"`
+}
+
+type ImportRowResult struct {
+	Row         int            `json:"row"`
+	Status      string         `json:"status"`
+	Errors      []string       `json:"errors,omitempty"`
+	Person      *models.Person `json:"person,omitempty"`
+	DuplicateOf string         `json:"duplicateOf,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun     bool              `json:"dryRun"`
+	Mode       string            `json:"mode"`
+	Total      int               `json:"total"`
+	Created    int               `json:"created"`
+	Updated    int               `json:"updated"`
+	Skipped    int               `json:"skipped"`
+	Duplicates int               `json:"duplicates"`
+	Conflicts  int               `json:"conflicts"`
+	Invalid    int               `json:"invalid"`
+	Failed     int               `json:"failed"`
+	Rows       []ImportRowResult `json:"rows"`
+}
+
+// importedRow is a row already seen in the file, kept to find rows repeating
+// an earlier one.
+type importedRow struct {
+	row    int
+	person models.Person
+}
+
+var importFields = []string{"name", "birthday", "phone", "address", "fr", "degree", "email", "language"}
+
+var birthdayLayouts = []string{
+	"2006-01-02",
+	"02/01/2006",
+	"2/1/2006",
+	"02-01-2006",
+	"2006/01/02",
+	time.RFC3339,
+}
+
+// ImportPersons reads persons from rows, validating each one and matching it
+// against existing people. A row is the same person as an existing one when
+// their phones match, or their names and birthdays do. In insert mode
+// duplicates are reported and left untouched, in upsert mode they are
+// updated and in skip mode they are silently skipped. A row sharing only its
+// name with an existing person, with no phone or birthday to tell them
+// apart, is reported as a conflict. With DryRun set nothing is written and
+// the report describes what would have happened.
+//
+// The report counts every row but only lists the rows that were not created
+// or updated, so large files are imported without holding them in memory.
+func (s *PersonService) ImportPersons(ctx context.Context, rows RowReader, opts ImportOptions) (*ImportReport, error) {
+	if opts.Mode == "" {
+		opts.Mode = ImportModeInsert
+	}
+	if opts.Mode != ImportModeInsert && opts.Mode != ImportModeUpsert && opts.Mode != ImportModeSkip {
+		return nil, cerrors.NewBadRequestError("ImportPersons", "PersonService", fmt.Errorf("unknown import mode %q", opts.Mode))
+	}
+
+	header, err := rows.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, cerrors.NewBadRequestError("ImportPersons", "PersonService", errors.New("import file is empty"))
+		}
+		fmt.Printf("Error while reading import header: %v\n", err)
+		return nil, cerrors.NewBadRequestError("ImportPersons", "PersonService", err)
+	}
+
+	columns, err := mapColumns(header, opts.Mapping)
+	if err != nil {
+		return nil, cerrors.NewBadRequestError("ImportPersons", "PersonService", err)
+	}
+
+	report := &ImportReport{DryRun: opts.DryRun, Mode: opts.Mode, Rows: []ImportRowResult{}}
+	seenPhones := map[string]int{}
+	seenNames := map[string][]importedRow{}
+
+	for rowNum := 2; ; rowNum++ {
+		record, err := rows.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			fmt.Printf("Error while reading import row %d: %v\n", rowNum, err)
+			return nil, cerrors.NewBadRequestError("ImportPersons", "PersonService", fmt.Errorf("row %d: %w", rowNum, err))
+		}
+		if isBlankRow(record) {
+			continue
+		}
+
+		report.Total++
+		result := s.importRow(ctx, rowNum, record, columns, opts, seenPhones, seenNames)
+		switch result.Status {
+		case ImportRowCreated:
+			report.Created++
+			continue
+		case ImportRowUpdated:
+			report.Updated++
+			continue
+		case ImportRowSkipped:
+			report.Skipped++
+		case ImportRowDuplicate:
+			report.Duplicates++
+		case ImportRowConflict:
+			report.Conflicts++
+		case ImportRowInvalid:
+			report.Invalid++
+		case ImportRowFailed:
+			report.Failed++
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	if !opts.DryRun && report.Created+report.Updated > 0 {
+		s.index.invalidate()
+	}
+
+	return report, nil
+}
+
+func (s *PersonService) importRow(ctx context.Context, rowNum int, record []string, columns map[string]int, opts ImportOptions, seenPhones map[string]int, seenNames map[string][]importedRow) ImportRowResult {
+	result := ImportRowResult{Row: rowNum}
+
+	person, errs := parsePersonRow(record, columns)
+	if len(errs) > 0 {
+		result.Status = ImportRowInvalid
+		result.Errors = errs
+		return result
+	}
+	result.Person = &person
+
+	phone := models.NormalizePhone(person.Phone)
+	nameKey := strings.ToLower(person.Name)
+	if prev, ok := seenPhones[phone]; ok && phone != "" {
+		result.Status = ImportRowDuplicate
+		result.Errors = []string{fmt.Sprintf("same phone as row %d", prev)}
+		return result
+	}
+	for _, prev := range seenNames[nameKey] {
+		if comparePersons(prev.person, person) != personsDiffer {
+			result.Status = ImportRowDuplicate
+			result.Errors = []string{fmt.Sprintf("same name as row %d", prev.row)}
+			return result
+		}
+	}
+	if phone != "" {
+		seenPhones[phone] = rowNum
+	}
+	seenNames[nameKey] = append(seenNames[nameKey], importedRow{row: rowNum, person: models.Person{Name: person.Name, Phone: person.Phone, Birthday: person.Birthday}})
+
+	phones := []string{}
+	if person.Phone != "" {
+		phones = append(phones, person.Phone)
+		if phone != person.Phone {
+			phones = append(phones, phone)
+		}
+	}
+	existing, err := s.repo.FindPersonsByPhoneOrName(ctx, phones, person.Name)
+	if err != nil {
+		result.Status = ImportRowFailed
+		result.Errors = []string{err.Error()}
+		return result
+	}
+
+	var match *models.Person
+	for i := range existing {
+		switch comparePersons(existing[i], person) {
+		case personsSame:
+			match = &existing[i]
+		case personsUnsure:
+			if result.DuplicateOf == "" {
+				result.DuplicateOf = existing[i].ID.Hex()
+			}
+		}
+		if match != nil {
+			break
+		}
+	}
+	if match == nil && result.DuplicateOf != "" {
+		result.Status = ImportRowConflict
+		result.Errors = []string{"same name as existing person " + result.DuplicateOf + " but no phone or birthday to tell whether it is the same person"}
+		return result
+	}
+
+	if match != nil {
+		result.DuplicateOf = match.ID.Hex()
+		switch opts.Mode {
+		case ImportModeInsert:
+			result.Status = ImportRowDuplicate
+			result.Errors = []string{"matches existing person " + match.ID.Hex()}
+			return result
+		case ImportModeSkip:
+			result.Status = ImportRowSkipped
+			return result
+		}
+
+		merged := mergeImportedPerson(*match, person)
+		result.Person = &merged
+		result.Status = ImportRowUpdated
+		if opts.DryRun {
+			return result
+		}
+		if _, err := s.repo.UpdatePerson(ctx, match.ID.Hex(), merged); err != nil {
+			result.Status = ImportRowFailed
+			result.Errors = []string{err.Error()}
+		}
+		return result
+	}
+
+	result.Status = ImportRowCreated
+	if opts.DryRun {
+		return result
+	}
+	p, err := s.repo.CreatePerson(ctx, person)
+	if err != nil {
+		result.Status = ImportRowFailed
+		result.Errors = []string{err.Error()}
+		return result
+	}
+	result.Person = p
+	return result
+}
+
+func mapColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	index := map[string]int{}
+	for i, h := range header {
+		index[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+
+	columns := map[string]int{}
+	for field := range mapping {
+		if !isImportField(field) {
+			return nil, fmt.Errorf("unknown person field %q in mapping", field)
+		}
+	}
+	for _, field := range importFields {
+		column := field
+		if mapped, ok := mapping[field]; ok {
+			column = mapped
+		}
+		i, ok := index[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			if _, mapped := mapping[field]; mapped {
+				return nil, fmt.Errorf("column %q mapped to %s not found in header", column, field)
+			}
+			continue
+		}
+		columns[field] = i
+	}
+
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("no column mapped to name")
+	}
+	return columns, nil
+}
+
+func parsePersonRow(record []string, columns map[string]int) (models.Person, []string) {
+	value := func(field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	errs := []string{}
+	person := models.Person{
+		Name:     value("name"),
+		Phone:    value("phone"),
+		Address:  value("address"),
+		Fr:       value("fr"),
+		Degree:   value("degree"),
+		Email:    value("email"),
+		Language: strings.ToLower(value("language")),
+	}
+
+	if person.Name == "" {
+		errs = append(errs, "name is required")
+	}
+	if person.Phone != "" {
+		digits := models.NormalizePhone(person.Phone)
+		if len(digits) < 7 || len(digits) > 15 {
+			errs = append(errs, fmt.Sprintf("phone %q is not a valid phone number", person.Phone))
+		}
+	}
+	if raw := value("birthday"); raw != "" {
+		birthday, err := parseBirthday(raw)
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else {
+			person.Birthday = birthday
+		}
+	}
+
+	return person, errs
+}
+
+// minBirthdaySerial is the smallest spreadsheet serial date taken as a
+// birthday, 1910-12-13. Smaller numbers are more likely a bare year such as
+// "1999", which as a serial date would fall in 1905.
+const minBirthdaySerial = 4000
+
+func parseBirthday(raw string) (time.Time, error) {
+	for _, layout := range birthdayLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+	// Spreadsheets store dates as the number of days since 1899-12-30.
+	if serial, err := strconv.ParseFloat(raw, 64); err == nil && serial >= minBirthdaySerial && serial < 2958466 {
+		base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+		return base.AddDate(0, 0, int(serial)), nil
+	}
+	return time.Time{}, fmt.Errorf("birthday %q is not a valid date", raw)
+}
+
+type personsMatch int
+
+const (
+	personsDiffer personsMatch = iota
+	personsSame
+	personsUnsure
+)
+
+// comparePersons tells whether an imported person is the same as another one
+// found by phone or name. The same phone, or the same name and birthday, make
+// the same person. Persons with the same name are told apart by different
+// birthdays or phones; without those it cannot be told.
+func comparePersons(existing, imported models.Person) personsMatch {
+	phone, importedPhone := models.NormalizePhone(existing.Phone), models.NormalizePhone(imported.Phone)
+	if phone != "" && phone == importedPhone {
+		return personsSame
+	}
+	if !strings.EqualFold(strings.TrimSpace(existing.Name), strings.TrimSpace(imported.Name)) {
+		return personsDiffer
+	}
+	if !existing.Birthday.IsZero() && !imported.Birthday.IsZero() {
+		if existing.Birthday.UTC().Format("2006-01-02") == imported.Birthday.UTC().Format("2006-01-02") {
+			return personsSame
+		}
+		return personsDiffer
+	}
+	if phone != "" && importedPhone != "" {
+		return personsDiffer
+	}
+	return personsUnsure
+}
+
+// mergeImportedPerson fills the existing person with the non empty values of
+// the imported one.
+func mergeImportedPerson(existing, imported models.Person) models.Person {
+	merged := existing
+	if imported.Name != "" {
+		merged.Name = imported.Name
+	}
+	if !imported.Birthday.IsZero() {
+		merged.Birthday = imported.Birthday
+	}
+	if imported.Phone != "" {
+		merged.Phone = imported.Phone
+	}
+	if imported.Address != "" {
+		merged.Address = imported.Address
+	}
+	if imported.Fr != "" {
+		merged.Fr = imported.Fr
+	}
+	if imported.Degree != "" {
+		merged.Degree = imported.Degree
+	}
+	if imported.Email != "" {
+		merged.Email = imported.Email
+	}
+	if imported.Language != "" {
+		merged.Language = imported.Language
+	}
+	return merged
+}
+
+func isImportField(field string) bool {
+	for _, f := range importFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlankRow(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeImportRepo holds persons in memory for the import. Other methods are
+// not used by the import and panic.
+type fakeImportRepo struct {
+	repositories.PersonRepoInterface
+	persons []models.Person
+}
+
+func (r *fakeImportRepo) FindPersonsByPhoneOrName(ctx context.Context, phones []string, name string) ([]models.Person, error) {
+	found := []models.Person{}
+	for _, p := range r.persons {
+		match := strings.EqualFold(p.Name, name)
+		for _, phone := range phones {
+			match = match || p.Phone != "" && models.NormalizePhone(p.Phone) == models.NormalizePhone(phone)
+		}
+		if match {
+			found = append(found, p)
+		}
+	}
+	return found, nil
+}
+
+func (r *fakeImportRepo) CreatePerson(ctx context.Context, p models.Person) (*models.Person, error) {
+	p.ID = primitive.NewObjectID()
+	r.persons = append(r.persons, p)
+	return &p, nil
+}
+
+func (r *fakeImportRepo) UpdatePerson(ctx context.Context, id string, p models.Person) (*models.Person, error) {
+	for i := range r.persons {
+		if r.persons[i].ID.Hex() == id {
+			r.persons[i] = p
+			return &p, nil
+		}
+	}
+	panic("unknown person " + id)
+}
+
+func birthday(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestImportPersonsMatching(t *testing.T) {
+	existing := []models.Person{
+		{ID: primitive.NewObjectID(), Name: "Mina Girgis", Phone: "01001234567", Birthday: birthday("1999-10-11")},
+		{ID: primitive.NewObjectID(), Name: "Peter Samir"},
+	}
+	tests := []struct {
+		name    string
+		csv     string
+		want    ImportReport
+		updated string
+	}{
+		{
+			name:    "same phone updates",
+			csv:     "name,phone,address\nMina G.,+20 100 123 4567,Cairo\n",
+			want:    ImportReport{Mode: ImportModeUpsert, Total: 1, Updated: 1},
+			updated: "Cairo",
+		},
+		{
+			name:    "same name and birthday updates",
+			csv:     "name,phone,birthday,address\nmina girgis,01112223334,1999-10-11,Alex\n",
+			want:    ImportReport{Mode: ImportModeUpsert, Total: 1, Updated: 1},
+			updated: "Alex",
+		},
+		{
+			name: "same name, other phone is someone else",
+			csv:  "name,phone\nMina Girgis,01112223334\n",
+			want: ImportReport{Mode: ImportModeUpsert, Total: 1, Created: 1},
+		},
+		{
+			name: "same name, other birthday is someone else",
+			csv:  "name,birthday\nMina Girgis,2005-01-01\n",
+			want: ImportReport{Mode: ImportModeUpsert, Total: 1, Created: 1},
+		},
+		{
+			name: "same name only is a conflict",
+			csv:  "name,phone\nPeter Samir,01112223334\n",
+			want: ImportReport{Mode: ImportModeUpsert, Total: 1, Conflicts: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeImportRepo{persons: append([]models.Person{}, existing...)}
+			s := NewPersonService(repo, nil)
+			report, err := s.ImportPersons(context.Background(), csv.NewReader(strings.NewReader(tt.csv)), ImportOptions{Mode: ImportModeUpsert})
+			require.NoError(t, err)
+			rows := report.Rows
+			report.Rows = nil
+			assert.Equal(t, tt.want, *report)
+			assert.Len(t, rows, tt.want.Conflicts)
+			if tt.want.Conflicts > 0 {
+				assert.Equal(t, ImportRowConflict, rows[0].Status)
+				assert.Equal(t, existing[1].ID.Hex(), rows[0].DuplicateOf)
+			}
+			assert.Len(t, repo.persons, len(existing)+tt.want.Created)
+			if tt.updated != "" {
+				assert.Equal(t, tt.updated, repo.persons[0].Address)
+				assert.Equal(t, existing[0].ID, repo.persons[0].ID)
+			}
+		})
+	}
+}
+
+func TestImportPersonsReportsOnlyProblemRows(t *testing.T) {
+	repo := &fakeImportRepo{}
+	s := NewPersonService(repo, nil)
+	rows := "name,phone,birthday\n" +
+		"Mina Girgis,01001234567,\n" +
+		"Peter Samir,01112223334,\n" +
+		",01222333444,\n" +
+		"Mina Gerges,+201001234567,\n" +
+		"Marina Adel,,1999\n" +
+		"Mark,,\n" +
+		"mark,,\n" +
+		"Mina Girgis,01555666777,\n"
+	report, err := s.ImportPersons(context.Background(), csv.NewReader(strings.NewReader(rows)), ImportOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 8, report.Total)
+	assert.Equal(t, 4, report.Created)
+	assert.Equal(t, 2, report.Invalid)
+	assert.Equal(t, 2, report.Duplicates)
+	got := []int{}
+	for _, r := range report.Rows {
+		got = append(got, r.Row)
+	}
+	assert.Equal(t, []int{4, 5, 6, 8}, got)
+}
+
+func TestImportPersonsRejectsBadInput(t *testing.T) {
+	s := NewPersonService(&fakeImportRepo{}, nil)
+	tests := []struct {
+		name string
+		csv  string
+		opts ImportOptions
+	}{
+		{"unknown mode", "name\nMina\n", ImportOptions{Mode: "replace"}},
+		{"empty file", "", ImportOptions{}},
+		{"no name column", "phone\n0100\n", ImportOptions{}},
+		{"unknown field", "name\nMina\n", ImportOptions{Mapping: map[string]string{"height": "name"}}},
+		{"broken csv", "name\n\"Mina\n", ImportOptions{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.ImportPersons(context.Background(), csv.NewReader(strings.NewReader(tt.csv)), tt.opts)
+			var badReqErr *cerrors.BadRequestError
+			assert.True(t, errors.As(err, &badReqErr), "%v", err)
+		})
+	}
+}
+
+func TestParseBirthday(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{"1999-10-11", "1999-10-11", false},
+		{"11/10/1999", "1999-10-11", false},
+		{"1/2/2005", "2005-02-01", false},
+		{"36444", "1999-10-11", false},
+		{"36444.5", "1999-10-11", false},
+		{"1999", "", true},
+		{"12", "", true},
+		{"-5", "", true},
+		{"yesterday", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseBirthday(tt.raw)
+		if tt.wantErr {
+			assert.Error(t, err, tt.raw)
+			continue
+		}
+		require.NoError(t, err, tt.raw)
+		assert.Equal(t, tt.want, got.Format("2006-01-02"), tt.raw)
+	}
+}
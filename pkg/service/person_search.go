@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"html"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+)
+
+// personIndexTTL bounds how stale the search index may get when persons are
+// changed by another instance of the server.
+const personIndexTTL = time.Minute
+
+const DefaultSearchLimit = 20
+
+type PersonSearchResult struct {
+	Person     models.Person     `json:"person"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// personIndex is an in-memory search index over all persons. It is built
+// lazily on the first search and rebuilt after a write or once it is older
+// than personIndexTTL. generation counts the invalidations, so a rebuild that
+// overlapped a write is not kept.
+type personIndex struct {
+	mu         sync.Mutex
+	built      time.Time
+	generation int
+	entries    []personIndexEntry
+}
+
+type personIndexEntry struct {
+	person   models.Person
+	name     []string
+	skeleton []string
+	address  []string
+	fr       []string
+	phone    string
+}
+
+func newPersonIndex() *personIndex {
+	return &personIndex{}
+}
+
+func (idx *personIndex) invalidate() {
+	idx.mu.Lock()
+	idx.built = time.Time{}
+	idx.generation++
+	idx.mu.Unlock()
+}
+
+// snapshot returns the entries of the index, rebuilding them when they are
+// stale. Persons are read without holding the lock, so writes and other
+// searches are not held up by a rebuild; the new entries are swapped in
+// afterwards unless the index was invalidated in the meantime.
+func (idx *personIndex) snapshot(ctx context.Context, repo repositories.PersonRepoInterface) ([]personIndexEntry, error) {
+	idx.mu.Lock()
+	if !idx.built.IsZero() && time.Since(idx.built) < personIndexTTL {
+		entries := idx.entries
+		idx.mu.Unlock()
+		return entries, nil
+	}
+	generation := idx.generation
+	idx.mu.Unlock()
+
+	started := time.Now()
+	entries, err := buildPersonIndex(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.generation == generation {
+		idx.entries = entries
+		idx.built = started
+	}
+	return entries, nil
+}
+
+func buildPersonIndex(ctx context.Context, repo repositories.PersonRepoInterface) ([]personIndexEntry, error) {
+	entries := []personIndexEntry{}
+	err := repo.StreamPersons(ctx, repositories.PersonFilter{}, func(p models.Person) error {
+		name := strings.Fields(NormalizeName(p.Name))
+		skeleton := make([]string, len(name))
+		for i, token := range name {
+			skeleton[i] = nameSkeleton(token)
+		}
+		entries = append(entries, personIndexEntry{
+			person:   p,
+			name:     name,
+			skeleton: skeleton,
+			address:  strings.Fields(NormalizeName(p.Address)),
+			fr:       strings.Fields(NormalizeName(p.Fr)),
+			phone:    models.NormalizePhone(p.Phone),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// SearchPersons finds persons whose name, phone, address or confession
+// father match every term of q. Letter variants and diacritics are ignored,
+// names match across Arabic and Latin script, terms match as prefixes and
+// digit terms match anywhere in the phone number. Results are ranked with
+// name matches first.
+func (s *PersonService) SearchPersons(ctx context.Context, q string, limit int) ([]PersonSearchResult, error) {
+	terms := searchTerms(q)
+	results := []PersonSearchResult{}
+	if len(terms) == 0 {
+		return results, nil
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+
+	entries, err := s.index.snapshot(ctx, s.repo)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		score, matched := e.match(terms)
+		if score == 0 {
+			continue
+		}
+		results = append(results, PersonSearchResult{
+			Person:     e.person,
+			Score:      score,
+			Highlights: highlightPerson(e.person, matched),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Person.Name < results[j].Person.Name
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func searchTerms(q string) []string {
+	terms := []string{}
+	for _, field := range strings.Fields(q) {
+		if !hasLetter(field) && countDigits(field) >= 3 {
+			terms = append(terms, models.NormalizePhone(field))
+			continue
+		}
+		terms = append(terms, strings.Fields(NormalizeName(field))...)
+	}
+	return terms
+}
+
+// match scores every term against the entry and returns 0 when a term
+// matches nothing. matched holds, per field, the terms that matched it.
+func (e personIndexEntry) match(terms []string) (float64, map[string][]string) {
+	total := 0.0
+	matched := map[string][]string{}
+	for _, term := range terms {
+		best, field := 0.0, ""
+
+		if isDigits(term) {
+			if e.phone != "" && strings.Contains(e.phone, term) {
+				best, field = 2.5, "phone"
+				if strings.HasPrefix(e.phone, term) || strings.HasSuffix(e.phone, term) {
+					best = 3
+				}
+			}
+		} else {
+			if s := tokenScore(term, e.name, 3); s > best {
+				best, field = s, "name"
+			}
+			if skel := nameSkeleton(term); len(skel) >= 2 && isArabic(term) != e.nameIsArabic() {
+				if s := tokenScore(skel, e.skeleton, 2); s > best {
+					best, field = s, "name"
+				}
+			}
+			if s := tokenScore(term, e.fr, 1.5); s > best {
+				best, field = s, "fr"
+			}
+			if s := tokenScore(term, e.address, 1); s > best {
+				best, field = s, "address"
+			}
+		}
+
+		if best == 0 {
+			return 0, nil
+		}
+		total += best
+		matched[field] = append(matched[field], term)
+	}
+	return total, matched
+}
+
+func (e personIndexEntry) nameIsArabic() bool {
+	return len(e.name) > 0 && isArabic(e.name[0])
+}
+
+// tokenScore scores term against the tokens of a field: weight for an exact
+// token, 80% of it for a prefix and 50% for a close spelling. The Arabic
+// definite article is ignored so "اسكندريه" matches "الاسكندريه".
+func tokenScore(term string, tokens []string, weight float64) float64 {
+	best := 0.0
+	for _, token := range tokens {
+		if stripped := strings.TrimPrefix(token, "ال"); stripped != token && !strings.HasPrefix(term, "ال") && len([]rune(stripped)) > 1 {
+			token = stripped
+		}
+		switch {
+		case token == term:
+			return weight
+		case strings.HasPrefix(token, term):
+			best = max(best, 0.8*weight)
+		case len([]rune(term)) >= 4 && jaroWinkler(term, token) >= 0.9:
+			best = max(best, 0.5*weight)
+		}
+	}
+	return best
+}
+
+// highlightPerson wraps the words of each matched field in <em> tags. The
+// rest of the text is HTML escaped so highlights can be rendered as is.
+func highlightPerson(p models.Person, matched map[string][]string) map[string]string {
+	highlights := map[string]string{}
+	for field, terms := range matched {
+		switch field {
+		case "name":
+			highlights[field] = highlightWords(p.Name, terms)
+		case "address":
+			highlights[field] = highlightWords(p.Address, terms)
+		case "fr":
+			highlights[field] = highlightWords(p.Fr, terms)
+		case "phone":
+			phone := models.NormalizePhone(p.Phone)
+			for _, term := range terms {
+				phone = strings.Replace(phone, term, "<em>"+term+"</em>", 1)
+			}
+			highlights[field] = phone
+		}
+	}
+	return highlights
+}
+
+func highlightWords(text string, terms []string) string {
+	var b strings.Builder
+	word := []rune{}
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		w := string(word)
+		if wordMatches(w, terms) {
+			b.WriteString("<em>" + html.EscapeString(w) + "</em>")
+		} else {
+			b.WriteString(html.EscapeString(w))
+		}
+		word = word[:0]
+	}
+	for _, ch := range text {
+		if unicode.IsLetter(ch) || unicode.IsDigit(ch) || unicode.Is(unicode.Mn, ch) || ch == 'ـ' {
+			word = append(word, ch)
+			continue
+		}
+		flush()
+		b.WriteString(html.EscapeString(string(ch)))
+	}
+	flush()
+	return b.String()
+}
+
+func wordMatches(word string, terms []string) bool {
+	normalized := NormalizeName(word)
+	skeleton := nameSkeleton(normalized)
+	for _, term := range terms {
+		if tokenScore(term, []string{normalized}, 1) > 0 {
+			return true
+		}
+		if isArabic(term) != isArabic(normalized) && len(nameSkeleton(term)) >= 2 && tokenScore(nameSkeleton(term), []string{skeleton}, 1) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigits(s string) bool {
+	return s != "" && countDigits(s) == len([]rune(s))
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, ch := range s {
+		if unicode.IsDigit(ch) {
+			n++
+		}
+	}
+	return n
+}
+
+func hasLetter(s string) bool {
+	for _, ch := range s {
+		if unicode.IsLetter(ch) {
+			return true
+		}
+	}
+	return false
+}
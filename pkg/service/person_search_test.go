@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// slowPersonRepo streams persons only once release is closed, after telling
+// streaming that it started.
+type slowPersonRepo struct {
+	fakePersonRepo
+	streaming chan struct{}
+	release   chan struct{}
+	streams   int
+}
+
+func (r *slowPersonRepo) StreamPersons(ctx context.Context, filter repositories.PersonFilter, fn func(models.Person) error) error {
+	r.streams++
+	r.streaming <- struct{}{}
+	<-r.release
+	return r.fakePersonRepo.StreamPersons(ctx, filter, fn)
+}
+
+func TestPersonIndexRebuildsOutsideTheLock(t *testing.T) {
+	repo := &slowPersonRepo{
+		fakePersonRepo: fakePersonRepo{persons: []models.Person{{ID: primitive.NewObjectID(), Name: "Mina Girgis"}}},
+		streaming:      make(chan struct{}, 1),
+		release:        make(chan struct{}),
+	}
+	idx := newPersonIndex()
+
+	done := make(chan []personIndexEntry)
+	go func() {
+		entries, err := idx.snapshot(context.Background(), repo)
+		assert.NoError(t, err)
+		done <- entries
+	}()
+	<-repo.streaming
+
+	// A write while the index is being built neither waits for the build nor
+	// is lost: the build is used by its search but not kept.
+	invalidated := make(chan struct{})
+	go func() {
+		idx.invalidate()
+		close(invalidated)
+	}()
+	select {
+	case <-invalidated:
+	case <-time.After(time.Second):
+		t.Fatal("invalidate waited for the rebuild")
+	}
+	close(repo.release)
+	assert.Len(t, <-done, 1)
+
+	_, err := idx.snapshot(context.Background(), repo)
+	require.NoError(t, err)
+	<-repo.streaming
+	assert.Equal(t, 2, repo.streams, "rebuilt after the write")
+
+	_, err = idx.snapshot(context.Background(), repo)
+	require.NoError(t, err)
+	assert.Equal(t, 2, repo.streams, "kept once built")
+}
@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PointsBalance struct {
+	PersonID primitive.ObjectID   `json:"personId"`
+	Balance  int                  `json:"balance"`
+	Entries  []models.PointsEntry `json:"entries"`
+}
+
+type LeaderboardEntry struct {
+	Rank     int                `json:"rank"`
+	PersonID primitive.ObjectID `json:"personId"`
+	Name     string             `json:"name"`
+	Points   int                `json:"points"`
+}
+
+// PointsRequest is the body of a manual adjustment or a redemption.
+type PointsRequest struct {
+	Points int                `json:"points"`
+	Reason string             `json:"reason"`
+	ByID   primitive.ObjectID `json:"byId"`
+}
+
+type PointsService struct {
+	repo           repositories.PointsRepoInterface
+	serviceRepo    repositories.ServiceRepoInterface
+	assignmentRepo repositories.AssignmentRepoInterface
+	personRepo     repositories.PersonRepoInterface
+	groupRepo      repositories.GroupRepoInterface
+}
+
+func NewPointsService(repo repositories.PointsRepoInterface, serviceRepo repositories.ServiceRepoInterface, assignmentRepo repositories.AssignmentRepoInterface, personRepo repositories.PersonRepoInterface, groupRepo repositories.GroupRepoInterface) *PointsService {
+	return &PointsService{
+		repo:           repo,
+		serviceRepo:    serviceRepo,
+		assignmentRepo: assignmentRepo,
+		personRepo:     personRepo,
+		groupRepo:      groupRepo,
+	}
+}
+
+func (s *PointsService) GetRules(ctx context.Context) ([]models.PointsRule, error) {
+	return s.repo.GetPointsRules(ctx)
+}
+
+func (s *PointsService) GetRuleById(ctx context.Context, id string) (*models.PointsRule, error) {
+	return s.repo.GetPointsRuleById(ctx, id)
+}
+
+func (s *PointsService) CreateRule(ctx context.Context, rule models.PointsRule) (*models.PointsRule, error) {
+	if err := validatePointsRule("CreateRule", rule); err != nil {
+		return nil, err
+	}
+	return s.repo.CreatePointsRule(ctx, rule)
+}
+
+// UpdateRule changes a rule. Points already awarded keep their value until
+// the events they were earned on are evaluated again, see Recalculate.
+func (s *PointsService) UpdateRule(ctx context.Context, id string, rule models.PointsRule) (*models.PointsRule, error) {
+	if err := validatePointsRule("UpdateRule", rule); err != nil {
+		return nil, err
+	}
+	return s.repo.UpdatePointsRule(ctx, id, rule)
+}
+
+func (s *PointsService) DeleteRule(ctx context.Context, id string) error {
+	return s.repo.DeletePointsRule(ctx, id)
+}
+
+func validatePointsRule(method string, rule models.PointsRule) error {
+	if rule.Name == "" {
+		return cerrors.NewBadRequestError(method, "PointsService", errors.New("name is required"))
+	}
+	switch rule.Event {
+	case models.PointsOnAttendance, models.PointsOnTime, models.PointsOnSubmission, models.PointsOnTimeSubmission, models.PointsOnGrade:
+	default:
+		return cerrors.NewBadRequestError(method, "PointsService", fmt.Errorf("unknown event %q", rule.Event))
+	}
+	if rule.GraceMinutes < 0 || rule.MinPercent < 0 || rule.MinPercent > 100 {
+		return cerrors.NewBadRequestError(method, "PointsService", errors.New("graceMinutes and minPercent must be within range"))
+	}
+	return nil
+}
+
+// EvaluateAttendance awards the points the person earned by their
+// attendance at the service, and takes back points no longer earned, e.g.
+// after the record was deleted or marked absent.
+func (s *PointsService) EvaluateAttendance(ctx context.Context, serv *models.Service, personID primitive.ObjectID) error {
+	rules, err := s.repo.GetPointsRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	var record *models.AttendanceRecord
+	for i := range serv.AttendanceRecord {
+		if serv.AttendanceRecord[i].PersonID == personID {
+			record = &serv.AttendanceRecord[i]
+			break
+		}
+	}
+
+	entries := []models.PointsEntry{}
+	for _, rule := range rules {
+		if record == nil || !ruleApplies(rule, serv.GroupID) || !record.Attended() {
+			continue
+		}
+		earned := false
+		switch rule.Event {
+		case models.PointsOnAttendance:
+			earned = true
+		case models.PointsOnTime:
+			grace := time.Duration(rule.GraceMinutes) * time.Minute
+			earned = !serv.Date.IsZero() && !record.Time.IsZero() && !record.Time.After(serv.Date.Add(grace))
+		}
+		if earned {
+			entries = append(entries, ruleEntry(rule, serv.ID, personID, serv.GroupID, serv.Date))
+		}
+	}
+	return s.repo.SettleRuleEntries(ctx, serv.ID, personID, entries)
+}
+
+// EvaluateSubmission awards the points the person earned by their
+// submission to the assignment.
+func (s *PointsService) EvaluateSubmission(ctx context.Context, a *models.Assignment, personID primitive.ObjectID) error {
+	rules, err := s.repo.GetPointsRules(ctx)
+	if err != nil {
+		return err
+	}
+	var groupID primitive.ObjectID
+	if !a.ServiceID.IsZero() {
+		serv, err := s.serviceRepo.GetServiceById(ctx, a.ServiceID.Hex())
+		if err == nil {
+			groupID = serv.GroupID
+		}
+	}
+
+	sub := findSubmission(a, personID)
+	entries := []models.PointsEntry{}
+	for _, rule := range rules {
+		if sub == nil || !ruleApplies(rule, groupID) {
+			continue
+		}
+		earned := false
+		switch rule.Event {
+		case models.PointsOnSubmission:
+			earned = true
+		case models.PointsOnTimeSubmission:
+			earned = a.Deadline.IsZero() || !sub.Time.After(a.Deadline)
+		case models.PointsOnGrade:
+			earned = sub.State == models.SubmissionGraded && sub.Score != nil &&
+				(a.MaxScore <= 0 || *sub.Score/a.MaxScore*100 >= rule.MinPercent)
+		}
+		if earned {
+			entries = append(entries, ruleEntry(rule, a.ID, personID, groupID, sub.Time))
+		}
+	}
+	return s.repo.SettleRuleEntries(ctx, a.ID, personID, entries)
+}
+
+// Recalculate evaluates every attendance record of the services and every
+// submission to the assignments due between from and to again, so changed
+// rules apply to past events. It returns the number of events evaluated.
+func (s *PointsService) Recalculate(ctx context.Context, from, to time.Time) (int, error) {
+	count := 0
+	err := s.serviceRepo.StreamServices(ctx, repositories.ServiceFilter{From: from, To: to}, func(serv models.Service) error {
+		for _, ar := range serv.AttendanceRecord {
+			if err := s.EvaluateAttendance(ctx, &serv, ar.PersonID); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	err = s.assignmentRepo.StreamAssignments(ctx, repositories.AssignmentFilter{DeadlineFrom: from, DeadlineTo: to}, func(a models.Assignment) error {
+		for _, sub := range a.Submissions {
+			if err := s.EvaluateSubmission(ctx, &a, sub.PersonID); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+func ruleApplies(rule models.PointsRule, groupID primitive.ObjectID) bool {
+	return !rule.Disabled && (rule.GroupID.IsZero() || rule.GroupID == groupID)
+}
+
+func ruleEntry(rule models.PointsRule, sourceID, personID, groupID primitive.ObjectID, date time.Time) models.PointsEntry {
+	if date.IsZero() {
+		date = time.Now()
+	}
+	return models.PointsEntry{
+		Key:       rule.ID.Hex() + ":" + sourceID.Hex() + ":" + personID.Hex(),
+		PersonID:  personID,
+		Points:    rule.Points,
+		Kind:      models.PointsKindRule,
+		RuleID:    rule.ID,
+		SourceID:  sourceID,
+		GroupID:   groupID,
+		Reason:    rule.Name,
+		Date:      date,
+		CreatedAt: time.Now(),
+	}
+}
+
+// Adjust adds or removes points by hand. Only servants of one of the
+// person's groups may adjust points, and a reason is required.
+func (s *PointsService) Adjust(ctx context.Context, personID string, req PointsRequest) (*models.PointsEntry, error) {
+	pid, err := primitive.ObjectIDFromHex(personID)
+	if err != nil {
+		return nil, cerrors.NewInvalidIDError("Adjust", "PointsService", err)
+	}
+	if req.Points == 0 || req.Reason == "" {
+		return nil, cerrors.NewBadRequestError("Adjust", "PointsService", errors.New("points and reason are required"))
+	}
+	groupID, err := s.servantGroup(ctx, pid, req.ByID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return s.repo.AddEntry(ctx, models.PointsEntry{
+		PersonID:  pid,
+		Points:    req.Points,
+		Kind:      models.PointsKindAdjustment,
+		GroupID:   groupID,
+		Reason:    req.Reason,
+		ByID:      req.ByID,
+		Date:      now,
+		CreatedAt: now,
+	})
+}
+
+// Redeem spends req.Points of the person's balance on a reward, recorded in
+// the reason.
+func (s *PointsService) Redeem(ctx context.Context, personID string, req PointsRequest) (*models.PointsEntry, error) {
+	pid, err := primitive.ObjectIDFromHex(personID)
+	if err != nil {
+		return nil, cerrors.NewInvalidIDError("Redeem", "PointsService", err)
+	}
+	if req.Points <= 0 || req.Reason == "" {
+		return nil, cerrors.NewBadRequestError("Redeem", "PointsService", errors.New("a positive number of points and a reason are required"))
+	}
+	groupID, err := s.servantGroup(ctx, pid, req.ByID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return s.repo.RedeemPoints(ctx, models.PointsEntry{
+		PersonID:  pid,
+		Points:    -req.Points,
+		Kind:      models.PointsKindRedemption,
+		GroupID:   groupID,
+		Reason:    req.Reason,
+		ByID:      req.ByID,
+		Date:      now,
+		CreatedAt: now,
+	})
+}
+
+// servantGroup returns a group in which byID currently serves and personID
+// is currently a member.
+func (s *PointsService) servantGroup(ctx context.Context, personID, byID primitive.ObjectID) (primitive.ObjectID, error) {
+	if _, err := s.personRepo.GetPersonById(ctx, personID.Hex()); err != nil {
+		return primitive.NilObjectID, err
+	}
+	groups, err := s.groupRepo.GetGroupsByPerson(ctx, personID)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	now := time.Now()
+	for _, g := range groups {
+		for _, m := range g.Memberships {
+			if m.PersonID == byID && m.Role == models.GroupRoleServant && m.ActiveAt(now) {
+				return g.ID, nil
+			}
+		}
+	}
+	return primitive.NilObjectID, cerrors.NewBadRequestError("servantGroup", "PointsService", errors.New("byId is not a servant of the person's groups"))
+}
+
+// GetBalance returns the person's balance and their latest ledger entries.
+func (s *PointsService) GetBalance(ctx context.Context, personID string, limit int64) (*PointsBalance, error) {
+	pid, err := primitive.ObjectIDFromHex(personID)
+	if err != nil {
+		return nil, cerrors.NewInvalidIDError("GetBalance", "PointsService", err)
+	}
+	if _, err := s.personRepo.GetPersonById(ctx, personID); err != nil {
+		return nil, err
+	}
+	totals, err := s.repo.SumPoints(ctx, repositories.PointsFilter{PersonIDs: []primitive.ObjectID{pid}})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.repo.GetEntries(ctx, repositories.PointsFilter{PersonIDs: []primitive.ObjectID{pid}, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	return &PointsBalance{PersonID: pid, Balance: totals[pid], Entries: entries}, nil
+}
+
+// Leaderboard ranks the members of the group by the points they earned
+// between from and to. Redemptions do not lower a person's rank. Persons
+// with equal points share a rank.
+func (s *PointsService) Leaderboard(ctx context.Context, groupID string, from, to time.Time, limit int) ([]LeaderboardEntry, error) {
+	group, err := s.groupRepo.GetGroupById(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	at := to
+	if at.IsZero() || at.After(time.Now()) {
+		at = time.Now()
+	}
+	members, err := s.personRepo.GetPersonsByIds(ctx, groupRosterIDs(group, at, models.GroupRoleMember))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(members))
+	for _, p := range members {
+		ids = append(ids, p.ID)
+	}
+	totals, err := s.repo.SumPoints(ctx, repositories.PointsFilter{
+		PersonIDs: ids,
+		Kinds:     []string{models.PointsKindRule, models.PointsKindAdjustment},
+		From:      from,
+		To:        to,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	board := make([]LeaderboardEntry, 0, len(members))
+	for _, p := range members {
+		board = append(board, LeaderboardEntry{PersonID: p.ID, Name: p.Name, Points: totals[p.ID]})
+	}
+	sort.SliceStable(board, func(i, j int) bool {
+		if board[i].Points != board[j].Points {
+			return board[i].Points > board[j].Points
+		}
+		return board[i].Name < board[j].Name
+	})
+	for i := range board {
+		board[i].Rank = i + 1
+		if i > 0 && board[i].Points == board[i-1].Points {
+			board[i].Rank = board[i-1].Rank
+		}
+	}
+	if limit > 0 && len(board) > limit {
+		board = board[:limit]
+	}
+	return board, nil
+}
+
+// PeriodRange returns the range of the current week, month or year, or an
+// unbounded range for "all". Weeks start on Sunday like the service week.
+func PeriodRange(period string, now time.Time) (time.Time, time.Time, error) {
+	day := truncateDay(now)
+	switch period {
+	case "", "all":
+		return time.Time{}, time.Time{}, nil
+	case "week":
+		from := day.AddDate(0, 0, -int(day.Weekday()))
+		return from, from.AddDate(0, 0, 7).Add(-time.Nanosecond), nil
+	case "month":
+		from := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+		return from, from.AddDate(0, 1, 0).Add(-time.Nanosecond), nil
+	case "year":
+		from := time.Date(day.Year(), 1, 1, 0, 0, 0, 0, day.Location())
+		return from, from.AddDate(1, 0, 0).Add(-time.Nanosecond), nil
+	default:
+		return time.Time{}, time.Time{}, cerrors.NewBadRequestError("PeriodRange", "PointsService", fmt.Errorf("unknown period %q", period))
+	}
+}
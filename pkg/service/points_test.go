@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakePointsRepo serves rules and records the entries settled for an event.
+// Other methods are not used by the rule evaluation and panic.
+type fakePointsRepo struct {
+	repositories.PointsRepoInterface
+	rules   []models.PointsRule
+	settled []models.PointsEntry
+}
+
+func (r *fakePointsRepo) GetPointsRules(ctx context.Context) ([]models.PointsRule, error) {
+	return r.rules, nil
+}
+
+func (r *fakePointsRepo) SettleRuleEntries(ctx context.Context, sourceID, personID primitive.ObjectID, entries []models.PointsEntry) error {
+	r.settled = entries
+	return nil
+}
+
+func earnedRules(entries []models.PointsEntry) []string {
+	got := []string{}
+	for _, e := range entries {
+		got = append(got, e.Reason)
+	}
+	return got
+}
+
+func TestValidatePointsRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    models.PointsRule
+		wantErr bool
+	}{
+		{"attendance", models.PointsRule{Name: "Came", Event: models.PointsOnAttendance, Points: 5}, false},
+		{"grade", models.PointsRule{Name: "Good grade", Event: models.PointsOnGrade, MinPercent: 80}, false},
+		{"no name", models.PointsRule{Event: models.PointsOnAttendance}, true},
+		{"unknown event", models.PointsRule{Name: "x", Event: "birthday"}, true},
+		{"negative grace", models.PointsRule{Name: "x", Event: models.PointsOnTime, GraceMinutes: -1}, true},
+		{"percent over 100", models.PointsRule{Name: "x", Event: models.PointsOnGrade, MinPercent: 101}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePointsRule("CreateRule", tt.rule)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRuleApplies(t *testing.T) {
+	group, other := primitive.NewObjectID(), primitive.NewObjectID()
+	tests := []struct {
+		name    string
+		rule    models.PointsRule
+		groupID primitive.ObjectID
+		want    bool
+	}{
+		{"every group", models.PointsRule{}, group, true},
+		{"no group", models.PointsRule{}, primitive.NilObjectID, true},
+		{"same group", models.PointsRule{GroupID: group}, group, true},
+		{"other group", models.PointsRule{GroupID: group}, other, false},
+		{"rule of a group, event without", models.PointsRule{GroupID: group}, primitive.NilObjectID, false},
+		{"disabled", models.PointsRule{Disabled: true}, group, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ruleApplies(tt.rule, tt.groupID), tt.name)
+	}
+}
+
+func TestRuleEntryKey(t *testing.T) {
+	rule := models.PointsRule{ID: primitive.NewObjectID(), Name: "Came", Points: 5}
+	source, person, group := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	date := time.Date(2024, 3, 1, 19, 0, 0, 0, time.UTC)
+
+	e := ruleEntry(rule, source, person, group, date)
+	assert.Equal(t, rule.ID.Hex()+":"+source.Hex()+":"+person.Hex(), e.Key)
+	assert.Equal(t, models.PointsKindRule, e.Kind)
+	assert.Equal(t, 5, e.Points)
+	assert.Equal(t, date, e.Date)
+	assert.Equal(t, e.Key, ruleEntry(rule, source, person, primitive.NilObjectID, time.Time{}).Key)
+	assert.NotEqual(t, e.Key, ruleEntry(rule, source, primitive.NewObjectID(), group, date).Key)
+	assert.False(t, ruleEntry(rule, source, person, group, time.Time{}).Date.IsZero())
+}
+
+func TestEvaluateAttendance(t *testing.T) {
+	group := primitive.NewObjectID()
+	person := primitive.NewObjectID()
+	start := time.Date(2024, 3, 1, 19, 0, 0, 0, time.UTC)
+	rules := []models.PointsRule{
+		{ID: primitive.NewObjectID(), Name: "attended", Event: models.PointsOnAttendance, Points: 5},
+		{ID: primitive.NewObjectID(), Name: "on time", Event: models.PointsOnTime, Points: 2, GraceMinutes: 10},
+		{ID: primitive.NewObjectID(), Name: "other group", Event: models.PointsOnAttendance, Points: 1, GroupID: primitive.NewObjectID()},
+		{ID: primitive.NewObjectID(), Name: "disabled", Event: models.PointsOnAttendance, Points: 1, Disabled: true},
+		{ID: primitive.NewObjectID(), Name: "submitted", Event: models.PointsOnSubmission, Points: 1},
+	}
+
+	tests := []struct {
+		name    string
+		records []models.AttendanceRecord
+		want    []string
+	}{
+		{"on time", []models.AttendanceRecord{{PersonID: person, Time: start.Add(5 * time.Minute)}}, []string{"attended", "on time"}},
+		{"at the end of the grace period", []models.AttendanceRecord{{PersonID: person, Time: start.Add(10 * time.Minute)}}, []string{"attended", "on time"}},
+		{"late", []models.AttendanceRecord{{PersonID: person, Time: start.Add(11 * time.Minute)}}, []string{"attended"}},
+		{"no check-in time", []models.AttendanceRecord{{PersonID: person}}, []string{"attended"}},
+		{"absent", []models.AttendanceRecord{{PersonID: person, Time: start, Status: " Absent "}}, []string{}},
+		{"no record", []models.AttendanceRecord{{PersonID: primitive.NewObjectID(), Time: start}}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakePointsRepo{rules: rules}
+			s := NewPointsService(repo, nil, nil, nil, nil)
+			serv := &models.Service{ID: primitive.NewObjectID(), Date: start, GroupID: group, AttendanceRecord: tt.records}
+			require.NoError(t, s.EvaluateAttendance(context.Background(), serv, person))
+			assert.Equal(t, tt.want, earnedRules(repo.settled))
+		})
+	}
+}
+
+func TestEvaluateSubmission(t *testing.T) {
+	person := primitive.NewObjectID()
+	deadline := time.Date(2024, 3, 8, 23, 59, 0, 0, time.UTC)
+	rules := []models.PointsRule{
+		{ID: primitive.NewObjectID(), Name: "submitted", Event: models.PointsOnSubmission, Points: 1},
+		{ID: primitive.NewObjectID(), Name: "in time", Event: models.PointsOnTimeSubmission, Points: 2},
+		{ID: primitive.NewObjectID(), Name: "good grade", Event: models.PointsOnGrade, Points: 3, MinPercent: 80},
+		{ID: primitive.NewObjectID(), Name: "attended", Event: models.PointsOnAttendance, Points: 5},
+	}
+	score := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name       string
+		deadline   time.Time
+		maxScore   float64
+		submission *models.AssignmentSubmission
+		want       []string
+	}{
+		{"in time", deadline, 10, &models.AssignmentSubmission{PersonID: person, Time: deadline}, []string{"submitted", "in time"}},
+		{"late", deadline, 10, &models.AssignmentSubmission{PersonID: person, Time: deadline.Add(time.Minute)}, []string{"submitted"}},
+		{"no deadline", time.Time{}, 10, &models.AssignmentSubmission{PersonID: person, Time: deadline}, []string{"submitted", "in time"}},
+		{"good grade", deadline, 10, &models.AssignmentSubmission{PersonID: person, Time: deadline, State: models.SubmissionGraded, Score: score(8)}, []string{"submitted", "in time", "good grade"}},
+		{"low grade", deadline, 10, &models.AssignmentSubmission{PersonID: person, Time: deadline, State: models.SubmissionGraded, Score: score(7.9)}, []string{"submitted", "in time"}},
+		{"score not graded yet", deadline, 10, &models.AssignmentSubmission{PersonID: person, Time: deadline, Score: score(10)}, []string{"submitted", "in time"}},
+		{"graded without a maximum", deadline, 0, &models.AssignmentSubmission{PersonID: person, Time: deadline, State: models.SubmissionGraded, Score: score(1)}, []string{"submitted", "in time", "good grade"}},
+		{"no submission", deadline, 10, nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakePointsRepo{rules: rules}
+			s := NewPointsService(repo, nil, nil, nil, nil)
+			a := &models.Assignment{ID: primitive.NewObjectID(), Deadline: tt.deadline, MaxScore: tt.maxScore}
+			if tt.submission != nil {
+				a.Submissions = []models.AssignmentSubmission{*tt.submission}
+			}
+			require.NoError(t, s.EvaluateSubmission(context.Background(), a, person))
+			assert.Equal(t, tt.want, earnedRules(repo.settled))
+		})
+	}
+}
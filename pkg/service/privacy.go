@@ -0,0 +1,335 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PrivacyService answers privacy requests about a person: exporting
+// everything stored about them and erasing it. Both are done by priests,
+// since they reach notes of every visibility.
+type PrivacyService struct {
+	personRepo       repositories.PersonRepoInterface
+	serviceRepo      repositories.ServiceRepoInterface
+	assignmentRepo   repositories.AssignmentRepoInterface
+	quizRepo         repositories.QuizRepoInterface
+	pointsRepo       repositories.PointsRepoInterface
+	notificationRepo repositories.NotificationRepoInterface
+	fatherRepo       repositories.FatherRepoInterface
+	groupRepo        repositories.GroupRepoInterface
+	householdRepo    repositories.HouseholdRepoInterface
+	noteRepo         repositories.NoteRepoInterface
+	attachments      *AttachmentService
+	persons          *PersonService
+}
+
+func NewPrivacyService(personRepo repositories.PersonRepoInterface, serviceRepo repositories.ServiceRepoInterface, assignmentRepo repositories.AssignmentRepoInterface, quizRepo repositories.QuizRepoInterface, pointsRepo repositories.PointsRepoInterface, notificationRepo repositories.NotificationRepoInterface, fatherRepo repositories.FatherRepoInterface, groupRepo repositories.GroupRepoInterface, householdRepo repositories.HouseholdRepoInterface, noteRepo repositories.NoteRepoInterface, attachments *AttachmentService, persons *PersonService) *PrivacyService {
+	return &PrivacyService{
+		personRepo:       personRepo,
+		serviceRepo:      serviceRepo,
+		assignmentRepo:   assignmentRepo,
+		quizRepo:         quizRepo,
+		pointsRepo:       pointsRepo,
+		notificationRepo: notificationRepo,
+		fatherRepo:       fatherRepo,
+		groupRepo:        groupRepo,
+		householdRepo:    householdRepo,
+		noteRepo:         noteRepo,
+		attachments:      attachments,
+		persons:          persons,
+	}
+}
+
+type exportAttendance struct {
+	ServiceID primitive.ObjectID `json:"serviceId"`
+	Date      time.Time          `json:"date"`
+	Subject   string             `json:"subject"`
+	GroupID   primitive.ObjectID `json:"groupId,omitempty"`
+	Time      time.Time          `json:"time"`
+	Status    string             `json:"status"`
+}
+
+type exportSubmission struct {
+	AssignmentID primitive.ObjectID          `json:"assignmentId"`
+	Title        string                      `json:"title"`
+	Deadline     time.Time                   `json:"deadline"`
+	Submission   models.AssignmentSubmission `json:"submission"`
+}
+
+type exportQuizSubmission struct {
+	QuizID     primitive.ObjectID    `json:"quizId"`
+	Title      string                `json:"title"`
+	Submission models.QuizSubmission `json:"submission"`
+}
+
+type exportMembership struct {
+	GroupID   primitive.ObjectID `json:"groupId"`
+	GroupName string             `json:"groupName"`
+	models.GroupMembership
+}
+
+// exportFile is a JSON file of the archive.
+type exportFile struct {
+	name string
+	data interface{}
+}
+
+type exportManifest struct {
+	PersonID   primitive.ObjectID `json:"personId"`
+	ExportedAt time.Time          `json:"exportedAt"`
+	ExportedBy primitive.ObjectID `json:"exportedBy"`
+	Files      []string           `json:"files"`
+}
+
+// ExportPersonData writes a ZIP of everything stored about the person to w:
+// their record, attendance, submissions, points, notifications, confessions,
+// groups, household, notes with who read them, and the files they
+// submitted. The notes exported are recorded as read by the reader. Nothing
+// is written to w when an error is returned before the archive is started.
+func (s *PrivacyService) ExportPersonData(ctx context.Context, id, reader string, w io.Writer, now time.Time) error {
+	readerID, err := s.priest(ctx, "ExportPersonData", reader)
+	if err != nil {
+		return err
+	}
+	person, err := s.personRepo.GetPersonById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	files := []exportFile{}
+	add := func(name string, data interface{}) {
+		files = append(files, exportFile{name, data})
+	}
+	add("person.json", person)
+
+	services, err := s.serviceRepo.GetServicesByAttendee(ctx, person.ID)
+	if err != nil {
+		return err
+	}
+	attendance := []exportAttendance{}
+	for _, serv := range services {
+		for _, ar := range serv.AttendanceRecord {
+			if ar.PersonID == person.ID {
+				attendance = append(attendance, exportAttendance{ServiceID: serv.ID, Date: serv.Date, Subject: serv.Subject, GroupID: serv.GroupID, Time: ar.Time, Status: ar.Status})
+			}
+		}
+	}
+	add("attendance.json", attendance)
+
+	assignments, err := s.assignmentRepo.GetAssignmentsBySubmitter(ctx, person.ID)
+	if err != nil {
+		return err
+	}
+	submissions := []exportSubmission{}
+	for i := range assignments {
+		if sub := findSubmission(&assignments[i], person.ID); sub != nil {
+			submissions = append(submissions, exportSubmission{AssignmentID: assignments[i].ID, Title: assignments[i].Title, Deadline: assignments[i].Deadline, Submission: *sub})
+		}
+	}
+	add("submissions.json", submissions)
+
+	quizzes, err := s.quizRepo.GetQuizzes(ctx, repositories.QuizFilter{PersonID: person.ID})
+	if err != nil {
+		return err
+	}
+	quizSubmissions := []exportQuizSubmission{}
+	for _, quiz := range quizzes {
+		for _, sub := range quiz.Submissions {
+			if sub.PersonID == person.ID {
+				quizSubmissions = append(quizSubmissions, exportQuizSubmission{QuizID: quiz.ID, Title: quiz.Title, Submission: sub})
+			}
+		}
+	}
+	add("quiz-submissions.json", quizSubmissions)
+
+	points, err := s.pointsRepo.GetEntries(ctx, repositories.PointsFilter{PersonIDs: []primitive.ObjectID{person.ID}})
+	if err != nil {
+		return err
+	}
+	add("points.json", points)
+
+	notifications, err := s.notificationRepo.GetNotifications(ctx, repositories.NotificationFilter{PersonID: person.ID})
+	if err != nil {
+		return err
+	}
+	add("notifications.json", notifications)
+
+	confessions, err := s.fatherRepo.GetConfessionsByPerson(ctx, person.ID)
+	if err != nil {
+		return err
+	}
+	add("confessions.json", confessions)
+
+	groups, err := s.groupRepo.GetGroupsByPerson(ctx, person.ID)
+	if err != nil {
+		return err
+	}
+	memberships := []exportMembership{}
+	for _, g := range groups {
+		for _, m := range g.Memberships {
+			if m.PersonID == person.ID {
+				memberships = append(memberships, exportMembership{GroupID: g.ID, GroupName: g.Name, GroupMembership: m})
+			}
+		}
+	}
+	add("groups.json", memberships)
+
+	household, err := s.householdRepo.GetHouseholdByMember(ctx, person.ID)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+	add("household.json", household)
+	relationships, err := s.householdRepo.GetRelationshipsByPerson(ctx, person.ID)
+	if err != nil {
+		return err
+	}
+	add("relationships.json", relationships)
+
+	notes, err := s.noteRepo.GetNotesByPerson(ctx, person.ID)
+	if err != nil {
+		return err
+	}
+	add("notes.json", notes)
+	noteReads, err := s.noteRepo.GetNoteReads(ctx, repositories.NoteReadFilter{PersonID: person.ID})
+	if err != nil {
+		return err
+	}
+	add("audit/note-reads.json", noteReads)
+	readsBy, err := s.noteRepo.GetNoteReads(ctx, repositories.NoteReadFilter{ReaderID: person.ID})
+	if err != nil {
+		return err
+	}
+	add("audit/reads-by-person.json", readsBy)
+
+	attachments, err := s.attachments.GetPersonAttachments(ctx, person.ID)
+	if err != nil {
+		return err
+	}
+	add("attachments.json", attachments)
+
+	reads := make([]models.NoteRead, len(notes))
+	for i, note := range notes {
+		reads[i] = models.NoteRead{NoteID: note.ID, PersonID: person.ID, ReaderID: readerID, ReadAt: now}
+	}
+	if err := s.noteRepo.AddNoteReads(ctx, reads); err != nil {
+		return err
+	}
+
+	manifest := exportManifest{PersonID: person.ID, ExportedAt: now, ExportedBy: readerID, Files: []string{}}
+	for _, f := range files {
+		manifest.Files = append(manifest.Files, f.name)
+	}
+	for _, a := range attachments {
+		manifest.Files = append(manifest.Files, attachmentPath(a))
+	}
+
+	zw := zip.NewWriter(w)
+	if err := writeZipJSON(zw, "manifest.json", manifest, now); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := writeZipJSON(zw, f.name, f.data, now); err != nil {
+			return err
+		}
+	}
+	for _, a := range attachments {
+		if err := s.writeAttachment(ctx, zw, a); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ErasePerson anonymizes the person: their record keeps only its ID and
+// degree, and their answers, notes, notifications, confessions, family links
+// and submitted files are removed. Attendance, scores, points and group
+// memberships stay, so counts and totals do not change. Files are deleted
+// only once the rest is erased, so a failed erasure leaves them in place;
+// erasing an erased person again deletes the files left behind.
+func (s *PrivacyService) ErasePerson(ctx context.Context, id, reader string, now time.Time) (*models.Person, error) {
+	if _, err := s.priest(ctx, "ErasePerson", reader); err != nil {
+		return nil, err
+	}
+	person, err := s.personRepo.GetPersonById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if person.ErasedAt.IsZero() {
+		erased := models.Person{
+			ID:       person.ID,
+			Name:     models.ErasedPersonName,
+			Degree:   person.Degree,
+			ErasedAt: now,
+		}
+		if err := s.personRepo.ErasePerson(ctx, erased); err != nil {
+			return nil, err
+		}
+		s.persons.InvalidateSearchIndex()
+		person = &erased
+	}
+	if err := s.attachments.DeleteByPerson(ctx, person.ID); err != nil {
+		return nil, err
+	}
+	return person, nil
+}
+
+// priest checks that the reader is a priest and returns their ID.
+func (s *PrivacyService) priest(ctx context.Context, method, reader string) (primitive.ObjectID, error) {
+	oid, err := primitive.ObjectIDFromHex(reader)
+	if err != nil {
+		return oid, cerrors.NewBadRequestError(method, "PrivacyService", errors.New("a valid reader id is required"))
+	}
+	ok, err := s.fatherRepo.IsPriest(ctx, oid)
+	if err != nil {
+		return oid, err
+	}
+	if !ok {
+		return oid, cerrors.NewForbiddenError(method, "PrivacyService", errors.New("only priests can handle privacy requests"))
+	}
+	return oid, nil
+}
+
+func (s *PrivacyService) writeAttachment(ctx context.Context, zw *zip.Writer, attachment models.Attachment) error {
+	_, rc, err := s.attachments.OpenAttachment(ctx, attachment.ID.Hex())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: attachmentPath(attachment), Method: zip.Deflate, Modified: attachment.UploadedAt})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, rc)
+	return err
+}
+
+// attachmentPath names the attachment's file in the archive. The ID keeps
+// files with the same name apart.
+func attachmentPath(attachment models.Attachment) string {
+	return fmt.Sprintf("attachments/%s-%s", attachment.ID.Hex(), path.Base("/"+attachment.Filename))
+}
+
+func writeZipJSON(zw *zip.Writer, name string, data interface{}, now time.Time) error {
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(fw)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
@@ -0,0 +1,449 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// QuizResultColumns are the fixed columns of a quiz results export. They are
+// followed by one column per question, named q1, q2 and so on, holding the
+// person's answer.
+var QuizResultColumns = []string{"quizId", "personId", "name", "submissionTime", "score", "maxScore", "percent"}
+
+type QuestionStats struct {
+	QuestionID  primitive.ObjectID `json:"questionId"`
+	Text        string             `json:"text"`
+	Type        string             `json:"type"`
+	Answered    int                `json:"answered"`
+	Correct     int                `json:"correct"`
+	CorrectRate float64            `json:"correctRate"`
+	// Answers counts how often each answer was given. Short answers are
+	// counted in their normalized form.
+	Answers map[string]int `json:"answers"`
+}
+
+type QuizStats struct {
+	QuizID      primitive.ObjectID `json:"quizId"`
+	Title       string             `json:"title"`
+	MaxScore    float64            `json:"maxScore"`
+	Submissions int                `json:"submissions"`
+	Average     float64            `json:"average"`
+	Median      float64            `json:"median"`
+	Min         float64            `json:"min"`
+	Max         float64            `json:"max"`
+	Questions   []QuestionStats    `json:"questions"`
+}
+
+// QuizReceipt is what a person gets back for submitting a quiz. The scored
+// Result is withheld until the quiz closes, so answers cannot be tried one by
+// one until they are marked correct.
+type QuizReceipt struct {
+	Time         time.Time              `json:"time"`
+	Attempts     int                    `json:"attempts"`
+	AttemptsLeft int                    `json:"attemptsLeft"`
+	Result       *models.QuizSubmission `json:"result,omitempty"`
+}
+
+type QuizService struct {
+	repo           repositories.QuizRepoInterface
+	serviceRepo    repositories.ServiceRepoInterface
+	assignmentRepo repositories.AssignmentRepoInterface
+	personRepo     repositories.PersonRepoInterface
+}
+
+func NewQuizService(repo repositories.QuizRepoInterface, serviceRepo repositories.ServiceRepoInterface, assignmentRepo repositories.AssignmentRepoInterface, personRepo repositories.PersonRepoInterface) *QuizService {
+	return &QuizService{
+		repo:           repo,
+		serviceRepo:    serviceRepo,
+		assignmentRepo: assignmentRepo,
+		personRepo:     personRepo,
+	}
+}
+
+func (s *QuizService) GetQuizzes(ctx context.Context, filter repositories.QuizFilter) ([]models.Quiz, error) {
+	return s.repo.GetQuizzes(ctx, filter)
+}
+
+func (s *QuizService) GetQuizById(ctx context.Context, id string) (*models.Quiz, error) {
+	return s.repo.GetQuizById(ctx, id)
+}
+
+// GetQuizPaper returns the quiz as shown to persons taking it, without the
+// correct answers and the submissions of others.
+func (s *QuizService) GetQuizPaper(ctx context.Context, id string) (*models.Quiz, error) {
+	quiz, err := s.repo.GetQuizById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	quiz.Submissions = nil
+	for i := range quiz.Questions {
+		quiz.Questions[i].Correct = nil
+	}
+	return quiz, nil
+}
+
+func (s *QuizService) CreateQuiz(ctx context.Context, quiz models.Quiz) (*models.Quiz, error) {
+	if err := s.validateQuiz(ctx, "CreateQuiz", &quiz); err != nil {
+		return nil, err
+	}
+	quiz.Submissions = nil
+	return s.repo.CreateQuiz(ctx, quiz)
+}
+
+// UpdateQuiz replaces the quiz's questions. Existing submissions are scored
+// again against the new questions.
+func (s *QuizService) UpdateQuiz(ctx context.Context, id string, quiz models.Quiz) (*models.Quiz, error) {
+	existing, err := s.repo.GetQuizById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateQuiz(ctx, "UpdateQuiz", &quiz); err != nil {
+		return nil, err
+	}
+	q, err := s.repo.UpdateQuiz(ctx, id, quiz)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range existing.Submissions {
+		if q, err = s.repo.SetQuizSubmission(ctx, q.ID, scoreQuiz(q, sub)); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+func (s *QuizService) DeleteQuiz(ctx context.Context, id string) error {
+	return s.repo.DeleteQuiz(ctx, id)
+}
+
+// validateQuiz checks the quiz's parent and questions and gives new questions
+// an id so answers can refer to them.
+func (s *QuizService) validateQuiz(ctx context.Context, method string, quiz *models.Quiz) error {
+	if quiz.Title == "" {
+		return cerrors.NewBadRequestError(method, "QuizService", errors.New("title is required"))
+	}
+	if quiz.ServiceID.IsZero() && quiz.AssignmentID.IsZero() {
+		return cerrors.NewBadRequestError(method, "QuizService", errors.New("a quiz needs a serviceId or an assignmentId"))
+	}
+	if !quiz.ServiceID.IsZero() {
+		if _, err := s.serviceRepo.GetServiceById(ctx, quiz.ServiceID.Hex()); err != nil {
+			return err
+		}
+	}
+	if !quiz.AssignmentID.IsZero() {
+		if _, err := s.assignmentRepo.GetAssignmentById(ctx, quiz.AssignmentID.Hex()); err != nil {
+			return err
+		}
+	}
+	if len(quiz.Questions) == 0 {
+		return cerrors.NewBadRequestError(method, "QuizService", errors.New("a quiz needs at least one question"))
+	}
+	if quiz.MaxAttempts < 0 {
+		return cerrors.NewBadRequestError(method, "QuizService", errors.New("maxAttempts must not be negative"))
+	}
+
+	seen := map[primitive.ObjectID]bool{}
+	for i := range quiz.Questions {
+		q := &quiz.Questions[i]
+		if q.ID.IsZero() || seen[q.ID] {
+			q.ID = primitive.NewObjectID()
+		}
+		seen[q.ID] = true
+		if q.Points == 0 {
+			q.Points = 1
+		}
+		if err := validateQuestion(q); err != nil {
+			return cerrors.NewBadRequestError(method, "QuizService", fmt.Errorf("question %d: %w", i+1, err))
+		}
+	}
+	return nil
+}
+
+func validateQuestion(q *models.QuizQuestion) error {
+	if strings.TrimSpace(q.Text) == "" {
+		return errors.New("text is required")
+	}
+	if q.Points < 0 {
+		return errors.New("points must not be negative")
+	}
+	switch q.Type {
+	case models.QuestionMultipleChoice:
+		if len(q.Choices) < 2 {
+			return errors.New("a multiple choice question needs at least two choices")
+		}
+		if len(q.Correct) == 0 {
+			return errors.New("no correct choice given")
+		}
+		for _, c := range q.Correct {
+			if !containsString(q.Choices, c) {
+				return fmt.Errorf("correct answer %q is not one of the choices", c)
+			}
+		}
+	case models.QuestionTrueFalse:
+		q.Choices = nil
+		if len(q.Correct) != 1 {
+			return errors.New("a true/false question needs exactly one correct answer")
+		}
+		v, err := strconv.ParseBool(strings.TrimSpace(q.Correct[0]))
+		if err != nil {
+			return fmt.Errorf("correct answer %q is not true or false", q.Correct[0])
+		}
+		q.Correct = []string{strconv.FormatBool(v)}
+	case models.QuestionShortAnswer:
+		q.Choices = nil
+		if len(q.Correct) == 0 {
+			return errors.New("no accepted answer given")
+		}
+	default:
+		return fmt.Errorf("unknown question type %q", q.Type)
+	}
+	return nil
+}
+
+// SubmitQuiz scores the person's answers and records them at now, replacing
+// an earlier submission. Answers to unknown questions are rejected;
+// unanswered questions score zero. A person gets the quiz's MaxAttempts, or
+// models.DefaultQuizAttempts, and sees the result once the quiz closes, or
+// right away when it never closes.
+func (s *QuizService) SubmitQuiz(ctx context.Context, quizID string, submission models.QuizSubmission, now time.Time) (*QuizReceipt, error) {
+	quiz, err := s.repo.GetQuizById(ctx, quizID)
+	if err != nil {
+		return nil, err
+	}
+	submission.Time = now
+	if !quiz.ClosesAt.IsZero() && now.After(quiz.ClosesAt) {
+		return nil, cerrors.NewBadRequestError("SubmitQuiz", "QuizService", errors.New("quiz is closed"))
+	}
+	if _, err := s.personRepo.GetPersonById(ctx, submission.PersonID.Hex()); err != nil {
+		return nil, err
+	}
+	var previous *models.QuizSubmission
+	for i := range quiz.Submissions {
+		if quiz.Submissions[i].PersonID == submission.PersonID {
+			previous = &quiz.Submissions[i]
+		}
+	}
+	submission.Attempts = 1
+	if previous != nil {
+		// Submissions from before attempts were counted count as one.
+		submission.Attempts = max(previous.Attempts, 1) + 1
+	}
+	maxAttempts := quizAttempts(quiz)
+	if submission.Attempts > maxAttempts {
+		return nil, cerrors.NewBadRequestError("SubmitQuiz", "QuizService", fmt.Errorf("the quiz can be submitted %d times", maxAttempts))
+	}
+
+	answered := map[primitive.ObjectID]bool{}
+	for _, a := range submission.Answers {
+		if findQuestion(quiz, a.QuestionID) == nil {
+			return nil, cerrors.NewBadRequestError("SubmitQuiz", "QuizService", fmt.Errorf("unknown question %v", a.QuestionID.Hex()))
+		}
+		if answered[a.QuestionID] {
+			return nil, cerrors.NewBadRequestError("SubmitQuiz", "QuizService", fmt.Errorf("question %v answered twice", a.QuestionID.Hex()))
+		}
+		answered[a.QuestionID] = true
+	}
+
+	scored := scoreQuiz(quiz, submission)
+	err = s.repo.ReplaceQuizSubmission(ctx, quiz.ID, previous, scored)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, cerrors.NewBadRequestError("SubmitQuiz", "QuizService", errors.New("the quiz was submitted again meanwhile"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	receipt := &QuizReceipt{Time: scored.Time, Attempts: scored.Attempts, AttemptsLeft: maxAttempts - scored.Attempts}
+	if quiz.ClosesAt.IsZero() {
+		receipt.Result = &scored
+	}
+	return receipt, nil
+}
+
+// quizAttempts returns how often a person may submit the quiz.
+func quizAttempts(quiz *models.Quiz) int {
+	if quiz.MaxAttempts > 0 {
+		return quiz.MaxAttempts
+	}
+	return models.DefaultQuizAttempts
+}
+
+// scoreQuiz marks every answer of the submission and totals its score. The
+// answers are returned in question order.
+func scoreQuiz(quiz *models.Quiz, submission models.QuizSubmission) models.QuizSubmission {
+	given := map[primitive.ObjectID]string{}
+	for _, a := range submission.Answers {
+		given[a.QuestionID] = a.Answer
+	}
+
+	scored := models.QuizSubmission{PersonID: submission.PersonID, Time: submission.Time, Attempts: submission.Attempts, Answers: []models.QuizAnswer{}}
+	for _, q := range quiz.Questions {
+		scored.MaxScore += q.Points
+		answer, ok := given[q.ID]
+		if !ok {
+			continue
+		}
+		a := models.QuizAnswer{QuestionID: q.ID, Answer: answer, Correct: answerIsCorrect(q, answer)}
+		if a.Correct {
+			a.Points = q.Points
+			scored.Score += q.Points
+		}
+		scored.Answers = append(scored.Answers, a)
+	}
+	return scored
+}
+
+func answerIsCorrect(q models.QuizQuestion, answer string) bool {
+	switch q.Type {
+	case models.QuestionMultipleChoice:
+		return containsString(q.Correct, strings.TrimSpace(answer))
+	case models.QuestionTrueFalse:
+		v, err := strconv.ParseBool(strings.TrimSpace(answer))
+		return err == nil && len(q.Correct) == 1 && strconv.FormatBool(v) == q.Correct[0]
+	case models.QuestionShortAnswer:
+		normalized := NormalizeName(answer)
+		for _, c := range q.Correct {
+			if normalized != "" && NormalizeName(c) == normalized {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// QuizStats summarizes the scores of a quiz and how each question was
+// answered.
+func (s *QuizService) QuizStats(ctx context.Context, quizID string) (*QuizStats, error) {
+	quiz, err := s.repo.GetQuizById(ctx, quizID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &QuizStats{QuizID: quiz.ID, Title: quiz.Title, Submissions: len(quiz.Submissions), Questions: []QuestionStats{}}
+	byQuestion := map[primitive.ObjectID]*QuestionStats{}
+	for _, q := range quiz.Questions {
+		stats.MaxScore += q.Points
+		stats.Questions = append(stats.Questions, QuestionStats{QuestionID: q.ID, Text: q.Text, Type: q.Type, Answers: map[string]int{}})
+	}
+	for i := range stats.Questions {
+		byQuestion[stats.Questions[i].QuestionID] = &stats.Questions[i]
+	}
+
+	scores := []float64{}
+	for _, sub := range quiz.Submissions {
+		scores = append(scores, sub.Score)
+		for _, a := range sub.Answers {
+			qs, ok := byQuestion[a.QuestionID]
+			if !ok {
+				continue
+			}
+			qs.Answered++
+			if a.Correct {
+				qs.Correct++
+			}
+			key := strings.TrimSpace(a.Answer)
+			if qs.Type == models.QuestionShortAnswer {
+				key = NormalizeName(a.Answer)
+			}
+			qs.Answers[key]++
+		}
+	}
+	for i := range stats.Questions {
+		if qs := &stats.Questions[i]; qs.Answered > 0 {
+			qs.CorrectRate = float64(qs.Correct) / float64(qs.Answered)
+		}
+	}
+
+	if len(scores) > 0 {
+		sort.Float64s(scores)
+		total := 0.0
+		for _, score := range scores {
+			total += score
+		}
+		stats.Average = total / float64(len(scores))
+		stats.Min = scores[0]
+		stats.Max = scores[len(scores)-1]
+		if n := len(scores); n%2 == 1 {
+			stats.Median = scores[n/2]
+		} else {
+			stats.Median = (scores[n/2-1] + scores[n/2]) / 2
+		}
+	}
+	return stats, nil
+}
+
+// ExportResults writes one row per submission to the quiz in the given
+// export format.
+func (s *QuizService) ExportResults(ctx context.Context, w io.Writer, format, quizID string) error {
+	quiz, err := s.repo.GetQuizById(ctx, quizID)
+	if err != nil {
+		return err
+	}
+
+	columns := append([]string{}, QuizResultColumns...)
+	for i := range quiz.Questions {
+		columns = append(columns, "q"+strconv.Itoa(i+1))
+	}
+	out, err := newRecordWriter("ExportResults", w, format, "Results", columns)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(quiz.Submissions))
+	for _, sub := range quiz.Submissions {
+		ids = append(ids, sub.PersonID)
+	}
+	persons, err := s.personRepo.GetPersonsByIds(ctx, ids)
+	if err != nil {
+		return err
+	}
+	names := map[primitive.ObjectID]string{}
+	for _, p := range persons {
+		names[p.ID] = p.Name
+	}
+
+	for _, sub := range quiz.Submissions {
+		values := map[string]string{
+			"quizId":         quiz.ID.Hex(),
+			"personId":       sub.PersonID.Hex(),
+			"name":           names[sub.PersonID],
+			"submissionTime": formatExportDate(sub.Time),
+			"score":          strconv.FormatFloat(sub.Score, 'f', -1, 64),
+			"maxScore":       strconv.FormatFloat(sub.MaxScore, 'f', -1, 64),
+		}
+		if sub.MaxScore > 0 {
+			values["percent"] = strconv.FormatFloat(sub.Score/sub.MaxScore*100, 'f', 1, 64)
+		}
+		answers := map[primitive.ObjectID]string{}
+		for _, a := range sub.Answers {
+			answers[a.QuestionID] = a.Answer
+		}
+		for i, q := range quiz.Questions {
+			values["q"+strconv.Itoa(i+1)] = answers[q.ID]
+		}
+		if err := out.Write(values); err != nil {
+			return err
+		}
+	}
+	return out.Close()
+}
+
+func findQuestion(quiz *models.Quiz, id primitive.ObjectID) *models.QuizQuestion {
+	for i := range quiz.Questions {
+		if quiz.Questions[i].ID == id {
+			return &quiz.Questions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAnswerIsCorrect(t *testing.T) {
+	choice := models.QuizQuestion{Type: models.QuestionMultipleChoice, Choices: []string{"Peter", "Paul", "John"}, Correct: []string{"Peter", "John"}}
+	trueFalse := models.QuizQuestion{Type: models.QuestionTrueFalse, Correct: []string{"true"}}
+	short := models.QuizQuestion{Type: models.QuestionShortAnswer, Correct: []string{"St. Mark", "مرقس الرسول"}}
+
+	tests := []struct {
+		name     string
+		question models.QuizQuestion
+		answer   string
+		want     bool
+	}{
+		{"choice", choice, "Peter", true},
+		{"second correct choice", choice, " John ", true},
+		{"wrong choice", choice, "Paul", false},
+		{"choice is case sensitive", choice, "peter", false},
+		{"true", trueFalse, "true", true},
+		{"true spelled differently", trueFalse, "True", true},
+		{"false", trueFalse, "false", false},
+		{"not a boolean", trueFalse, "yes", false},
+		{"short answer", short, "st mark", true},
+		{"short answer punctuation", short, "St.-Mark!", true},
+		{"arabic short answer", short, "مَرْقُس الرسول", true},
+		{"wrong short answer", short, "St. Luke", false},
+		{"empty short answer", models.QuizQuestion{Type: models.QuestionShortAnswer, Correct: []string{"..."}}, "!", false},
+		{"unknown type", models.QuizQuestion{Type: "essay", Correct: []string{"x"}}, "x", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, answerIsCorrect(tt.question, tt.answer))
+		})
+	}
+}
+
+func TestScoreQuiz(t *testing.T) {
+	q1, q2, q3 := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	quiz := &models.Quiz{Questions: []models.QuizQuestion{
+		{ID: q1, Type: models.QuestionTrueFalse, Correct: []string{"false"}, Points: 1},
+		{ID: q2, Type: models.QuestionShortAnswer, Correct: []string{"Bethlehem"}, Points: 2},
+		{ID: q3, Type: models.QuestionMultipleChoice, Choices: []string{"3", "12"}, Correct: []string{"12"}, Points: 0.5},
+	}}
+	person := primitive.NewObjectID()
+	now := time.Date(2024, 3, 1, 19, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		answers   []models.QuizAnswer
+		wantScore float64
+		want      []models.QuizAnswer
+	}{
+		{
+			name:      "all correct, in any order",
+			answers:   []models.QuizAnswer{{QuestionID: q3, Answer: "12"}, {QuestionID: q1, Answer: "false"}, {QuestionID: q2, Answer: "bethlehem"}},
+			wantScore: 3.5,
+			want: []models.QuizAnswer{
+				{QuestionID: q1, Answer: "false", Correct: true, Points: 1},
+				{QuestionID: q2, Answer: "bethlehem", Correct: true, Points: 2},
+				{QuestionID: q3, Answer: "12", Correct: true, Points: 0.5},
+			},
+		},
+		{
+			name:      "wrong and missing answers score nothing",
+			answers:   []models.QuizAnswer{{QuestionID: q2, Answer: "Nazareth"}, {QuestionID: q3, Answer: "12"}},
+			wantScore: 0.5,
+			want: []models.QuizAnswer{
+				{QuestionID: q2, Answer: "Nazareth", Correct: false, Points: 0},
+				{QuestionID: q3, Answer: "12", Correct: true, Points: 0.5},
+			},
+		},
+		{
+			name:      "marks sent by the client are ignored",
+			answers:   []models.QuizAnswer{{QuestionID: q1, Answer: "true", Correct: true, Points: 100}},
+			wantScore: 0,
+			want:      []models.QuizAnswer{{QuestionID: q1, Answer: "true", Correct: false, Points: 0}},
+		},
+		{
+			name:      "no answers",
+			answers:   nil,
+			wantScore: 0,
+			want:      []models.QuizAnswer{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scoreQuiz(quiz, models.QuizSubmission{PersonID: person, Time: now, Attempts: 2, Answers: tt.answers, Score: 99})
+			assert.Equal(t, models.QuizSubmission{PersonID: person, Time: now, Attempts: 2, Answers: tt.want, Score: tt.wantScore, MaxScore: 3.5}, got)
+		})
+	}
+}
+
+func TestQuizAttempts(t *testing.T) {
+	assert.Equal(t, models.DefaultQuizAttempts, quizAttempts(&models.Quiz{}))
+	assert.Equal(t, 1, quizAttempts(&models.Quiz{MaxAttempts: 1}))
+}
+
+func TestValidateQuestion(t *testing.T) {
+	tests := []struct {
+		name     string
+		question models.QuizQuestion
+		wantErr  bool
+		want     models.QuizQuestion
+	}{
+		{
+			name:     "multiple choice",
+			question: models.QuizQuestion{Type: models.QuestionMultipleChoice, Text: "Who?", Choices: []string{"a", "b"}, Correct: []string{"b"}},
+			want:     models.QuizQuestion{Type: models.QuestionMultipleChoice, Text: "Who?", Choices: []string{"a", "b"}, Correct: []string{"b"}},
+		},
+		{
+			name:     "true/false is normalized",
+			question: models.QuizQuestion{Type: models.QuestionTrueFalse, Text: "Really?", Choices: []string{"x"}, Correct: []string{" T "}},
+			want:     models.QuizQuestion{Type: models.QuestionTrueFalse, Text: "Really?", Correct: []string{"true"}},
+		},
+		{
+			name:     "short answer drops choices",
+			question: models.QuizQuestion{Type: models.QuestionShortAnswer, Text: "Where?", Choices: []string{"x"}, Correct: []string{"Bethlehem"}},
+			want:     models.QuizQuestion{Type: models.QuestionShortAnswer, Text: "Where?", Correct: []string{"Bethlehem"}},
+		},
+		{name: "no text", question: models.QuizQuestion{Type: models.QuestionShortAnswer, Text: " ", Correct: []string{"x"}}, wantErr: true},
+		{name: "negative points", question: models.QuizQuestion{Type: models.QuestionShortAnswer, Text: "q", Correct: []string{"x"}, Points: -1}, wantErr: true},
+		{name: "one choice", question: models.QuizQuestion{Type: models.QuestionMultipleChoice, Text: "q", Choices: []string{"a"}, Correct: []string{"a"}}, wantErr: true},
+		{name: "no correct choice", question: models.QuizQuestion{Type: models.QuestionMultipleChoice, Text: "q", Choices: []string{"a", "b"}}, wantErr: true},
+		{name: "correct is not a choice", question: models.QuizQuestion{Type: models.QuestionMultipleChoice, Text: "q", Choices: []string{"a", "b"}, Correct: []string{"c"}}, wantErr: true},
+		{name: "two true/false answers", question: models.QuizQuestion{Type: models.QuestionTrueFalse, Text: "q", Correct: []string{"true", "false"}}, wantErr: true},
+		{name: "not a boolean", question: models.QuizQuestion{Type: models.QuestionTrueFalse, Text: "q", Correct: []string{"yes"}}, wantErr: true},
+		{name: "no accepted answer", question: models.QuizQuestion{Type: models.QuestionShortAnswer, Text: "q"}, wantErr: true},
+		{name: "unknown type", question: models.QuizQuestion{Type: "essay", Text: "q", Correct: []string{"x"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.question
+			err := validateQuestion(&q)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, q)
+		})
+	}
+}
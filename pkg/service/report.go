@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/pdf"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const reportDateLayout = "02 Jan 2006"
+
+type ReportService struct {
+	personRepo     repositories.PersonRepoInterface
+	serviceRepo    repositories.ServiceRepoInterface
+	assignmentRepo repositories.AssignmentRepoInterface
+	groupRepo      repositories.GroupRepoInterface
+	fonts          *pdf.Fonts
+	loc            *time.Location
+}
+
+// NewReportService creates a ReportService drawing reports in fonts, or in
+// the standard PDF fonts when fonts is nil. Dates and times are written in
+// loc.
+func NewReportService(personRepo repositories.PersonRepoInterface, serviceRepo repositories.ServiceRepoInterface, assignmentRepo repositories.AssignmentRepoInterface, groupRepo repositories.GroupRepoInterface, fonts *pdf.Fonts, loc *time.Location) *ReportService {
+	return &ReportService{
+		personRepo:     personRepo,
+		serviceRepo:    serviceRepo,
+		assignmentRepo: assignmentRepo,
+		groupRepo:      groupRepo,
+		fonts:          fonts,
+		loc:            loc,
+	}
+}
+
+// ServiceSignInSheet writes a blank sign-in sheet listing every person of
+// the service's roster with room for a signature.
+func (s *ReportService) ServiceSignInSheet(ctx context.Context, serviceID string, w io.Writer) error {
+	serv, err := s.serviceRepo.GetServiceById(ctx, serviceID)
+	if err != nil {
+		return err
+	}
+	roster, err := serviceRoster(ctx, s.personRepo, s.groupRepo, serv)
+	if err != nil {
+		return err
+	}
+	sortPersonsByName(roster)
+
+	b := pdf.NewBuilder("Sign-in sheet - "+serv.Subject, "Sign-in sheet "+formatReportDate(serv.Date, s.loc), s.fonts)
+	b.Title("Sign-in sheet")
+	writeServiceFields(b, serv, s.loc)
+
+	rows := make([][]string, len(roster))
+	for i, p := range roster {
+		rows[i] = []string{strconv.Itoa(i + 1), p.Name, p.Phone, "", ""}
+	}
+	b.Heading(fmt.Sprintf("Roster (%d)", len(roster)))
+	b.Table([]pdf.Column{
+		{Title: "#", Width: 30},
+		{Title: "Name", Width: 185},
+		{Title: "Phone", Width: 100},
+		{Title: "Time", Width: 60},
+		{Title: "Signature", Width: 140},
+	}, rows, 24)
+
+	_, err = b.Document().WriteTo(w)
+	return err
+}
+
+// ServiceAttendanceReport writes the recorded attendance of a service followed
+// by the persons of its roster who have no attendance record.
+func (s *ReportService) ServiceAttendanceReport(ctx context.Context, serviceID string, w io.Writer) error {
+	serv, err := s.serviceRepo.GetServiceById(ctx, serviceID)
+	if err != nil {
+		return err
+	}
+	roster, err := serviceRoster(ctx, s.personRepo, s.groupRepo, serv)
+	if err != nil {
+		return err
+	}
+	sortPersonsByName(roster)
+	names := map[primitive.ObjectID]models.Person{}
+	for _, p := range roster {
+		names[p.ID] = p
+	}
+	// Attendees from outside the roster, such as visitors, are still named.
+	outsiders := []primitive.ObjectID{}
+	for _, ar := range serv.AttendanceRecord {
+		if _, ok := names[ar.PersonID]; !ok {
+			outsiders = append(outsiders, ar.PersonID)
+		}
+	}
+	visitors, err := s.personRepo.GetPersonsByIds(ctx, outsiders)
+	if err != nil {
+		return err
+	}
+	for _, p := range visitors {
+		names[p.ID] = p
+	}
+
+	b := pdf.NewBuilder("Attendance report - "+serv.Subject, "Attendance report "+formatReportDate(serv.Date, s.loc), s.fonts)
+	b.Title("Attendance report")
+	writeServiceFields(b, serv, s.loc)
+
+	statuses := map[string]int{}
+	attended := map[primitive.ObjectID]bool{}
+	rows := [][]string{}
+	for i, ar := range serv.AttendanceRecord {
+		attended[ar.PersonID] = true
+		statuses[ar.Status]++
+		name := ar.PersonID.Hex()
+		if p, ok := names[ar.PersonID]; ok {
+			name = p.Name
+		}
+		rows = append(rows, []string{strconv.Itoa(i + 1), name, ar.Status, formatReportTime(ar.Time, s.loc)})
+	}
+
+	b.Heading("Summary")
+	b.Field("Roster", strconv.Itoa(len(roster)))
+	b.Field("Recorded", strconv.Itoa(len(serv.AttendanceRecord)))
+	for _, status := range sortedKeys(statuses) {
+		label := status
+		if label == "" {
+			label = "No status"
+		}
+		b.Field(label, strconv.Itoa(statuses[status]))
+	}
+
+	b.Heading("Attendance")
+	b.Table([]pdf.Column{
+		{Title: "#", Width: 30},
+		{Title: "Name", Width: 235},
+		{Title: "Status", Width: 120},
+		{Title: "Time", Width: 130},
+	}, rows, 0)
+
+	absent := [][]string{}
+	for _, p := range roster {
+		if !attended[p.ID] {
+			absent = append(absent, []string{strconv.Itoa(len(absent) + 1), p.Name, p.Phone})
+		}
+	}
+	b.Heading(fmt.Sprintf("No record (%d)", len(absent)))
+	b.Table([]pdf.Column{
+		{Title: "#", Width: 30},
+		{Title: "Name", Width: 285},
+		{Title: "Phone", Width: 200},
+	}, absent, 0)
+
+	_, err = b.Document().WriteTo(w)
+	return err
+}
+
+// PersonProfile writes a person's details with their attendance history and
+// assignment submissions.
+func (s *ReportService) PersonProfile(ctx context.Context, personID string, w io.Writer) error {
+	person, err := s.personRepo.GetPersonById(ctx, personID)
+	if err != nil {
+		return err
+	}
+	services, err := s.serviceRepo.GetServicesByAttendee(ctx, person.ID)
+	if err != nil {
+		return err
+	}
+	assignments, err := s.assignmentRepo.GetAssignmentsBySubmitter(ctx, person.ID)
+	if err != nil {
+		return err
+	}
+
+	b := pdf.NewBuilder("Profile - "+person.Name, "Profile "+person.Name, s.fonts)
+	b.Title(person.Name)
+	// Birthdays are calendar dates stored as midnight UTC.
+	b.Field("Birthday", formatReportDate(person.Birthday, time.UTC))
+	b.Field("Phone", person.Phone)
+	b.Field("Address", person.Address)
+	b.Field("Confession father", person.Fr)
+	b.Field("Degree", person.Degree)
+
+	attendance := [][]string{}
+	for _, serv := range services {
+		for _, ar := range serv.AttendanceRecord {
+			if ar.PersonID != person.ID {
+				continue
+			}
+			attendance = append(attendance, []string{formatReportDate(serv.Date, s.loc), serv.Subject, ar.Status, formatReportTime(ar.Time, s.loc)})
+		}
+	}
+	b.Heading(fmt.Sprintf("Attendance history (%d)", len(attendance)))
+	b.Table([]pdf.Column{
+		{Title: "Date", Width: 80},
+		{Title: "Subject", Width: 235},
+		{Title: "Status", Width: 90},
+		{Title: "Time", Width: 110},
+	}, attendance, 0)
+
+	submissions := [][]string{}
+	for _, a := range assignments {
+		for _, sub := range a.Submissions {
+			if sub.PersonID != person.ID {
+				continue
+			}
+			state := "On time"
+			if !a.Deadline.IsZero() && sub.Time.After(a.Deadline) {
+				state = "Late"
+			}
+			submissions = append(submissions, []string{a.Title, formatReportDate(a.Deadline, s.loc), formatReportTime(sub.Time, s.loc), state})
+		}
+	}
+	b.Heading(fmt.Sprintf("Assignment submissions (%d)", len(submissions)))
+	b.Table([]pdf.Column{
+		{Title: "Assignment", Width: 215},
+		{Title: "Deadline", Width: 90},
+		{Title: "Submitted", Width: 130},
+		{Title: "State", Width: 80},
+	}, submissions, 0)
+
+	_, err = b.Document().WriteTo(w)
+	return err
+}
+
+// ServiceReport writes the report of the given kind, either "signin" or
+// "attendance".
+func (s *ReportService) ServiceReport(ctx context.Context, serviceID, kind string, w io.Writer) error {
+	switch kind {
+	case "", "attendance":
+		return s.ServiceAttendanceReport(ctx, serviceID, w)
+	case "signin":
+		return s.ServiceSignInSheet(ctx, serviceID, w)
+	default:
+		return cerrors.NewBadRequestError("ServiceReport", "ReportService", fmt.Errorf("unknown report type %q", kind))
+	}
+}
+
+func writeServiceFields(b *pdf.Builder, serv *models.Service, loc *time.Location) {
+	b.Field("Date", formatReportDate(serv.Date, loc))
+	b.Field("Subject", serv.Subject)
+	b.Field("Speaker", serv.Speaker)
+	b.Field("Bible chapter", serv.BibleChapter)
+}
+
+func sortPersonsByName(persons []models.Person) {
+	sort.SliceStable(persons, func(i, j int) bool {
+		return persons[i].Name < persons[j].Name
+	})
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatReportDate(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(loc).Format(reportDateLayout)
+}
+
+func formatReportTime(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(loc).Format("02 Jan 2006 15:04")
+}
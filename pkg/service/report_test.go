@@ -0,0 +1,30 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatReportTimesInLocation(t *testing.T) {
+	cairo := time.FixedZone("EEST", 3*60*60)
+	tests := []struct {
+		name     string
+		t        time.Time
+		loc      *time.Location
+		wantDate string
+		wantTime string
+	}{
+		{"same day", time.Date(2026, 10, 19, 15, 4, 0, 0, time.UTC), cairo, "19 Oct 2026", "19 Oct 2026 18:04"},
+		{"next day in the location", time.Date(2026, 10, 19, 22, 30, 0, 0, time.UTC), cairo, "20 Oct 2026", "20 Oct 2026 01:30"},
+		{"UTC", time.Date(2026, 10, 19, 22, 30, 0, 0, time.UTC), time.UTC, "19 Oct 2026", "19 Oct 2026 22:30"},
+		{"zero", time.Time{}, cairo, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantDate, formatReportDate(tt.t, tt.loc))
+			assert.Equal(t, tt.wantTime, formatReportTime(tt.t, tt.loc))
+		})
+	}
+}
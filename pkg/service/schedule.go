@@ -0,0 +1,707 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/coptic"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// OccurrenceScopeThis changes a single occurrence of a schedule.
+	OccurrenceScopeThis = "this"
+	// OccurrenceScopeFollowing changes an occurrence and every later one by
+	// splitting the schedule in two.
+	OccurrenceScopeFollowing = "following"
+)
+
+// OccurrenceEdit is the body of a change to an occurrence. Date moves a
+// single occurrence; Time changes the start time of the following ones.
+// Empty fields are left unchanged.
+type OccurrenceEdit struct {
+	Date     time.Time `json:"date"`
+	Time     string    `json:"time"`
+	Subject  string    `json:"subject"`
+	Speaker  string    `json:"speaker"`
+	Location string    `json:"location"`
+}
+
+// ScheduleOccurrence is an occurrence of a schedule as it will be, or was,
+// generated.
+type ScheduleOccurrence struct {
+	Occurrence time.Time          `json:"occurrence"`
+	Date       time.Time          `json:"date"`
+	Subject    string             `json:"subject"`
+	Speaker    string             `json:"speaker"`
+	Location   string             `json:"location"`
+	Skipped    bool               `json:"skipped"`
+	Feast      string             `json:"feast,omitempty"`
+	ServiceID  primitive.ObjectID `json:"serviceId,omitempty"`
+}
+
+type ScheduleService struct {
+	repo        repositories.ScheduleRepoInterface
+	serviceRepo repositories.ServiceRepoInterface
+	curricula   *CurriculumService
+	horizon     time.Duration
+	timeZone    string
+}
+
+// NewScheduleService creates a ScheduleService generating services horizon
+// ahead. Schedules without a time zone use timeZone.
+func NewScheduleService(repo repositories.ScheduleRepoInterface, serviceRepo repositories.ServiceRepoInterface, curricula *CurriculumService, horizon time.Duration, timeZone string) *ScheduleService {
+	return &ScheduleService{
+		repo:        repo,
+		serviceRepo: serviceRepo,
+		curricula:   curricula,
+		horizon:     horizon,
+		timeZone:    timeZone,
+	}
+}
+
+func (s *ScheduleService) GetAllSchedules(ctx context.Context) ([]models.ServiceSchedule, error) {
+	return s.repo.GetAllSchedules(ctx)
+}
+
+func (s *ScheduleService) GetScheduleById(ctx context.Context, id string) (*models.ServiceSchedule, error) {
+	return s.repo.GetScheduleById(ctx, id)
+}
+
+// CreateSchedule stores the schedule and generates its upcoming services.
+func (s *ScheduleService) CreateSchedule(ctx context.Context, schedule models.ServiceSchedule) (*models.ServiceSchedule, error) {
+	if err := s.validateSchedule("CreateSchedule", &schedule); err != nil {
+		return nil, err
+	}
+	created, err := s.repo.CreateSchedule(ctx, schedule)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.generate(ctx, created, time.Now()); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateSchedule changes the whole series. Upcoming services are updated in
+// place; skipped dates and exceptions are kept.
+func (s *ScheduleService) UpdateSchedule(ctx context.Context, id string, schedule models.ServiceSchedule) (*models.ServiceSchedule, error) {
+	existing, err := s.repo.GetScheduleById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	schedule.ID = existing.ID
+	schedule.Skipped = existing.Skipped
+	schedule.Exceptions = existing.Exceptions
+	if err := s.validateSchedule("UpdateSchedule", &schedule); err != nil {
+		return nil, err
+	}
+	updated, err := s.repo.ReplaceSchedule(ctx, schedule)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.regenerate(ctx, existing, updated, time.Now()); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteSchedule deletes the schedule and its upcoming services without
+// attendance. Past services stay.
+func (s *ScheduleService) DeleteSchedule(ctx context.Context, id string) error {
+	schedule, err := s.repo.GetScheduleById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := s.serviceRepo.DeleteOccurrences(ctx, schedule.ID, time.Now()); err != nil {
+		return err
+	}
+	return s.repo.DeleteSchedule(ctx, id)
+}
+
+func (s *ScheduleService) validateSchedule(method string, schedule *models.ServiceSchedule) error {
+	bad := func(format string, args ...interface{}) error {
+		return cerrors.NewBadRequestError(method, "ScheduleService", fmt.Errorf(format, args...))
+	}
+	if schedule.Interval == 0 {
+		schedule.Interval = 1
+	}
+	if schedule.Interval < 0 {
+		return bad("interval must be positive")
+	}
+	for _, d := range schedule.Weekdays {
+		if d < 0 || d > 6 {
+			return bad("weekday %d is not between 0 (Sunday) and 6", d)
+		}
+	}
+	switch schedule.Frequency {
+	case models.ScheduleWeekly:
+		if len(schedule.Weekdays) == 0 {
+			return bad("a weekly schedule needs at least one weekday")
+		}
+	case models.ScheduleMonthly:
+		switch {
+		case schedule.Week != 0:
+			if schedule.Week < -1 || schedule.Week > 5 || len(schedule.Weekdays) != 1 {
+				return bad("a monthly schedule by week needs a week between 1 and 5, or -1, and one weekday")
+			}
+		case schedule.MonthDay < 1 || schedule.MonthDay > 31:
+			return bad("a monthly schedule needs a monthDay between 1 and 31 or a week")
+		}
+	default:
+		return bad("unknown frequency %q", schedule.Frequency)
+	}
+	switch schedule.OnFeast {
+	case "", models.FeastSkip:
+		schedule.FeastShiftDays = 0
+	case models.FeastShift:
+		if schedule.FeastShiftDays == 0 {
+			schedule.FeastShiftDays = 1
+		}
+	default:
+		return bad("onFeast must be %q or %q", models.FeastSkip, models.FeastShift)
+	}
+	if _, err := time.Parse("15:04", schedule.Time); err != nil {
+		return bad("time %q is not in 15:04 format", schedule.Time)
+	}
+	if schedule.TimeZone == "" {
+		schedule.TimeZone = s.timeZone
+	}
+	loc, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return bad("unknown time zone %q", schedule.TimeZone)
+	}
+	if schedule.StartDate.IsZero() {
+		schedule.StartDate = time.Now()
+	}
+	schedule.StartDate = dayIn(schedule.StartDate, loc)
+	if !schedule.EndDate.IsZero() {
+		schedule.EndDate = dayIn(schedule.EndDate, loc).AddDate(0, 0, 1).Add(-time.Nanosecond)
+		if schedule.EndDate.Before(schedule.StartDate) {
+			return bad("endDate is before startDate")
+		}
+	}
+	return nil
+}
+
+// GenerateServices creates the services of every schedule's occurrences
+// between now and the horizon that were not generated yet. It returns the
+// number of services created.
+func (s *ScheduleService) GenerateServices(ctx context.Context, now time.Time) (int, error) {
+	schedules, err := s.repo.GetAllSchedules(ctx)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for i := range schedules {
+		n, err := s.generate(ctx, &schedules[i], now)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (s *ScheduleService) generate(ctx context.Context, schedule *models.ServiceSchedule, now time.Time) (int, error) {
+	created := 0
+	for _, occ := range scheduleOccurrences(schedule, now, now.Add(s.horizon)) {
+		if occ.Skipped {
+			continue
+		}
+		ok, err := s.serviceRepo.CreateOccurrence(ctx, models.Service{
+			Date:       occ.Date,
+			Subject:    occ.Subject,
+			Speaker:    occ.Speaker,
+			Location:   occ.Location,
+			GroupID:    schedule.GroupID,
+			ScheduleID: schedule.ID,
+			Occurrence: occ.Occurrence,
+		})
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+	}
+	return created, nil
+}
+
+// regenerate brings the schedule's services from now on in line with the
+// schedule after it changed from old.
+func (s *ScheduleService) regenerate(ctx context.Context, old, schedule *models.ServiceSchedule, now time.Time) error {
+	if err := s.syncOccurrences(ctx, old, schedule, now); err != nil {
+		return err
+	}
+	s.rescheduleCurricula(ctx, schedule, now)
+	return nil
+}
+
+// syncOccurrences brings the services generated by old for occurrences from
+// from on in line with schedule, which is old after a change or the schedule
+// taking over from it. Each service is matched to schedule's occurrence on
+// the same day and updated in place, so its attendance, speaker, lesson and
+// whatever points at it stay. Services on days schedule no longer meets are
+// deleted unless attendance was recorded, and occurrences without a service
+// are generated.
+func (s *ScheduleService) syncOccurrences(ctx context.Context, old, schedule *models.ServiceSchedule, from time.Time) error {
+	services, err := s.serviceRepo.GetOccurrencesFrom(ctx, old.ID, from)
+	if err != nil {
+		return err
+	}
+	for _, serv := range services {
+		next, ok := occurrenceOn(schedule, serv.Occurrence)
+		if !ok || next.Skipped {
+			if len(serv.AttendanceRecord) == 0 {
+				if err := s.serviceRepo.DeleteService(ctx, serv.ID.Hex()); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		var prev *ScheduleOccurrence
+		if occ, ok := occurrenceOn(old, serv.Occurrence); ok {
+			prev = &occ
+		}
+		if err := s.serviceRepo.UpdateOccurrence(ctx, syncService(serv, prev, next, old.GroupID, schedule)); err != nil {
+			return err
+		}
+	}
+	_, err = s.generate(ctx, schedule, from)
+	return err
+}
+
+// syncService returns serv moved to the occurrence next of schedule. Fields
+// that are empty or still hold what prev, the old occurrence that day, had
+// take next's values; the others were edited by hand and are kept. prev is
+// nil when the old schedule did not meet that day.
+func syncService(serv models.Service, prev *ScheduleOccurrence, next ScheduleOccurrence, oldGroupID primitive.ObjectID, schedule *models.ServiceSchedule) models.Service {
+	var was ScheduleOccurrence
+	if prev != nil {
+		was = *prev
+	}
+	generated := func(current, old string) bool {
+		return current == "" || prev != nil && current == old
+	}
+	if serv.Date.IsZero() || prev != nil && serv.Date.Equal(was.Date) {
+		serv.Date = next.Date
+	}
+	if generated(serv.Subject, was.Subject) {
+		serv.Subject = next.Subject
+	}
+	if serv.SpeakerID.IsZero() && generated(serv.Speaker, was.Speaker) {
+		serv.Speaker = next.Speaker
+	}
+	if generated(serv.Location, was.Location) {
+		serv.Location = next.Location
+	}
+	if serv.GroupID.IsZero() || serv.GroupID == oldGroupID {
+		serv.GroupID = schedule.GroupID
+	}
+	serv.ScheduleID = schedule.ID
+	serv.Occurrence = next.Occurrence
+	return serv
+}
+
+// occurrenceOn returns the schedule's occurrence on the day of t, in the
+// schedule's time zone.
+func occurrenceOn(schedule *models.ServiceSchedule, t time.Time) (ScheduleOccurrence, bool) {
+	loc, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return ScheduleOccurrence{}, false
+	}
+	day := dayIn(t, loc)
+	occurrences := scheduleOccurrences(schedule, day, day.AddDate(0, 0, 1).Add(-time.Nanosecond))
+	if len(occurrences) == 0 {
+		return ScheduleOccurrence{}, false
+	}
+	return occurrences[0], true
+}
+
+// rescheduleCurricula plans the curricula of the schedule's services again
+// after some were cancelled or generated again. The services are already
+// saved, so failures are only logged.
+func (s *ScheduleService) rescheduleCurricula(ctx context.Context, schedule *models.ServiceSchedule, now time.Time) {
+	if err := s.curricula.RescheduleFor(ctx, schedule.GroupID, schedule.ID, now); err != nil {
+		fmt.Printf("Error while rescheduling curricula: %v\n", err)
+	}
+}
+
+// GetOccurrences lists the schedule's occurrences between from and to with
+// the services generated for them.
+func (s *ScheduleService) GetOccurrences(ctx context.Context, id string, from, to time.Time) ([]ScheduleOccurrence, error) {
+	schedule, err := s.repo.GetScheduleById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if from.IsZero() {
+		from = time.Now()
+	}
+	if to.IsZero() {
+		to = from.Add(s.horizon)
+	}
+	occurrences := scheduleOccurrences(schedule, from, to)
+	for i := range occurrences {
+		serv, err := s.serviceRepo.GetOccurrence(ctx, schedule.ID, occurrences[i].Occurrence)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		if serv != nil {
+			occurrences[i].ServiceID = serv.ID
+		}
+	}
+	return occurrences, nil
+}
+
+// SkipOccurrence cancels the occurrence on day, "2006-01-02". Its service is
+// deleted unless attendance was already recorded.
+func (s *ScheduleService) SkipOccurrence(ctx context.Context, id, day string) (*models.ServiceSchedule, error) {
+	schedule, occ, err := s.findOccurrence(ctx, "SkipOccurrence", id, day)
+	if err != nil {
+		return nil, err
+	}
+	if !containsTime(schedule.Skipped, occ) {
+		schedule.Skipped = append(schedule.Skipped, occ)
+	}
+	updated, err := s.repo.ReplaceSchedule(ctx, *schedule)
+	if err != nil {
+		return nil, err
+	}
+	serv, err := s.serviceRepo.GetOccurrence(ctx, schedule.ID, occ)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return updated, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(serv.AttendanceRecord) == 0 {
+		if err := s.serviceRepo.DeleteService(ctx, serv.ID.Hex()); err != nil {
+			return nil, err
+		}
+		if !serv.CurriculumID.IsZero() {
+			s.rescheduleCurricula(ctx, schedule, time.Now())
+		}
+	}
+	return updated, nil
+}
+
+// EditOccurrence changes the occurrence on day, "2006-01-02". With scope
+// "this" only that occurrence changes. With scope "following" the schedule
+// ends the day before and a new schedule with the changes takes over from
+// that occurrence on; the new schedule is returned.
+func (s *ScheduleService) EditOccurrence(ctx context.Context, id, day, scope string, edit OccurrenceEdit) (*models.ServiceSchedule, error) {
+	schedule, occ, err := s.findOccurrence(ctx, "EditOccurrence", id, day)
+	if err != nil {
+		return nil, err
+	}
+	switch scope {
+	case "", OccurrenceScopeThis:
+		return s.editOne(ctx, schedule, occ, edit)
+	case OccurrenceScopeFollowing:
+		return s.editFollowing(ctx, schedule, occ, edit)
+	default:
+		return nil, cerrors.NewBadRequestError("EditOccurrence", "ScheduleService", fmt.Errorf("unknown scope %q", scope))
+	}
+}
+
+func (s *ScheduleService) editOne(ctx context.Context, schedule *models.ServiceSchedule, occ time.Time, edit OccurrenceEdit) (*models.ServiceSchedule, error) {
+	if edit.Time != "" {
+		return nil, cerrors.NewBadRequestError("EditOccurrence", "ScheduleService", errors.New("set date to move a single occurrence"))
+	}
+	exception := models.ScheduleException{Occurrence: occ}
+	kept := schedule.Exceptions[:0]
+	for _, e := range schedule.Exceptions {
+		if e.Occurrence.Equal(occ) {
+			exception = e
+		} else {
+			kept = append(kept, e)
+		}
+	}
+	if !edit.Date.IsZero() {
+		exception.Date = edit.Date
+	}
+	if edit.Subject != "" {
+		exception.Subject = edit.Subject
+	}
+	if edit.Speaker != "" {
+		exception.Speaker = edit.Speaker
+	}
+	if edit.Location != "" {
+		exception.Location = edit.Location
+	}
+	schedule.Exceptions = append(kept, exception)
+	updated, err := s.repo.ReplaceSchedule(ctx, *schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	serv, err := s.serviceRepo.GetOccurrence(ctx, schedule.ID, occ)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Not generated yet, the exception applies when it is.
+		return updated, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !exception.Date.IsZero() {
+		serv.Date = exception.Date
+	}
+	if exception.Subject != "" {
+		serv.Subject = exception.Subject
+	}
+	if exception.Speaker != "" {
+		serv.Speaker = exception.Speaker
+	}
+	if exception.Location != "" {
+		serv.Location = exception.Location
+	}
+	if _, err := s.serviceRepo.UpdateService(ctx, serv.ID.Hex(), *serv); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *ScheduleService) editFollowing(ctx context.Context, schedule *models.ServiceSchedule, occ time.Time, edit OccurrenceEdit) (*models.ServiceSchedule, error) {
+	if !edit.Date.IsZero() {
+		return nil, cerrors.NewBadRequestError("EditOccurrence", "ScheduleService", errors.New("change the weekdays of the schedule to move the following occurrences"))
+	}
+	loc, _ := time.LoadLocation(schedule.TimeZone)
+
+	old := *schedule
+	next := *schedule
+	next.ID = primitive.NilObjectID
+	next.StartDate = dayIn(occ, loc)
+	next.Skipped, schedule.Skipped = splitTimes(schedule.Skipped, occ)
+	next.Exceptions, schedule.Exceptions = splitExceptions(schedule.Exceptions, occ)
+	if edit.Time != "" {
+		next.Time = edit.Time
+	}
+	if edit.Subject != "" {
+		next.Subject = edit.Subject
+	}
+	if edit.Speaker != "" {
+		next.Speaker = edit.Speaker
+	}
+	if edit.Location != "" {
+		next.Location = edit.Location
+	}
+	if err := s.validateSchedule("EditOccurrence", &next); err != nil {
+		return nil, err
+	}
+
+	schedule.EndDate = next.StartDate.Add(-time.Nanosecond)
+	if _, err := s.repo.ReplaceSchedule(ctx, *schedule); err != nil {
+		return nil, err
+	}
+	created, err := s.repo.CreateSchedule(ctx, next)
+	if err != nil {
+		return nil, err
+	}
+	from := occ
+	if now := time.Now(); now.After(from) {
+		from = now
+	}
+	if err := s.syncOccurrences(ctx, &old, created, from); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// findOccurrence returns the schedule and its occurrence on day.
+func (s *ScheduleService) findOccurrence(ctx context.Context, method, id, day string) (*models.ServiceSchedule, time.Time, error) {
+	schedule, err := s.repo.GetScheduleById(ctx, id)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	loc, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	date, err := time.ParseInLocation("2006-01-02", day, loc)
+	if err != nil {
+		return nil, time.Time{}, cerrors.NewBadRequestError(method, "ScheduleService", fmt.Errorf("date %q is not in 2006-01-02 format", day))
+	}
+	occurrences := scheduleOccurrences(schedule, date, date.AddDate(0, 0, 1).Add(-time.Nanosecond))
+	if len(occurrences) == 0 {
+		return nil, time.Time{}, cerrors.NewBadRequestError(method, "ScheduleService", fmt.Errorf("the schedule does not meet on %v", day))
+	}
+	return schedule, occurrences[0].Occurrence, nil
+}
+
+// scheduleOccurrences returns the occurrences of the schedule starting
+// between from and to, with exceptions applied and skipped ones marked.
+// Occurrences moved into the range from outside it are not included.
+func scheduleOccurrences(schedule *models.ServiceSchedule, from, to time.Time) []ScheduleOccurrence {
+	loc, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return nil
+	}
+	start, err := time.Parse("15:04", schedule.Time)
+	if err != nil {
+		return nil
+	}
+	if from.Before(schedule.StartDate) {
+		from = schedule.StartDate
+	}
+	if !schedule.EndDate.IsZero() && to.After(schedule.EndDate) {
+		to = schedule.EndDate
+	}
+
+	occurrences := []ScheduleOccurrence{}
+	for day := dayIn(from, loc); !day.After(to); day = day.AddDate(0, 0, 1) {
+		if !scheduleMeetsOn(schedule, day, loc) {
+			continue
+		}
+		at := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+		if at.Before(from) || at.After(to) {
+			continue
+		}
+		occ := ScheduleOccurrence{
+			Occurrence: at,
+			Date:       at,
+			Subject:    schedule.Subject,
+			Speaker:    schedule.Speaker,
+			Location:   schedule.Location,
+			Skipped:    containsTime(schedule.Skipped, at),
+		}
+		if schedule.OnFeast != "" {
+			if feast := majorFeastOn(day, loc); feast != "" {
+				occ.Feast = feast
+				switch schedule.OnFeast {
+				case models.FeastSkip:
+					occ.Skipped = true
+				case models.FeastShift:
+					occ.Date = at.AddDate(0, 0, schedule.FeastShiftDays)
+				}
+			}
+		}
+		for _, e := range schedule.Exceptions {
+			if !e.Occurrence.Equal(at) {
+				continue
+			}
+			if !e.Date.IsZero() {
+				occ.Date = e.Date
+			}
+			if e.Subject != "" {
+				occ.Subject = e.Subject
+			}
+			if e.Speaker != "" {
+				occ.Speaker = e.Speaker
+			}
+			if e.Location != "" {
+				occ.Location = e.Location
+			}
+		}
+		occurrences = append(occurrences, occ)
+	}
+	return occurrences
+}
+
+// scheduleMeetsOn reports whether the schedule meets on day, a midnight in
+// loc on or after the schedule's start.
+func scheduleMeetsOn(schedule *models.ServiceSchedule, day time.Time, loc *time.Location) bool {
+	start := dayIn(schedule.StartDate, loc)
+	switch schedule.Frequency {
+	case models.ScheduleWeekly:
+		if !containsInt(schedule.Weekdays, int(day.Weekday())) {
+			return false
+		}
+		startWeek := start.AddDate(0, 0, -int(start.Weekday()))
+		week := daysBetween(startWeek, day) / 7
+		return week%schedule.Interval == 0
+	case models.ScheduleMonthly:
+		months := (day.Year()-start.Year())*12 + int(day.Month()) - int(start.Month())
+		if months%schedule.Interval != 0 {
+			return false
+		}
+		if schedule.Week != 0 {
+			if len(schedule.Weekdays) == 0 || int(day.Weekday()) != schedule.Weekdays[0] {
+				return false
+			}
+			if schedule.Week == -1 {
+				return day.AddDate(0, 0, 7).Month() != day.Month()
+			}
+			return (day.Day()-1)/7+1 == schedule.Week
+		}
+		// Months shorter than MonthDay meet on their last day.
+		last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, loc).Day()
+		want := schedule.MonthDay
+		if want > last {
+			want = last
+		}
+		return day.Day() == want
+	}
+	return false
+}
+
+// majorFeastOn returns the name of the major feast on day, or "".
+func majorFeastOn(day time.Time, loc *time.Location) string {
+	for _, f := range coptic.FeastsOn(day, loc) {
+		if f.Major {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+// dayIn returns midnight of t's day in loc.
+func dayIn(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// daysBetween counts the calendar days from a to b, both midnights in the
+// same location, ignoring daylight saving shifts.
+func daysBetween(a, b time.Time) int {
+	ua := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	ub := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(ub.Sub(ua).Hours() / 24)
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTime(values []time.Time, t time.Time) bool {
+	for _, x := range values {
+		if x.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTimes splits times into those at or after at and those before it.
+func splitTimes(times []time.Time, at time.Time) (after, before []time.Time) {
+	for _, t := range times {
+		if t.Before(at) {
+			before = append(before, t)
+		} else {
+			after = append(after, t)
+		}
+	}
+	return after, before
+}
+
+func splitExceptions(exceptions []models.ScheduleException, at time.Time) (after, before []models.ScheduleException) {
+	for _, e := range exceptions {
+		if e.Occurrence.Before(at) {
+			before = append(before, e)
+		} else {
+			after = append(after, e)
+		}
+	}
+	return after, before
+}
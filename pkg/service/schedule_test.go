@@ -0,0 +1,214 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func cairo(t *testing.T) *time.Location {
+	loc, err := time.LoadLocation("Africa/Cairo")
+	require.NoError(t, err)
+	return loc
+}
+
+func dates(occurrences []ScheduleOccurrence, loc *time.Location) []string {
+	got := []string{}
+	for _, o := range occurrences {
+		got = append(got, o.Date.In(loc).Format("2006-01-02 15:04"))
+	}
+	return got
+}
+
+func TestScheduleOccurrences(t *testing.T) {
+	utc := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		require.NoError(t, err)
+		return d
+	}
+	tests := []struct {
+		name     string
+		schedule models.ServiceSchedule
+		from, to string
+		want     []string
+	}{
+		{
+			name:     "weekly",
+			schedule: models.ServiceSchedule{Frequency: models.ScheduleWeekly, Interval: 1, Weekdays: []int{5}, Time: "19:00", TimeZone: "UTC", StartDate: utc("2024-01-01")},
+			from:     "2024-03-01", to: "2024-03-31",
+			want: []string{"2024-03-01 19:00", "2024-03-08 19:00", "2024-03-15 19:00", "2024-03-22 19:00", "2024-03-29 19:00"},
+		},
+		{
+			name:     "every other week counts from the start week",
+			schedule: models.ServiceSchedule{Frequency: models.ScheduleWeekly, Interval: 2, Weekdays: []int{0, 3}, Time: "18:30", TimeZone: "UTC", StartDate: utc("2024-03-06")},
+			from:     "2024-03-01", to: "2024-03-21",
+			want: []string{"2024-03-06 18:30", "2024-03-17 18:30", "2024-03-20 18:30"},
+		},
+		{
+			name:     "end date",
+			schedule: models.ServiceSchedule{Frequency: models.ScheduleWeekly, Interval: 1, Weekdays: []int{5}, Time: "19:00", TimeZone: "UTC", StartDate: utc("2024-01-01"), EndDate: utc("2024-03-09")},
+			from:     "2024-03-01", to: "2024-03-31",
+			want: []string{"2024-03-01 19:00", "2024-03-08 19:00"},
+		},
+		{
+			name:     "monthly day falls back to the last day",
+			schedule: models.ServiceSchedule{Frequency: models.ScheduleMonthly, Interval: 1, MonthDay: 31, Time: "10:00", TimeZone: "UTC", StartDate: utc("2024-01-01")},
+			from:     "2024-01-01", to: "2024-04-30",
+			want: []string{"2024-01-31 10:00", "2024-02-29 10:00", "2024-03-31 10:00", "2024-04-30 10:00"},
+		},
+		{
+			name:     "every other month",
+			schedule: models.ServiceSchedule{Frequency: models.ScheduleMonthly, Interval: 2, MonthDay: 15, Time: "10:00", TimeZone: "UTC", StartDate: utc("2024-01-01")},
+			from:     "2024-01-01", to: "2024-06-30",
+			want: []string{"2024-01-15 10:00", "2024-03-15 10:00", "2024-05-15 10:00"},
+		},
+		{
+			name:     "second Tuesday",
+			schedule: models.ServiceSchedule{Frequency: models.ScheduleMonthly, Interval: 1, Week: 2, Weekdays: []int{2}, Time: "20:00", TimeZone: "UTC", StartDate: utc("2024-01-01")},
+			from:     "2024-01-01", to: "2024-03-31",
+			want: []string{"2024-01-09 20:00", "2024-02-13 20:00", "2024-03-12 20:00"},
+		},
+		{
+			name:     "last Friday",
+			schedule: models.ServiceSchedule{Frequency: models.ScheduleMonthly, Interval: 1, Week: -1, Weekdays: []int{5}, Time: "20:00", TimeZone: "UTC", StartDate: utc("2024-01-01")},
+			from:     "2024-01-01", to: "2024-03-31",
+			want: []string{"2024-01-26 20:00", "2024-02-23 20:00", "2024-03-29 20:00"},
+		},
+		{
+			name:     "bad time zone",
+			schedule: models.ServiceSchedule{Frequency: models.ScheduleWeekly, Interval: 1, Weekdays: []int{5}, Time: "19:00", TimeZone: "Nowhere/City", StartDate: utc("2024-01-01")},
+			from:     "2024-03-01", to: "2024-03-31",
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scheduleOccurrences(&tt.schedule, utc(tt.from), utc(tt.to).Add(24*time.Hour-time.Nanosecond))
+			assert.Equal(t, tt.want, dates(got, time.UTC))
+		})
+	}
+}
+
+func TestScheduleOccurrencesKeepLocalTimeOverDST(t *testing.T) {
+	loc := cairo(t)
+	// Egypt moved its clocks forward on 26 April 2024.
+	schedule := models.ServiceSchedule{Frequency: models.ScheduleWeekly, Interval: 1, Weekdays: []int{5}, Time: "19:00", TimeZone: "Africa/Cairo", StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, loc)}
+	got := scheduleOccurrences(&schedule, time.Date(2024, 4, 19, 0, 0, 0, 0, loc), time.Date(2024, 5, 4, 0, 0, 0, 0, loc))
+	assert.Equal(t, []string{"2024-04-19 19:00", "2024-04-26 19:00", "2024-05-03 19:00"}, dates(got, loc))
+	if assert.Len(t, got, 3) {
+		assert.Equal(t, 17, got[0].Date.UTC().Hour())
+		assert.Equal(t, 16, got[1].Date.UTC().Hour())
+	}
+}
+
+func TestScheduleOccurrencesOnFeasts(t *testing.T) {
+	loc := cairo(t)
+	base := models.ServiceSchedule{Frequency: models.ScheduleWeekly, Interval: 1, Weekdays: []int{0}, Time: "10:00", TimeZone: "Africa/Cairo", StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, loc)}
+	from, to := time.Date(2024, 5, 1, 0, 0, 0, 0, loc), time.Date(2024, 5, 13, 0, 0, 0, 0, loc)
+
+	tests := []struct {
+		name        string
+		onFeast     string
+		shiftDays   int
+		wantDates   []string
+		wantSkipped []bool
+	}{
+		{"ignored", "", 0, []string{"2024-05-05 10:00", "2024-05-12 10:00"}, []bool{false, false}},
+		{"skipped", models.FeastSkip, 0, []string{"2024-05-05 10:00", "2024-05-12 10:00"}, []bool{true, false}},
+		{"shifted", models.FeastShift, 1, []string{"2024-05-06 10:00", "2024-05-12 10:00"}, []bool{false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := base
+			schedule.OnFeast, schedule.FeastShiftDays = tt.onFeast, tt.shiftDays
+			got := scheduleOccurrences(&schedule, from, to)
+			assert.Equal(t, tt.wantDates, dates(got, loc))
+			skipped := []bool{}
+			for _, o := range got {
+				skipped = append(skipped, o.Skipped)
+			}
+			assert.Equal(t, tt.wantSkipped, skipped)
+			if tt.onFeast != "" && assert.Len(t, got, 2) {
+				assert.Equal(t, "Easter", got[0].Feast)
+				// The occurrence stays on the day the schedule meets.
+				assert.Equal(t, "2024-05-05", got[0].Occurrence.In(loc).Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestScheduleOccurrencesSkipsAndExceptions(t *testing.T) {
+	march := func(day, hour int) time.Time { return time.Date(2024, 3, day, hour, 0, 0, 0, time.UTC) }
+	schedule := models.ServiceSchedule{
+		Frequency: models.ScheduleWeekly, Interval: 1, Weekdays: []int{5}, Time: "19:00", TimeZone: "UTC", StartDate: march(1, 0),
+		Subject: "Meeting", Speaker: "Fr. Mina", Location: "Hall",
+		Skipped:    []time.Time{march(8, 19)},
+		Exceptions: []models.ScheduleException{{Occurrence: march(15, 19), Date: march(16, 18), Subject: "Retreat"}},
+	}
+	got := scheduleOccurrences(&schedule, march(1, 0), march(22, 0))
+	require.Len(t, got, 3)
+
+	assert.False(t, got[0].Skipped)
+	assert.Equal(t, ScheduleOccurrence{Occurrence: march(1, 19), Date: march(1, 19), Subject: "Meeting", Speaker: "Fr. Mina", Location: "Hall"}, got[0])
+	assert.True(t, got[1].Skipped)
+	assert.Equal(t, ScheduleOccurrence{Occurrence: march(15, 19), Date: march(16, 18), Subject: "Retreat", Speaker: "Fr. Mina", Location: "Hall"}, got[2])
+
+	occ, ok := occurrenceOn(&schedule, march(15, 8))
+	require.True(t, ok)
+	assert.Equal(t, "Retreat", occ.Subject)
+	_, ok = occurrenceOn(&schedule, march(14, 19))
+	assert.False(t, ok)
+}
+
+func TestSyncService(t *testing.T) {
+	oldGroup, newGroup, otherGroup := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	speakerID := primitive.NewObjectID()
+	at := time.Date(2024, 3, 1, 19, 0, 0, 0, time.UTC)
+	later := at.Add(time.Hour)
+	schedule := &models.ServiceSchedule{ID: primitive.NewObjectID(), GroupID: newGroup}
+	prev := &ScheduleOccurrence{Occurrence: at, Date: at, Subject: "Old", Speaker: "Old speaker", Location: "Old hall"}
+	next := ScheduleOccurrence{Occurrence: later, Date: later, Subject: "New", Speaker: "New speaker", Location: "New hall"}
+
+	tests := []struct {
+		name string
+		serv models.Service
+		prev *ScheduleOccurrence
+		want models.Service
+	}{
+		{
+			name: "generated fields follow the schedule",
+			serv: models.Service{Date: at, Subject: "Old", Speaker: "Old speaker", Location: "Old hall", GroupID: oldGroup},
+			prev: prev,
+			want: models.Service{Date: later, Subject: "New", Speaker: "New speaker", Location: "New hall", GroupID: newGroup},
+		},
+		{
+			name: "hand edits are kept",
+			serv: models.Service{Date: at.Add(-time.Hour), Subject: "Edited", Speaker: "Guest", Location: "Church", GroupID: otherGroup},
+			prev: prev,
+			want: models.Service{Date: at.Add(-time.Hour), Subject: "Edited", Speaker: "Guest", Location: "Church", GroupID: otherGroup},
+		},
+		{
+			name: "a linked speaker is kept",
+			serv: models.Service{Date: at, Subject: "Old", Speaker: "Old speaker", SpeakerID: speakerID, Location: "Old hall"},
+			prev: prev,
+			want: models.Service{Date: later, Subject: "New", Speaker: "Old speaker", SpeakerID: speakerID, Location: "New hall", GroupID: newGroup},
+		},
+		{
+			name: "without a previous occurrence only empty fields are filled",
+			serv: models.Service{Date: at, Subject: "Old", Location: ""},
+			prev: nil,
+			want: models.Service{Date: at, Subject: "Old", Speaker: "New speaker", Location: "New hall", GroupID: newGroup},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.ScheduleID = schedule.ID
+			tt.want.Occurrence = later
+			assert.Equal(t, tt.want, syncService(tt.serv, tt.prev, next, oldGroup, schedule))
+		})
+	}
+}
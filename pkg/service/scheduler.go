@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MaxJobRetries is how often a failed scheduled run is retried before the job
+// waits for its next scheduled time.
+const MaxJobRetries = 3
+
+const (
+	jobPollInterval = 15 * time.Second
+	jobRetryDelay   = time.Minute
+)
+
+// JobFunc is the work of a scheduled job.
+type JobFunc func(ctx context.Context) error
+
+type scheduledJob struct {
+	name     string
+	spec     string
+	schedule cron.Schedule
+	lease    time.Duration
+	run      JobFunc
+}
+
+// Scheduler runs jobs on cron schedules. Job state lives in the database so
+// every instance of the server can run a scheduler: an instance only runs a
+// job after taking its lease, so each run happens once.
+type Scheduler struct {
+	repo  repositories.JobRepoInterface
+	owner string
+
+	mu      sync.Mutex
+	ctx     context.Context
+	jobs    map[string]*scheduledJob
+	running map[string]bool
+}
+
+func NewScheduler(repo repositories.JobRepoInterface) *Scheduler {
+	return &Scheduler{
+		repo:    repo,
+		owner:   schedulerOwner(),
+		jobs:    map[string]*scheduledJob{},
+		running: map[string]bool{},
+	}
+}
+
+type JobStatus struct {
+	models.Job
+	Registered bool `json:"registered"`
+	Running    bool `json:"running"`
+}
+
+// Register adds a job running on spec, a standard five field cron expression
+// optionally prefixed with CRON_TZ=<zone>, or a descriptor such as @daily or
+// @every 10m. A run may take up to lease before another instance considers
+// it dead and runs the job again.
+func (s *Scheduler) Register(name, spec string, lease time.Duration, run JobFunc) error {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[name] = &scheduledJob{name: name, spec: spec, schedule: schedule, lease: lease, run: run}
+	return nil
+}
+
+// Start stores the registered jobs and checks for due jobs until ctx is
+// cancelled.
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+	for _, job := range s.registered() {
+		_, err := s.repo.EnsureJob(ctx, job.name, job.spec, job.schedule.Next(time.Now()))
+		if err != nil {
+			return err
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(jobPollInterval)
+		defer ticker.Stop()
+		for {
+			s.poll(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// GetJobs returns the stored state of every job.
+func (s *Scheduler) GetJobs(ctx context.Context) ([]JobStatus, error) {
+	jobs, err := s.repo.GetAllJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]JobStatus, len(jobs))
+	for i, job := range jobs {
+		statuses[i] = s.status(job)
+	}
+	return statuses, nil
+}
+
+func (s *Scheduler) GetJob(ctx context.Context, name string) (*JobStatus, error) {
+	job, err := s.repo.GetJob(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	status := s.status(*job)
+	return &status, nil
+}
+
+// Trigger runs the job right away, on whichever instance picks it up first.
+// Paused jobs run too. The run belongs to the scheduler rather than to ctx, so
+// it stops when the scheduler does.
+func (s *Scheduler) Trigger(ctx context.Context, name string) (*JobStatus, error) {
+	if err := s.checkRegistered("Trigger", name); err != nil {
+		return nil, err
+	}
+	job, err := s.repo.Trigger(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	schedulerCtx := s.ctx
+	s.mu.Unlock()
+	if schedulerCtx != nil && schedulerCtx.Err() == nil {
+		go s.poll(schedulerCtx)
+	}
+	status := s.status(*job)
+	return &status, nil
+}
+
+func (s *Scheduler) Pause(ctx context.Context, name string) (*JobStatus, error) {
+	return s.setPaused(ctx, name, true)
+}
+
+func (s *Scheduler) Resume(ctx context.Context, name string) (*JobStatus, error) {
+	return s.setPaused(ctx, name, false)
+}
+
+func (s *Scheduler) setPaused(ctx context.Context, name string, paused bool) (*JobStatus, error) {
+	job, err := s.repo.SetPaused(ctx, name, paused)
+	if err != nil {
+		return nil, err
+	}
+	status := s.status(*job)
+	return &status, nil
+}
+
+func (s *Scheduler) poll(ctx context.Context) {
+	for _, job := range s.registered() {
+		s.mu.Lock()
+		busy := s.running[job.name]
+		s.mu.Unlock()
+		if busy {
+			continue
+		}
+
+		now := time.Now()
+		stored, err := s.repo.AcquireLease(ctx, job.name, s.owner, now, now.Add(job.lease))
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			fmt.Printf("Error while acquiring lease of job %s: %v\n", job.name, err)
+			continue
+		}
+
+		s.mu.Lock()
+		s.running[job.name] = true
+		s.mu.Unlock()
+		go s.runJob(ctx, job, *stored)
+	}
+}
+
+// runJob runs a job it holds the lease of and stores the outcome.
+func (s *Scheduler) runJob(ctx context.Context, job *scheduledJob, stored models.Job) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, job.name)
+		s.mu.Unlock()
+	}()
+
+	runCtx, cancel := context.WithTimeout(ctx, job.lease)
+	start := time.Now()
+	err := runSafely(runCtx, job.run)
+	cancel()
+	end := time.Now()
+
+	stored.LastDuration = end.Sub(start).Round(time.Millisecond).String()
+	stored.NextRun = job.schedule.Next(end)
+	if err == nil {
+		stored.LastStatus = models.JobStatusSucceeded
+		stored.LastError = ""
+		stored.Failures = 0
+	} else {
+		fmt.Printf("Error while running job %s: %v\n", job.name, err)
+		stored.LastError = err.Error()
+		recordFailure(&stored, end)
+	}
+
+	// The run's context may be cancelled by now, the outcome is stored anyway.
+	if err := s.repo.FinishRun(context.Background(), stored, s.owner); err != nil {
+		fmt.Printf("Error while storing run of job %s: %v\n", job.name, err)
+	}
+}
+
+// recordFailure counts a failed run of the job that ended at end, whose
+// NextRun holds its next scheduled run. The failures of a scheduled run are
+// retried MaxJobRetries times, after one minute and doubling with every
+// further failure, but never later than the next scheduled run. After that
+// the job is marked exhausted and waits for the next scheduled run, which
+// gets its own retries. Failures keeps counting until a run succeeds.
+func recordFailure(job *models.Job, end time.Time) {
+	job.Failures++
+	retry := (job.Failures - 1) % (MaxJobRetries + 1)
+	if retry == MaxJobRetries {
+		job.LastStatus = models.JobStatusExhausted
+		return
+	}
+	job.LastStatus = models.JobStatusFailed
+	if at := end.Add(jobRetryDelay << retry); at.Before(job.NextRun) {
+		job.NextRun = at
+	}
+}
+
+func (s *Scheduler) registered() []*scheduledJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]*scheduledJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].name < jobs[j].name })
+	return jobs
+}
+
+func (s *Scheduler) checkRegistered(method, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; !ok {
+		return cerrors.NewBadRequestError(method, "Scheduler", fmt.Errorf("job %q is not registered on this instance", name))
+	}
+	return nil
+}
+
+func (s *Scheduler) status(job models.Job) JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, registered := s.jobs[job.Name]
+	return JobStatus{Job: job, Registered: registered, Running: s.running[job.Name]}
+}
+
+// runSafely turns a panicking job into a failed run.
+func runSafely(ctx context.Context, run JobFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return run(ctx)
+}
+
+// schedulerOwner identifies this instance in job leases.
+func schedulerOwner() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
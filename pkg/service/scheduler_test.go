@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeJobRepo holds jobs in memory and leases them with the same conditions
+// as the MongoDB queries. It is shared by the schedulers of a test, like the
+// database is shared by the instances of the server.
+type fakeJobRepo struct {
+	repositories.JobRepoInterface
+	mu   sync.Mutex
+	jobs map[string]*models.Job
+}
+
+func (r *fakeJobRepo) EnsureJob(ctx context.Context, name, schedule string, nextRun time.Time) (*models.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.jobs[name] == nil {
+		r.jobs[name] = &models.Job{Name: name, Schedule: schedule, NextRun: nextRun}
+	}
+	job := *r.jobs[name]
+	return &job, nil
+}
+
+func (r *fakeJobRepo) Trigger(ctx context.Context, name string) (*models.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[name].Triggered = true
+	job := *r.jobs[name]
+	return &job, nil
+}
+
+func (r *fakeJobRepo) AcquireLease(ctx context.Context, name, owner string, now, until time.Time) (*models.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job := r.jobs[name]
+	due := job.Triggered || !job.Paused && !job.NextRun.After(now)
+	if !due || !job.LeaseUntil.IsZero() && !job.LeaseUntil.Before(now) {
+		return nil, mongo.ErrNoDocuments
+	}
+	job.LeaseOwner = owner
+	job.LeaseUntil = until
+	job.LastStatus = models.JobStatusRunning
+	job.LastRun = now
+	job.Triggered = false
+	leased := *job
+	return &leased, nil
+}
+
+func (r *fakeJobRepo) FinishRun(ctx context.Context, job models.Job, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.jobs[job.Name]
+	if stored.LeaseOwner != owner {
+		return nil
+	}
+	stored.NextRun = job.NextRun
+	stored.LastDuration = job.LastDuration
+	stored.LastStatus = job.LastStatus
+	stored.LastError = job.LastError
+	stored.Failures = job.Failures
+	stored.LeaseOwner = ""
+	stored.LeaseUntil = time.Time{}
+	return nil
+}
+
+func (r *fakeJobRepo) get(name string) models.Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.jobs[name]
+}
+
+// waitIdle waits until the scheduler finished the runs it started.
+func waitIdle(t *testing.T, s *Scheduler) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.running) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestRecordFailure(t *testing.T) {
+	end := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		failures     int
+		nextRun      time.Time
+		wantStatus   string
+		wantNextRun  time.Time
+		wantFailures int
+	}{
+		{"first failure", 0, end.Add(24 * time.Hour), models.JobStatusFailed, end.Add(time.Minute), 1},
+		{"second failure", 1, end.Add(24 * time.Hour), models.JobStatusFailed, end.Add(2 * time.Minute), 2},
+		{"third failure", 2, end.Add(24 * time.Hour), models.JobStatusFailed, end.Add(4 * time.Minute), 3},
+		{"retries exhausted", 3, end.Add(24 * time.Hour), models.JobStatusExhausted, end.Add(24 * time.Hour), 4},
+		{"next scheduled run retried again", 4, end.Add(24 * time.Hour), models.JobStatusFailed, end.Add(time.Minute), 5},
+		{"exhausted again", 7, end.Add(24 * time.Hour), models.JobStatusExhausted, end.Add(24 * time.Hour), 8},
+		{"capped at the next run", 2, end.Add(3 * time.Minute), models.JobStatusFailed, end.Add(3 * time.Minute), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := models.Job{Failures: tt.failures, NextRun: tt.nextRun}
+			recordFailure(&job, end)
+			assert.Equal(t, tt.wantStatus, job.LastStatus)
+			assert.Equal(t, tt.wantNextRun, job.NextRun)
+			assert.Equal(t, tt.wantFailures, job.Failures)
+		})
+	}
+}
+
+func TestRunJobKeepsFailuresUntilSuccess(t *testing.T) {
+	repo := &fakeJobRepo{jobs: map[string]*models.Job{}}
+	s := NewScheduler(repo)
+	fail := true
+	require.NoError(t, s.Register("job", "@daily", time.Minute, func(ctx context.Context) error {
+		if fail {
+			return errors.New("broken")
+		}
+		return nil
+	}))
+	job := s.jobs["job"]
+	_, err := repo.EnsureJob(context.Background(), "job", job.spec, time.Now())
+	require.NoError(t, err)
+
+	run := func() models.Job {
+		stored, err := repo.AcquireLease(context.Background(), "job", s.owner, time.Now(), time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		s.runJob(context.Background(), job, *stored)
+		after := repo.get("job")
+		// Run again right away rather than waiting for the retry.
+		repo.jobs["job"].NextRun = time.Now()
+		return after
+	}
+
+	for i := 1; i <= MaxJobRetries; i++ {
+		stored := run()
+		assert.Equal(t, models.JobStatusFailed, stored.LastStatus)
+		assert.Equal(t, i, stored.Failures)
+		assert.Equal(t, "broken", stored.LastError)
+	}
+	stored := run()
+	assert.Equal(t, models.JobStatusExhausted, stored.LastStatus)
+	assert.Equal(t, MaxJobRetries+1, stored.Failures)
+	assert.WithinDuration(t, job.schedule.Next(time.Now()), stored.NextRun, time.Second)
+	assert.Empty(t, stored.LeaseOwner)
+
+	stored = run()
+	assert.Equal(t, models.JobStatusFailed, stored.LastStatus)
+	assert.Equal(t, MaxJobRetries+2, stored.Failures)
+
+	fail = false
+	stored = run()
+	assert.Equal(t, models.JobStatusSucceeded, stored.LastStatus)
+	assert.Zero(t, stored.Failures)
+	assert.Empty(t, stored.LastError)
+}
+
+func TestPollRunsEachJobOnce(t *testing.T) {
+	repo := &fakeJobRepo{jobs: map[string]*models.Job{}}
+	var mu sync.Mutex
+	runs := 0
+	release := make(chan struct{})
+	newScheduler := func() *Scheduler {
+		s := NewScheduler(repo)
+		require.NoError(t, s.Register("job", "@daily", time.Minute, func(ctx context.Context) error {
+			mu.Lock()
+			runs++
+			mu.Unlock()
+			<-release
+			return nil
+		}))
+		return s
+	}
+	first, second := newScheduler(), newScheduler()
+	_, err := repo.EnsureJob(context.Background(), "job", "@daily", time.Now().Add(-time.Second))
+	require.NoError(t, err)
+
+	first.poll(context.Background())
+	first.poll(context.Background())
+	second.poll(context.Background())
+	assert.Equal(t, first.owner, repo.get("job").LeaseOwner)
+	close(release)
+	waitIdle(t, first)
+	waitIdle(t, second)
+
+	assert.Equal(t, 1, runs)
+	stored := repo.get("job")
+	assert.Equal(t, models.JobStatusSucceeded, stored.LastStatus)
+	assert.True(t, stored.NextRun.After(time.Now()))
+
+	// Not due any more.
+	second.poll(context.Background())
+	waitIdle(t, second)
+	assert.Equal(t, 1, runs)
+}
+
+func TestPollTakesOverExpiredLeases(t *testing.T) {
+	tests := []struct {
+		name       string
+		job        models.Job
+		wantRunner bool
+	}{
+		{"due", models.Job{NextRun: time.Now().Add(-time.Second)}, true},
+		{"not due", models.Job{NextRun: time.Now().Add(time.Hour)}, false},
+		{"paused", models.Job{NextRun: time.Now().Add(-time.Second), Paused: true}, false},
+		{"paused but triggered", models.Job{NextRun: time.Now().Add(time.Hour), Paused: true, Triggered: true}, true},
+		{"leased elsewhere", models.Job{NextRun: time.Now().Add(-time.Second), LeaseOwner: "other", LeaseUntil: time.Now().Add(time.Minute)}, false},
+		{"lease expired", models.Job{NextRun: time.Now().Add(-time.Second), LeaseOwner: "other", LeaseUntil: time.Now().Add(-time.Second)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := tt.job
+			job.Name = "job"
+			repo := &fakeJobRepo{jobs: map[string]*models.Job{"job": &job}}
+			s := NewScheduler(repo)
+			ran := false
+			require.NoError(t, s.Register("job", "@daily", time.Minute, func(ctx context.Context) error {
+				ran = true
+				return nil
+			}))
+
+			s.poll(context.Background())
+			waitIdle(t, s)
+			assert.Equal(t, tt.wantRunner, ran)
+		})
+	}
+}
+
+func TestTriggerRunsInTheSchedulerContext(t *testing.T) {
+	repo := &fakeJobRepo{jobs: map[string]*models.Job{}}
+	s := NewScheduler(repo)
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	require.NoError(t, s.Register("job", "@daily", time.Minute, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return ctx.Err()
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, s.Start(ctx))
+
+	requestCtx, requestDone := context.WithCancel(context.Background())
+	_, err := s.Trigger(requestCtx, "job")
+	require.NoError(t, err)
+	requestDone()
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("triggered job did not run")
+	}
+	select {
+	case <-stopped:
+		t.Fatal("job stopped with the request")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case err := <-stopped:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("job did not stop with the scheduler")
+	}
+	waitIdle(t, s)
+}
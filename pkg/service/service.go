@@ -3,19 +3,32 @@ package service
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"time"
 
+	"github.com/Mario-Kamel/EKMS/pkg/bible"
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/coptic"
 	"github.com/Mario-Kamel/EKMS/pkg/models"
 	"github.com/Mario-Kamel/EKMS/pkg/repositories"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type ServiceService struct {
-	repo repositories.ServiceRepoInterface
+	repo      repositories.ServiceRepoInterface
+	points    *PointsService
+	curricula *CurriculumService
+	loc       *time.Location
 }
 
-func NewServiceService(repo repositories.ServiceRepoInterface) *ServiceService {
+// NewServiceService creates a ServiceService. Coptic dates of services are
+// taken from their date in loc.
+func NewServiceService(repo repositories.ServiceRepoInterface, points *PointsService, curricula *CurriculumService, loc *time.Location) *ServiceService {
 	return &ServiceService{
-		repo: repo,
+		repo:      repo,
+		points:    points,
+		curricula: curricula,
+		loc:       loc,
 	}
 }
 
@@ -24,6 +37,9 @@ func (s *ServiceService) GetAllServices(ctx context.Context) ([]models.Service,
 	if err != nil {
 		return nil, err
 	}
+	for i := range services {
+		s.setCopticDate(&services[i])
+	}
 	return services, nil
 }
 
@@ -32,30 +48,46 @@ func (s *ServiceService) GetServiceById(ctx context.Context, id string) (*models
 	if err != nil {
 		return nil, err
 	}
+	s.setCopticDate(service)
 	return service, nil
 }
 
 func (s *ServiceService) CreateService(ctx context.Context, service models.Service) (*models.Service, error) {
+	setBibleRefs(&service)
 	serv, err := s.repo.CreateService(ctx, service)
 	if err != nil {
 		return nil, err
 	}
+	s.setCopticDate(serv)
 	return serv, nil
 }
 
 func (s *ServiceService) UpdateService(ctx context.Context, id string, service models.Service) (*models.Service, error) {
+	setBibleRefs(&service)
 	serv, err := s.repo.UpdateService(ctx, id, service)
 	if err != nil {
 		return nil, err
 	}
+	s.setCopticDate(serv)
 	return serv, nil
 }
 
+// DeleteService deletes the service. A lesson planned for it carries over
+// to the next free service of its curriculum.
 func (s *ServiceService) DeleteService(ctx context.Context, id string) error {
-	err := s.repo.DeleteService(ctx, id)
+	serv, err := s.repo.GetServiceById(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = s.repo.DeleteService(ctx, id)
 	if err != nil {
 		return err
 	}
+	if !serv.CurriculumID.IsZero() {
+		if _, err := s.curricula.Reschedule(ctx, serv.CurriculumID.Hex()); err != nil {
+			fmt.Printf("Error while rescheduling curriculum: %v\n", err)
+		}
+	}
 	return nil
 }
 
@@ -69,6 +101,8 @@ func (s *ServiceService) AddAttendanceRecord(ctx context.Context, serviceID stri
 	if err != nil {
 		return nil, err
 	}
+	s.evaluatePoints(ctx, serv, ar.PersonID)
+	s.setCopticDate(serv)
 	return serv, nil
 }
 
@@ -82,6 +116,8 @@ func (s *ServiceService) EditAttendanceRecord(ctx context.Context, serviceID str
 	if err != nil {
 		return nil, err
 	}
+	s.evaluatePoints(ctx, serv, ar.PersonID)
+	s.setCopticDate(serv)
 	return serv, nil
 }
 
@@ -95,5 +131,92 @@ func (s *ServiceService) DeleteAttendanceRecord(ctx context.Context, serviceID s
 	if err != nil {
 		return nil, err
 	}
+	s.evaluatePoints(ctx, serv, ar.PersonID)
+	s.setCopticDate(serv)
 	return serv, nil
 }
+
+// evaluatePoints updates the points the person earned at the service. The
+// attendance change is already saved, so failures are only logged.
+func (s *ServiceService) evaluatePoints(ctx context.Context, serv *models.Service, personID primitive.ObjectID) {
+	if err := s.points.EvaluateAttendance(ctx, serv, personID); err != nil {
+		fmt.Printf("Error while evaluating attendance points: %v\n", err)
+	}
+}
+
+func (s *ServiceService) setCopticDate(serv *models.Service) {
+	if serv == nil || serv.Date.IsZero() {
+		return
+	}
+	d := coptic.FromGregorian(serv.Date.In(s.loc))
+	serv.CopticDate = &d
+}
+
+// SearchByReference returns the services between from and to reading any
+// verse of the references in query, e.g. "John 3".
+func (s *ServiceService) SearchByReference(ctx context.Context, query string, from, to time.Time) ([]models.Service, error) {
+	refs, err := bible.Parse(query)
+	if err != nil {
+		return nil, cerrors.NewBadRequestError("SearchByReference", "ServiceService", err)
+	}
+	if len(refs) == 0 {
+		return nil, cerrors.NewBadRequestError("SearchByReference", "ServiceService", fmt.Errorf("no reference given"))
+	}
+	services := []models.Service{}
+	err = s.repo.StreamServices(ctx, repositories.ServiceFilter{From: from, To: to, References: refs}, func(serv models.Service) error {
+		if referencesOverlap(serv.BibleRefs, refs) {
+			s.setCopticDate(&serv)
+			services = append(services, serv)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+// ReindexBibleRefs parses the BibleChapter of every service again, for
+// services saved before references were parsed or after the parser learned
+// new names. It returns how many services changed.
+func (s *ServiceService) ReindexBibleRefs(ctx context.Context) (int, error) {
+	changed := 0
+	err := s.repo.StreamServices(ctx, repositories.ServiceFilter{}, func(serv models.Service) error {
+		old := serv.BibleRefs
+		setBibleRefs(&serv)
+		if reflect.DeepEqual(old, serv.BibleRefs) {
+			return nil
+		}
+		changed++
+		return s.repo.SetBibleRefs(ctx, serv.ID, serv.BibleRefs)
+	})
+	return changed, err
+}
+
+// setBibleRefs parses the service's BibleChapter. Text that is not a
+// reference is kept as is, without references.
+func setBibleRefs(serv *models.Service) {
+	serv.BibleRefs = nil
+	if serv.BibleChapter == "" {
+		return
+	}
+	refs, err := bible.Parse(serv.BibleChapter)
+	if err != nil {
+		fmt.Printf("Bible chapter %q is not a reference: %v\n", serv.BibleChapter, err)
+		return
+	}
+	if len(refs) > 0 {
+		serv.BibleRefs = refs
+	}
+}
+
+func referencesOverlap(a, b []bible.Reference) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x.Overlaps(y) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,462 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/Mario-Kamel/EKMS/pkg/cerrors"
+	"github.com/Mario-Kamel/EKMS/pkg/models"
+	"github.com/Mario-Kamel/EKMS/pkg/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ConflictDoubleBooked = "double_booked"
+	ConflictUnavailable  = "unavailable"
+)
+
+// speakerHistoryWindow is how far back the planner counts talks when
+// spreading them fairly.
+const speakerHistoryWindow = 365 * 24 * time.Hour
+
+// speakerPlanHorizon is how far ahead the planner looks when no end is
+// given.
+const speakerPlanHorizon = 8 * 7 * 24 * time.Hour
+
+var SpeakerHistoryColumns = []string{"serviceId", "date", "subject", "bibleChapter", "groupId", "location"}
+
+// SpeakerTalk is a service a speaker spoke at.
+type SpeakerTalk struct {
+	ServiceID    primitive.ObjectID `json:"serviceId"`
+	Date         time.Time          `json:"date"`
+	Subject      string             `json:"subject"`
+	BibleChapter string             `json:"bibleChapter"`
+	GroupID      primitive.ObjectID `json:"groupId"`
+	Location     string             `json:"location"`
+}
+
+type SpeakerHistory struct {
+	Speaker  models.Speaker `json:"speaker"`
+	Talks    []SpeakerTalk  `json:"talks"`
+	Subjects []string       `json:"subjects"`
+	Chapters []string       `json:"chapters"`
+}
+
+// SpeakerConflict is a speaker booked at overlapping services, or while
+// unavailable.
+type SpeakerConflict struct {
+	SpeakerID primitive.ObjectID `json:"speakerId"`
+	Speaker   string             `json:"speaker"`
+	Kind      string             `json:"kind"`
+	Services  []SpeakerTalk      `json:"services"`
+}
+
+// SpeakerProposal is the speaker proposed for a service without one. A zero
+// SpeakerID means nobody is available.
+type SpeakerProposal struct {
+	ServiceID primitive.ObjectID `json:"serviceId"`
+	Date      time.Time          `json:"date"`
+	Subject   string             `json:"subject"`
+	GroupID   primitive.ObjectID `json:"groupId"`
+	SpeakerID primitive.ObjectID `json:"speakerId,omitempty"`
+	Speaker   string             `json:"speaker"`
+	Talks     int                `json:"talks"`
+}
+
+type SpeakerService struct {
+	repo        repositories.SpeakerRepoInterface
+	serviceRepo repositories.ServiceRepoInterface
+	personRepo  repositories.PersonRepoInterface
+}
+
+func NewSpeakerService(repo repositories.SpeakerRepoInterface, serviceRepo repositories.ServiceRepoInterface, personRepo repositories.PersonRepoInterface) *SpeakerService {
+	return &SpeakerService{
+		repo:        repo,
+		serviceRepo: serviceRepo,
+		personRepo:  personRepo,
+	}
+}
+
+func (s *SpeakerService) GetAllSpeakers(ctx context.Context) ([]models.Speaker, error) {
+	return s.repo.GetAllSpeakers(ctx)
+}
+
+func (s *SpeakerService) GetSpeakerById(ctx context.Context, id string) (*models.Speaker, error) {
+	return s.repo.GetSpeakerById(ctx, id)
+}
+
+func (s *SpeakerService) CreateSpeaker(ctx context.Context, speaker models.Speaker) (*models.Speaker, error) {
+	if err := s.validateSpeaker(ctx, "CreateSpeaker", &speaker); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateSpeaker(ctx, speaker)
+}
+
+// UpdateSpeaker replaces the speaker. Services keep the name they were given.
+func (s *SpeakerService) UpdateSpeaker(ctx context.Context, id string, speaker models.Speaker) (*models.Speaker, error) {
+	existing, err := s.repo.GetSpeakerById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	speaker.ID = existing.ID
+	if err := s.validateSpeaker(ctx, "UpdateSpeaker", &speaker); err != nil {
+		return nil, err
+	}
+	return s.repo.ReplaceSpeaker(ctx, speaker)
+}
+
+// DeleteSpeaker deletes the speaker. Services keep the speaker's name.
+func (s *SpeakerService) DeleteSpeaker(ctx context.Context, id string) error {
+	return s.repo.DeleteSpeaker(ctx, id)
+}
+
+// validateSpeaker checks the speaker's windows and takes the name of the
+// linked person when it has none.
+func (s *SpeakerService) validateSpeaker(ctx context.Context, method string, speaker *models.Speaker) error {
+	if !speaker.PersonID.IsZero() {
+		person, err := s.personRepo.GetPersonById(ctx, speaker.PersonID.Hex())
+		if err != nil {
+			return err
+		}
+		if speaker.Name == "" {
+			speaker.Name = person.Name
+		}
+	}
+	if speaker.Name == "" {
+		return cerrors.NewBadRequestError(method, "SpeakerService", fmt.Errorf("name is required"))
+	}
+	for _, w := range append(append([]models.SpeakerWindow{}, speaker.Available...), speaker.Unavailable...) {
+		if !w.From.IsZero() && !w.To.IsZero() && w.To.Before(w.From) {
+			return cerrors.NewBadRequestError(method, "SpeakerService", fmt.Errorf("window to %v is before from %v", w.To, w.From))
+		}
+		for _, d := range w.Weekdays {
+			if d < 0 || d > 6 {
+				return cerrors.NewBadRequestError(method, "SpeakerService", fmt.Errorf("weekday %d is not between 0 (Sunday) and 6", d))
+			}
+		}
+	}
+	return nil
+}
+
+// AssignSpeaker makes the speaker speak at the service. Unless force is
+// set, speakers who are unavailable or already speak at an overlapping
+// service are refused.
+func (s *SpeakerService) AssignSpeaker(ctx context.Context, serviceID, speakerID string, force bool) (*models.Service, error) {
+	serv, err := s.serviceRepo.GetServiceById(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	speaker, err := s.repo.GetSpeakerById(ctx, speakerID)
+	if err != nil {
+		return nil, err
+	}
+	if !force {
+		if !speaker.AvailableAt(serv.Date) {
+			return nil, cerrors.NewBadRequestError("AssignSpeaker", "SpeakerService", fmt.Errorf("%s is not available on %s", speaker.Name, serv.Date.Format(time.RFC3339)))
+		}
+		err := s.serviceRepo.StreamServices(ctx, repositories.ServiceFilter{
+			From:      serv.Date.Add(-serviceDuration),
+			To:        serv.Date.Add(serviceDuration),
+			SpeakerID: speaker.ID,
+		}, func(other models.Service) error {
+			if other.ID != serv.ID && overlaps(other.Date, serv.Date) {
+				return cerrors.NewBadRequestError("AssignSpeaker", "SpeakerService", fmt.Errorf("%s already speaks at service %s on %s", speaker.Name, other.ID.Hex(), other.Date.Format(time.RFC3339)))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := s.serviceRepo.SetSpeaker(ctx, serv.ID, speaker.ID, speaker.Name); err != nil {
+		return nil, err
+	}
+	serv.SpeakerID = speaker.ID
+	serv.Speaker = speaker.Name
+	return serv, nil
+}
+
+// UnassignSpeaker leaves the service without a speaker.
+func (s *SpeakerService) UnassignSpeaker(ctx context.Context, serviceID string) (*models.Service, error) {
+	serv, err := s.serviceRepo.GetServiceById(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.serviceRepo.SetSpeaker(ctx, serv.ID, primitive.NilObjectID, ""); err != nil {
+		return nil, err
+	}
+	serv.SpeakerID = primitive.NilObjectID
+	serv.Speaker = ""
+	return serv, nil
+}
+
+// Conflicts lists the speakers of the services between from and to who are
+// booked at overlapping services or while unavailable.
+func (s *SpeakerService) Conflicts(ctx context.Context, from, to time.Time) ([]SpeakerConflict, error) {
+	speakers, err := s.speakerIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	talks := map[primitive.ObjectID][]SpeakerTalk{}
+	err = s.serviceRepo.StreamServices(ctx, repositories.ServiceFilter{From: from, To: to}, func(serv models.Service) error {
+		if speaker := speakers.resolve(serv); speaker != nil {
+			talks[speaker.ID] = append(talks[speaker.ID], speakerTalk(serv))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts := []SpeakerConflict{}
+	for _, speaker := range speakers.all {
+		booked := talks[speaker.ID]
+		unavailable := []SpeakerTalk{}
+		for i, t := range booked {
+			if !speaker.AvailableAt(t.Date) {
+				unavailable = append(unavailable, t)
+			}
+			// Services are in date order, so overlapping ones are next to
+			// each other.
+			if i > 0 && overlaps(booked[i-1].Date, t.Date) {
+				conflicts = append(conflicts, SpeakerConflict{SpeakerID: speaker.ID, Speaker: speaker.Name, Kind: ConflictDoubleBooked, Services: []SpeakerTalk{booked[i-1], t}})
+			}
+		}
+		if len(unavailable) > 0 {
+			conflicts = append(conflicts, SpeakerConflict{SpeakerID: speaker.ID, Speaker: speaker.Name, Kind: ConflictUnavailable, Services: unavailable})
+		}
+	}
+	return conflicts, nil
+}
+
+// PlanSpeakers proposes speakers for the services between from and to that
+// have none, optionally only those of a group. from defaults to now and to
+// to eight weeks after from. Each goes to the available,
+// not otherwise booked speaker with the fewest talks in the past year,
+// then the one who spoke least recently. The speaker of the group's
+// previous service is only proposed when nobody else is available.
+func (s *SpeakerService) PlanSpeakers(ctx context.Context, from, to time.Time, groupID string) ([]SpeakerProposal, error) {
+	var group primitive.ObjectID
+	if groupID != "" {
+		oid, err := primitive.ObjectIDFromHex(groupID)
+		if err != nil {
+			return nil, cerrors.NewInvalidIDError("PlanSpeakers", "SpeakerService", err)
+		}
+		group = oid
+	}
+	if from.IsZero() {
+		from = time.Now()
+	}
+	if to.IsZero() {
+		to = from.Add(speakerPlanHorizon)
+	}
+	speakers, err := s.speakerIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	services := []models.Service{}
+	err = s.serviceRepo.StreamServices(ctx, repositories.ServiceFilter{From: from.Add(-speakerHistoryWindow), To: to}, func(serv models.Service) error {
+		services = append(services, serv)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	talks := map[primitive.ObjectID]int{}
+	last := map[primitive.ObjectID]time.Time{}
+	booked := map[primitive.ObjectID][]time.Time{}
+	for _, serv := range services {
+		if speaker := speakers.resolve(serv); speaker != nil {
+			booked[speaker.ID] = append(booked[speaker.ID], serv.Date)
+		}
+	}
+
+	proposals := []SpeakerProposal{}
+	previous := map[primitive.ObjectID]primitive.ObjectID{}
+	for _, serv := range services {
+		if speaker := speakers.resolve(serv); speaker != nil {
+			talks[speaker.ID]++
+			last[speaker.ID] = serv.Date
+			previous[serv.GroupID] = speaker.ID
+			continue
+		}
+		if serv.Speaker != "" || serv.Date.Before(from) || (!group.IsZero() && serv.GroupID != group) {
+			continue
+		}
+
+		var best, fallback *models.Speaker
+		for i := range speakers.all {
+			speaker := &speakers.all[i]
+			if speaker.Inactive || !speaker.AvailableAt(serv.Date) || bookedAt(booked[speaker.ID], serv.Date) {
+				continue
+			}
+			if speaker.ID == previous[serv.GroupID] {
+				fallback = speaker
+				continue
+			}
+			if best == nil || talks[speaker.ID] < talks[best.ID] ||
+				(talks[speaker.ID] == talks[best.ID] && last[speaker.ID].Before(last[best.ID])) {
+				best = speaker
+			}
+		}
+		if best == nil {
+			best = fallback
+		}
+		proposal := SpeakerProposal{ServiceID: serv.ID, Date: serv.Date, Subject: serv.Subject, GroupID: serv.GroupID}
+		if best != nil {
+			proposal.SpeakerID = best.ID
+			proposal.Speaker = best.Name
+			proposal.Talks = talks[best.ID]
+			talks[best.ID]++
+			last[best.ID] = serv.Date
+			booked[best.ID] = append(booked[best.ID], serv.Date)
+			previous[serv.GroupID] = best.ID
+		}
+		proposals = append(proposals, proposal)
+	}
+	return proposals, nil
+}
+
+// ApplyPlan assigns the speakers PlanSpeakers proposes and returns the
+// proposals.
+func (s *SpeakerService) ApplyPlan(ctx context.Context, from, to time.Time, groupID string) ([]SpeakerProposal, error) {
+	proposals, err := s.PlanSpeakers(ctx, from, to, groupID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range proposals {
+		if p.SpeakerID.IsZero() {
+			continue
+		}
+		if err := s.serviceRepo.SetSpeaker(ctx, p.ServiceID, p.SpeakerID, p.Speaker); err != nil {
+			return nil, err
+		}
+	}
+	return proposals, nil
+}
+
+// History lists the services the speaker spoke at between from and to,
+// including those naming the speaker without linking it, with the subjects
+// and chapters covered.
+func (s *SpeakerService) History(ctx context.Context, id string, from, to time.Time) (*SpeakerHistory, error) {
+	speaker, err := s.repo.GetSpeakerById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	index := newSpeakerIndex([]models.Speaker{*speaker})
+	history := &SpeakerHistory{Speaker: *speaker, Talks: []SpeakerTalk{}, Subjects: []string{}, Chapters: []string{}}
+	err = s.serviceRepo.StreamServices(ctx, repositories.ServiceFilter{From: from, To: to}, func(serv models.Service) error {
+		if index.resolve(serv) == nil {
+			return nil
+		}
+		history.Talks = append(history.Talks, speakerTalk(serv))
+		if serv.Subject != "" && !containsString(history.Subjects, serv.Subject) {
+			history.Subjects = append(history.Subjects, serv.Subject)
+		}
+		if serv.BibleChapter != "" && !containsString(history.Chapters, serv.BibleChapter) {
+			history.Chapters = append(history.Chapters, serv.BibleChapter)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(history.Subjects)
+	sort.Strings(history.Chapters)
+	return history, nil
+}
+
+// ExportHistory writes the speaker's history in format.
+func (s *SpeakerService) ExportHistory(ctx context.Context, w io.Writer, format, id string, from, to time.Time) error {
+	history, err := s.History(ctx, id, from, to)
+	if err != nil {
+		return err
+	}
+	out, err := newRecordWriter("ExportHistory", w, format, "History", SpeakerHistoryColumns)
+	if err != nil {
+		return err
+	}
+	for _, t := range history.Talks {
+		err := out.Write(map[string]string{
+			"serviceId":    t.ServiceID.Hex(),
+			"date":         formatExportDate(t.Date),
+			"subject":      t.Subject,
+			"bibleChapter": t.BibleChapter,
+			"groupId":      hexOrEmpty(t.GroupID.IsZero(), t.GroupID.Hex()),
+			"location":     t.Location,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return out.Close()
+}
+
+func (s *SpeakerService) speakerIndex(ctx context.Context) (*speakerIndex, error) {
+	speakers, err := s.repo.GetAllSpeakers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newSpeakerIndex(speakers), nil
+}
+
+// speakerIndex finds the speaker of a service by its SpeakerID or, for
+// services predating speakers, by the normalized free-text name.
+type speakerIndex struct {
+	all    []models.Speaker
+	byID   map[primitive.ObjectID]*models.Speaker
+	byName map[string]*models.Speaker
+}
+
+func newSpeakerIndex(speakers []models.Speaker) *speakerIndex {
+	index := &speakerIndex{
+		all:    speakers,
+		byID:   map[primitive.ObjectID]*models.Speaker{},
+		byName: map[string]*models.Speaker{},
+	}
+	for i := range speakers {
+		index.byID[speakers[i].ID] = &speakers[i]
+		index.byName[NormalizeName(speakers[i].Name)] = &speakers[i]
+	}
+	return index
+}
+
+func (i *speakerIndex) resolve(serv models.Service) *models.Speaker {
+	if !serv.SpeakerID.IsZero() {
+		return i.byID[serv.SpeakerID]
+	}
+	if serv.Speaker == "" {
+		return nil
+	}
+	return i.byName[NormalizeName(serv.Speaker)]
+}
+
+func speakerTalk(serv models.Service) SpeakerTalk {
+	return SpeakerTalk{
+		ServiceID:    serv.ID,
+		Date:         serv.Date,
+		Subject:      serv.Subject,
+		BibleChapter: serv.BibleChapter,
+		GroupID:      serv.GroupID,
+		Location:     serv.Location,
+	}
+}
+
+// overlaps reports whether services starting at a and b overlap.
+func overlaps(a, b time.Time) bool {
+	d := a.Sub(b)
+	return d < serviceDuration && d > -serviceDuration
+}
+
+func bookedAt(dates []time.Time, t time.Time) bool {
+	for _, d := range dates {
+		if overlaps(d, t) {
+			return true
+		}
+	}
+	return false
+}
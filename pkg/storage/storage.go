@@ -0,0 +1,126 @@
+// Package storage keeps the contents of uploaded files, either in MongoDB
+// GridFS or in a directory on the local filesystem.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrNotFound is returned when no file is stored under an id.
+var ErrNotFound = errors.New("file not found")
+
+// Store stores file contents by id.
+type Store interface {
+	Save(ctx context.Context, id primitive.ObjectID, name string, r io.Reader) error
+	Open(ctx context.Context, id primitive.ObjectID) (io.ReadCloser, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// GridFSStore stores files in a GridFS bucket.
+type GridFSStore struct {
+	bucket *gridfs.Bucket
+}
+
+func NewGridFSStore(db *mongo.Database, bucket string) (*GridFSStore, error) {
+	b, err := gridfs.NewBucket(db, options.GridFSBucket().SetName(bucket))
+	if err != nil {
+		return nil, err
+	}
+	return &GridFSStore{bucket: b}, nil
+}
+
+func (s *GridFSStore) Save(ctx context.Context, id primitive.ObjectID, name string, r io.Reader) error {
+	stream, err := s.bucket.OpenUploadStreamWithID(id, name)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetWriteDeadline(deadline)
+	}
+	if _, err := io.Copy(stream, r); err != nil {
+		stream.Abort()
+		return err
+	}
+	return stream.Close()
+}
+
+func (s *GridFSStore) Open(ctx context.Context, id primitive.ObjectID) (io.ReadCloser, error) {
+	stream, err := s.bucket.OpenDownloadStream(id)
+	if err == gridfs.ErrFileNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		stream.SetReadDeadline(deadline)
+	}
+	return stream, nil
+}
+
+func (s *GridFSStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	err := s.bucket.DeleteContext(ctx, id)
+	if err == gridfs.ErrFileNotFound {
+		return nil
+	}
+	return err
+}
+
+// FileStore stores every file in its own file in a directory.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save writes to a temporary file first so a failed upload never leaves a
+// partial file behind.
+func (s *FileStore) Save(ctx context.Context, id primitive.ObjectID, name string, r io.Reader) error {
+	tmp, err := os.CreateTemp(s.dir, id.Hex()+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(id))
+}
+
+func (s *FileStore) Open(ctx context.Context, id primitive.ObjectID) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *FileStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStore) path(id primitive.ObjectID) string {
+	return filepath.Join(s.dir, id.Hex())
+}
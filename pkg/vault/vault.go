@@ -0,0 +1,175 @@
+// Package vault encrypts values stored at rest with AES-256-GCM.
+//
+// Sealed values carry the ID of the key they were sealed with, so keys can
+// be rotated: new values are sealed with the current key and older values
+// open as long as their key stays in the keyring.
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix marks sealed values, "v1:<key id>:<base64 nonce and ciphertext>".
+const prefix = "v1:"
+
+var ErrUnknownKey = errors.New("vault: value was sealed with an unknown key")
+
+type Keyring struct {
+	current string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewKeyring creates a keyring sealing with the key current. keys maps key
+// IDs to 32-byte keys.
+func NewKeyring(current string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("vault: current key %q is not in the keyring", current)
+	}
+	k := &Keyring{current: current, aeads: map[string]cipher.AEAD{}}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("vault: invalid key id %q", id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("vault: key %q is %d bytes, want 32", id, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.aeads[id] = aead
+	}
+	return k, nil
+}
+
+// ParseKeyring parses "id:base64key,id:base64key,...". The first key is the
+// current one.
+func ParseKeyring(s string) (*Keyring, error) {
+	keys := map[string][]byte{}
+	current := ""
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("vault: key %q has no id", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("vault: key %q: %v", id, err)
+		}
+		if _, dup := keys[id]; dup {
+			return nil, fmt.Errorf("vault: duplicate key id %q", id)
+		}
+		keys[id] = key
+		if current == "" {
+			current = id
+		}
+	}
+	if current == "" {
+		return nil, errors.New("vault: no keys")
+	}
+	return NewKeyring(current, keys)
+}
+
+// Current returns the ID of the key new values are sealed with.
+func (k *Keyring) Current() string {
+	return k.current
+}
+
+// Seal encrypts plaintext with the current key. The empty string stays
+// empty.
+func (k *Keyring) Seal(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	aead := k.aeads[k.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(k.current))
+	return prefix + k.current + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value sealed by Seal.
+func (k *Keyring) Open(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	id, data, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok || !IsSealed(value) {
+		return "", errors.New("vault: value is not sealed")
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return "", ErrUnknownKey
+	}
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("vault: sealed value is too short")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// IsSealed reports whether value looks like a value sealed by Seal.
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// KeyID returns the ID of the key value was sealed with, or "" if it is not
+// sealed.
+func KeyID(value string) string {
+	if !IsSealed(value) {
+		return ""
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	return id
+}
+
+// Index computes blind indexes, keyed hashes of values that can be looked up
+// by equality without storing the values. Its key must stay the same for as
+// long as the indexes are used.
+type Index struct {
+	key []byte
+}
+
+// NewIndex creates an Index with a key of at least 32 bytes.
+func NewIndex(key []byte) (*Index, error) {
+	if len(key) < 32 {
+		return nil, fmt.Errorf("vault: index key is %d bytes, want at least 32", len(key))
+	}
+	return &Index{key: key}, nil
+}
+
+// Of returns the blind index of value. The empty string stays empty.
+func (i *Index) Of(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, i.key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,167 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func key(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func encodedKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(key(b))
+}
+
+func TestSealOpen(t *testing.T) {
+	k, err := NewKeyring("1", map[string][]byte{"1": key(1)})
+	require.NoError(t, err)
+
+	tests := []string{"", "+201001234567", "مينا جرجس", strings.Repeat("long ", 1000), "v1:not sealed"}
+	for _, plaintext := range tests {
+		sealed, err := k.Seal(plaintext)
+		require.NoError(t, err)
+		if plaintext == "" {
+			assert.Empty(t, sealed)
+		} else {
+			assert.True(t, IsSealed(sealed))
+			assert.Equal(t, "1", KeyID(sealed))
+			assert.NotContains(t, sealed, plaintext)
+		}
+		opened, err := k.Open(sealed)
+		require.NoError(t, err)
+		assert.Equal(t, plaintext, opened)
+	}
+}
+
+func TestSealIsRandomized(t *testing.T) {
+	k, err := NewKeyring("1", map[string][]byte{"1": key(1)})
+	require.NoError(t, err)
+	a, err := k.Seal("same")
+	require.NoError(t, err)
+	b, err := k.Seal("same")
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b)
+}
+
+func TestRotation(t *testing.T) {
+	old, err := ParseKeyring("1:" + encodedKey(1))
+	require.NoError(t, err)
+	sealedOld, err := old.Seal("secret")
+	require.NoError(t, err)
+
+	rotated, err := ParseKeyring("2:" + encodedKey(2) + ", 1:" + encodedKey(1))
+	require.NoError(t, err)
+	assert.Equal(t, "2", rotated.Current())
+
+	opened, err := rotated.Open(sealedOld)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", opened)
+
+	resealed, err := rotated.Seal(opened)
+	require.NoError(t, err)
+	assert.Equal(t, "2", KeyID(resealed))
+
+	// Once the old key is dropped, only the resealed value opens.
+	dropped, err := ParseKeyring("2:" + encodedKey(2))
+	require.NoError(t, err)
+	_, err = dropped.Open(sealedOld)
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+	opened, err = dropped.Open(resealed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", opened)
+}
+
+func TestOpenRejects(t *testing.T) {
+	k, err := NewKeyring("1", map[string][]byte{"1": key(1), "2": key(1)})
+	require.NoError(t, err)
+	sealed, err := k.Seal("secret")
+	require.NoError(t, err)
+	data := strings.TrimPrefix(sealed, "v1:1:")
+	raw, err := base64.StdEncoding.DecodeString(data)
+	require.NoError(t, err)
+	raw[len(raw)-1] ^= 1
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"plaintext", "secret"},
+		{"no key id", "v1:" + data},
+		{"unknown key", "v1:3:" + data},
+		{"tampered", "v1:1:" + base64.StdEncoding.EncodeToString(raw)},
+		// The key ID is authenticated, so a value cannot be moved to another
+		// key even when the keys are the same.
+		{"relabeled", "v1:2:" + data},
+		{"not base64", "v1:1:%%%"},
+		{"too short", "v1:1:" + base64.StdEncoding.EncodeToString([]byte{1, 2})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := k.Open(tt.value)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseKeyringRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		keys string
+	}{
+		{"empty", ""},
+		{"only separators", " , "},
+		{"no id", encodedKey(1)},
+		{"empty id", ":" + encodedKey(1)},
+		{"not base64", "1:%%%"},
+		{"short key", "1:" + base64.StdEncoding.EncodeToString(key(1)[:16])},
+		{"duplicate id", "1:" + encodedKey(1) + ",1:" + encodedKey(2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseKeyring(tt.keys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewKeyringRejectsMissingCurrent(t *testing.T) {
+	_, err := NewKeyring("2", map[string][]byte{"1": key(1)})
+	assert.Error(t, err)
+}
+
+func TestKeyID(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"plain", ""},
+		{"v1:2024:abc", "2024"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, KeyID(tt.value), tt.value)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	_, err := NewIndex(key(1)[:31])
+	assert.Error(t, err)
+
+	a, err := NewIndex(key(1))
+	require.NoError(t, err)
+	b, err := NewIndex(key(2))
+	require.NoError(t, err)
+
+	assert.Empty(t, a.Of(""))
+	assert.Equal(t, a.Of("+201001234567"), a.Of("+201001234567"))
+	assert.NotEqual(t, a.Of("+201001234567"), a.Of("+201001234568"))
+	assert.NotEqual(t, a.Of("+201001234567"), b.Of("+201001234567"))
+	assert.Len(t, a.Of("x"), 64)
+}
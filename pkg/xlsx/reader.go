@@ -0,0 +1,343 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Sheets have at most MaxRows rows and MaxColumns columns, as in Excel.
+// References past them are rejected rather than padded with empty cells.
+const (
+	MaxRows    = 1048576
+	MaxColumns = 16384
+)
+
+// Reader reads the rows of the first worksheet of an XLSX workbook one at a
+// time, the same way csv.Reader does for CSV files. Only the shared strings
+// table is loaded up front; the sheet itself is decoded as a token stream.
+type Reader struct {
+	zr      *zip.Reader
+	sheet   io.ReadCloser
+	dec     *xml.Decoder
+	strings []string
+	nextRow int
+	pending []string
+	pendRow int
+}
+
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		fmt.Printf("Error while opening xlsx archive: %v\n", err)
+		return nil, err
+	}
+
+	reader := &Reader{zr: zr, nextRow: 1}
+
+	reader.strings, err = reader.readSharedStrings()
+	if err != nil {
+		fmt.Printf("Error while reading shared strings: %v\n", err)
+		return nil, err
+	}
+
+	sheetPath, err := reader.firstSheetPath()
+	if err != nil {
+		fmt.Printf("Error while locating first sheet: %v\n", err)
+		return nil, err
+	}
+
+	f, err := reader.open(sheetPath)
+	if err != nil {
+		fmt.Printf("Error while opening sheet %v: %v\n", sheetPath, err)
+		return nil, err
+	}
+	reader.sheet = f
+	reader.dec = xml.NewDecoder(f)
+
+	return reader, nil
+}
+
+// Read returns the next row of the sheet. Empty rows that are missing from
+// the sheet XML are returned as nil slices so row numbers stay aligned with
+// what the user sees in their spreadsheet. It returns io.EOF after the last row.
+func (r *Reader) Read() ([]string, error) {
+	if r.pending != nil {
+		if r.nextRow < r.pendRow {
+			r.nextRow++
+			return nil, nil
+		}
+		row := r.pending
+		r.pending = nil
+		r.nextRow++
+		return row, nil
+	}
+
+	for {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		rowNum := r.nextRow
+		if v := attr(start, "r"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil {
+				rowNum = n
+			}
+		}
+		if rowNum < r.nextRow {
+			return nil, fmt.Errorf("xlsx: row %d comes after row %d", rowNum, r.nextRow-1)
+		}
+		if rowNum > MaxRows {
+			return nil, fmt.Errorf("xlsx: row %d is past the last row %d", rowNum, MaxRows)
+		}
+
+		row, err := r.readRow()
+		if err != nil {
+			return nil, err
+		}
+
+		if rowNum > r.nextRow {
+			r.pending = row
+			r.pendRow = rowNum
+			r.nextRow++
+			return nil, nil
+		}
+		r.nextRow++
+		return row, nil
+	}
+}
+
+func (r *Reader) Close() error {
+	if r.sheet == nil {
+		return nil
+	}
+	return r.sheet.Close()
+}
+
+func (r *Reader) readRow() ([]string, error) {
+	row := []string{}
+	for {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "c" {
+				continue
+			}
+			col := len(row)
+			if ref := attr(t, "r"); ref != "" {
+				c, err := columnIndex(ref)
+				if err != nil {
+					return nil, err
+				}
+				col = c
+			}
+			if col >= MaxColumns {
+				return nil, fmt.Errorf("xlsx: more than %d columns", MaxColumns)
+			}
+			if col < len(row) {
+				return nil, fmt.Errorf("xlsx: cell %s comes after column %s", attr(t, "r"), columnName(len(row)-1))
+			}
+			value, err := r.readCell(attr(t, "t"))
+			if err != nil {
+				return nil, err
+			}
+			for len(row) < col {
+				row = append(row, "")
+			}
+			row = append(row, value)
+		case xml.EndElement:
+			if t.Name.Local == "row" {
+				return row, nil
+			}
+		}
+	}
+}
+
+func (r *Reader) readCell(cellType string) (string, error) {
+	var value strings.Builder
+	inValue := false
+	for {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "v" || t.Name.Local == "t" {
+				inValue = true
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				return r.cellValue(cellType, value.String())
+			}
+		}
+	}
+}
+
+func (r *Reader) cellValue(cellType, raw string) (string, error) {
+	switch cellType {
+	case "s":
+		idx, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || idx < 0 || idx >= len(r.strings) {
+			return "", fmt.Errorf("xlsx: invalid shared string index %q", raw)
+		}
+		return r.strings[idx], nil
+	case "b":
+		if raw == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	default:
+		return raw, nil
+	}
+}
+
+func (r *Reader) readSharedStrings() ([]string, error) {
+	f, err := r.open("xl/sharedStrings.xml")
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	table := []string{}
+	dec := xml.NewDecoder(f)
+	var current *strings.Builder
+	inText := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return table, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				current = &strings.Builder{}
+			case "t":
+				inText = true
+			}
+		case xml.CharData:
+			if inText && current != nil {
+				current.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "si":
+				table = append(table, current.String())
+				current = nil
+			}
+		}
+	}
+}
+
+func (r *Reader) firstSheetPath() (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+
+	if err := r.decode("xl/workbook.xml", &workbook); err != nil || len(workbook.Sheets) == 0 {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	if err := r.decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].ID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+
+	return "", errors.New("xlsx: workbook has no readable sheet")
+}
+
+var errNotFound = errors.New("xlsx: file not found in archive")
+
+func (r *Reader) open(name string) (io.ReadCloser, error) {
+	for _, f := range r.zr.File {
+		if f.Name == name {
+			return f.Open()
+		}
+	}
+	return nil, errNotFound
+}
+
+func (r *Reader) decode(name string, v interface{}) error {
+	f, err := r.open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return xml.NewDecoder(f).Decode(v)
+}
+
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// columnIndex converts a cell reference such as "C12" to a zero based column
+// index (2 for "C12"). Columns past XFD, the last of MaxColumns, are
+// rejected.
+func columnIndex(ref string) (int, error) {
+	col := 0
+	n := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+		n++
+		if col > MaxColumns {
+			return 0, fmt.Errorf("xlsx: cell reference %q is past column XFD", ref)
+		}
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("xlsx: invalid cell reference %q", ref)
+	}
+	return col - 1, nil
+}
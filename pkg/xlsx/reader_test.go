@@ -0,0 +1,194 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSharedStrings = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="3" uniqueCount="3"><si><t>Name</t></si><si><t>مينا</t></si><si><r><t>Rich </t></r><r><t>text</t></r></si></sst>`
+
+// workbook builds an XLSX archive from a sheet's sheetData content.
+func workbook(t *testing.T, sheetData string) *bytes.Reader {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"[Content_Types].xml":        contentTypesXML,
+		"_rels/.rels":                rootRelsXML,
+		"xl/workbook.xml":            workbookXML("Sheet1"),
+		"xl/_rels/workbook.xml.rels": workbookRelsXML,
+		"xl/sharedStrings.xml":       testSharedStrings,
+		"xl/worksheets/sheet1.xml":   sheetHeaderXML + sheetData + sheetFooterXML,
+	}
+	for name, content := range parts {
+		f, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = io.WriteString(f, content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func readAll(t *testing.T, r *bytes.Reader) ([][]string, error) {
+	reader, err := NewReader(r, r.Size())
+	require.NoError(t, err)
+	defer reader.Close()
+	rows := [][]string{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return rows, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name  string
+		sheet string
+		want  [][]string
+	}{
+		{
+			name:  "shared and inline strings",
+			sheet: `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="inlineStr"><is><t>Phone</t></is></c></row><row r="2"><c r="A2" t="s"><v>1</v></c><c r="B2"><v>201001234567</v></c></row>`,
+			want:  [][]string{{"Name", "Phone"}, {"مينا", "201001234567"}},
+		},
+		{
+			name:  "rich text",
+			sheet: `<row r="1"><c r="A1" t="s"><v>2</v></c></row>`,
+			want:  [][]string{{"Rich text"}},
+		},
+		{
+			name:  "booleans",
+			sheet: `<row r="1"><c r="A1" t="b"><v>1</v></c><c r="B1" t="b"><v>0</v></c></row>`,
+			want:  [][]string{{"TRUE", "FALSE"}},
+		},
+		{
+			name:  "skipped cells are empty",
+			sheet: `<row r="1"><c r="A1"><v>1</v></c><c r="D1"><v>4</v></c></row>`,
+			want:  [][]string{{"1", "", "", "4"}},
+		},
+		{
+			name:  "skipped rows are nil",
+			sheet: `<row r="1"><c r="A1"><v>1</v></c></row><row r="4"><c r="A4"><v>4</v></c></row>`,
+			want:  [][]string{{"1"}, nil, nil, {"4"}},
+		},
+		{
+			name:  "cells and rows without references",
+			sheet: `<row><c><v>1</v></c><c><v>2</v></c></row><row><c><v>3</v></c></row>`,
+			want:  [][]string{{"1", "2"}, {"3"}},
+		},
+		{
+			name:  "last column",
+			sheet: `<row r="1"><c r="XFD1"><v>x</v></c></row>`,
+			want:  [][]string{append(make([]string, MaxColumns-1), "x")},
+		},
+		{
+			name:  "empty sheet",
+			sheet: ``,
+			want:  [][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readAll(t, workbook(t, tt.sheet))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rows)
+		})
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		sheet string
+	}{
+		{"column past XFD", `<row r="1"><c r="XFE1"><v>x</v></c></row>`},
+		{"very long column", `<row r="1"><c r="AAAAAAAAAAAAAAAAAAAAAAAA1"><v>x</v></c></row>`},
+		{"reference without column", `<row r="1"><c r="12"><v>x</v></c></row>`},
+		{"row past the last", `<row r="1048577"><c r="A1048577"><v>x</v></c></row>`},
+		{"rows out of order", `<row r="3"><c r="A3"><v>3</v></c></row><row r="2"><c r="A2"><v>2</v></c></row>`},
+		{"repeated row", `<row r="2"><c r="A2"><v>2</v></c></row><row r="2"><c r="A2"><v>2</v></c></row>`},
+		{"cells out of order", `<row r="1"><c r="C1"><v>3</v></c><c r="A1"><v>1</v></c></row>`},
+		{"repeated cell", `<row r="1"><c r="A1"><v>1</v></c><c r="A1"><v>1</v></c></row>`},
+		{"shared string out of range", `<row r="1"><c r="A1" t="s"><v>7</v></c></row>`},
+		{"shared string not a number", `<row r="1"><c r="A1" t="s"><v>x</v></c></row>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readAll(t, workbook(t, tt.sheet))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	rows := [][]string{
+		{"Name", "Phone", "Notes"},
+		{"مينا", "+20 100 123 4567", `<b> & "quoted"`},
+		{"", "", "  spaced  "},
+		{"Only"},
+	}
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, "Persons & more")
+	require.NoError(t, err)
+	for _, row := range rows {
+		require.NoError(t, w.Write(row))
+	}
+	require.NoError(t, w.Close())
+
+	got, err := readAll(t, bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"Name", "Phone", "Notes"},
+		{"مينا", "+20 100 123 4567", `<b> & "quoted"`},
+		{"", "", "  spaced  "},
+		{"Only"},
+	}, got)
+}
+
+func TestColumnIndex(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    int
+		wantErr bool
+	}{
+		{"A1", 0, false},
+		{"Z9", 25, false},
+		{"AA1", 26, false},
+		{"AB12", 27, false},
+		{"XFD1048576", MaxColumns - 1, false},
+		{"XFE1", 0, true},
+		{"1", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := columnIndex(tt.ref)
+		if tt.wantErr {
+			assert.Error(t, err, tt.ref)
+			continue
+		}
+		require.NoError(t, err, tt.ref)
+		assert.Equal(t, tt.want, got, tt.ref)
+		assert.Equal(t, tt.ref[:len(tt.ref)-len(trimColumn(tt.ref))], columnName(got), tt.ref)
+	}
+}
+
+func trimColumn(ref string) string {
+	for i, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			return ref[i:]
+		}
+	}
+	return ""
+}